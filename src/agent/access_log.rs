@@ -0,0 +1,305 @@
+//! Read-access log for sensitive buildings.
+//!
+//! Writes are already audited by git history; some sites also need a record of who
+//! *viewed* what. When `.arxos/access_log.yaml` enables it, every audited read is
+//! appended as a JSON line to `.arxos/access.log`:
+//!
+//! ```yaml
+//! enabled: true
+//! retention_days: 365
+//! ```
+//!
+//! Logging is best-effort: the agent writes entries off the request path and a
+//! failed write is logged, never surfaced to the reader. Entries older than the
+//! retention window are dropped by [`AccessLog::prune`], which the agent runs every
+//! [`PRUNE_INTERVAL_SECS`]. Appends and prunes are serialized within the process and
+//! a prune replaces the file atomically, so a concurrent read is never lost.
+
+use std::io::Write;
+use std::path::{Path, PathBuf};
+use std::sync::Mutex;
+
+use anyhow::{Context, Result};
+use chrono::{DateTime, Duration, Utc};
+use serde::{Deserialize, Serialize};
+
+/// Project file enabling read auditing.
+pub const ACCESS_LOG_CONFIG: &str = ".arxos/access_log.yaml";
+/// JSON-lines log of audited reads.
+pub const ACCESS_LOG_FILE: &str = ".arxos/access.log";
+/// Retention when the config does not set one.
+pub const DEFAULT_RETENTION_DAYS: u32 = 365;
+/// How often the agent prunes expired entries.
+pub const PRUNE_INTERVAL_SECS: u64 = 60 * 60;
+
+/// Serializes appends and prunes of log files within the process.
+static LOG_LOCK: Mutex<()> = Mutex::new(());
+
+fn default_retention_days() -> u32 {
+    DEFAULT_RETENTION_DAYS
+}
+
+#[derive(Debug, Clone, PartialEq, Serialize, Deserialize)]
+pub struct AccessLogConfig {
+    #[serde(default)]
+    pub enabled: bool,
+    #[serde(default = "default_retention_days")]
+    pub retention_days: u32,
+}
+
+impl Default for AccessLogConfig {
+    fn default() -> Self {
+        Self {
+            enabled: false,
+            retention_days: DEFAULT_RETENTION_DAYS,
+        }
+    }
+}
+
+impl AccessLogConfig {
+    /// Load the config for `repo_root`; a missing file leaves auditing off.
+    pub fn load(repo_root: &Path) -> Result<Self> {
+        let path = repo_root.join(ACCESS_LOG_CONFIG);
+        if !path.exists() {
+            return Ok(Self::default());
+        }
+        let content = std::fs::read_to_string(&path)
+            .with_context(|| format!("Failed to read {}", path.display()))?;
+        serde_yaml::from_str(&content)
+            .with_context(|| format!("Failed to parse {}", path.display()))
+    }
+}
+
+/// One audited read.
+#[derive(Debug, Clone, PartialEq, Serialize, Deserialize)]
+pub struct AccessEntry {
+    pub timestamp: DateTime<Utc>,
+    /// `root` for the agent token, `service:<token id>` for a service token.
+    pub actor: String,
+    /// What was read, e.g. `object.history` or `floor.as_of`.
+    pub action: String,
+    /// Object id or floor level.
+    pub object: String,
+}
+
+/// Filter for [`AccessLog::query`].
+#[derive(Debug, Clone, Default, Deserialize)]
+pub struct AccessQuery {
+    pub actor: Option<String>,
+    pub object: Option<String>,
+    pub since: Option<DateTime<Utc>>,
+    pub limit: Option<usize>,
+}
+
+/// File-backed access log rooted at a repository.
+pub struct AccessLog {
+    path: PathBuf,
+    config: AccessLogConfig,
+}
+
+impl AccessLog {
+    pub fn open(repo_root: &Path) -> Result<Self> {
+        Ok(Self {
+            path: repo_root.join(ACCESS_LOG_FILE),
+            config: AccessLogConfig::load(repo_root)?,
+        })
+    }
+
+    pub fn is_enabled(&self) -> bool {
+        self.config.enabled
+    }
+
+    /// Append `entry`; a no-op when auditing is off.
+    pub fn record(&self, entry: &AccessEntry) -> Result<()> {
+        if !self.config.enabled {
+            return Ok(());
+        }
+        if let Some(parent) = self.path.parent() {
+            std::fs::create_dir_all(parent)?;
+        }
+        let _guard = LOG_LOCK.lock().unwrap_or_else(|e| e.into_inner());
+        let mut file = std::fs::OpenOptions::new()
+            .create(true)
+            .append(true)
+            .open(&self.path)
+            .with_context(|| format!("Failed to open {}", self.path.display()))?;
+        writeln!(file, "{}", serde_json::to_string(entry)?)?;
+        Ok(())
+    }
+
+    /// Every entry in the log, oldest first. Lines that do not parse are skipped.
+    fn entries(&self) -> Result<Vec<AccessEntry>> {
+        if !self.path.exists() {
+            return Ok(Vec::new());
+        }
+        let content = std::fs::read_to_string(&self.path)
+            .with_context(|| format!("Failed to read {}", self.path.display()))?;
+        Ok(content
+            .lines()
+            .filter_map(|line| serde_json::from_str(line).ok())
+            .collect())
+    }
+
+    /// Matching entries, newest first.
+    pub fn query(&self, query: &AccessQuery) -> Result<Vec<AccessEntry>> {
+        let mut entries: Vec<AccessEntry> = self
+            .entries()?
+            .into_iter()
+            .filter(|e| query.actor.as_ref().map_or(true, |a| &e.actor == a))
+            .filter(|e| query.object.as_ref().map_or(true, |o| &e.object == o))
+            .filter(|e| query.since.map_or(true, |s| e.timestamp >= s))
+            .collect();
+        entries.reverse();
+        if let Some(limit) = query.limit {
+            entries.truncate(limit);
+        }
+        Ok(entries)
+    }
+
+    /// Drop entries older than the retention window. Returns how many were removed.
+    pub fn prune(&self, now: DateTime<Utc>) -> Result<usize> {
+        let _guard = LOG_LOCK.lock().unwrap_or_else(|e| e.into_inner());
+        let entries = self.entries()?;
+        let cutoff = now - Duration::days(self.config.retention_days as i64);
+        let kept: Vec<&AccessEntry> = entries.iter().filter(|e| e.timestamp >= cutoff).collect();
+        let removed = entries.len() - kept.len();
+        if removed > 0 {
+            let mut content = String::new();
+            for entry in kept {
+                content.push_str(&serde_json::to_string(entry)?);
+                content.push('\n');
+            }
+            let tmp = self
+                .path
+                .with_extension(format!("log.{}.tmp", uuid::Uuid::new_v4().simple()));
+            std::fs::write(&tmp, content)
+                .with_context(|| format!("Failed to write {}", tmp.display()))?;
+            std::fs::rename(&tmp, &self.path)
+                .with_context(|| format!("Failed to replace {}", self.path.display()))?;
+        }
+        Ok(removed)
+    }
+}
+
+/// Entries as CSV (`timestamp,actor,action,object`) for export.
+pub fn entries_csv(entries: &[AccessEntry]) -> String {
+    fn field(value: &str) -> String {
+        if value.contains([',', '"', '\n']) {
+            format!("\"{}\"", value.replace('"', "\"\""))
+        } else {
+            value.to_string()
+        }
+    }
+    let mut out = String::from("timestamp,actor,action,object\n");
+    for e in entries {
+        out.push_str(&format!(
+            "{},{},{},{}\n",
+            e.timestamp.to_rfc3339(),
+            field(&e.actor),
+            field(&e.action),
+            field(&e.object)
+        ));
+    }
+    out
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn entry(actor: &str, object: &str, timestamp: DateTime<Utc>) -> AccessEntry {
+        AccessEntry {
+            timestamp,
+            actor: actor.into(),
+            action: "object.history".into(),
+            object: object.into(),
+        }
+    }
+
+    #[test]
+    fn unaudited_building_records_nothing() {
+        let dir = tempfile::tempdir().unwrap();
+        let log = AccessLog::open(dir.path()).unwrap();
+        assert!(!log.is_enabled());
+        log.record(&entry("root", "ahu-1", Utc::now())).unwrap();
+        assert!(!dir.path().join(ACCESS_LOG_FILE).exists());
+        assert!(log.query(&AccessQuery::default()).unwrap().is_empty());
+    }
+
+    fn audited(retention_days: u32) -> tempfile::TempDir {
+        let dir = tempfile::tempdir().unwrap();
+        std::fs::create_dir_all(dir.path().join(".arxos")).unwrap();
+        let config = AccessLogConfig {
+            enabled: true,
+            retention_days,
+        };
+        std::fs::write(
+            dir.path().join(ACCESS_LOG_CONFIG),
+            serde_yaml::to_string(&config).unwrap(),
+        )
+        .unwrap();
+        dir
+    }
+
+    #[test]
+    fn audited_reads_are_queryable_and_pruned() {
+        let dir = audited(30);
+        let log = AccessLog::open(dir.path()).unwrap();
+        let now = Utc::now();
+        log.record(&entry("service:tok-1", "ahu-1", now - Duration::days(40)))
+            .unwrap();
+        log.record(&entry("root", "ahu-1", now)).unwrap();
+        log.record(&entry("service:tok-1", "room-2", now)).unwrap();
+
+        let by_actor = log
+            .query(&AccessQuery {
+                actor: Some("service:tok-1".into()),
+                ..Default::default()
+            })
+            .unwrap();
+        let objects: Vec<&str> = by_actor.iter().map(|e| e.object.as_str()).collect();
+        assert_eq!(objects, vec!["room-2", "ahu-1"]);
+
+        let by_object = log
+            .query(&AccessQuery {
+                object: Some("ahu-1".into()),
+                since: Some(now - Duration::days(1)),
+                ..Default::default()
+            })
+            .unwrap();
+        assert_eq!(by_object.len(), 1);
+        assert_eq!(by_object[0].actor, "root");
+        assert!(entries_csv(&by_object).contains(",root,object.history,ahu-1\n"));
+
+        assert_eq!(log.prune(now).unwrap(), 1);
+        assert_eq!(log.query(&AccessQuery::default()).unwrap().len(), 2);
+    }
+
+    #[test]
+    fn prune_keeps_reads_recorded_meanwhile() {
+        let dir = audited(30);
+        let log = AccessLog::open(dir.path()).unwrap();
+        let now = Utc::now();
+        for i in 0..50 {
+            let old = entry("root", &format!("old-{}", i), now - Duration::days(40));
+            log.record(&old).unwrap();
+        }
+
+        std::thread::scope(|s| {
+            for t in 0..4 {
+                let log = &log;
+                s.spawn(move || {
+                    for i in 0..20 {
+                        log.record(&entry("root", &format!("new-{}-{}", t, i), now))
+                            .unwrap();
+                    }
+                });
+            }
+            for _ in 0..10 {
+                log.prune(now).unwrap();
+            }
+        });
+        log.prune(now).unwrap();
+        assert_eq!(log.query(&AccessQuery::default()).unwrap().len(), 80);
+    }
+}
@@ -85,16 +85,249 @@ fn action_required_capability(action: &str) -> Option<&'static str> {
         "git.diff" => Some("git.diff"),
         "git.commit" => Some("git.commit"),
         "files.read" => Some("files.read"),
-        "building.get" => Some("building.get"),
+        "building.get" | "building.changes.pull" | "building.validate" | "equipment.next_id" => {
+            Some("building.get")
+        }
+        "building.changes.push" => Some("building.sync"),
         "ifc.import" => Some("ifc.import"),
         "ifc.export" => Some("ifc.export"),
         "auth.rotate" | "auth.negotiate" => Some("auth.manage"),
+        "auth.tokens.create" | "auth.tokens.list" | "auth.tokens.revoke" => Some("auth.manage"),
         "collab.sync" => Some("collab.sync"),
         "collab.config.get" | "collab.config.set" => Some("collab.config"),
+        "claim.list_pending" | "claim.get_status" => Some("building.get"),
+        "claim.review" => Some("building.sync"),
         _ => None,
     }
 }
 
+/// Who may call a REST route.
+///
+/// A bad token gets 401 and a valid one without the capability 403. Organization
+/// scoping never hides a resource: every token, whatever its organization, sees the
+/// repo's single building, so a 403 cannot reveal anything a 404 would conceal.
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub enum RouteAccess {
+    /// No token needed (liveness and readiness probes).
+    Public,
+    /// Any valid token; the route checks capabilities per call (`/rpc`, `/ws`).
+    Authenticated,
+    /// A token holding this capability.
+    Capability(&'static str),
+}
+
+/// Capability each REST route requires, keyed by method and route pattern.
+/// Reads need `building.get`, writes `building.sync`, and definitions and audit
+/// data `auth.manage`, which only the root token holds.
+pub const REST_ROUTES: &[(&str, &str, RouteAccess)] = &[
+    ("GET", "/health", RouteAccess::Public),
+    ("GET", "/ready", RouteAccess::Public),
+    ("GET", "/ws", RouteAccess::Authenticated),
+    ("POST", "/rpc", RouteAccess::Authenticated),
+    (
+        "GET",
+        "/api/status",
+        RouteAccess::Capability("building.get"),
+    ),
+    ("GET", "/metrics", RouteAccess::Capability("building.get")),
+    (
+        "GET",
+        "/api/claims/status",
+        RouteAccess::Capability("building.get"),
+    ),
+    (
+        "GET",
+        "/api/claims/staging",
+        RouteAccess::Capability("building.get"),
+    ),
+    (
+        "POST",
+        "/api/claims/:id/approve",
+        RouteAccess::Capability("building.sync"),
+    ),
+    (
+        "POST",
+        "/api/claims/:id/reject",
+        RouteAccess::Capability("building.sync"),
+    ),
+    (
+        "GET",
+        "/api/v1/arxobjects/:id",
+        RouteAccess::Capability("building.get"),
+    ),
+    (
+        "GET",
+        "/api/v1/arxobjects/:id/history",
+        RouteAccess::Capability("building.get"),
+    ),
+    (
+        "POST",
+        "/api/v1/arxobjects/validate/batch",
+        RouteAccess::Capability("building.sync"),
+    ),
+    (
+        "GET",
+        "/api/v1/floors/:level/arxobjects",
+        RouteAccess::Capability("building.get"),
+    ),
+    (
+        "POST",
+        "/api/v1/floors/reorder",
+        RouteAccess::Capability("building.sync"),
+    ),
+    (
+        "GET",
+        "/api/v1/buildings",
+        RouteAccess::Capability("building.get"),
+    ),
+    (
+        "GET",
+        "/api/v1/buildings/:id",
+        RouteAccess::Capability("building.get"),
+    ),
+    (
+        "POST",
+        "/api/v1/buildings/:id/clone",
+        RouteAccess::Capability("building.sync"),
+    ),
+    (
+        "POST",
+        "/api/v1/buildings/import/json",
+        RouteAccess::Capability("building.sync"),
+    ),
+    (
+        "GET",
+        "/api/v1/buildings/:id/review/suggestions",
+        RouteAccess::Capability("building.get"),
+    ),
+    (
+        "POST",
+        "/api/v1/buildings/:id/review/suggestions/apply",
+        RouteAccess::Capability("building.sync"),
+    ),
+    (
+        "GET",
+        "/api/v1/buildings/:id/duplicates",
+        RouteAccess::Capability("building.get"),
+    ),
+    (
+        "POST",
+        "/api/v1/buildings/:id/merge",
+        RouteAccess::Capability("building.sync"),
+    ),
+    (
+        "POST",
+        "/api/v1/buildings/:id/objects/delete-by-query",
+        RouteAccess::Capability("building.sync"),
+    ),
+    (
+        "POST",
+        "/api/v1/buildings/:id/reclassify",
+        RouteAccess::Capability("building.sync"),
+    ),
+    (
+        "POST",
+        "/api/v1/buildings/:id/heatmap",
+        RouteAccess::Capability("building.get"),
+    ),
+    (
+        "POST",
+        "/api/v1/buildings/:id/validate",
+        RouteAccess::Capability("building.get"),
+    ),
+    (
+        "GET",
+        "/api/v1/buildings/:id/changes",
+        RouteAccess::Capability("building.get"),
+    ),
+    (
+        "POST",
+        "/api/v1/buildings/:id/changes",
+        RouteAccess::Capability("building.sync"),
+    ),
+    (
+        "POST",
+        "/api/v1/buildings/:id/transform",
+        RouteAccess::Capability("building.sync"),
+    ),
+    (
+        "POST",
+        "/api/v1/buildings/:id/transform/undo",
+        RouteAccess::Capability("building.sync"),
+    ),
+    (
+        "GET",
+        "/api/v1/buildings/:id/out-of-bounds",
+        RouteAccess::Capability("building.get"),
+    ),
+    (
+        "GET",
+        "/api/v1/buildings/:id/custom-fields",
+        RouteAccess::Capability("building.get"),
+    ),
+    (
+        "PATCH",
+        "/api/v1/buildings/:id/custom-fields",
+        RouteAccess::Capability("building.sync"),
+    ),
+    (
+        "GET",
+        "/api/v1/templates",
+        RouteAccess::Capability("building.get"),
+    ),
+    (
+        "GET",
+        "/api/v1/equipment-types",
+        RouteAccess::Capability("building.get"),
+    ),
+    (
+        "PUT",
+        "/api/v1/equipment-types/:name",
+        RouteAccess::Capability("auth.manage"),
+    ),
+    (
+        "DELETE",
+        "/api/v1/equipment-types/:name",
+        RouteAccess::Capability("auth.manage"),
+    ),
+    (
+        "GET",
+        "/api/v1/custom-fields",
+        RouteAccess::Capability("building.get"),
+    ),
+    (
+        "PUT",
+        "/api/v1/custom-fields/:name",
+        RouteAccess::Capability("auth.manage"),
+    ),
+    (
+        "GET",
+        "/api/v1/access-log",
+        RouteAccess::Capability("auth.manage"),
+    ),
+    (
+        "GET",
+        "/api/v1/features",
+        RouteAccess::Capability("building.get"),
+    ),
+];
+
+/// Access rule for `method` on the matched `route` pattern. HEAD follows GET; a
+/// route missing from [`REST_ROUTES`] requires `auth.manage`, so a new endpoint is
+/// root-only until it is listed.
+pub fn route_access(method: &str, route: &str) -> RouteAccess {
+    let method = if method.eq_ignore_ascii_case("HEAD") {
+        "GET"
+    } else {
+        method
+    };
+    REST_ROUTES
+        .iter()
+        .find(|(m, r, _)| m.eq_ignore_ascii_case(method) && *r == route)
+        .map(|(_, _, access)| *access)
+        .unwrap_or(RouteAccess::Capability("auth.manage"))
+}
+
 #[cfg(test)]
 mod tests {
     use super::*;
@@ -119,6 +352,79 @@ mod tests {
         assert!(ensure_capability("git.commit", &capabilities).is_err());
     }
 
+    #[test]
+    fn every_mutating_method_requires_a_capability() {
+        for method in crate::agent::idempotency::NON_IDEMPOTENT_METHODS {
+            assert!(
+                action_required_capability(method).is_some(),
+                "{} has no capability",
+                method
+            );
+        }
+        let viewer = vec!["building.get".to_string()];
+        assert!(ensure_capability("claim.list_pending", &viewer).is_ok());
+        assert!(ensure_capability("claim.review", &viewer).is_err());
+        assert!(ensure_capability("claim.list_pending", &["git.status".into()]).is_err());
+    }
+
+    #[test]
+    fn rest_routes_require_scoped_capabilities() {
+        assert_eq!(route_access("GET", "/health"), RouteAccess::Public);
+        assert_eq!(route_access("POST", "/rpc"), RouteAccess::Authenticated);
+        assert_eq!(
+            route_access("HEAD", "/api/v1/buildings/:id/duplicates"),
+            RouteAccess::Capability("building.get")
+        );
+        for route in [
+            "/api/claims/:id/approve",
+            "/api/v1/floors/reorder",
+            "/api/v1/buildings/:id/merge",
+            "/api/v1/buildings/:id/objects/delete-by-query",
+            "/api/v1/arxobjects/validate/batch",
+        ] {
+            assert_eq!(
+                route_access("POST", route),
+                RouteAccess::Capability("building.sync")
+            );
+        }
+        assert_eq!(
+            route_access("PUT", "/api/v1/custom-fields/:name"),
+            RouteAccess::Capability("auth.manage")
+        );
+        // Unlisted routes and methods default to root-only.
+        assert_eq!(
+            route_access("DELETE", "/api/v1/buildings/:id/merge"),
+            RouteAccess::Capability("auth.manage")
+        );
+
+        // Every write needs a write or admin capability; a few POSTs only compute.
+        let read_only_posts = [
+            "/api/v1/buildings/:id/heatmap",
+            "/api/v1/buildings/:id/validate",
+        ];
+        for (i, (method, route, access)) in REST_ROUTES.iter().enumerate() {
+            if *method != "GET" && route.starts_with("/api/") && !read_only_posts.contains(route) {
+                assert!(
+                    matches!(
+                        access,
+                        RouteAccess::Capability("building.sync" | "auth.manage")
+                    ),
+                    "{} {} is a write",
+                    method,
+                    route
+                );
+            }
+            assert!(
+                !REST_ROUTES[..i]
+                    .iter()
+                    .any(|(m, r, _)| m == method && r == route),
+                "{} {} listed twice",
+                method,
+                route
+            );
+        }
+    }
+
     #[test]
     fn filter_capabilities_partitions_requested() {
         let default = vec!["git.status".into(), "git.diff".into()];
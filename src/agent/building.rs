@@ -8,6 +8,9 @@ use anyhow::{anyhow, Result};
 use serde::Serialize;
 
 use crate::core::review::{equipment_review_status, room_review_status, ReviewStatus};
+use crate::agent::protocol::AgentError;
+use crate::core::derived::{BuildingDerivedValues, DerivedConfig};
+use crate::core::operations::strip_deleted;
 use crate::core::{summarize_review, Building};
 use crate::persistence::{load_building_at, BUILDING_YAML};
 
@@ -23,12 +26,19 @@ pub struct BuildingGetResult {
     pub floors: usize,
     pub rooms: usize,
     pub equipment: usize,
+    /// Read-only values computed from the current geometry (area, clearance, …).
+    pub derived: BuildingDerivedValues,
 }
 
 /// Load durable `building.yaml` and attach review summary for the phone Review UI.
+/// Soft-deleted equipment is left out.
 pub fn get_building(repo_root: &Path) -> Result<BuildingGetResult> {
-    let building = load_building_at(repo_root)
+    if !repo_root.join(BUILDING_YAML).exists() {
+        return Err(AgentError::not_found(format!("{} not found", BUILDING_YAML)).into());
+    }
+    let mut building = load_building_at(repo_root)
         .map_err(|e| anyhow!("Failed to load {}: {}", BUILDING_YAML, e))?;
+    strip_deleted(&mut building);
 
     let summary = summarize_review(&building);
     let proposed_rooms = building
@@ -45,6 +55,9 @@ pub fn get_building(repo_root: &Path) -> Result<BuildingGetResult> {
     let equipment = building.get_all_equipment().len();
     let floors = building.floors.len();
     let review_warnings = summary.warning_lines();
+    let derived = DerivedConfig::load(repo_root)
+        .map_err(|e| anyhow!("Failed to load derived properties: {}", e))?
+        .building_values(&building);
 
     Ok(BuildingGetResult {
         building,
@@ -55,6 +68,7 @@ pub fn get_building(repo_root: &Path) -> Result<BuildingGetResult> {
         floors,
         rooms,
         equipment,
+        derived,
     })
 }
 
@@ -73,6 +87,11 @@ mod tests {
         let mut wing = Wing::new("A".into());
         let mut room = Room::new("Scan Room".into(), RoomType::Office);
         mark_proposed(&mut room.properties);
+        // Derived values come from the footprint.
+        let bbox = &mut room.spatial_properties.bounding_box;
+        bbox.max.x = bbox.min.x + 3.0;
+        bbox.max.y = bbox.min.y + 4.0;
+        let room_id = room.id.clone();
         wing.add_room(room);
         floor.add_wing(wing);
         b.add_floor(floor);
@@ -83,5 +102,59 @@ mod tests {
         assert_eq!(got.rooms, 1);
         assert_eq!(got.building.name, "Pilot");
         assert!(!got.review_warnings.is_empty());
+        assert_eq!(got.derived.rooms[&room_id]["area"], 12.0);
+    }
+
+    #[test]
+    fn confirmed_delete_disappears_from_get() {
+        use crate::core::operations::{
+            find_matching, mark_deleted, DeleteConfirmations, EquipmentQuery, ObjectSource,
+        };
+        use crate::core::{Equipment, EquipmentType};
+
+        let dir = tempdir().unwrap();
+        let mut b = Building::new("Pilot".into(), "/pilot".into());
+        let mut floor = Floor::new("L1".into(), 0);
+        let mut scan = Equipment::new("scan-1".into(), "/scan-1".into(), EquipmentType::HVAC);
+        scan.lidar_enrichment = Some(crate::core::LidarEnrichment {
+            point_count: 10,
+            confidence_score: 0.2,
+            last_scan_timestamp: None,
+            classification_heuristic: None,
+        });
+        floor.equipment.push(scan);
+        floor.equipment.push(Equipment::new(
+            "AHU-1".into(),
+            "/ahu-1".into(),
+            EquipmentType::HVAC,
+        ));
+        b.add_floor(floor);
+
+        let query = EquipmentQuery {
+            source: Some(ObjectSource::Lidar),
+            ..Default::default()
+        };
+        let registry = Default::default();
+        let ids = find_matching(&b, &registry, &query);
+        let now = chrono::Utc::now();
+        let token =
+            DeleteConfirmations::update(dir.path(), |c| Ok(c.issue(&b.id, &ids, now))).unwrap();
+        let confirmed =
+            DeleteConfirmations::update(dir.path(), |c| c.redeem(&b.id, &token, now)).unwrap();
+        mark_deleted(&mut b, &confirmed, now);
+        save_building_at(dir.path(), &b).unwrap();
+
+        let got = get_building(dir.path()).unwrap();
+        assert_eq!(got.equipment, 1);
+        assert!(got.building.find_equipment(&ids[0]).is_none());
+        assert_eq!(got.building.floors[0].equipment[0].name, "AHU-1");
+    }
+
+    #[test]
+    fn get_building_missing_is_not_found() {
+        let dir = tempdir().unwrap();
+        let err = get_building(dir.path()).unwrap_err();
+        let agent_err = err.downcast_ref::<AgentError>().unwrap();
+        assert_eq!(agent_err.code, crate::error::ErrorCode::NotFound);
     }
 }
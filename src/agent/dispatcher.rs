@@ -5,10 +5,13 @@ use anyhow::Result;
 use serde_json::Value;
 
 use crate::agent::auth::{ensure_capability, TokenState};
+use crate::agent::idempotency::is_non_idempotent;
 use crate::agent::protocol::{
-    JsonRpcRequest, JsonRpcResponse, AUTH_ERROR, INTERNAL_ERROR,
-    METHOD_NOT_FOUND,
+    AgentError, FieldErrors, JsonRpcRequest, JsonRpcResponse, INVALID_REQUEST,
 };
+use crate::error::ErrorCode;
+use crate::agent::service_tokens::{check_token_request, ServiceTokenStore};
+use crate::ingest::delta;
 use crate::agent::{building, collab, files, git, ifc};
 
 pub struct AgentState {
@@ -16,21 +19,98 @@ pub struct AgentState {
     pub token: Arc<Mutex<TokenState>>,
     pub metrics: Arc<crate::agent::observability::AgentMetrics>,
     pub reload_handle: Option<tracing_subscriber::reload::Handle<tracing_subscriber::EnvFilter, tracing_subscriber::Registry>>,
+    /// Cached responses for `Idempotency-Key` retries on `/rpc`.
+    pub idempotency: Arc<crate::agent::idempotency::IdempotencyStore>,
+    /// Cached responses for `Idempotency-Key` retries on REST POSTs.
+    pub http_idempotency:
+        Arc<crate::agent::idempotency::IdempotencyStore<crate::agent::idempotency::HttpReplay>>,
 }
 
 pub async fn dispatch(state: Arc<AgentState>, request: JsonRpcRequest) -> JsonRpcResponse {
-    let id = request.id.clone();
-    let method = request.method.as_str();
-    let params = request.params.unwrap_or(Value::Null);
-
-    // 1. Check capabilities
     let capabilities = {
         let token_guard = state.token.lock().unwrap();
         token_guard.capabilities().to_vec()
     };
+    dispatch_with_capabilities(state, request, &capabilities).await
+}
 
-    if let Err(e) = ensure_capability(method, &capabilities) {
-        return JsonRpcResponse::error(id, AUTH_ERROR, format!("Permission denied: {}", e), None);
+/// Largest JSON-RPC batch accepted on `/rpc`.
+pub const MAX_BATCH_SIZE: usize = 50;
+
+/// Dispatch a JSON-RPC batch; every item runs with the caller's `capabilities`.
+///
+/// Read-only calls run concurrently. A mutating call waits for everything before it
+/// and runs alone, so later items observe its writes. Responses keep request order,
+/// and a failing or malformed item only fails its own slot.
+pub async fn dispatch_batch(
+    state: Arc<AgentState>,
+    items: Vec<Value>,
+    capabilities: &[String],
+) -> Vec<JsonRpcResponse> {
+    type Pending = Vec<(Option<Value>, tokio::task::JoinHandle<JsonRpcResponse>)>;
+
+    async fn drain(pending: &mut Pending, out: &mut Vec<JsonRpcResponse>) {
+        for (id, handle) in std::mem::take(pending) {
+            out.push(handle.await.unwrap_or_else(|e| {
+                JsonRpcResponse::from_agent_error(
+                    id,
+                    AgentError::new(ErrorCode::Internal, format!("Batch item failed: {}", e)),
+                )
+            }));
+        }
+    }
+
+    let mut responses = Vec::with_capacity(items.len());
+    let mut pending: Pending = Vec::new();
+    for item in items {
+        let request: JsonRpcRequest = match serde_json::from_value(item) {
+            Ok(request) => request,
+            Err(e) => {
+                drain(&mut pending, &mut responses).await;
+                responses.push(JsonRpcResponse::error(
+                    None,
+                    INVALID_REQUEST,
+                    format!("Invalid request: {}", e),
+                    None,
+                ));
+                continue;
+            }
+        };
+        if is_non_idempotent(&request.method) {
+            drain(&mut pending, &mut responses).await;
+            responses.push(dispatch_with_capabilities(state.clone(), request, capabilities).await);
+        } else {
+            let id = request.id.clone();
+            let state = state.clone();
+            let capabilities = capabilities.to_vec();
+            pending.push((
+                id,
+                tokio::spawn(async move {
+                    dispatch_with_capabilities(state, request, &capabilities).await
+                }),
+            ));
+        }
+    }
+    drain(&mut pending, &mut responses).await;
+    responses
+}
+
+/// Dispatch on behalf of a caller holding `capabilities` (e.g. a scoped service token).
+pub async fn dispatch_with_capabilities(
+    state: Arc<AgentState>,
+    request: JsonRpcRequest,
+    capabilities: &[String],
+) -> JsonRpcResponse {
+    let id = request.id.clone();
+    let method = request.method.as_str();
+    let params = request.params.unwrap_or(Value::Null);
+
+    // 1. Check capabilities
+    if let Err(e) = ensure_capability(method, capabilities) {
+        return JsonRpcResponse::from_agent_error(
+            id,
+            AgentError::new(ErrorCode::Forbidden, format!("Permission denied: {}", e)),
+        );
     }
 
     // 2. Dispatch to handler
@@ -40,25 +120,27 @@ pub async fn dispatch(state: Arc<AgentState>, request: JsonRpcRequest) -> JsonRp
         "git.commit" => handle_git_commit(&state, params),
         "files.read" => handle_files_read(&state.repo_root, params),
         "building.get" => handle_building_get(&state.repo_root),
-        "ifc.import" => handle_ifc_import(&state.repo_root, params),
+        "building.validate" => handle_building_validate(&state.repo_root, params),
+        "building.changes.pull" => handle_changes_pull(&state.repo_root, params),
+        "building.changes.push" => handle_changes_push(&state.repo_root, params),
+        "equipment.next_id" => handle_equipment_next_id(&state.repo_root, params),
+        "ifc.import" => handle_ifc_import(&state, params),
         "ifc.export" => handle_ifc_export(&state.repo_root, params),
         "collab.sync" => handle_collab_sync(params).await,
         "claim.list_pending" => handle_claim_list_pending(&state.repo_root),
         "claim.review" => handle_claim_review(&state.repo_root, params),
         "claim.get_status" => handle_claim_get_status(&state.repo_root, params),
-        _ => Err(anyhow::anyhow!("Method not found")),
+        "auth.tokens.create" => handle_tokens_create(&state.repo_root, params),
+        "auth.tokens.list" => handle_tokens_list(&state.repo_root),
+        "auth.tokens.revoke" => handle_tokens_revoke(&state.repo_root, params),
+        _ => Err(AgentError::new(ErrorCode::MethodNotFound, "Method not found")
+            .with_details(serde_json::json!({ "method": method }))
+            .into()),
     };
 
     match result {
         Ok(value) => JsonRpcResponse::success(id, value),
-        Err(e) => {
-            let msg = e.to_string();
-            if msg == "Method not found" {
-                JsonRpcResponse::error(id, METHOD_NOT_FOUND, msg, None)
-            } else {
-                JsonRpcResponse::error(id, INTERNAL_ERROR, msg, None)
-            }
-        }
+        Err(e) => JsonRpcResponse::from_error(id, &e),
     }
 }
 
@@ -79,7 +161,7 @@ fn handle_git_commit(state: &AgentState, params: Value) -> Result<Value> {
     let message = params
         .get("message")
         .and_then(|v| v.as_str())
-        .ok_or_else(|| anyhow::anyhow!("Missing 'message' parameter"))?;
+        .ok_or_else(|| AgentError::missing_param("message"))?;
 
     let stage_all = params
         .get("stageAll")
@@ -100,7 +182,7 @@ fn handle_files_read(root: &std::path::Path, params: Value) -> Result<Value> {
     let path = params
         .get("path")
         .and_then(|v| v.as_str())
-        .ok_or_else(|| anyhow::anyhow!("Missing 'path' parameter"))?;
+        .ok_or_else(|| AgentError::missing_param("path"))?;
 
     let content = files::read_file(root, path)?;
     Ok(serde_json::to_value(content)?)
@@ -111,19 +193,125 @@ fn handle_building_get(root: &std::path::Path) -> Result<Value> {
     Ok(serde_json::to_value(result)?)
 }
 
-fn handle_ifc_import(root: &std::path::Path, params: Value) -> Result<Value> {
+/// Load building.yaml, reporting a missing file as `ARX-NOT-FOUND`.
+fn load_building(root: &std::path::Path) -> Result<crate::core::Building> {
+    use crate::persistence::BUILDING_YAML;
+
+    if !root.join(BUILDING_YAML).exists() {
+        return Err(AgentError::not_found(format!("{} not found", BUILDING_YAML)).into());
+    }
+    crate::persistence::load_building_at(root).map_err(|e| {
+        AgentError::validation(format!("Failed to load {}: {}", BUILDING_YAML, e)).into()
+    })
+}
+
+fn handle_building_validate(root: &std::path::Path, params: Value) -> Result<Value> {
+    use crate::validation::ruleset;
+
+    let building = load_building(root)?;
+    let rule_set = match params.get("rules") {
+        Some(Value::String(spec)) if spec == "starter" => Some(ruleset::starter_ruleset()),
+        Some(Value::Null) | None => {
+            ruleset::resolve_ruleset(root, None).map_err(AgentError::validation)?
+        }
+        Some(inline) => {
+            let set: ruleset::RuleSet = serde_json::from_value(inline.clone())
+                .map_err(|e| AgentError::invalid_params(format!("Invalid rules format: {}", e)))?;
+            set.check().map_err(AgentError::validation)?;
+            Some(set)
+        }
+    };
+
+    let report = ruleset::validate_project(root, &building, rule_set.as_ref())
+        .map_err(AgentError::validation)?;
+    Ok(serde_json::json!({
+        "valid": !report.has_errors(),
+        "violations": report.results,
+    }))
+}
+
+fn handle_changes_pull(root: &std::path::Path, params: Value) -> Result<Value> {
+    let since = params.get("since").and_then(|v| v.as_str());
+    let building = load_building(root)?;
+    let changes =
+        delta::pull_changes(root, &building, since).map_err(AgentError::invalid_params)?;
+    Ok(serde_json::to_value(changes)?)
+}
+
+fn handle_changes_push(root: &std::path::Path, params: Value) -> Result<Value> {
+    let changes_val = params
+        .get("changes")
+        .ok_or_else(|| AgentError::missing_param("changes"))?;
+    let changes: Vec<delta::ObjectChange> = serde_json::from_value(changes_val.clone())
+        .map_err(|e| AgentError::invalid_params(format!("Invalid changes format: {}", e)))?;
+
+    let mut building = load_building(root)?;
+    let outcome = delta::push_changes(&mut building, &changes);
+    if outcome.has_changes() {
+        let message = format!("Sync {} change(s) from offline client", outcome.applied.len());
+        crate::ingest::persist_building_at(root, building, false, Some(&message))
+            .map_err(|e| anyhow::anyhow!("{}", e))?;
+    }
+
+    // Applied changes come back, with their new versions, on the next pull.
+    Ok(serde_json::to_value(outcome)?)
+}
+
+fn handle_equipment_next_id(root: &std::path::Path, params: Value) -> Result<Value> {
+    use crate::core::id_template::IdTemplates;
+
+    let room = params
+        .get("room")
+        .and_then(|v| v.as_str())
+        .ok_or_else(|| AgentError::missing_param("room"))?;
+    let equipment_type = params
+        .get("type")
+        .and_then(|v| v.as_str())
+        .ok_or_else(|| AgentError::missing_param("type"))?;
+
+    let building = load_building(root)?;
+    let templates = IdTemplates::load(root).map_err(AgentError::validation)?;
+    let id = templates
+        .next_for_room(&building, room, equipment_type)
+        .map_err(AgentError::not_found)?;
+    Ok(serde_json::json!({
+        "id": id,
+        "template": templates.template_for(equipment_type).map(|t| t.as_str().to_string()),
+    }))
+}
+
+fn handle_ifc_import(state: &AgentState, params: Value) -> Result<Value> {
     let filename = params
         .get("filename")
         .and_then(|v| v.as_str())
-        .ok_or_else(|| anyhow::anyhow!("Missing 'filename' parameter"))?;
+        .ok_or_else(|| AgentError::missing_param("filename"))?;
 
     let data_base64 = params
         .get("data")
         .and_then(|v| v.as_str())
-        .ok_or_else(|| anyhow::anyhow!("Missing 'data' parameter"))?;
-
-    let result = ifc::import_ifc(root, filename, data_base64)?;
-    Ok(serde_json::to_value(result)?)
+        .ok_or_else(|| AgentError::missing_param("data"))?;
+    // Selects the importer; without it the filename's extension does.
+    let content_type = params.get("content_type").and_then(|v| v.as_str());
+
+    let started = std::time::Instant::now();
+    match ifc::import_ifc(&state.repo_root, filename, data_base64, content_type) {
+        Ok(result) => {
+            state.metrics.imports.record_success(
+                "ifc_upload",
+                started.elapsed(),
+                result.rooms + result.equipment,
+            );
+            Ok(serde_json::to_value(result)?)
+        }
+        Err(e) => {
+            let message = e.to_string();
+            state
+                .metrics
+                .imports
+                .record_failure("ifc_upload", started.elapsed(), &message);
+            Err(e.into())
+        }
+    }
 }
 
 fn handle_ifc_export(root: &std::path::Path, params: Value) -> Result<Value> {
@@ -148,13 +336,13 @@ fn handle_ifc_export(root: &std::path::Path, params: Value) -> Result<Value> {
 async fn handle_collab_sync(params: Value) -> Result<Value> {
     let messages_val = params
         .get("messages")
-        .ok_or_else(|| anyhow::anyhow!("Missing 'messages' parameter"))?;
+        .ok_or_else(|| AgentError::missing_param("messages"))?;
 
     let messages: Vec<collab::CollabMessage> = serde_json::from_value(messages_val.clone())
-        .map_err(|e| anyhow::anyhow!("Invalid messages format: {}", e))?;
+        .map_err(|e| AgentError::invalid_params(format!("Invalid messages format: {}", e)))?;
 
-    let config =
-        collab::load_config()?.ok_or_else(|| anyhow::anyhow!("Collaboration config not found"))?;
+    let config = collab::load_config()?
+        .ok_or_else(|| AgentError::not_found("Collaboration config not found"))?;
 
     let token = collab::github_token()?.unwrap_or_default();
 
@@ -162,6 +350,100 @@ async fn handle_collab_sync(params: Value) -> Result<Value> {
     Ok(serde_json::to_value(outcome)?)
 }
 
+fn handle_tokens_create(root: &std::path::Path, params: Value) -> Result<Value> {
+    let mut errors = FieldErrors::new();
+    let name = errors
+        .require("name", params.get("name").and_then(|v| v.as_str()))
+        .unwrap_or_default();
+    let organization = params
+        .get("organization")
+        .and_then(|v| v.as_str())
+        .map(str::to_string)
+        .or_else(|| {
+            crate::config::ConfigManager::new()
+                .ok()
+                .and_then(|m| m.get_config().user.organization.clone())
+        });
+    let organization = errors.require("organization", organization).unwrap_or_default();
+    let capabilities: Vec<String> = match params.get("capabilities").cloned() {
+        None => Vec::new(),
+        Some(v) => serde_json::from_value(v).unwrap_or_else(|e| {
+            errors.add("capabilities", format!("must be a list of strings: {}", e));
+            Vec::new()
+        }),
+    };
+    let ttl = match params.get("ttlHours") {
+        None | Some(Value::Null) => None,
+        Some(v) => match v.as_i64() {
+            Some(hours) => Some(chrono::Duration::hours(hours)),
+            None => {
+                errors.add("ttlHours", "must be an integer");
+                None
+            }
+        },
+    };
+    errors.extend(check_token_request(name, &organization, &capabilities, ttl));
+    errors.into_result()?;
+
+    let (token, secret) = ServiceTokenStore::update(root, |store| {
+        store
+            .create(name, &organization, &capabilities, ttl)
+            .map_err(|e| match e.downcast::<AgentError>() {
+                Ok(agent_err) => agent_err.into(),
+                Err(e) => AgentError::validation(e.to_string()).into(),
+            })
+    })?;
+    tracing::info!(token_id = %token.id, organization = %token.organization, "Service token created");
+
+    Ok(serde_json::json!({
+        "id": token.id,
+        "name": token.name,
+        "organization": token.organization,
+        "capabilities": token.capabilities,
+        "expiresAt": token.expires_at,
+        "token": secret,
+    }))
+}
+
+fn handle_tokens_list(root: &std::path::Path) -> Result<Value> {
+    let store = ServiceTokenStore::load(root)?;
+    let now = chrono::Utc::now();
+    let list: Vec<Value> = store
+        .tokens()
+        .iter()
+        .map(|t| {
+            serde_json::json!({
+                "id": t.id,
+                "name": t.name,
+                "organization": t.organization,
+                "capabilities": t.capabilities,
+                "createdAt": t.created_at,
+                "expiresAt": t.expires_at,
+                "revokedAt": t.revoked_at,
+                "lastUsedAt": t.last_used_at,
+                "active": t.is_active(now),
+            })
+        })
+        .collect();
+    Ok(Value::Array(list))
+}
+
+fn handle_tokens_revoke(root: &std::path::Path, params: Value) -> Result<Value> {
+    let token_id = params
+        .get("id")
+        .and_then(|v| v.as_str())
+        .ok_or_else(|| AgentError::missing_param("id"))?;
+
+    ServiceTokenStore::update(root, |store| {
+        store
+            .revoke(token_id)
+            .map_err(|e| AgentError::not_found(e.to_string()).into())
+    })?;
+    tracing::info!(token_id = %token_id, "Service token revoked");
+
+    Ok(serde_json::json!({ "id": token_id, "revoked": true }))
+}
+
 fn handle_claim_list_pending(root: &std::path::Path) -> Result<Value> {
     use crate::agent::claim::GraceWindowManager;
     use crate::yaml::BuildingYamlSerializer;
@@ -210,13 +492,13 @@ fn handle_claim_review(root: &std::path::Path, params: Value) -> Result<Value> {
     
     let building_id = params.get("building_id")
         .and_then(|v| v.as_str())
-        .ok_or_else(|| anyhow::anyhow!("Missing building_id"))?;
+        .ok_or_else(|| AgentError::missing_param("building_id"))?;
     let index = params.get("index")
         .and_then(|v| v.as_u64())
-        .ok_or_else(|| anyhow::anyhow!("Missing index"))? as usize;
+        .ok_or_else(|| AgentError::missing_param("index"))? as usize;
     let approve = params.get("approve")
         .and_then(|v| v.as_bool())
-        .ok_or_else(|| anyhow::anyhow!("Missing approve"))?;
+        .ok_or_else(|| AgentError::missing_param("approve"))?;
     let owner_address = params.get("owner_address")
         .and_then(|v| v.as_str())
         .unwrap_or("0x1234567890abcdef");
@@ -247,7 +529,7 @@ fn handle_claim_get_status(_root: &std::path::Path, params: Value) -> Result<Val
     
     let building_id = params.get("building_id")
         .and_then(|v| v.as_str())
-        .ok_or_else(|| anyhow::anyhow!("Missing building_id"))?;
+        .ok_or_else(|| AgentError::missing_param("building_id"))?;
 
     let mut manager = GraceWindowManager::new();
     manager.register_active_claim(building_id.to_string(), 14);
@@ -265,3 +547,136 @@ fn handle_claim_get_status(_root: &std::path::Path, params: Value) -> Result<Val
 fn map_grace_error(e: String) -> anyhow::Error {
     anyhow::anyhow!(e)
 }
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::agent::auth::TokenState;
+    use serde_json::json;
+    use tempfile::TempDir;
+
+    fn state(root: &std::path::Path) -> Arc<AgentState> {
+        Arc::new(AgentState {
+            repo_root: root.to_path_buf(),
+            token: Arc::new(Mutex::new(TokenState::new("root".into(), vec![]))),
+            metrics: Arc::new(crate::agent::observability::AgentMetrics::new()),
+            reload_handle: None,
+            idempotency: Default::default(),
+            http_idempotency: Default::default(),
+        })
+    }
+
+    fn rpc(id: i64, method: &str, params: Value) -> Value {
+        json!({ "jsonrpc": "2.0", "id": id, "method": method, "params": params })
+    }
+
+    fn error_code(response: &JsonRpcResponse) -> Value {
+        response.error.as_ref().unwrap().data.as_ref().unwrap()["code"].clone()
+    }
+
+    #[tokio::test]
+    async fn batch_preserves_order_and_isolates_failures() {
+        let temp = TempDir::new().unwrap();
+        let caps = vec!["auth.manage".to_string(), "building.get".to_string()];
+        let items = vec![
+            rpc(1, "auth.tokens.list", Value::Null),
+            rpc(2, "building.get", Value::Null),
+            rpc(
+                3,
+                "auth.tokens.create",
+                json!({ "name": "ci", "organization": "acme", "capabilities": ["git.status"] }),
+            ),
+            rpc(4, "auth.tokens.list", Value::Null),
+            json!({ "not": "a request" }),
+            rpc(6, "nope", Value::Null),
+        ];
+
+        let out = dispatch_batch(state(temp.path()), items, &caps).await;
+        assert_eq!(out.len(), 6);
+        let ids: Vec<_> = out.iter().map(|r| r.id.clone().unwrap_or(Value::Null)).collect();
+        assert_eq!(ids, vec![json!(1), json!(2), json!(3), json!(4), Value::Null, json!(6)]);
+
+        assert_eq!(out[0].result, Some(json!([])));
+        assert_eq!(error_code(&out[1]), "ARX-NOT-FOUND");
+        assert!(out[2].result.as_ref().unwrap()["token"].is_string());
+        assert_eq!(out[3].result.as_ref().unwrap().as_array().unwrap().len(), 1);
+        assert_eq!(out[4].error.as_ref().unwrap().code, INVALID_REQUEST);
+        assert_eq!(error_code(&out[5]), "ARX-METHOD-NOT-FOUND");
+    }
+
+    #[tokio::test]
+    async fn batch_items_inherit_caller_capabilities() {
+        let temp = TempDir::new().unwrap();
+        let caps = vec!["building.get".to_string()];
+        let items = vec![
+            rpc(1, "auth.tokens.list", Value::Null),
+            rpc(2, "building.get", Value::Null),
+        ];
+
+        let out = dispatch_batch(state(temp.path()), items, &caps).await;
+        assert_eq!(error_code(&out[0]), "ARX-FORBIDDEN");
+        assert_eq!(error_code(&out[1]), "ARX-NOT-FOUND");
+    }
+
+    #[tokio::test]
+    async fn scoped_tokens_cannot_call_methods_outside_their_scope() {
+        let temp = TempDir::new().unwrap();
+        let state = state(temp.path());
+        let git_only = vec!["git.status".to_string()];
+        for method in [
+            "claim.review",
+            "claim.list_pending",
+            "claim.get_status",
+            "building.get",
+            "building.changes.push",
+            "auth.tokens.create",
+            "auth.tokens.revoke",
+        ] {
+            let request: JsonRpcRequest =
+                serde_json::from_value(rpc(1, method, json!({}))).unwrap();
+            let response = dispatch_with_capabilities(state.clone(), request, &git_only).await;
+            assert_eq!(error_code(&response), "ARX-FORBIDDEN", "{}", method);
+        }
+
+        let viewer = vec!["building.get".to_string()];
+        let review: JsonRpcRequest = serde_json::from_value(rpc(
+            2,
+            "claim.review",
+            json!({ "id": "c1", "approve": true }),
+        ))
+        .unwrap();
+        let response = dispatch_with_capabilities(state.clone(), review, &viewer).await;
+        assert_eq!(error_code(&response), "ARX-FORBIDDEN");
+        let pending: JsonRpcRequest =
+            serde_json::from_value(rpc(3, "claim.list_pending", Value::Null)).unwrap();
+        let response = dispatch_with_capabilities(state, pending, &viewer).await;
+        let code = response.error.as_ref().map(|_| error_code(&response));
+        assert_ne!(code, Some(json!("ARX-FORBIDDEN")));
+    }
+
+    #[tokio::test]
+    async fn token_create_reports_every_invalid_field() {
+        let temp = TempDir::new().unwrap();
+        let caps = vec!["auth.manage".to_string()];
+        let items = vec![rpc(
+            1,
+            "auth.tokens.create",
+            json!({
+                "organization": "acme",
+                "capabilities": ["auth.manage", "git.status"],
+                "ttlHours": "soon",
+            }),
+        )];
+
+        let out = dispatch_batch(state(temp.path()), items, &caps).await;
+        assert_eq!(error_code(&out[0]), "ARX-VALIDATION");
+        let data = out[0].error.as_ref().unwrap().data.clone().unwrap();
+        let fields: Vec<&str> = data["details"]["errors"]
+            .as_array()
+            .unwrap()
+            .iter()
+            .map(|e| e["field"].as_str().unwrap())
+            .collect();
+        assert_eq!(fields, vec!["name", "ttlHours", "capabilities"]);
+    }
+}
@@ -0,0 +1,148 @@
+//! Runtime feature flags.
+//!
+//! Features that should be switched on or off without a rebuild are gated by name.
+//! Defaults live in [`KNOWN_FLAGS`]; a project overrides them, globally or for one
+//! organization, in `.arxos/features.yaml`:
+//!
+//! ```yaml
+//! flags:
+//!   bulk_delete: false
+//! organizations:
+//!   acme:
+//!     bulk_delete: true
+//! ```
+//!
+//! An organization override beats the global value, which beats the default. Names
+//! not in [`KNOWN_FLAGS`] are allowed (clients may gate their own features on them)
+//! and default to off. Gated handlers answer 404 while their flag is off, so a
+//! disabled feature looks like one that does not exist.
+
+use std::collections::BTreeMap;
+use std::path::Path;
+
+use serde::{Deserialize, Serialize};
+
+use crate::agent::protocol::AgentError;
+
+/// Project file holding flag overrides.
+pub const FEATURES_FILE: &str = ".arxos/features.yaml";
+
+/// `POST /api/v1/buildings/:id/objects/delete-by-query`.
+pub const FLAG_BULK_DELETE: &str = "bulk_delete";
+/// `POST /api/v1/buildings/:id/merge`.
+pub const FLAG_DUPLICATE_MERGE: &str = "duplicate_merge";
+
+/// Flags the agent knows about, with their defaults.
+pub const KNOWN_FLAGS: &[(&str, bool)] = &[(FLAG_BULK_DELETE, true), (FLAG_DUPLICATE_MERGE, true)];
+
+#[derive(Debug, Clone, Default, Serialize, Deserialize)]
+pub struct FeatureFlags {
+    #[serde(default)]
+    pub flags: BTreeMap<String, bool>,
+    /// Per-organization overrides, keyed by organization name.
+    #[serde(default)]
+    pub organizations: BTreeMap<String, BTreeMap<String, bool>>,
+}
+
+impl FeatureFlags {
+    /// Load `.arxos/features.yaml` under `base`; a missing file yields the defaults.
+    pub fn load(base: &Path) -> Result<Self, String> {
+        let path = base.join(FEATURES_FILE);
+        if !path.exists() {
+            return Ok(Self::default());
+        }
+        let content = std::fs::read_to_string(&path)
+            .map_err(|e| format!("read {}: {}", path.display(), e))?;
+        serde_yaml::from_str(&content).map_err(|e| format!("parse {}: {}", path.display(), e))
+    }
+
+    /// Whether `flag` is on for `organization` (`None` for the root token).
+    pub fn enabled(&self, flag: &str, organization: Option<&str>) -> bool {
+        organization
+            .and_then(|org| self.organizations.get(org))
+            .and_then(|overrides| overrides.get(flag))
+            .or_else(|| self.flags.get(flag))
+            .copied()
+            .unwrap_or_else(|| {
+                KNOWN_FLAGS
+                    .iter()
+                    .find(|(name, _)| *name == flag)
+                    .is_some_and(|(_, default)| *default)
+            })
+    }
+
+    /// Not-found error while `flag` is off for `organization`.
+    pub fn require(&self, flag: &str, organization: Option<&str>) -> Result<(), AgentError> {
+        if self.enabled(flag, organization) {
+            Ok(())
+        } else {
+            Err(AgentError::not_found(format!(
+                "Feature '{}' is not enabled",
+                flag
+            )))
+        }
+    }
+
+    /// Every known or configured flag with its value for `organization`.
+    pub fn resolved(&self, organization: Option<&str>) -> BTreeMap<String, bool> {
+        let names = KNOWN_FLAGS
+            .iter()
+            .map(|(name, _)| name.to_string())
+            .chain(self.flags.keys().cloned())
+            .chain(
+                organization
+                    .and_then(|org| self.organizations.get(org))
+                    .into_iter()
+                    .flat_map(|overrides| overrides.keys().cloned()),
+            );
+        names
+            .map(|name| {
+                let on = self.enabled(&name, organization);
+                (name, on)
+            })
+            .collect()
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::error::ErrorCode;
+
+    #[test]
+    fn org_overrides_beat_global_values_and_defaults() {
+        let dir = tempfile::tempdir().unwrap();
+        let defaults = FeatureFlags::load(dir.path()).unwrap();
+        assert!(defaults.enabled(FLAG_BULK_DELETE, None));
+        assert!(!defaults.enabled("energy_viz", None));
+
+        let mut config = FeatureFlags::default();
+        config.flags.insert(FLAG_BULK_DELETE.into(), false);
+        config.flags.insert("energy_viz".into(), true);
+        config
+            .organizations
+            .entry("acme".into())
+            .or_default()
+            .insert(FLAG_BULK_DELETE.into(), true);
+        std::fs::create_dir_all(dir.path().join(".arxos")).unwrap();
+        std::fs::write(
+            dir.path().join(FEATURES_FILE),
+            serde_yaml::to_string(&config).unwrap(),
+        )
+        .unwrap();
+        let flags = FeatureFlags::load(dir.path()).unwrap();
+
+        // Gated handlers map the error to 404 while the flag is off.
+        let err = flags.require(FLAG_BULK_DELETE, None).unwrap_err();
+        assert_eq!(err.code, ErrorCode::NotFound);
+        assert_eq!(err.code.http_status(), 404);
+        assert!(flags.require(FLAG_BULK_DELETE, Some("globex")).is_err());
+        assert!(flags.require(FLAG_BULK_DELETE, Some("acme")).is_ok());
+        assert!(flags.require(FLAG_DUPLICATE_MERGE, None).is_ok());
+
+        let resolved = flags.resolved(Some("acme"));
+        assert_eq!(resolved.get(FLAG_BULK_DELETE), Some(&true));
+        assert_eq!(resolved.get("energy_viz"), Some(&true));
+        assert_eq!(flags.resolved(None).get(FLAG_BULK_DELETE), Some(&false));
+    }
+}
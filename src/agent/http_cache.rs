@@ -0,0 +1,191 @@
+//! `Cache-Control` and `ETag` policy for agent HTTP responses.
+//!
+//! Routes fall into classes by path: API GETs are private and revalidated with an
+//! ETag (a matching `If-None-Match` gets `304 Not Modified`), HTML and unfingerprinted
+//! files are `no-cache`, and fingerprinted assets (`app.3f9a1c2e.js`) are cached as
+//! immutable. WebSocket, RPC, metrics and probe responses and every non-GET are left
+//! alone.
+//!
+//! Max ages are configurable via env: `ARX_API_CACHE_MAX_AGE` (seconds, default 0:
+//! always revalidate) and `ARX_ASSET_CACHE_MAX_AGE` (default one year).
+
+use sha2::{Digest, Sha256};
+
+/// Default max age (seconds) of API GET responses; 0 means revalidate every time.
+pub const DEFAULT_API_CACHE_MAX_AGE: u64 = 0;
+
+/// Default max age (seconds) of fingerprinted static assets.
+pub const DEFAULT_ASSET_CACHE_MAX_AGE: u64 = 365 * 24 * 60 * 60;
+
+/// Shortest hex run in a file name that counts as a content fingerprint.
+const MIN_FINGERPRINT_LEN: usize = 8;
+
+/// Paths whose responses are never given caching headers.
+const UNCACHED_PATHS: &[&str] = &["/ws", "/rpc", "/metrics", "/health", "/ready"];
+
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub enum RouteClass {
+    /// JSON API GET: private, revalidated with an ETag.
+    Api,
+    /// HTML or a file without a fingerprint: always revalidated.
+    Revalidate,
+    /// Fingerprinted static asset: safe to cache forever.
+    Immutable,
+    /// Not touched by the cache middleware.
+    Uncached,
+}
+
+impl RouteClass {
+    /// Whether responses of this class carry an `ETag` and honour `If-None-Match`.
+    pub fn uses_etag(self) -> bool {
+        matches!(self, RouteClass::Api | RouteClass::Revalidate)
+    }
+}
+
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub struct CacheConfig {
+    pub api_max_age_secs: u64,
+    pub asset_max_age_secs: u64,
+}
+
+impl Default for CacheConfig {
+    fn default() -> Self {
+        Self {
+            api_max_age_secs: DEFAULT_API_CACHE_MAX_AGE,
+            asset_max_age_secs: DEFAULT_ASSET_CACHE_MAX_AGE,
+        }
+    }
+}
+
+fn env_u64(key: &str, default: u64) -> u64 {
+    std::env::var(key)
+        .ok()
+        .and_then(|s| s.parse().ok())
+        .unwrap_or(default)
+}
+
+impl CacheConfig {
+    /// `ARX_API_CACHE_MAX_AGE` / `ARX_ASSET_CACHE_MAX_AGE`, or the defaults.
+    pub fn from_env() -> Self {
+        Self {
+            api_max_age_secs: env_u64("ARX_API_CACHE_MAX_AGE", DEFAULT_API_CACHE_MAX_AGE),
+            asset_max_age_secs: env_u64("ARX_ASSET_CACHE_MAX_AGE", DEFAULT_ASSET_CACHE_MAX_AGE),
+        }
+    }
+
+    /// `Cache-Control` value for `class`, `None` to leave the response alone.
+    pub fn cache_control(&self, class: RouteClass) -> Option<String> {
+        match class {
+            RouteClass::Api if self.api_max_age_secs == 0 => Some("private, no-cache".into()),
+            RouteClass::Api => Some(format!(
+                "private, max-age={}, must-revalidate",
+                self.api_max_age_secs
+            )),
+            RouteClass::Revalidate => Some("no-cache".into()),
+            RouteClass::Immutable => Some(format!(
+                "public, max-age={}, immutable",
+                self.asset_max_age_secs
+            )),
+            RouteClass::Uncached => None,
+        }
+    }
+}
+
+/// Whether a file name carries a content hash segment, e.g. `app.3f9a1c2e.js` or
+/// `app-3f9a1c2e.css`.
+fn is_fingerprinted(file_name: &str) -> bool {
+    let Some((stem, _ext)) = file_name.rsplit_once('.') else {
+        return false;
+    };
+    stem.split(['.', '-', '_']).skip(1).any(|part| {
+        part.len() >= MIN_FINGERPRINT_LEN && part.chars().all(|c| c.is_ascii_hexdigit())
+    })
+}
+
+/// Class of a request for `path`; only GET (and HEAD) requests are cached.
+pub fn classify(method_is_get: bool, path: &str) -> RouteClass {
+    if !method_is_get || UNCACHED_PATHS.contains(&path) {
+        return RouteClass::Uncached;
+    }
+    if path.starts_with("/api/") {
+        return RouteClass::Api;
+    }
+    let file_name = path.rsplit('/').next().unwrap_or_default();
+    if is_fingerprinted(file_name) {
+        RouteClass::Immutable
+    } else {
+        RouteClass::Revalidate
+    }
+}
+
+/// Strong ETag of a response body.
+pub fn etag(body: &[u8]) -> String {
+    let digest = Sha256::digest(body);
+    let hex: String = digest[..16].iter().map(|b| format!("{:02x}", b)).collect();
+    format!("\"{}\"", hex)
+}
+
+/// Whether an `If-None-Match` header value matches `etag` (weak comparison, as
+/// RFC 9110 requires for `If-None-Match`).
+pub fn if_none_match(header: &str, etag: &str) -> bool {
+    let strip = |t: &str| t.trim().trim_start_matches("W/").to_string();
+    let etag = strip(etag);
+    header
+        .split(',')
+        .any(|candidate| candidate.trim() == "*" || strip(candidate) == etag)
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn cache_control_per_route_class() {
+        let config = CacheConfig::default();
+        let header = |get: bool, path: &str| config.cache_control(classify(get, path));
+
+        assert_eq!(
+            header(true, "/api/v1/buildings/hq/duplicates").as_deref(),
+            Some("private, no-cache")
+        );
+        assert_eq!(header(true, "/").as_deref(), Some("no-cache"));
+        assert_eq!(
+            header(true, "/demo/index.html").as_deref(),
+            Some("no-cache")
+        );
+        assert_eq!(header(true, "/demo/app.js").as_deref(), Some("no-cache"));
+        assert_eq!(
+            header(true, "/demo/assets/app.3f9a1c2e.js").as_deref(),
+            Some("public, max-age=31536000, immutable")
+        );
+        assert_eq!(
+            header(true, "/demo/assets/index-5d41402abc4b.css").as_deref(),
+            Some("public, max-age=31536000, immutable")
+        );
+        assert_eq!(header(false, "/api/v1/buildings/hq/merge"), None);
+        assert_eq!(header(true, "/ws"), None);
+        assert_eq!(header(true, "/metrics"), None);
+        assert_eq!(header(true, "/ready"), None);
+
+        let configured = CacheConfig {
+            api_max_age_secs: 30,
+            ..Default::default()
+        };
+        assert_eq!(
+            configured.cache_control(RouteClass::Api).as_deref(),
+            Some("private, max-age=30, must-revalidate")
+        );
+    }
+
+    #[test]
+    fn etag_revalidation() {
+        let tag = etag(br#"{"clusters":[]}"#);
+        assert_eq!(tag, etag(br#"{"clusters":[]}"#));
+        assert_ne!(tag, etag(br#"{"clusters":[1]}"#));
+        assert!(if_none_match(&tag, &tag));
+        assert!(if_none_match(&format!("\"stale\", W/{}", tag), &tag));
+        assert!(if_none_match("*", &tag));
+        assert!(!if_none_match("\"stale\"", &tag));
+        assert!(RouteClass::Api.uses_etag() && !RouteClass::Immutable.uses_etag());
+    }
+}
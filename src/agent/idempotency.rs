@@ -0,0 +1,433 @@
+//! `Idempotency-Key` support for non-idempotent RPC calls and REST POSTs.
+//!
+//! The first response for a key is cached per principal + method (or route) for a TTL. A retry
+//! with the same key and the same params receives the cached response instead of
+//! re-executing; reusing a key with different params is rejected. A retry that
+//! arrives while the first request is still running is rejected as in flight.
+//!
+//! Only final responses are cached: successes and rejections of the request itself.
+//! Internal, conflict, auth and not-found errors may succeed on retry, so they release
+//! the key instead, as does a request that is cancelled before it completes.
+
+use std::collections::HashMap;
+use std::sync::atomic::{AtomicU64, Ordering};
+use std::sync::Mutex;
+use std::time::{Duration, Instant};
+
+use serde_json::Value;
+use sha2::{Digest, Sha256};
+
+use crate::agent::protocol::{
+    JsonRpcResponse, INVALID_PARAMS, INVALID_REQUEST, METHOD_NOT_FOUND, PARSE_ERROR,
+    VALIDATION_ERROR,
+};
+
+/// Default lifetime of a cached response.
+pub const DEFAULT_IDEMPOTENCY_TTL: Duration = Duration::from_secs(24 * 60 * 60);
+
+/// Upper bound on accepted key length.
+pub const MAX_IDEMPOTENCY_KEY_LEN: usize = 255;
+
+/// Default bound on stored keys; the oldest completed ones are evicted first.
+pub const MAX_IDEMPOTENCY_ENTRIES: usize = 10_000;
+
+/// RPC methods that create or mutate state and therefore honour `Idempotency-Key`.
+pub const NON_IDEMPOTENT_METHODS: &[&str] = &[
+    "git.commit",
+    "ifc.import",
+    "building.changes.push",
+    "claim.review",
+    "auth.tokens.create",
+    "auth.tokens.revoke",
+];
+
+pub fn is_non_idempotent(method: &str) -> bool {
+    NON_IDEMPOTENT_METHODS.contains(&method)
+}
+
+/// REST POST routes (as registered) that honour `Idempotency-Key`.
+pub const IDEMPOTENT_POST_ROUTES: &[&str] = &[
+    "/api/v1/buildings/import/json",
+    "/api/v1/buildings/:id/clone",
+    "/api/v1/buildings/:id/changes",
+    "/api/v1/arxobjects/validate/batch",
+];
+
+/// A response the store can cache and replay.
+pub trait Replayable: Clone {
+    /// Whether a retry would get the same response: a success, or a rejection of the
+    /// request itself.
+    fn is_final(&self) -> bool;
+}
+
+impl Replayable for JsonRpcResponse {
+    fn is_final(&self) -> bool {
+        self.error.as_ref().map_or(true, |e| {
+            matches!(
+                e.code,
+                PARSE_ERROR
+                    | INVALID_REQUEST
+                    | METHOD_NOT_FOUND
+                    | INVALID_PARAMS
+                    | VALIDATION_ERROR
+            )
+        })
+    }
+}
+
+/// A REST response as cached for replay.
+#[derive(Debug, Clone)]
+pub struct HttpReplay {
+    pub status: u16,
+    pub content_type: Option<String>,
+    pub body: Vec<u8>,
+}
+
+impl Replayable for HttpReplay {
+    /// Successes, and the 400 / 422 rejections of the body itself.
+    fn is_final(&self) -> bool {
+        (200..300).contains(&self.status) || matches!(self.status, 400 | 422)
+    }
+}
+
+/// Result of claiming a key before executing a request.
+#[derive(Debug)]
+pub enum IdempotencyCheck<'a, R = JsonRpcResponse> {
+    /// First use: execute, then call [`IdempotencyClaim::complete`].
+    New(IdempotencyClaim<'a, R>),
+    /// Same key and params already completed: return this response.
+    Replay(R),
+    /// Key was used with different params.
+    Mismatch,
+    /// Key is held by a request that has not finished yet.
+    InFlight,
+    /// The store is full of requests that have not finished yet.
+    Full,
+}
+
+/// A key held by a running request. Dropping it without
+/// [`IdempotencyClaim::complete`] (the request was cancelled) releases the key.
+#[derive(Debug)]
+pub struct IdempotencyClaim<'a, R = JsonRpcResponse> {
+    store: &'a IdempotencyStore<R>,
+    scope: String,
+    id: u64,
+    completed: bool,
+}
+
+impl<R: Replayable> IdempotencyClaim<'_, R> {
+    /// Record the response: final ones are replayed to retries, others release the key.
+    pub fn complete(mut self, response: &R) {
+        self.completed = true;
+        let mut entries = self.store.entries.lock().unwrap();
+        if !response.is_final() {
+            remove_pending(&mut entries, &self.scope, self.id);
+        } else if let Some(entry) = entries.get_mut(&self.scope).filter(|e| e.claim == self.id) {
+            entry.state = EntryState::Done(response.clone());
+        }
+    }
+}
+
+impl<R> Drop for IdempotencyClaim<'_, R> {
+    fn drop(&mut self) {
+        if !self.completed {
+            if let Ok(mut entries) = self.store.entries.lock() {
+                remove_pending(&mut entries, &self.scope, self.id);
+            }
+        }
+    }
+}
+
+/// Remove `scope` if it is still the pending entry of claim `id` (not a newer claim
+/// made after this one expired).
+fn remove_pending<R>(entries: &mut HashMap<String, Entry<R>>, scope: &str, id: u64) {
+    if entries
+        .get(scope)
+        .is_some_and(|e| e.claim == id && matches!(e.state, EntryState::Pending))
+    {
+        entries.remove(scope);
+    }
+}
+
+#[derive(Debug)]
+enum EntryState<R> {
+    Pending,
+    Done(R),
+}
+
+#[derive(Debug)]
+struct Entry<R> {
+    fingerprint: String,
+    /// Id of the claim that created the entry.
+    claim: u64,
+    created_at: Instant,
+    state: EntryState<R>,
+}
+
+/// In-memory TTL store of idempotent responses, shared by all connections.
+#[derive(Debug)]
+pub struct IdempotencyStore<R = JsonRpcResponse> {
+    ttl: Duration,
+    max_entries: usize,
+    next_claim: AtomicU64,
+    entries: Mutex<HashMap<String, Entry<R>>>,
+}
+
+impl<R: Replayable> Default for IdempotencyStore<R> {
+    fn default() -> Self {
+        Self::new(DEFAULT_IDEMPOTENCY_TTL)
+    }
+}
+
+impl IdempotencyStore {
+    /// Scope a client key to the caller and method so keys never collide across them.
+    pub fn scope(principal: &str, method: &str, key: &str) -> String {
+        format!("{}:{}:{}", hash_hex(principal.as_bytes()), method, key)
+    }
+
+    /// Fingerprint of the request params used to detect key reuse with a different body.
+    pub fn fingerprint(params: Option<&Value>) -> String {
+        let canonical = params.map(|p| p.to_string()).unwrap_or_default();
+        hash_hex(canonical.as_bytes())
+    }
+
+    /// Fingerprint of a raw REST request body.
+    pub fn fingerprint_body(body: &[u8]) -> String {
+        hash_hex(body)
+    }
+}
+
+impl<R: Replayable> IdempotencyStore<R> {
+    pub fn new(ttl: Duration) -> Self {
+        Self {
+            ttl,
+            max_entries: MAX_IDEMPOTENCY_ENTRIES,
+            next_claim: AtomicU64::new(0),
+            entries: Mutex::new(HashMap::new()),
+        }
+    }
+
+    pub fn with_max_entries(mut self, max_entries: usize) -> Self {
+        self.max_entries = max_entries;
+        self
+    }
+
+    /// Claim `scope` for a request with `fingerprint`.
+    pub fn begin(&self, scope: &str, fingerprint: &str) -> IdempotencyCheck<'_, R> {
+        let mut entries = self.entries.lock().unwrap();
+        let now = Instant::now();
+        entries.retain(|_, e| now.duration_since(e.created_at) < self.ttl);
+
+        match entries.get(scope) {
+            Some(entry) if entry.fingerprint != fingerprint => IdempotencyCheck::Mismatch,
+            Some(Entry {
+                state: EntryState::Done(response),
+                ..
+            }) => IdempotencyCheck::Replay(response.clone()),
+            Some(_) => IdempotencyCheck::InFlight,
+            None => {
+                if entries.len() >= self.max_entries {
+                    let oldest = entries
+                        .iter()
+                        .filter(|(_, e)| matches!(e.state, EntryState::Done(_)))
+                        .min_by_key(|(_, e)| e.created_at)
+                        .map(|(k, _)| k.clone());
+                    match oldest {
+                        Some(key) => entries.remove(&key),
+                        None => return IdempotencyCheck::Full,
+                    };
+                }
+                let id = self.next_claim.fetch_add(1, Ordering::Relaxed);
+                entries.insert(
+                    scope.to_string(),
+                    Entry {
+                        fingerprint: fingerprint.to_string(),
+                        claim: id,
+                        created_at: now,
+                        state: EntryState::Pending,
+                    },
+                );
+                IdempotencyCheck::New(IdempotencyClaim {
+                    store: self,
+                    scope: scope.to_string(),
+                    id,
+                    completed: false,
+                })
+            }
+        }
+    }
+
+    pub fn len(&self) -> usize {
+        self.entries.lock().unwrap().len()
+    }
+
+    pub fn is_empty(&self) -> bool {
+        self.len() == 0
+    }
+}
+
+fn hash_hex(bytes: &[u8]) -> String {
+    Sha256::digest(bytes)
+        .iter()
+        .map(|b| format!("{:02x}", b))
+        .collect()
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::agent::protocol::INTERNAL_ERROR;
+    use serde_json::json;
+
+    fn scope() -> String {
+        IdempotencyStore::scope("token", "git.commit", "key-1")
+    }
+
+    #[test]
+    fn replay_returns_cached_response() {
+        let store = IdempotencyStore::default();
+        let params = json!({ "message": "Add AHU" });
+        let fp = IdempotencyStore::fingerprint(Some(&params));
+
+        let IdempotencyCheck::New(claim) = store.begin(&scope(), &fp) else {
+            panic!("expected a new claim");
+        };
+        assert!(matches!(
+            store.begin(&scope(), &fp),
+            IdempotencyCheck::InFlight
+        ));
+
+        let response = JsonRpcResponse::success(Some(json!(1)), json!({ "commit": "abc" }));
+        claim.complete(&response);
+        match store.begin(&scope(), &fp) {
+            IdempotencyCheck::Replay(cached) => {
+                assert_eq!(cached.result, Some(json!({ "commit": "abc" })))
+            }
+            other => panic!("expected replay, got {:?}", other),
+        }
+
+        let other_caller = IdempotencyStore::scope("other", "git.commit", "key-1");
+        assert!(matches!(
+            store.begin(&other_caller, &fp),
+            IdempotencyCheck::New(_)
+        ));
+    }
+
+    #[test]
+    fn reuse_with_different_body_is_rejected() {
+        let store = IdempotencyStore::default();
+        let fp = IdempotencyStore::fingerprint(Some(&json!({ "message": "a" })));
+        let other = IdempotencyStore::fingerprint(Some(&json!({ "message": "b" })));
+
+        let IdempotencyCheck::New(claim) = store.begin(&scope(), &fp) else {
+            panic!("expected a new claim");
+        };
+        claim.complete(&JsonRpcResponse::success(None, json!(true)));
+        assert!(matches!(
+            store.begin(&scope(), &other),
+            IdempotencyCheck::Mismatch
+        ));
+    }
+
+    #[test]
+    fn cancelled_or_transient_requests_release_the_key() {
+        let store = IdempotencyStore::default();
+        let fp = IdempotencyStore::fingerprint(None);
+
+        // Dropped before completing, as when the client disconnects mid-request.
+        drop(store.begin(&scope(), &fp));
+        assert!(store.is_empty());
+
+        let IdempotencyCheck::New(claim) = store.begin(&scope(), &fp) else {
+            panic!("expected a new claim");
+        };
+        claim.complete(&JsonRpcResponse::error(
+            None,
+            INTERNAL_ERROR,
+            "disk full".into(),
+            None,
+        ));
+        assert!(matches!(
+            store.begin(&scope(), &fp),
+            IdempotencyCheck::New(_)
+        ));
+
+        let IdempotencyCheck::New(claim) = store.begin(&scope(), &fp) else {
+            panic!("expected a new claim");
+        };
+        claim.complete(&JsonRpcResponse::error(
+            None,
+            INVALID_PARAMS,
+            "bad path".into(),
+            None,
+        ));
+        assert!(matches!(
+            store.begin(&scope(), &fp),
+            IdempotencyCheck::Replay(_)
+        ));
+    }
+
+    #[test]
+    fn http_responses_replay_only_when_final() {
+        let store = IdempotencyStore::<HttpReplay>::default();
+        let scope = IdempotencyStore::scope("token", "POST /api/v1/buildings/import/json", "k");
+        let fp = IdempotencyStore::fingerprint_body(b"{\"name\":\"HQ\"}");
+        let replay = |status| HttpReplay {
+            status,
+            content_type: Some("application/json".into()),
+            body: b"{}".to_vec(),
+        };
+
+        let IdempotencyCheck::New(claim) = store.begin(&scope, &fp) else {
+            panic!("expected a new claim");
+        };
+        claim.complete(&replay(409));
+        let IdempotencyCheck::New(claim) = store.begin(&scope, &fp) else {
+            panic!("a conflict should release the key");
+        };
+        claim.complete(&replay(201));
+        match store.begin(&scope, &fp) {
+            IdempotencyCheck::Replay(cached) => assert_eq!(cached.status, 201),
+            other => panic!("expected replay, got {:?}", other),
+        }
+        let other = IdempotencyStore::fingerprint_body(b"{}");
+        assert!(matches!(
+            store.begin(&scope, &other),
+            IdempotencyCheck::Mismatch
+        ));
+    }
+
+    #[test]
+    fn full_store_evicts_oldest_completed_key() {
+        let store = IdempotencyStore::default().with_max_entries(2);
+        let fp = IdempotencyStore::fingerprint(None);
+        let key = |n: u32| IdempotencyStore::scope("token", "git.commit", &n.to_string());
+
+        let IdempotencyCheck::New(first) = store.begin(&key(1), &fp) else {
+            panic!("expected a new claim");
+        };
+        first.complete(&JsonRpcResponse::success(None, json!(1)));
+        let IdempotencyCheck::New(_running) = store.begin(&key(2), &fp) else {
+            panic!("expected a new claim");
+        };
+        let IdempotencyCheck::New(_third) = store.begin(&key(3), &fp) else {
+            panic!("expected a new claim");
+        };
+        assert_eq!(store.len(), 2);
+        assert!(matches!(store.begin(&key(1), &fp), IdempotencyCheck::Full));
+    }
+
+    #[test]
+    fn entries_expire_after_ttl() {
+        let store: IdempotencyStore = IdempotencyStore::new(Duration::ZERO);
+        let fp = IdempotencyStore::fingerprint(None);
+        let first = store.begin(&scope(), &fp);
+        let second = store.begin(&scope(), &fp);
+        assert!(matches!(second, IdempotencyCheck::New(_)));
+        // Releasing the expired claim leaves the newer one in place.
+        drop(first);
+        assert_eq!(store.len(), 1);
+        assert!(is_non_idempotent("ifc.import"));
+        assert!(!is_non_idempotent("building.get"));
+    }
+}
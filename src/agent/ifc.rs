@@ -3,7 +3,7 @@ use std::path::Path;
 
 use crate::agent::git::SyncState;
 use crate::export::ifc::IFCExporter;
-use crate::ingest::import_ifc_path;
+use crate::ingest::{ImportOptions, ImporterRegistry};
 use crate::persistence::{load_building_at, save_building_at, BUILDING_YAML};
 use crate::utils::path_safety::PathSafety;
 use anyhow::{anyhow, bail, Result};
@@ -28,39 +28,52 @@ pub struct IfcExportResult {
     pub size_bytes: usize,
 }
 
-pub fn import_ifc(repo_root: &Path, filename: &str, data_base64: &str) -> Result<IfcImportResult> {
+/// Write an upload under `imports/` and import it with the importer selected by
+/// `content_type`, or by the filename's extension when none is given. A filename
+/// without an extension is taken as IFC, as older clients send bare names.
+pub fn import_ifc(
+    repo_root: &Path,
+    filename: &str,
+    data_base64: &str,
+    content_type: Option<&str>,
+) -> Result<IfcImportResult> {
     let bytes = decode_base64(data_base64)?;
     let max_ifc = crate::resource_limits::max_ifc_bytes() as usize;
     if bytes.len() > max_ifc {
         bail!(
-            "IFC payload exceeds {} bytes (ARX_MAX_IFC_BYTES / pilot default). See docs/resource-limits.md.",
+            "Import payload exceeds {} bytes (ARX_MAX_IFC_BYTES / pilot default). See docs/resource-limits.md.",
             max_ifc
         );
     }
 
-    let sanitized_name = ensure_extension(&sanitize_filename(filename, "upload.ifc"), ".ifc");
+    let mut sanitized_name = sanitize_filename(filename, "upload.ifc");
+    if Path::new(&sanitized_name).extension().is_none() {
+        sanitized_name = ensure_extension(&sanitized_name, ".ifc");
+    }
+    // Refuse unsupported uploads before anything is written.
+    ImporterRegistry::default().require(Path::new(&sanitized_name), content_type)?;
+
     let imports_dir = repo_root.join("imports");
     fs::create_dir_all(&imports_dir)?;
 
     let import_path = imports_dir.join(&sanitized_name);
     PathSafety::validate_path_for_write(&import_path).map_err(|e| anyhow!(e))?;
-    fs::write(&import_path, &bytes).map_err(|e| {
-        anyhow!(
-            "Failed to write IFC upload to {}: {}",
-            import_path.display(),
-            e
-        )
-    })?;
+    fs::write(&import_path, &bytes)
+        .map_err(|e| anyhow!("Failed to write upload to {}: {}", import_path.display(), e))?;
 
-    finish_import(repo_root, &import_path)
+    finish_import(repo_root, &import_path, content_type)
 }
 
 pub fn import_ifc_local(repo_root: &Path, ifc_path: &Path) -> Result<IfcImportResult> {
-    finish_import(repo_root, ifc_path)
+    finish_import(repo_root, ifc_path, None)
 }
 
 /// Shared import pipeline via `ingest` (parse → merge → validate → write YAML).
-fn finish_import(repo_root: &Path, ifc_path: &Path) -> Result<IfcImportResult> {
+fn finish_import(
+    repo_root: &Path,
+    import_path: &Path,
+    content_type: Option<&str>,
+) -> Result<IfcImportResult> {
     // Prefer merging with an existing YAML named after the eventual building, if present
     let building_yaml = repo_root.join("building.yaml");
     let existing = if building_yaml.exists() {
@@ -69,12 +82,21 @@ fn finish_import(repo_root: &Path, ifc_path: &Path) -> Result<IfcImportResult> {
         None
     };
 
-    let result = import_ifc_path(ifc_path, existing, false, true)
-        .map_err(|e| anyhow!("IFC import failed: {}", e))?;
+    let registry = ImporterRegistry::default();
+    let importer = registry.require(import_path, content_type)?;
+    let options = ImportOptions {
+        existing_yaml: existing,
+        validate: true,
+        ..Default::default()
+    };
+    let result = importer
+        .import(import_path, &options)
+        .map_err(|e| anyhow!("{} import failed: {}", importer.name(), e))?;
 
     if result.validation.has_errors() {
         return Err(anyhow!(
-            "IFC import validation failed; refusing to write {}: {}",
+            "{} import validation failed; refusing to write {}: {}",
+            importer.name(),
             BUILDING_YAML,
             result.summary_lines().join("; ")
         ));
@@ -263,11 +285,25 @@ mod tests {
         let ifc_data = sample_ifc_bytes();
         let encoded = general_purpose::STANDARD.encode(&ifc_data);
 
-        let result = import_ifc(repo_root, "Sample Building.ifc", &encoded).unwrap();
+        let result = import_ifc(repo_root, "Sample Building.ifc", &encoded, None).unwrap();
         assert!(repo_root.join(&result.yaml_path).exists());
         assert!(result.floors > 0);
     }
 
+    #[test]
+    fn import_selects_importer_by_content_type() {
+        let temp = TempDir::new().unwrap();
+        let repo_root = temp.path();
+        let encoded = general_purpose::STANDARD.encode(sample_ifc_bytes());
+
+        let result = import_ifc(repo_root, "upload.bin", &encoded, Some("model/ifc")).unwrap();
+        assert!(result.floors > 0);
+
+        let err = import_ifc(repo_root, "notes.pdf", &encoded, None).unwrap_err();
+        assert!(err.to_string().contains("no importer"));
+        assert!(!repo_root.join("imports").join("notes.pdf").exists());
+    }
+
     #[test]
     fn export_round_trip() {
         let temp = TempDir::new().unwrap();
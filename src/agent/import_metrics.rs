@@ -0,0 +1,239 @@
+//! Ingestion throughput and outcome metrics.
+//!
+//! Every import the agent runs (IFC uploads over RPC, IFC files picked up by the
+//! watcher, JSON restores) is recorded here and exported on `/metrics`:
+//!
+//! - `arx_agent_imports_total{source,outcome,reason}`: counter; `reason` is empty on
+//!   success and one of [`classify_import_error`]'s reasons on failure;
+//! - `arx_agent_import_duration_seconds{source}`: histogram of wall time;
+//! - `arx_agent_import_objects{source}`: histogram of rooms + equipment produced by
+//!   successful imports, so a drop in yield shows up as a shifted distribution.
+
+use std::collections::BTreeMap;
+use std::fmt::Write;
+use std::sync::Mutex;
+use std::time::Duration;
+
+/// Upper bounds (seconds) of the duration histogram.
+pub const IMPORT_DURATION_BUCKETS: &[f64] = &[0.1, 0.5, 1.0, 2.5, 5.0, 10.0, 30.0, 60.0, 300.0];
+/// Upper bounds of the objects-per-import histogram.
+pub const IMPORT_OBJECT_BUCKETS: &[f64] = &[0.0, 10.0, 50.0, 100.0, 500.0, 1000.0, 5000.0, 10000.0];
+
+/// Coarse failure reason for the `reason` label, from an import error message.
+pub fn classify_import_error(message: &str) -> &'static str {
+    let message = message.to_ascii_lowercase();
+    if message.contains("exceeds") {
+        "too_large"
+    } else if message.contains("validation") {
+        "validation"
+    } else if message.contains("failed to write") || message.contains("failed to read") {
+        "io"
+    } else if message.contains("base64") || message.contains("invalid") || message.contains("parse")
+    {
+        "invalid_input"
+    } else {
+        "other"
+    }
+}
+
+#[derive(Debug, Clone)]
+struct Histogram {
+    bounds: &'static [f64],
+    /// Non-cumulative count per bucket; the last slot is `+Inf`.
+    counts: Vec<u64>,
+    sum: f64,
+    count: u64,
+}
+
+impl Histogram {
+    fn new(bounds: &'static [f64]) -> Self {
+        Self {
+            bounds,
+            counts: vec![0; bounds.len() + 1],
+            sum: 0.0,
+            count: 0,
+        }
+    }
+
+    fn observe(&mut self, value: f64) {
+        let slot = self
+            .bounds
+            .iter()
+            .position(|b| value <= *b)
+            .unwrap_or(self.bounds.len());
+        self.counts[slot] += 1;
+        self.sum += value;
+        self.count += 1;
+    }
+
+    fn render(&self, name: &str, source: &str, out: &mut String) {
+        let mut cumulative = 0;
+        for (i, count) in self.counts.iter().enumerate() {
+            cumulative += count;
+            let le = self
+                .bounds
+                .get(i)
+                .map_or_else(|| "+Inf".to_string(), |b| b.to_string());
+            let _ = writeln!(
+                out,
+                "{}_bucket{{source=\"{}\",le=\"{}\"}} {}",
+                name, source, le, cumulative
+            );
+        }
+        let _ = writeln!(out, "{}_sum{{source=\"{}\"}} {}", name, source, self.sum);
+        let _ = writeln!(
+            out,
+            "{}_count{{source=\"{}\"}} {}",
+            name, source, self.count
+        );
+    }
+}
+
+#[derive(Default)]
+struct Inner {
+    /// (source, outcome, reason) → count.
+    totals: BTreeMap<(String, &'static str, &'static str), u64>,
+    durations: BTreeMap<String, Histogram>,
+    objects: BTreeMap<String, Histogram>,
+}
+
+/// Thread-safe import metrics, held by [`super::observability::AgentMetrics`].
+#[derive(Default)]
+pub struct ImportMetrics {
+    inner: Mutex<Inner>,
+}
+
+impl ImportMetrics {
+    pub fn new() -> Self {
+        Self::default()
+    }
+
+    pub fn record_success(&self, source: &str, duration: Duration, objects: usize) {
+        let Ok(mut inner) = self.inner.lock() else {
+            return;
+        };
+        *inner
+            .totals
+            .entry((source.to_string(), "success", ""))
+            .or_default() += 1;
+        Self::observe_duration(&mut inner, source, duration);
+        inner
+            .objects
+            .entry(source.to_string())
+            .or_insert_with(|| Histogram::new(IMPORT_OBJECT_BUCKETS))
+            .observe(objects as f64);
+    }
+
+    pub fn record_failure(&self, source: &str, duration: Duration, error: &str) {
+        let Ok(mut inner) = self.inner.lock() else {
+            return;
+        };
+        let reason = classify_import_error(error);
+        *inner
+            .totals
+            .entry((source.to_string(), "failure", reason))
+            .or_default() += 1;
+        Self::observe_duration(&mut inner, source, duration);
+    }
+
+    fn observe_duration(inner: &mut Inner, source: &str, duration: Duration) {
+        inner
+            .durations
+            .entry(source.to_string())
+            .or_insert_with(|| Histogram::new(IMPORT_DURATION_BUCKETS))
+            .observe(duration.as_secs_f64());
+    }
+
+    /// Imports recorded for `source` with `outcome` (`success` / `failure`).
+    pub fn total(&self, source: &str, outcome: &str) -> u64 {
+        self.inner.lock().map_or(0, |inner| {
+            inner
+                .totals
+                .iter()
+                .filter(|((s, o, _), _)| s == source && *o == outcome)
+                .map(|(_, n)| n)
+                .sum()
+        })
+    }
+
+    /// Prometheus text exposition of every import metric.
+    pub fn render_prometheus(&self) -> String {
+        let mut out = String::new();
+        let Ok(inner) = self.inner.lock() else {
+            return out;
+        };
+        out.push_str(
+            "# HELP arx_agent_imports_total Imports run by the agent, by source and outcome.\n\
+             # TYPE arx_agent_imports_total counter\n",
+        );
+        for ((source, outcome, reason), n) in &inner.totals {
+            let _ = writeln!(
+                out,
+                "arx_agent_imports_total{{source=\"{}\",outcome=\"{}\",reason=\"{}\"}} {}",
+                source, outcome, reason, n
+            );
+        }
+        out.push_str(
+            "# HELP arx_agent_import_duration_seconds Wall time of each import.\n\
+             # TYPE arx_agent_import_duration_seconds histogram\n",
+        );
+        for (source, h) in &inner.durations {
+            h.render("arx_agent_import_duration_seconds", source, &mut out);
+        }
+        out.push_str(
+            "# HELP arx_agent_import_objects Rooms and equipment produced per successful import.\n\
+             # TYPE arx_agent_import_objects histogram\n",
+        );
+        for (source, h) in &inner.objects {
+            h.render("arx_agent_import_objects", source, &mut out);
+        }
+        out
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn records_successful_and_failed_imports() {
+        let metrics = ImportMetrics::new();
+        metrics.record_success("ifc_upload", Duration::from_millis(800), 120);
+        metrics.record_failure(
+            "ifc_upload",
+            Duration::from_millis(50),
+            "IFC import validation failed; refusing to write building.yaml",
+        );
+        metrics.record_failure(
+            "ifc_watch",
+            Duration::from_secs(2),
+            "IFC payload exceeds 10 bytes",
+        );
+
+        assert_eq!(metrics.total("ifc_upload", "success"), 1);
+        assert_eq!(metrics.total("ifc_upload", "failure"), 1);
+        assert_eq!(metrics.total("ifc_watch", "failure"), 1);
+
+        let text = metrics.render_prometheus();
+        assert!(text.contains(
+            "arx_agent_imports_total{source=\"ifc_upload\",outcome=\"failure\",reason=\"validation\"} 1"
+        ));
+        assert!(text.contains(
+            "arx_agent_imports_total{source=\"ifc_watch\",outcome=\"failure\",reason=\"too_large\"} 1"
+        ));
+        assert!(text.contains(
+            "arx_agent_import_duration_seconds_bucket{source=\"ifc_upload\",le=\"0.1\"} 1"
+        ));
+        assert!(text.contains(
+            "arx_agent_import_duration_seconds_bucket{source=\"ifc_upload\",le=\"1\"} 2"
+        ));
+        assert!(
+            text.contains("arx_agent_import_objects_bucket{source=\"ifc_upload\",le=\"100\"} 0")
+        );
+        assert!(
+            text.contains("arx_agent_import_objects_bucket{source=\"ifc_upload\",le=\"500\"} 1")
+        );
+        assert!(text.contains("arx_agent_import_objects_count{source=\"ifc_upload\"} 1"));
+        assert!(!text.contains("arx_agent_import_objects_count{source=\"ifc_watch\"}"));
+    }
+}
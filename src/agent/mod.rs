@@ -2,6 +2,8 @@
 //!
 //! Provides a local WebSocket server for PWA integration when agent feature is enabled.
 
+#[cfg(feature = "agent")]
+pub mod access_log;
 #[cfg(feature = "agent")]
 pub mod auth;
 #[cfg(feature = "agent")]
@@ -15,12 +17,24 @@ pub mod commands;
 #[cfg(feature = "agent")]
 pub mod discovery;
 #[cfg(feature = "agent")]
+pub mod feature_flags;
+#[cfg(feature = "agent")]
 pub mod files;
 #[cfg(feature = "agent")]
 pub mod git;
 #[cfg(feature = "agent")]
+pub mod http_cache;
+#[cfg(feature = "agent")]
 pub mod ifc;
 #[cfg(feature = "agent")]
+pub mod import_metrics;
+#[cfg(feature = "agent")]
+pub mod idempotency;
+#[cfg(feature = "agent")]
+pub mod ndjson;
+#[cfg(feature = "agent")]
+pub mod service_tokens;
+#[cfg(feature = "agent")]
 pub mod ssh_auth;
 #[cfg(feature = "agent")]
 pub mod ssh_server;
@@ -0,0 +1,103 @@
+//! Newline-delimited JSON for large list responses.
+//!
+//! Clients that send `Accept: application/x-ndjson` get one JSON object per line
+//! instead of a single document, so they can start processing before the last
+//! object is written. Lines are produced lazily from an iterator; an item that
+//! fails ends the stream with a final `{"error": "..."}` record rather than a
+//! truncated body.
+
+use serde::Serialize;
+use serde_json::Value;
+
+pub const NDJSON_CONTENT_TYPE: &str = "application/x-ndjson";
+
+/// Whether an `Accept` header value asks for NDJSON.
+pub fn accepts_ndjson(accept: &str) -> bool {
+    accept.split(',').any(|part| {
+        part.split(';')
+            .next()
+            .is_some_and(|media| media.trim().eq_ignore_ascii_case(NDJSON_CONTENT_TYPE))
+    })
+}
+
+/// One serialized record followed by `\n`.
+pub fn line<T: Serialize>(value: &T) -> String {
+    match serde_json::to_string(value) {
+        Ok(json) => json + "\n",
+        Err(e) => error_line(&format!("Failed to serialize record: {}", e)),
+    }
+}
+
+fn error_line(message: &str) -> String {
+    serde_json::json!({ "error": message }).to_string() + "\n"
+}
+
+/// Lazily encode `items`, stopping after the first error record.
+pub fn lines<I, E>(items: I) -> impl Iterator<Item = String>
+where
+    I: IntoIterator<Item = Result<Value, E>>,
+    E: std::fmt::Display,
+{
+    let mut failed = false;
+    items.into_iter().map_while(move |item| {
+        if failed {
+            return None;
+        }
+        Some(match item {
+            Ok(value) => line(&value),
+            Err(e) => {
+                failed = true;
+                error_line(&e.to_string())
+            }
+        })
+    })
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use std::cell::Cell;
+
+    #[test]
+    fn negotiates_on_accept_header() {
+        assert!(accepts_ndjson("application/x-ndjson"));
+        assert!(accepts_ndjson(
+            "application/json;q=0.5, application/x-ndjson"
+        ));
+        assert!(!accepts_ndjson("application/json"));
+        assert!(!accepts_ndjson("*/*"));
+    }
+
+    #[test]
+    fn encodes_each_object_lazily_and_ends_on_error() {
+        let pulled = Cell::new(0);
+        let items = (0..1000).map(|i| {
+            pulled.set(pulled.get() + 1);
+            Ok::<_, String>(serde_json::json!({ "id": format!("obj-{}", i) }))
+        });
+        let mut stream = lines(items);
+        let first = stream.next().unwrap();
+        assert_eq!(
+            pulled.get(),
+            1,
+            "only the first object is read before it is sent"
+        );
+        let rest: Vec<String> = stream.collect();
+        assert_eq!(rest.len(), 999);
+        for (i, l) in std::iter::once(&first).chain(&rest).enumerate() {
+            assert!(l.ends_with('\n') && !l.trim_end().contains('\n'));
+            let value: Value = serde_json::from_str(l).unwrap();
+            assert_eq!(value["id"], format!("obj-{}", i));
+        }
+
+        let failing = vec![
+            Ok(serde_json::json!({ "id": "a" })),
+            Err("disk read failed"),
+            Ok(serde_json::json!({ "id": "b" })),
+        ];
+        let out: Vec<String> = lines(failing).collect();
+        assert_eq!(out.len(), 2);
+        let last: Value = serde_json::from_str(&out[1]).unwrap();
+        assert_eq!(last["error"], "disk read failed");
+    }
+}
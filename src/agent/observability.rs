@@ -9,6 +9,8 @@ use std::sync::OnceLock;
 use regex::Regex;
 use tracing_subscriber::{reload, EnvFilter, Registry};
 
+use super::import_metrics::ImportMetrics;
+
 /// Thread-safe accumulator for agent operational metrics.
 pub struct AgentMetrics {
     pub start_time: Instant,
@@ -18,6 +20,7 @@ pub struct AgentMetrics {
     pub rewards_distributed_axd: Mutex<f64>,
     pub errors_encountered: AtomicUsize,
     pub active_ws_clients: AtomicUsize,
+    pub imports: ImportMetrics,
 }
 
 impl Default for AgentMetrics {
@@ -36,6 +39,7 @@ impl AgentMetrics {
             rewards_distributed_axd: Mutex::new(0.0),
             errors_encountered: AtomicUsize::new(0),
             active_ws_clients: AtomicUsize::new(0),
+            imports: ImportMetrics::new(),
         }
     }
 
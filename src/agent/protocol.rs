@@ -1,6 +1,8 @@
 use serde::{Deserialize, Serialize};
 use serde_json::Value;
 
+use crate::error::{ArxError, ErrorCode};
+
 #[derive(Debug, Clone, Serialize, Deserialize)]
 pub struct JsonRpcRequest {
     pub jsonrpc: String,
@@ -46,6 +48,27 @@ impl JsonRpcResponse {
             id,
         }
     }
+
+    /// Error response for a handler failure.
+    ///
+    /// `data` is always `{code, message, details}` where `code` is a stable
+    /// [`ErrorCode`] string; the numeric JSON-RPC code is derived from it.
+    pub fn from_error(id: Option<Value>, err: &anyhow::Error) -> Self {
+        let agent_err = match err.downcast_ref::<AgentError>() {
+            Some(e) => e.clone(),
+            None => match err.downcast_ref::<ArxError>() {
+                Some(e) => AgentError::new(e.code(), e.to_string()),
+                None => AgentError::new(ErrorCode::Internal, err.to_string()),
+            },
+        };
+        Self::from_agent_error(id, agent_err)
+    }
+
+    pub fn from_agent_error(id: Option<Value>, err: AgentError) -> Self {
+        let code = err.rpc_code();
+        let message = err.message.clone();
+        Self::error(id, code, message, Some(err.to_data()))
+    }
 }
 
 // Error codes
@@ -55,3 +78,221 @@ pub const METHOD_NOT_FOUND: i32 = -32601;
 pub const INVALID_PARAMS: i32 = -32602;
 pub const INTERNAL_ERROR: i32 = -32603;
 pub const AUTH_ERROR: i32 = -32001;
+pub const NOT_FOUND_ERROR: i32 = -32002;
+pub const VALIDATION_ERROR: i32 = -32003;
+pub const CONFLICT_ERROR: i32 = -32004;
+
+/// Handler error carrying a stable [`ErrorCode`].
+///
+/// Handlers return `anyhow::Result`; wrap failures clients should branch on in an
+/// `AgentError` and the dispatcher reports its code instead of `ARX-INTERNAL`.
+#[derive(Debug, Clone, PartialEq)]
+pub struct AgentError {
+    pub code: ErrorCode,
+    pub message: String,
+    pub details: Option<Value>,
+}
+
+impl AgentError {
+    pub fn new(code: ErrorCode, message: impl Into<String>) -> Self {
+        Self {
+            code,
+            message: message.into(),
+            details: None,
+        }
+    }
+
+    pub fn with_details(mut self, details: Value) -> Self {
+        self.details = Some(details);
+        self
+    }
+
+    pub fn not_found(message: impl Into<String>) -> Self {
+        Self::new(ErrorCode::NotFound, message)
+    }
+
+    pub fn validation(message: impl Into<String>) -> Self {
+        Self::new(ErrorCode::Validation, message)
+    }
+
+    pub fn conflict(message: impl Into<String>) -> Self {
+        Self::new(ErrorCode::Conflict, message)
+    }
+
+    pub fn invalid_params(message: impl Into<String>) -> Self {
+        Self::new(ErrorCode::InvalidParams, message)
+    }
+
+    pub fn missing_param(name: &str) -> Self {
+        Self::invalid_params(format!("Missing '{}' parameter", name))
+            .with_details(serde_json::json!({ "param": name }))
+    }
+
+    pub fn rpc_code(&self) -> i32 {
+        match self.code {
+            ErrorCode::NotFound => NOT_FOUND_ERROR,
+            ErrorCode::Validation => VALIDATION_ERROR,
+            ErrorCode::Conflict => CONFLICT_ERROR,
+            ErrorCode::InvalidParams => INVALID_PARAMS,
+            ErrorCode::Unauthorized | ErrorCode::Forbidden => AUTH_ERROR,
+            ErrorCode::MethodNotFound => METHOD_NOT_FOUND,
+            ErrorCode::Internal => INTERNAL_ERROR,
+        }
+    }
+
+    /// `{code, message, details}` body shared by JSON-RPC `data` and REST error responses.
+    pub fn to_data(&self) -> Value {
+        serde_json::json!({
+            "code": self.code,
+            "message": self.message,
+            "details": self.details,
+        })
+    }
+}
+
+impl std::fmt::Display for AgentError {
+    fn fmt(&self, f: &mut std::fmt::Formatter<'_>) -> std::fmt::Result {
+        write!(f, "{}", self.message)
+    }
+}
+
+impl std::error::Error for AgentError {}
+
+/// One invalid request field.
+#[derive(Debug, Clone, PartialEq, Serialize)]
+pub struct FieldError {
+    pub field: String,
+    pub message: String,
+}
+
+/// Collects every invalid field of a request, so a client fixes them in one round
+/// trip instead of one error at a time.
+#[derive(Debug, Clone, Default)]
+pub struct FieldErrors {
+    errors: Vec<FieldError>,
+}
+
+impl FieldErrors {
+    pub fn new() -> Self {
+        Self::default()
+    }
+
+    pub fn add(&mut self, field: &str, message: impl Into<String>) {
+        self.errors.push(FieldError {
+            field: field.to_string(),
+            message: message.into(),
+        });
+    }
+
+    /// `value` when present, otherwise records `field` as required.
+    pub fn require<T>(&mut self, field: &str, value: Option<T>) -> Option<T> {
+        if value.is_none() {
+            self.add(field, "is required");
+        }
+        value
+    }
+
+    /// Append `other`'s errors for fields not reported yet, so a param that is missing
+    /// is not also reported as invalid.
+    pub fn extend(&mut self, other: FieldErrors) {
+        let reported: Vec<String> = self.errors.iter().map(|e| e.field.clone()).collect();
+        self.errors.extend(
+            other
+                .errors
+                .into_iter()
+                .filter(|e| !reported.contains(&e.field)),
+        );
+    }
+
+    pub fn is_empty(&self) -> bool {
+        self.errors.is_empty()
+    }
+
+    pub fn errors(&self) -> &[FieldError] {
+        &self.errors
+    }
+
+    /// `Ok` when nothing was recorded; otherwise an [`ErrorCode::Validation`] error
+    /// (HTTP 422) with details `{errors: [{field, message}]}`.
+    pub fn into_result(self) -> Result<(), AgentError> {
+        if self.errors.is_empty() {
+            return Ok(());
+        }
+        let message = self
+            .errors
+            .iter()
+            .map(|e| format!("{}: {}", e.field, e.message))
+            .collect::<Vec<_>>()
+            .join("; ");
+        Err(AgentError::validation(message)
+            .with_details(serde_json::json!({ "errors": self.errors })))
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn coded_errors_map_to_rpc_codes_and_data() {
+        let err = anyhow::Error::new(AgentError::missing_param("path"));
+        let resp = JsonRpcResponse::from_error(Some(Value::from(1)), &err);
+        let rpc = resp.error.unwrap();
+        assert_eq!(rpc.code, INVALID_PARAMS);
+        let data = rpc.data.unwrap();
+        assert_eq!(data["code"], "ARX-INVALID-PARAMS");
+        assert_eq!(data["details"]["param"], "path");
+
+        let conflict = AgentError::conflict("stale version");
+        assert_eq!(conflict.rpc_code(), CONFLICT_ERROR);
+        assert_eq!(conflict.code.http_status(), 409);
+    }
+
+    #[test]
+    fn arx_and_untyped_errors_get_codes() {
+        let io = std::io::Error::new(std::io::ErrorKind::NotFound, "building.yaml");
+        let err = anyhow::Error::new(ArxError::from(io));
+        let rpc = JsonRpcResponse::from_error(None, &err).error.unwrap();
+        assert_eq!(rpc.code, NOT_FOUND_ERROR);
+        assert_eq!(rpc.data.unwrap()["code"], "ARX-NOT-FOUND");
+
+        let err = anyhow::Error::new(ArxError::Validation {
+            message: "bad".into(),
+            field: None,
+        });
+        let rpc = JsonRpcResponse::from_error(None, &err).error.unwrap();
+        assert_eq!(rpc.data.unwrap()["code"], "ARX-VALIDATION");
+
+        let rpc = JsonRpcResponse::from_error(None, &anyhow::anyhow!("boom")).error.unwrap();
+        assert_eq!(rpc.code, INTERNAL_ERROR);
+        assert_eq!(rpc.data.unwrap()["code"], "ARX-INTERNAL");
+    }
+
+    #[test]
+    fn field_errors_are_reported_together() {
+        assert!(FieldErrors::new().into_result().is_ok());
+
+        let mut errors = FieldErrors::new();
+        assert_eq!(errors.require("name", None::<&str>), None);
+        assert_eq!(errors.require("organization", Some("acme")), Some("acme"));
+        let mut more = FieldErrors::new();
+        more.add("name", "must not be empty");
+        more.add("ttlHours", "must be positive");
+        errors.extend(more);
+        let err = errors.into_result().unwrap_err();
+        assert_eq!(err.code.http_status(), 422);
+        assert_eq!(err.message, "name: is required; ttlHours: must be positive");
+
+        let rpc = JsonRpcResponse::from_error(None, &anyhow::Error::new(err))
+            .error
+            .unwrap();
+        assert_eq!(rpc.code, VALIDATION_ERROR);
+        assert_eq!(
+            rpc.data.unwrap()["details"]["errors"],
+            serde_json::json!([
+                { "field": "name", "message": "is required" },
+                { "field": "ttlHours", "message": "must be positive" },
+            ])
+        );
+    }
+}
@@ -9,12 +9,21 @@ use std::{
 #[cfg(feature = "agent")]
 #[cfg(feature = "agent")]
 use crate::agent::{
-    auth::{generate_did_key, TokenState},
-    dispatcher::{dispatch, AgentState},
-    protocol::{JsonRpcRequest, JsonRpcResponse, PARSE_ERROR},
+    auth::{generate_did_key, route_access, RouteAccess, TokenState},
+    dispatcher::{dispatch_batch, dispatch_with_capabilities, AgentState, MAX_BATCH_SIZE},
+    idempotency::{
+        is_non_idempotent, HttpReplay, IdempotencyCheck, IdempotencyStore, IDEMPOTENT_POST_ROUTES,
+        MAX_IDEMPOTENCY_KEY_LEN,
+    },
+    ndjson,
+    protocol::{
+        AgentError, FieldErrors, JsonRpcRequest, JsonRpcResponse, INVALID_REQUEST, PARSE_ERROR,
+    },
     workspace::detect_repo_root,
 };
 #[cfg(feature = "agent")]
+use crate::error::ErrorCode;
+#[cfg(feature = "agent")]
 use axum::{
     extract::{
         ws::{Message, WebSocket, WebSocketUpgrade},
@@ -22,7 +31,7 @@ use axum::{
     },
     http::{HeaderMap, StatusCode},
     response::IntoResponse,
-    routing::{get, post},
+    routing::{get, post, put},
     Json, Router,
 };
 #[cfg(feature = "agent")]
@@ -72,6 +81,7 @@ pub async fn start_agent() -> Result<(), Box<dyn std::error::Error>> {
         "git.commit".to_string(),
         "files.read".to_string(),
         "building.get".to_string(),
+        "building.sync".to_string(),
         "ifc.import".to_string(),
         "ifc.export".to_string(),
         "collab.sync".to_string(),
@@ -85,6 +95,8 @@ pub async fn start_agent() -> Result<(), Box<dyn std::error::Error>> {
         token: Arc::new(Mutex::new(token_state)),
         metrics: metrics.clone(),
         reload_handle: Some(reload_handle.clone()),
+        idempotency: Arc::new(IdempotencyStore::default()),
+        http_idempotency: Arc::new(IdempotencyStore::default()),
     });
 
     // Spawn log watcher
@@ -98,12 +110,76 @@ pub async fn start_agent() -> Result<(), Box<dyn std::error::Error>> {
     let app = Router::new()
         .route("/ws", get(ws_handler))
         .route("/rpc", post(rpc_handler))
+        .route("/health", get(http_health))
+        .route("/ready", get(http_ready))
         .route("/api/status", get(http_agent_status))
         .route("/api/claims/status", get(http_claims_status))
         .route("/metrics", get(http_prometheus_metrics))
         .route("/api/claims/staging", get(http_claims_staging))
         .route("/api/claims/:id/approve", post(http_claim_approve))
         .route("/api/claims/:id/reject", post(http_claim_reject))
+        .route("/api/v1/arxobjects/:id", get(http_object_as_of))
+        .route("/api/v1/arxobjects/:id/history", get(http_object_history))
+        .route("/api/v1/arxobjects/validate/batch", post(http_validate_batch))
+        .route("/api/v1/floors/:level/arxobjects", get(http_floor_as_of))
+        .route("/api/v1/floors/reorder", post(http_floors_reorder))
+        .route("/api/v1/buildings/:id/clone", post(http_building_clone))
+        .route("/api/v1/buildings/import/json", post(http_building_import_json))
+        .route(
+            "/api/v1/buildings/:id/review/suggestions",
+            get(http_review_suggestions),
+        )
+        .route(
+            "/api/v1/buildings/:id/review/suggestions/apply",
+            post(http_review_suggestion_apply),
+        )
+        .route("/api/v1/buildings/:id/duplicates", get(http_building_duplicates))
+        .route("/api/v1/buildings/:id/merge", post(http_building_merge))
+        .route(
+            "/api/v1/buildings/:id/objects/delete-by-query",
+            post(http_building_delete_by_query),
+        )
+        .route("/api/v1/buildings/:id/reclassify", post(http_building_reclassify))
+        .route("/api/v1/buildings/:id/heatmap", post(http_building_heatmap))
+        .route("/api/v1/buildings/:id/validate", post(http_building_validate))
+        .route("/api/v1/buildings/:id/transform", post(http_building_transform))
+        .route(
+            "/api/v1/buildings/:id/changes",
+            get(http_building_changes).post(http_building_changes_push),
+        )
+        .route(
+            "/api/v1/buildings/:id/transform/undo",
+            post(http_building_transform_undo),
+        )
+        .route(
+            "/api/v1/buildings/:id/out-of-bounds",
+            get(http_building_out_of_bounds),
+        )
+        .route("/api/v1/templates", get(http_templates_list))
+        .route("/api/v1/equipment-types", get(http_equipment_types_list))
+        .route(
+            "/api/v1/equipment-types/:name",
+            put(http_equipment_type_put).delete(http_equipment_type_delete),
+        )
+        .route("/api/v1/access-log", get(http_access_log))
+        .route("/api/v1/features", get(http_features))
+        .route("/api/v1/custom-fields", get(http_custom_fields_list))
+        .route("/api/v1/custom-fields/:name", put(http_custom_field_put))
+        .route("/api/v1/buildings", get(http_buildings_list))
+        .route("/api/v1/buildings/:id", get(http_building_get))
+        .route(
+            "/api/v1/buildings/:id/custom-fields",
+            get(http_building_custom_fields).patch(http_building_custom_fields_patch),
+        )
+        .route_layer(axum::middleware::from_fn_with_state(
+            state.clone(),
+            idempotent_posts,
+        ))
+        .route_layer(axum::middleware::from_fn_with_state(
+            state.clone(),
+            require_route_access,
+        ))
+        .layer(axum::middleware::from_fn(cache_headers))
         .with_state(state.clone());
 
     // 4. Start File Watchers
@@ -121,6 +197,25 @@ pub async fn start_agent() -> Result<(), Box<dyn std::error::Error>> {
         }
     });
 
+    // Drop access-log entries past retention
+    let prune_root = repo_root.clone();
+    tokio::spawn(async move {
+        use crate::agent::access_log::{AccessLog, PRUNE_INTERVAL_SECS};
+        loop {
+            tokio::time::sleep(std::time::Duration::from_secs(PRUNE_INTERVAL_SECS)).await;
+            let root = prune_root.clone();
+            let pruned = tokio::task::spawn_blocking(move || {
+                AccessLog::open(&root).and_then(|log| log.prune(chrono::Utc::now()))
+            })
+            .await;
+            match pruned {
+                Ok(Ok(_)) => {}
+                Ok(Err(e)) => tracing::warn!(error = %e, "Failed to prune access log"),
+                Err(e) => tracing::warn!(error = %e, "Access log prune task failed"),
+            }
+        }
+    });
+
     // 5. Start P2P Local Discovery
     crate::agent::discovery::start_discovery(root_token.clone(), 8787);
 
@@ -186,24 +281,460 @@ fn guess_lan_ips() -> Vec<String> {
     out
 }
 
+/// Bearer token from the `Authorization` header, falling back to the `token` query param.
 #[cfg(feature = "agent")]
-fn check_auth(headers: &HeaderMap, query_token: Option<&str>, state: &AgentState) -> bool {
-    let token_str = if let Some(bearer) = headers
+fn request_token(headers: &HeaderMap, query_token: Option<&str>) -> Option<String> {
+    headers
         .get("Authorization")
         .and_then(|h| h.to_str().ok())
         .and_then(|s| s.strip_prefix("Bearer "))
+        .or(query_token)
+        .map(str::to_string)
+}
+
+/// Resolve the caller's actor name and capabilities from the root agent token or a
+/// service token. The actor is `root` or `service:<token id>`.
+#[cfg(feature = "agent")]
+fn identify(
+    headers: &HeaderMap,
+    query_token: Option<&str>,
+    state: &AgentState,
+) -> Option<(String, Vec<String>)> {
+    let token = request_token(headers, query_token)?;
+
     {
-        Some(bearer.to_string())
-    } else {
-        query_token.map(|s| s.to_string())
+        let guard = state.token.lock().unwrap();
+        if guard.value() == token {
+            return Some(("root".to_string(), guard.capabilities().to_vec()));
+        }
+    }
+
+    crate::agent::service_tokens::authenticate_service_token(&state.repo_root, &token)
+        .map(|grant| (format!("service:{}", grant.token_id), grant.capabilities))
+}
+
+/// Resolve the caller's capabilities from the root agent token or a service token.
+#[cfg(feature = "agent")]
+fn authenticate(
+    headers: &HeaderMap,
+    query_token: Option<&str>,
+    state: &AgentState,
+) -> Option<Vec<String>> {
+    identify(headers, query_token, state).map(|(_, capabilities)| capabilities)
+}
+
+/// Record a read in the access log when the building is audited.
+///
+/// Runs off the request path; a failed write is logged and never fails the read.
+#[cfg(feature = "agent")]
+fn audit_read(state: &AgentState, actor: String, action: &str, object: String) {
+    use crate::agent::access_log::{AccessEntry, AccessLog};
+
+    let repo_root = state.repo_root.clone();
+    let entry = AccessEntry {
+        timestamp: chrono::Utc::now(),
+        actor,
+        action: action.to_string(),
+        object,
     };
+    tokio::task::spawn_blocking(move || {
+        if let Err(e) = AccessLog::open(&repo_root).and_then(|log| log.record(&entry)) {
+            tracing::warn!(error = %e, action = %entry.action, "Failed to write access log");
+        }
+    });
+}
 
-    if let Some(token) = token_str {
-        let guard = state.token.lock().unwrap();
-        guard.value() == token
-    } else {
-        false
+#[cfg(feature = "agent")]
+fn check_auth(headers: &HeaderMap, query_token: Option<&str>, state: &AgentState) -> bool {
+    authenticate(headers, query_token, state).is_some()
+}
+
+/// Authenticate the caller and resolve their actor name and organization:
+/// `("root", None)` for the root token, `("service:<token id>", Some(org))` for a
+/// service token.
+#[cfg(feature = "agent")]
+fn caller(
+    headers: &HeaderMap,
+    query_token: Option<&str>,
+    state: &AgentState,
+) -> Result<(String, Option<String>), axum::response::Response> {
+    let unauthorized = || {
+        state.metrics.record_error();
+        error_response(ErrorCode::Unauthorized, "Unauthorized")
+    };
+    let Some(token) = request_token(headers, query_token) else {
+        return Err(unauthorized());
+    };
+    if state.token.lock().unwrap().value() == token {
+        return Ok(("root".to_string(), None));
+    }
+    crate::agent::service_tokens::authenticate_service_token(&state.repo_root, &token)
+        .map(|grant| {
+            (
+                format!("service:{}", grant.token_id),
+                Some(grant.organization),
+            )
+        })
+        .ok_or_else(unauthorized)
+}
+
+/// Authenticate the caller and resolve their organization: `Ok(None)` for the root
+/// token, `Ok(Some(org))` for a service token.
+#[cfg(feature = "agent")]
+fn caller_organization(
+    headers: &HeaderMap,
+    query_token: Option<&str>,
+    state: &AgentState,
+) -> Result<Option<String>, axum::response::Response> {
+    caller(headers, query_token, state).map(|(_, organization)| organization)
+}
+
+/// RPC methods that serve building data or repository files. RPC reads name no
+/// building, so whole-building reads are recorded against `building` and
+/// `files.read` against its path.
+#[cfg(feature = "agent")]
+const AUDITED_RPC_READS: &[&str] = &[
+    "building.get",
+    "building.changes.pull",
+    "ifc.export",
+    "files.read",
+];
+
+/// The access-log action and object of an audited RPC read; `None` for other methods.
+#[cfg(feature = "agent")]
+fn rpc_read(request: &JsonRpcRequest) -> Option<(String, String)> {
+    if !AUDITED_RPC_READS.contains(&request.method.as_str()) {
+        return None;
+    }
+    let object = match request.method.as_str() {
+        "files.read" => request
+            .params
+            .as_ref()
+            .and_then(|p| p.get("path"))
+            .and_then(|p| p.as_str())
+            .unwrap_or_default()
+            .to_string(),
+        _ => "building".to_string(),
+    };
+    Some((request.method.clone(), object))
+}
+
+/// Record an RPC read once it has been served; denied or failed calls read nothing.
+#[cfg(feature = "agent")]
+fn audit_rpc_read(
+    state: &AgentState,
+    actor: &str,
+    read: Option<(String, String)>,
+    response: &JsonRpcResponse,
+) {
+    if let Some((action, object)) = read.filter(|_| response.error.is_none()) {
+        audit_read(state, actor.to_string(), &action, object);
+    }
+}
+
+/// Authenticate the caller and require `flag` to be on for their organization; a
+/// disabled feature answers 404.
+#[cfg(feature = "agent")]
+fn require_feature(
+    headers: &HeaderMap,
+    query_token: Option<&str>,
+    state: &AgentState,
+    flag: &str,
+) -> Result<(), axum::response::Response> {
+    let organization = caller_organization(headers, query_token, state)?;
+    let flags = crate::agent::feature_flags::FeatureFlags::load(&state.repo_root).map_err(|e| {
+        state.metrics.record_error();
+        error_response(ErrorCode::Internal, e)
+    })?;
+    flags
+        .require(flag, organization.as_deref())
+        .map_err(agent_error_response)
+}
+
+/// REST error body `{code, message, details}` with the status implied by `code`.
+#[cfg(feature = "agent")]
+fn error_response(code: ErrorCode, message: impl Into<String>) -> axum::response::Response {
+    agent_error_response(AgentError::new(code, message))
+}
+
+/// Code for a failed building load or save: `NotFound` when there is no building,
+/// `Validation` when it failed validation, `Internal` for I/O, YAML and git errors.
+#[cfg(feature = "agent")]
+fn persistence_error_code(err: &(dyn std::error::Error + 'static)) -> ErrorCode {
+    use crate::persistence::PersistenceError;
+
+    match err.downcast_ref::<PersistenceError>() {
+        Some(PersistenceError::NotFound(_)) => ErrorCode::NotFound,
+        Some(PersistenceError::ValidationError(_)) => ErrorCode::Validation,
+        _ => ErrorCode::Internal,
+    }
+}
+
+/// [`error_response`] for a `load_building_at` / `persist_building_at` failure.
+#[cfg(feature = "agent")]
+fn persistence_error_response(
+    state: &AgentState,
+    context: &str,
+    err: Box<dyn std::error::Error>,
+) -> axum::response::Response {
+    state.metrics.record_error();
+    error_response(
+        persistence_error_code(err.as_ref()),
+        format!("{}: {}", context, err),
+    )
+}
+
+/// [`error_response`] for an error that carries details, such as [`FieldErrors`].
+#[cfg(feature = "agent")]
+fn agent_error_response(err: AgentError) -> axum::response::Response {
+    let status =
+        StatusCode::from_u16(err.code.http_status()).unwrap_or(StatusCode::INTERNAL_SERVER_ERROR);
+    (status, Json(err.to_data())).into_response()
+}
+
+#[cfg(feature = "agent")]
+fn wants_ndjson(headers: &HeaderMap) -> bool {
+    headers
+        .get(axum::http::header::ACCEPT)
+        .and_then(|v| v.to_str().ok())
+        .is_some_and(ndjson::accepts_ndjson)
+}
+
+/// Stream `items` as NDJSON, encoding each record only as the client reads it.
+#[cfg(feature = "agent")]
+fn ndjson_response<I, E>(items: I) -> axum::response::Response
+where
+    I: IntoIterator<Item = Result<serde_json::Value, E>>,
+    I::IntoIter: Send + 'static,
+    E: std::fmt::Display + Send + 'static,
+{
+    let lines = ndjson::lines(items).map(Ok::<_, std::convert::Infallible>);
+    (
+        [(axum::http::header::CONTENT_TYPE, ndjson::NDJSON_CONTENT_TYPE)],
+        axum::body::Body::from_stream(futures_util::stream::iter(lines)),
+    )
+        .into_response()
+}
+
+/// Check the caller against the [`route_access`] rule of `method` on the matched
+/// `route` pattern: 401 without a valid token, 403 without the capability.
+#[cfg(feature = "agent")]
+fn authorize_route(
+    headers: &HeaderMap,
+    query_token: Option<&str>,
+    state: &AgentState,
+    method: &str,
+    route: &str,
+) -> Result<(), axum::response::Response> {
+    let required = match route_access(method, route) {
+        RouteAccess::Public => return Ok(()),
+        RouteAccess::Authenticated => None,
+        RouteAccess::Capability(capability) => Some(capability),
+    };
+    let Some((_, capabilities)) = identify(headers, query_token, state) else {
+        state.metrics.record_error();
+        return Err(error_response(ErrorCode::Unauthorized, "Unauthorized"));
+    };
+    match required {
+        Some(required) if !capabilities.iter().any(|c| c == required) => Err(error_response(
+            ErrorCode::Forbidden,
+            format!("Capability '{}' required", required),
+        )),
+        _ => Ok(()),
+    }
+}
+
+/// Enforce token scope on every REST route before its handler runs.
+#[cfg(feature = "agent")]
+async fn require_route_access(
+    State(state): State<Arc<AgentState>>,
+    matched: Option<axum::extract::MatchedPath>,
+    request: axum::extract::Request,
+    next: axum::middleware::Next,
+) -> axum::response::Response {
+    let route = matched
+        .map(|m| m.as_str().to_string())
+        .unwrap_or_else(|| request.uri().path().to_string());
+    let query_token = Query::<AuthParams>::try_from_uri(request.uri())
+        .ok()
+        .and_then(|Query(params)| params.token);
+    if let Err(response) = authorize_route(
+        request.headers(),
+        query_token.as_deref(),
+        &state,
+        request.method().as_str(),
+        &route,
+    ) {
+        return response;
+    }
+    next.run(request).await
+}
+
+/// The trimmed `Idempotency-Key` header, when set.
+#[cfg(feature = "agent")]
+fn idempotency_key(headers: &HeaderMap) -> Option<&str> {
+    headers
+        .get("Idempotency-Key")
+        .and_then(|h| h.to_str().ok())
+        .map(str::trim)
+        .filter(|k| !k.is_empty())
+}
+
+/// Largest REST body buffered for an `Idempotency-Key` fingerprint; axum's default
+/// body limit, so no request the handlers would accept is refused here.
+#[cfg(feature = "agent")]
+const MAX_IDEMPOTENT_BODY_BYTES: usize = 2 * 1024 * 1024;
+
+/// Honour `Idempotency-Key` on [`IDEMPOTENT_POST_ROUTES`] as `/rpc` does: a retry
+/// with the same key and body replays the first final response, reusing a key with a
+/// different body is rejected, and a retry while the first is running is a conflict.
+/// Runs after [`require_route_access`], so only authorized requests claim a key.
+#[cfg(feature = "agent")]
+async fn idempotent_posts(
+    State(state): State<Arc<AgentState>>,
+    matched: Option<axum::extract::MatchedPath>,
+    request: axum::extract::Request,
+    next: axum::middleware::Next,
+) -> axum::response::Response {
+    use axum::http::{header, HeaderValue, Method};
+
+    let honoured = request.method() == Method::POST
+        && matched.is_some_and(|m| IDEMPOTENT_POST_ROUTES.contains(&m.as_str()));
+    let Some(key) = idempotency_key(request.headers())
+        .filter(|_| honoured)
+        .map(str::to_string)
+    else {
+        return next.run(request).await;
+    };
+    if key.len() > MAX_IDEMPOTENCY_KEY_LEN {
+        return error_response(ErrorCode::InvalidParams, "Idempotency-Key is too long");
+    }
+
+    let query_token = Query::<AuthParams>::try_from_uri(request.uri())
+        .ok()
+        .and_then(|Query(params)| params.token);
+    let principal = request_token(request.headers(), query_token.as_deref()).unwrap_or_default();
+    let scope =
+        IdempotencyStore::scope(&principal, &format!("POST {}", request.uri().path()), &key);
+    let (parts, body) = request.into_parts();
+    let body = match axum::body::to_bytes(body, MAX_IDEMPOTENT_BODY_BYTES).await {
+        Ok(body) => body,
+        Err(e) => return error_response(ErrorCode::InvalidParams, format!("Request body: {}", e)),
+    };
+    let fingerprint = IdempotencyStore::fingerprint_body(&body);
+    let claim = match state.http_idempotency.begin(&scope, &fingerprint) {
+        IdempotencyCheck::New(claim) => claim,
+        IdempotencyCheck::Replay(cached) => {
+            let mut response = (
+                StatusCode::from_u16(cached.status).unwrap_or(StatusCode::OK),
+                cached.body,
+            )
+                .into_response();
+            if let Some(value) = cached
+                .content_type
+                .and_then(|ct| HeaderValue::from_str(&ct).ok())
+            {
+                response.headers_mut().insert(header::CONTENT_TYPE, value);
+            }
+            response
+                .headers_mut()
+                .insert("Idempotent-Replayed", HeaderValue::from_static("true"));
+            return response;
+        }
+        IdempotencyCheck::Mismatch => {
+            return error_response(
+                ErrorCode::Validation,
+                "Idempotency-Key was already used with a different body",
+            );
+        }
+        IdempotencyCheck::InFlight => {
+            return error_response(
+                ErrorCode::Conflict,
+                "A request with this Idempotency-Key is still in progress",
+            );
+        }
+        IdempotencyCheck::Full => {
+            return error_response(
+                ErrorCode::Conflict,
+                "Too many requests with an Idempotency-Key are in progress",
+            );
+        }
+    };
+
+    // Dropping the claim (client gone before the response) releases the key.
+    let response = next
+        .run(axum::extract::Request::from_parts(parts, body.into()))
+        .await;
+    let (parts, body) = response.into_parts();
+    let body = match axum::body::to_bytes(body, usize::MAX).await {
+        Ok(body) => body,
+        Err(e) => return error_response(ErrorCode::Internal, format!("Response body: {}", e)),
+    };
+    claim.complete(&HttpReplay {
+        status: parts.status.as_u16(),
+        content_type: parts
+            .headers
+            .get(header::CONTENT_TYPE)
+            .and_then(|v| v.to_str().ok())
+            .map(str::to_string),
+        body: body.to_vec(),
+    });
+    axum::response::Response::from_parts(parts, body.into())
+}
+
+/// Set `Cache-Control` per route class and answer revalidated GETs whose
+/// `If-None-Match` still matches with `304 Not Modified`. Streamed NDJSON and
+/// non-success responses pass through untouched; see [`crate::agent::http_cache`].
+#[cfg(feature = "agent")]
+async fn cache_headers(
+    request: axum::extract::Request,
+    next: axum::middleware::Next,
+) -> axum::response::Response {
+    use crate::agent::http_cache::{classify, etag, if_none_match, CacheConfig};
+    use axum::http::{header, HeaderValue, Method};
+
+    let method = request.method().clone();
+    let class = classify(
+        method == Method::GET || method == Method::HEAD,
+        request.uri().path(),
+    );
+    let if_none = request
+        .headers()
+        .get(header::IF_NONE_MATCH)
+        .and_then(|v| v.to_str().ok())
+        .map(str::to_string);
+    let response = next.run(request).await;
+    let Some(cache_control) = CacheConfig::from_env().cache_control(class) else {
+        return response;
+    };
+    if !response.status().is_success() {
+        return response;
     }
+    let streamed = response
+        .headers()
+        .get(header::CONTENT_TYPE)
+        .is_some_and(|v| v.as_bytes() == ndjson::NDJSON_CONTENT_TYPE.as_bytes());
+    let (mut parts, body) = response.into_parts();
+    if let Ok(value) = HeaderValue::from_str(&cache_control) {
+        parts.headers.insert(header::CACHE_CONTROL, value);
+    }
+    if !class.uses_etag() || streamed {
+        return axum::response::Response::from_parts(parts, body);
+    }
+    let bytes = match axum::body::to_bytes(body, usize::MAX).await {
+        Ok(bytes) => bytes,
+        Err(e) => return error_response(ErrorCode::Internal, format!("Response body: {}", e)),
+    };
+    let tag = etag(&bytes);
+    if let Ok(value) = HeaderValue::from_str(&tag) {
+        parts.headers.insert(header::ETAG, value);
+    }
+    if if_none.is_some_and(|h| if_none_match(&h, &tag)) {
+        parts.status = StatusCode::NOT_MODIFIED;
+        parts.headers.remove(header::CONTENT_LENGTH);
+        return axum::response::Response::from_parts(parts, axum::body::Body::empty());
+    }
+    axum::response::Response::from_parts(parts, axum::body::Body::from(bytes))
 }
 
 #[cfg(feature = "agent")]
@@ -234,7 +765,7 @@ pub async fn http_claims_staging(
     State(state): State<Arc<AgentState>>,
 ) -> impl IntoResponse {
     if !check_auth(&headers, params.token.as_deref(), &state) {
-        return (StatusCode::UNAUTHORIZED, "Unauthorized").into_response();
+        return error_response(ErrorCode::Unauthorized, "Unauthorized");
     }
 
     use crate::agent::claim::GraceWindowManager;
@@ -243,12 +774,12 @@ pub async fn http_claims_staging(
     let manager = GraceWindowManager::new();
     let repo_str = match state.repo_root.to_str() {
         Some(s) => s,
-        None => return (StatusCode::INTERNAL_SERVER_ERROR, "Invalid repo path").into_response(),
+        None => return error_response(ErrorCode::Internal, "Invalid repo path"),
     };
 
     let pending = match manager.list_pending_contributions(repo_str) {
         Ok(p) => p,
-        Err(e) => return (StatusCode::INTERNAL_SERVER_ERROR, e).into_response(),
+        Err(e) => return error_response(ErrorCode::Internal, e),
     };
 
     let mut dtos = Vec::new();
@@ -299,7 +830,7 @@ pub async fn http_claim_approve(
 ) -> impl IntoResponse {
     if !check_auth(&headers, params.token.as_deref(), &state) {
         state.metrics.record_error();
-        return (StatusCode::UNAUTHORIZED, "Unauthorized").into_response();
+        return error_response(ErrorCode::Unauthorized, "Unauthorized");
     }
 
     use crate::agent::claim::GraceWindowManager;
@@ -309,15 +840,12 @@ pub async fn http_claim_approve(
         Some(s) => s,
         None => {
             state.metrics.record_error();
-            return (StatusCode::INTERNAL_SERVER_ERROR, "Invalid repo path").into_response();
+            return error_response(ErrorCode::Internal, "Invalid repo path");
         }
     };
     let building = match crate::persistence::load_building_at(&state.repo_root) {
         Ok(b) => b,
-        Err(e) => {
-            state.metrics.record_error();
-            return (StatusCode::INTERNAL_SERVER_ERROR, format!("Failed to load building: {}", e)).into_response();
-        }
+        Err(e) => return persistence_error_response(&state, "Failed to load building", e),
     };
     let building_id = building.id.clone();
 
@@ -342,7 +870,7 @@ pub async fn http_claim_approve(
         }
         Err(e) => {
             state.metrics.record_error();
-            (StatusCode::BAD_REQUEST, e).into_response()
+            error_response(ErrorCode::Validation, e)
         }
     }
 }
@@ -356,7 +884,7 @@ pub async fn http_claim_reject(
 ) -> impl IntoResponse {
     if !check_auth(&headers, params.token.as_deref(), &state) {
         state.metrics.record_error();
-        return (StatusCode::UNAUTHORIZED, "Unauthorized").into_response();
+        return error_response(ErrorCode::Unauthorized, "Unauthorized");
     }
 
     use crate::agent::claim::GraceWindowManager;
@@ -366,15 +894,12 @@ pub async fn http_claim_reject(
         Some(s) => s,
         None => {
             state.metrics.record_error();
-            return (StatusCode::INTERNAL_SERVER_ERROR, "Invalid repo path").into_response();
+            return error_response(ErrorCode::Internal, "Invalid repo path");
         }
     };
     let building = match crate::persistence::load_building_at(&state.repo_root) {
         Ok(b) => b,
-        Err(e) => {
-            state.metrics.record_error();
-            return (StatusCode::INTERNAL_SERVER_ERROR, format!("Failed to load building: {}", e)).into_response();
-        }
+        Err(e) => return persistence_error_response(&state, "Failed to load building", e),
     };
     let building_id = building.id.clone();
 
@@ -397,145 +922,1944 @@ pub async fn http_claim_reject(
         }
         Err(e) => {
             state.metrics.record_error();
-            (StatusCode::BAD_REQUEST, e).into_response()
+            error_response(ErrorCode::Validation, e)
         }
     }
 }
 
 #[cfg(feature = "agent")]
-async fn ws_handler(
-    ws: WebSocketUpgrade,
-    headers: HeaderMap,
-    Query(params): Query<AuthParams>,
-    State(state): State<Arc<AgentState>>,
-) -> impl IntoResponse {
-    if !check_auth(&headers, params.token.as_deref(), &state) {
-        return (
-            StatusCode::UNAUTHORIZED,
-            "Unauthorized: Invalid or missing token",
-        )
-            .into_response();
-    }
-
-    ws.on_upgrade(|socket| handle_socket(socket, state))
+#[derive(Deserialize)]
+pub struct HttpHistoryParams {
+    pub token: Option<String>,
+    pub field: Option<String>,
+    pub offset: Option<usize>,
+    pub limit: Option<usize>,
 }
 
+/// Field-level change timeline of one room or equipment, oldest first.
 #[cfg(feature = "agent")]
-async fn rpc_handler(
+pub async fn http_object_history(
     headers: HeaderMap,
-    Query(params): Query<AuthParams>,
+    Query(params): Query<HttpHistoryParams>,
+    axum::extract::Path(id): axum::extract::Path<String>,
     State(state): State<Arc<AgentState>>,
-    Json(request): Json<JsonRpcRequest>,
 ) -> impl IntoResponse {
-    if !check_auth(&headers, params.token.as_deref(), &state) {
-        return (
-            StatusCode::UNAUTHORIZED,
-            "Unauthorized: Invalid or missing token",
-        )
-            .into_response();
-    }
-
-    let response = dispatch(state, request).await;
-    Json(response).into_response()
-}
+    let Some((actor, _)) = identify(&headers, params.token.as_deref(), &state) else {
+        state.metrics.record_error();
+        return error_response(ErrorCode::Unauthorized, "Unauthorized");
+    };
+    audit_read(&state, actor, "object.history", id.clone());
 
-#[cfg(feature = "agent")]
-async fn handle_socket(mut socket: WebSocket, state: Arc<AgentState>) {
-    struct WsGuard(Arc<AgentState>);
-    impl Drop for WsGuard {
-        fn drop(&mut self) {
-            self.0.metrics.active_ws_clients.fetch_sub(1, Ordering::SeqCst);
-            tracing::info!(active = self.0.metrics.active_ws_clients.load(Ordering::SeqCst), "WebSocket client disconnected");
+    let history = match crate::ingest::history::object_history_at(&state.repo_root, &id) {
+        Ok(h) => h,
+        Err(e) => {
+            state.metrics.record_error();
+            return error_response(ErrorCode::Internal, format!("Failed to read history: {}", e));
         }
+    };
+    if history.kind.is_none() {
+        return error_response(ErrorCode::NotFound, format!("No history for object '{}'", id));
     }
-    state.metrics.active_ws_clients.fetch_add(1, Ordering::SeqCst);
-    let _guard = WsGuard(state.clone());
-    tracing::info!(active = state.metrics.active_ws_clients.load(Ordering::SeqCst), "WebSocket client connected");
 
-    while let Some(msg) = socket.recv().await {
-        let msg = match msg {
-            Ok(msg) => msg,
-            Err(e) => {
-                tracing::error!(error = %e, "WebSocket socket error");
-                return;
-            }
-        };
+    let query = crate::ingest::history::HistoryQuery {
+        field: params.field,
+        offset: params.offset.unwrap_or(0),
+        limit: params.limit,
+    };
+    Json(history.page(&query)).into_response()
+}
 
-        match msg {
-            Message::Text(text) => {
-                // Parse JSON-RPC Request
-                let response = match serde_json::from_str::<JsonRpcRequest>(&text) {
-                    Ok(request) => dispatch(state.clone(), request).await,
-                    Err(e) => JsonRpcResponse::error(
-                        None,
-                        PARSE_ERROR,
-                        format!("Invalid JSON: {}", e),
-                        None,
-                    ),
-                };
+#[cfg(feature = "agent")]
+#[derive(Deserialize)]
+pub struct HttpAsOfParams {
+    pub token: Option<String>,
+    /// RFC 3339 or Unix seconds; defaults to now.
+    pub as_of: Option<String>,
+    /// `local` (default) or `wgs84` to add a `geo` position to each object.
+    pub crs: Option<String>,
+}
 
-                // Send Response
-                if let Ok(resp_text) = serde_json::to_string(&response) {
-                    if let Err(e) = socket.send(Message::Text(resp_text)).await {
-                        tracing::error!(error = %e, "Failed to send WebSocket response");
-                        return;
-                    }
-                }
-            }
-            Message::Close(_) => {
-                return;
-            }
-            _ => {}
+/// The building's geo reference when `crs=wgs84` was requested.
+#[cfg(feature = "agent")]
+fn crs_param(
+    params: &HttpAsOfParams,
+    state: &AgentState,
+) -> Result<Option<crate::core::spatial::geo::GeoReference>, axum::response::Response> {
+    use crate::core::spatial::geo::GeoReference;
+
+    match params.crs.as_deref() {
+        None | Some("local") => Ok(None),
+        Some("wgs84") => {
+            let building = crate::persistence::load_building_at(&state.repo_root)
+                .map_err(|e| persistence_error_response(state, "Failed to load building", e))?;
+            GeoReference::from_building(&building).map(Some).ok_or_else(|| {
+                error_response(
+                    ErrorCode::Validation,
+                    "Building has no geo reference (geo:latitude / geo:longitude metadata)",
+                )
+            })
         }
+        Some(other) => Err(error_response(
+            ErrorCode::InvalidParams,
+            format!("Unknown crs '{}' (local|wgs84)", other),
+        )),
     }
 }
 
+/// Add `geo: {latitude, longitude, altitude}` beside an object's local position.
+///
+/// Rooms keep theirs under `spatial_properties.position`, equipment under `position`.
 #[cfg(feature = "agent")]
-#[derive(serde::Serialize)]
-struct AgentStatusDto {
-    status: String,
-    uptime_seconds: u64,
-    active_ws_clients: usize,
-    cache_stats: CacheStatsDto,
+fn add_geo_position(geo: &crate::core::spatial::geo::GeoReference, object: &mut serde_json::Value) {
+    let position = object
+        .pointer("/position")
+        .or_else(|| object.pointer("/spatial_properties/position"));
+    let local = position.and_then(|p| {
+        Some(crate::core::spatial::Point3D::new(
+            p.get("x")?.as_f64()?,
+            p.get("y")?.as_f64()?,
+            p.get("z")?.as_f64()?,
+        ))
+    });
+    if let (Some(local), Some(map)) = (local, object.as_object_mut()) {
+        map.insert("geo".to_string(), serde_json::json!(geo.to_wgs84(&local)));
+    }
 }
 
 #[cfg(feature = "agent")]
-#[derive(serde::Serialize)]
-struct CacheStatsDto {
-    warm_cache_size: usize,
+fn as_of_param(
+    params: &HttpAsOfParams,
+) -> Result<chrono::DateTime<chrono::Utc>, axum::response::Response> {
+    match params.as_of.as_deref() {
+        None => Ok(chrono::Utc::now()),
+        Some(raw) => crate::ingest::history::parse_timestamp(raw).ok_or_else(|| {
+            error_response(
+                ErrorCode::InvalidParams,
+                format!("Invalid as_of '{}': expected RFC 3339 or Unix seconds", raw),
+            )
+        }),
+    }
 }
 
+/// One room or equipment as of a past time, replayed from its history.
+///
+/// 404 before the object was created; a tombstone after it was deleted.
 #[cfg(feature = "agent")]
-pub async fn http_agent_status(
+pub async fn http_object_as_of(
     headers: HeaderMap,
-    Query(params): Query<AuthParams>,
+    Query(params): Query<HttpAsOfParams>,
+    axum::extract::Path(id): axum::extract::Path<String>,
     State(state): State<Arc<AgentState>>,
 ) -> impl IntoResponse {
-    if !check_auth(&headers, params.token.as_deref(), &state) {
-        state.metrics.record_error();
-        return (StatusCode::UNAUTHORIZED, "Unauthorized").into_response();
-    }
-
-    let uptime = state.metrics.start_time.elapsed().as_secs();
-    let cache_size = 0; // Static placeholder or simple count of active connections
+    use crate::ingest::history::ObjectAsOf;
 
-    let status = AgentStatusDto {
-        status: "healthy".to_string(),
-        uptime_seconds: uptime,
-        active_ws_clients: state.metrics.active_ws_clients.load(Ordering::SeqCst),
-        cache_stats: CacheStatsDto {
-            warm_cache_size: cache_size,
-        },
+    let Some((actor, _)) = identify(&headers, params.token.as_deref(), &state) else {
+        state.metrics.record_error();
+        return error_response(ErrorCode::Unauthorized, "Unauthorized");
+    };
+    audit_read(&state, actor, "object.as_of", id.clone());
+    let at = match as_of_param(&params) {
+        Ok(at) => at,
+        Err(response) => return response,
     };
 
-    Json(status).into_response()
+    let history = match crate::ingest::history::object_history_at(&state.repo_root, &id) {
+        Ok(h) => h,
+        Err(e) => {
+            state.metrics.record_error();
+            return error_response(ErrorCode::Internal, format!("Failed to read history: {}", e));
+        }
+    };
+    let geo = match crs_param(&params, &state) {
+        Ok(geo) => geo,
+        Err(response) => return response,
+    };
+    match history.as_of(at) {
+        ObjectAsOf::NotCreated => error_response(
+            ErrorCode::NotFound,
+            format!("Object '{}' did not exist at {}", id, at.to_rfc3339()),
+        ),
+        ObjectAsOf::Exists { version, object } => {
+            let mut object = object.clone();
+            if let Some(geo) = &geo {
+                add_geo_position(geo, &mut object);
+            }
+            Json(serde_json::json!({
+                "id": id,
+                "kind": history.kind,
+                "as_of": at,
+                "version": version,
+                "object": object,
+            }))
+            .into_response()
+        }
+        ObjectAsOf::Deleted { version } => Json(serde_json::json!({
+            "id": id,
+            "kind": history.kind,
+            "as_of": at,
+            "op": "deleted",
+            "version": version,
+        }))
+        .into_response(),
+    }
 }
 
+/// Every room and equipment on a floor as of a past time.
+///
+/// With `Accept: application/x-ndjson` the objects are streamed one per line.
 #[cfg(feature = "agent")]
-#[derive(serde::Serialize)]
-struct ClaimsStatusDto {
-    claims_processed: usize,
-    claims_approved: usize,
+pub async fn http_floor_as_of(
+    headers: HeaderMap,
+    Query(params): Query<HttpAsOfParams>,
+    axum::extract::Path(level): axum::extract::Path<i32>,
+    State(state): State<Arc<AgentState>>,
+) -> impl IntoResponse {
+    let Some((actor, _)) = identify(&headers, params.token.as_deref(), &state) else {
+        state.metrics.record_error();
+        return error_response(ErrorCode::Unauthorized, "Unauthorized");
+    };
+    audit_read(&state, actor, "floor.as_of", level.to_string());
+    let at = match as_of_param(&params) {
+        Ok(at) => at,
+        Err(response) => return response,
+    };
+
+    let geo = match crs_param(&params, &state) {
+        Ok(geo) => geo,
+        Err(response) => return response,
+    };
+
+    match crate::ingest::history::floor_as_of(&state.repo_root, level, at) {
+        Ok(Some(mut snapshot)) => {
+            if let Some(geo) = &geo {
+                for object in snapshot.rooms.iter_mut().chain(snapshot.equipment.iter_mut()) {
+                    add_geo_position(geo, object);
+                }
+            }
+            if wants_ndjson(&headers) {
+                let objects = snapshot.rooms.into_iter().chain(snapshot.equipment);
+                return ndjson_response(objects.map(Ok::<_, std::convert::Infallible>));
+            }
+            Json(snapshot).into_response()
+        }
+        Ok(None) => error_response(
+            ErrorCode::NotFound,
+            format!("Floor {} did not exist at {}", level, at.to_rfc3339()),
+        ),
+        Err(e) => {
+            state.metrics.record_error();
+            error_response(ErrorCode::Internal, format!("Failed to read history: {}", e))
+        }
+    }
+}
+
+#[cfg(feature = "agent")]
+#[derive(Deserialize)]
+pub struct HttpFloorReorderRequest {
+    /// Every floor id, bottom to top.
+    pub order: Vec<String>,
+    /// Floor id that becomes level 0 (`G`).
+    pub ground: String,
+}
+
+/// Renumber floors to a new stacking order; rooms and equipment move with their floor.
+#[cfg(feature = "agent")]
+pub async fn http_floors_reorder(
+    headers: HeaderMap,
+    Query(params): Query<AuthParams>,
+    State(state): State<Arc<AgentState>>,
+    Json(body): Json<HttpFloorReorderRequest>,
+) -> impl IntoResponse {
+    use crate::core::operations::{floor_label, reorder_floors};
+
+    if !check_auth(&headers, params.token.as_deref(), &state) {
+        state.metrics.record_error();
+        return error_response(ErrorCode::Unauthorized, "Unauthorized");
+    }
+    let mut building = match crate::persistence::load_building_at(&state.repo_root) {
+        Ok(b) => b,
+        Err(e) => return persistence_error_response(&state, "Failed to load building", e),
+    };
+    let changes = match reorder_floors(&mut building, &body.order, &body.ground) {
+        Ok(changes) => changes,
+        Err(e) => return error_response(ErrorCode::InvalidParams, e),
+    };
+    let floors: Vec<serde_json::Value> = building
+        .floors
+        .iter()
+        .map(|f| serde_json::json!({ "id": f.id, "name": f.name, "level": f.level, "label": floor_label(f) }))
+        .collect();
+    if !changes.is_empty() {
+        let message = format!("Reorder floors ({} renumbered)", changes.len());
+        if let Err(e) =
+            crate::ingest::persist_building_at(&state.repo_root, building, true, Some(&message))
+        {
+            return persistence_error_response(&state, "Reorder not applied", e);
+        }
+    }
+    Json(serde_json::json!({ "changes": changes, "floors": floors })).into_response()
+}
+
+#[cfg(feature = "agent")]
+#[derive(Deserialize)]
+pub struct HttpValidateBatchRequest {
+    pub items: Vec<crate::core::review::FieldValidation>,
+}
+
+/// Apply offline-collected field reviews as one commit; items fail independently.
+#[cfg(feature = "agent")]
+pub async fn http_validate_batch(
+    headers: HeaderMap,
+    Query(params): Query<AuthParams>,
+    State(state): State<Arc<AgentState>>,
+    Json(body): Json<HttpValidateBatchRequest>,
+) -> impl IntoResponse {
+    use crate::core::review::{apply_field_validations, MAX_VALIDATION_BATCH};
+
+    if !check_auth(&headers, params.token.as_deref(), &state) {
+        state.metrics.record_error();
+        return error_response(ErrorCode::Unauthorized, "Unauthorized");
+    }
+    if body.items.is_empty() || body.items.len() > MAX_VALIDATION_BATCH {
+        return error_response(
+            ErrorCode::InvalidParams,
+            format!("items must hold 1..={} validations", MAX_VALIDATION_BATCH),
+        );
+    }
+
+    let mut building = match crate::persistence::load_building_at(&state.repo_root) {
+        Ok(b) => b,
+        Err(e) => return persistence_error_response(&state, "Failed to load building", e),
+    };
+    let outcomes = apply_field_validations(&mut building, &body.items, chrono::Utc::now());
+    let applied = outcomes.iter().filter(|o| o.ok).count();
+    if applied > 0 {
+        let message = format!("Field validation of {} object(s)", applied);
+        if let Err(e) =
+            crate::ingest::persist_building_at(&state.repo_root, building, true, Some(&message))
+        {
+            return persistence_error_response(&state, "Batch not applied", e);
+        }
+    }
+
+    Json(serde_json::json!({
+        "applied": applied,
+        "failed": outcomes.len() - applied,
+        "results": outcomes,
+    }))
+    .into_response()
+}
+
+#[cfg(feature = "agent")]
+#[derive(Deserialize)]
+pub struct HttpCloneRequest {
+    /// Name of the new building.
+    pub name: String,
+    /// Drop field-validation state, sensor bindings and CMMS links.
+    #[serde(default)]
+    pub clean: bool,
+    /// Also register the (clean) clone as a reusable template under this name, in
+    /// the repository's `.arxos/templates`. Requires `auth.manage`.
+    pub template: Option<String>,
+}
+
+/// Preview a clone of the building with fresh ids. A repository holds one building,
+/// so nothing is persisted: the client saves the returned building (e.g. as a new
+/// repository) itself. Only `template` writes to this repository.
+#[cfg(feature = "agent")]
+pub async fn http_building_clone(
+    headers: HeaderMap,
+    Query(params): Query<AuthParams>,
+    axum::extract::Path(id): axum::extract::Path<String>,
+    State(state): State<Arc<AgentState>>,
+    Json(body): Json<HttpCloneRequest>,
+) -> impl IntoResponse {
+    let Some((_, capabilities)) = identify(&headers, params.token.as_deref(), &state) else {
+        state.metrics.record_error();
+        return error_response(ErrorCode::Unauthorized, "Unauthorized");
+    };
+    if body.name.trim().is_empty() {
+        return error_response(ErrorCode::InvalidParams, "name must not be empty");
+    }
+    if body.template.is_some() && !capabilities.iter().any(|c| c == "auth.manage") {
+        return error_response(
+            ErrorCode::Forbidden,
+            "auth.manage required to register a template",
+        );
+    }
+
+    let building = match crate::persistence::load_building_at(&state.repo_root) {
+        Ok(b) => b,
+        Err(e) => return persistence_error_response(&state, "Failed to load building", e),
+    };
+    if building.id != id {
+        return error_response(ErrorCode::NotFound, format!("Building '{}' not found", id));
+    }
+
+    let template = match body.template.as_deref() {
+        Some(name) => {
+            let root = crate::persistence::templates::project_templates_dir(&state.repo_root);
+            match crate::persistence::templates::save_template(&root, name, &building) {
+                Ok(_) => Some(name.to_string()),
+                Err(e) => {
+                    return persistence_error_response(&state, "Template not saved", e.into())
+                }
+            }
+        }
+        None => None,
+    };
+
+    let options = crate::core::operations::CloneOptions {
+        name: body.name,
+        clean: body.clean || template.is_some(),
+    };
+    let cloned = crate::core::operations::clone_building(&building, &options);
+    Json(serde_json::json!({
+        "building": cloned.building,
+        "id_map": cloned.id_map,
+        "template": template,
+        "persisted": false,
+    }))
+    .into_response()
+}
+
+#[cfg(feature = "agent")]
+#[derive(Deserialize)]
+pub struct HttpJsonImportRequest {
+    /// The document written by `arx export --format json`.
+    pub document: serde_json::Value,
+    /// Keep exported ids (restore) instead of assigning fresh ones (copy).
+    #[serde(default)]
+    pub preserve_ids: bool,
+    /// Overwrite an existing building in this repository.
+    #[serde(default)]
+    pub replace: bool,
+}
+
+/// Recreate a building from its JSON export.
+///
+/// The whole document is parsed and validated before `building.yaml` is written,
+/// in a single commit, so a rejected import leaves the repository untouched.
+#[cfg(feature = "agent")]
+pub async fn http_building_import_json(
+    headers: HeaderMap,
+    Query(params): Query<AuthParams>,
+    State(state): State<Arc<AgentState>>,
+    Json(body): Json<HttpJsonImportRequest>,
+) -> impl IntoResponse {
+    use crate::ingest::json::{import_building_json, JsonImportOptions};
+
+    if !check_auth(&headers, params.token.as_deref(), &state) {
+        state.metrics.record_error();
+        return error_response(ErrorCode::Unauthorized, "Unauthorized");
+    }
+    if !body.replace
+        && state
+            .repo_root
+            .join(crate::persistence::BUILDING_YAML)
+            .exists()
+    {
+        return error_response(
+            ErrorCode::Conflict,
+            "Repository already has a building; set replace to overwrite it",
+        );
+    }
+
+    let started = std::time::Instant::now();
+    let options = JsonImportOptions {
+        preserve_ids: body.preserve_ids,
+    };
+    let imported = match import_building_json(&body.document, &options) {
+        Ok(imported) => imported,
+        Err(e) => {
+            let message = format!("Invalid export: {:#}", e);
+            state
+                .metrics
+                .imports
+                .record_failure("json", started.elapsed(), &message);
+            return error_response(ErrorCode::Validation, message);
+        }
+    };
+    let message = format!("Import building from JSON: {}", imported.building.name);
+    match crate::ingest::persist_building_at(
+        &state.repo_root,
+        imported.building,
+        true,
+        Some(&message),
+    ) {
+        Ok(building) => {
+            let objects = building.get_all_rooms().len() + building.get_all_equipment().len();
+            state
+                .metrics
+                .imports
+                .record_success("json", started.elapsed(), objects);
+            Json(serde_json::json!({
+                "building_id": building.id,
+                "floors": building.floors.len(),
+                "rooms": building.get_all_rooms().len(),
+                "equipment": building.get_all_equipment().len(),
+                "id_map": imported.id_map,
+                "dropped": imported.dropped,
+            }))
+            .into_response()
+        }
+        Err(e) => {
+            let message = format!("Import not applied: {}", e);
+            state.metrics.record_error();
+            state
+                .metrics
+                .imports
+                .record_failure("json", started.elapsed(), &message);
+            error_response(persistence_error_code(e.as_ref()), message)
+        }
+    }
+}
+
+/// Load the repository's building, answering 404 unless its id is `id`.
+#[cfg(feature = "agent")]
+fn load_building_by_id(
+    state: &AgentState,
+    id: &str,
+) -> Result<crate::core::Building, axum::response::Response> {
+    let building = crate::persistence::load_building_at(&state.repo_root)
+        .map_err(|e| persistence_error_response(state, "Failed to load building", e))?;
+    if building.id != id {
+        return Err(error_response(
+            ErrorCode::NotFound,
+            format!("Building '{}' not found", id),
+        ));
+    }
+    Ok(building)
+}
+
+/// Suggested fixes for equipment awaiting review, most confident first.
+#[cfg(feature = "agent")]
+pub async fn http_review_suggestions(
+    headers: HeaderMap,
+    Query(params): Query<AuthParams>,
+    axum::extract::Path(id): axum::extract::Path<String>,
+    State(state): State<Arc<AgentState>>,
+) -> impl IntoResponse {
+    if !check_auth(&headers, params.token.as_deref(), &state) {
+        state.metrics.record_error();
+        return error_response(ErrorCode::Unauthorized, "Unauthorized");
+    }
+    let building = match load_building_by_id(&state, &id) {
+        Ok(b) => b,
+        Err(response) => return response,
+    };
+    let suggestions = crate::core::suggestions::suggest_fixes(&building);
+    Json(serde_json::json!({ "suggestions": suggestions })).into_response()
+}
+
+/// Apply one suggestion returned by [`http_review_suggestions`] as a commit.
+#[cfg(feature = "agent")]
+pub async fn http_review_suggestion_apply(
+    headers: HeaderMap,
+    Query(params): Query<AuthParams>,
+    axum::extract::Path(id): axum::extract::Path<String>,
+    State(state): State<Arc<AgentState>>,
+    Json(suggestion): Json<crate::core::suggestions::Suggestion>,
+) -> impl IntoResponse {
+    if !check_auth(&headers, params.token.as_deref(), &state) {
+        state.metrics.record_error();
+        return error_response(ErrorCode::Unauthorized, "Unauthorized");
+    }
+    let mut building = match load_building_by_id(&state, &id) {
+        Ok(b) => b,
+        Err(response) => return response,
+    };
+    if let Err(e) = crate::core::suggestions::apply_suggestion(&mut building, &suggestion) {
+        return error_response(ErrorCode::Conflict, e);
+    }
+    let message = format!("Apply review suggestion to {}", suggestion.object_id);
+    match crate::ingest::persist_building_at(&state.repo_root, building, true, Some(&message)) {
+        Ok(_) => Json(serde_json::json!({ "applied": suggestion })).into_response(),
+        Err(e) => persistence_error_response(&state, "Suggestion not applied", e),
+    }
+}
+
+#[cfg(feature = "agent")]
+#[derive(Deserialize)]
+pub struct HttpDuplicatesParams {
+    pub token: Option<String>,
+    /// Match distance in metres; defaults to
+    /// [`crate::core::operations::duplicates::DEFAULT_DUPLICATE_TOLERANCE_M`].
+    pub tolerance: Option<f64>,
+}
+
+/// Clusters of equipment that look like the same physical object.
+#[cfg(feature = "agent")]
+pub async fn http_building_duplicates(
+    headers: HeaderMap,
+    Query(params): Query<HttpDuplicatesParams>,
+    axum::extract::Path(id): axum::extract::Path<String>,
+    State(state): State<Arc<AgentState>>,
+) -> impl IntoResponse {
+    if !check_auth(&headers, params.token.as_deref(), &state) {
+        state.metrics.record_error();
+        return error_response(ErrorCode::Unauthorized, "Unauthorized");
+    }
+    let tolerance = params
+        .tolerance
+        .unwrap_or(crate::core::operations::duplicates::DEFAULT_DUPLICATE_TOLERANCE_M);
+    if !tolerance.is_finite() || tolerance <= 0.0 {
+        return error_response(ErrorCode::InvalidParams, "tolerance must be positive");
+    }
+    let building = match load_building_by_id(&state, &id) {
+        Ok(b) => b,
+        Err(response) => return response,
+    };
+    let clusters = crate::core::operations::find_duplicates(&building, tolerance);
+    Json(serde_json::json!({ "tolerance": tolerance, "clusters": clusters })).into_response()
+}
+
+#[cfg(feature = "agent")]
+#[derive(Deserialize)]
+pub struct HttpMergeRequest {
+    /// Equipment ids describing one physical object.
+    pub ids: Vec<String>,
+}
+
+/// Merge duplicate equipment into one record as a single commit; reverting the
+/// commit undoes the merge.
+#[cfg(feature = "agent")]
+pub async fn http_building_merge(
+    headers: HeaderMap,
+    Query(params): Query<AuthParams>,
+    axum::extract::Path(id): axum::extract::Path<String>,
+    State(state): State<Arc<AgentState>>,
+    Json(req): Json<HttpMergeRequest>,
+) -> impl IntoResponse {
+    use crate::agent::feature_flags::FLAG_DUPLICATE_MERGE;
+
+    if let Err(response) =
+        require_feature(&headers, params.token.as_deref(), &state, FLAG_DUPLICATE_MERGE)
+    {
+        return response;
+    }
+    let mut building = match load_building_by_id(&state, &id) {
+        Ok(b) => b,
+        Err(response) => return response,
+    };
+    let outcome = match crate::core::operations::merge_equipment(&mut building, &req.ids) {
+        Ok(outcome) => outcome,
+        Err(e) => return error_response(ErrorCode::InvalidParams, e),
+    };
+    let message = format!(
+        "Merge {} duplicate(s) into {}",
+        outcome.merged.len(),
+        outcome.survivor
+    );
+    match crate::ingest::persist_building_at(&state.repo_root, building, true, Some(&message)) {
+        Ok(_) => Json(serde_json::json!({ "merge": outcome })).into_response(),
+        Err(e) => persistence_error_response(&state, "Merge not saved", e),
+    }
+}
+
+#[cfg(feature = "agent")]
+#[derive(Deserialize)]
+pub struct HttpDeleteByQueryRequest {
+    pub query: crate::core::operations::EquipmentQuery,
+    /// Token issued by a dry run of the same query; without it nothing is deleted.
+    #[serde(default)]
+    pub confirm: Option<String>,
+}
+
+/// Soft-delete every equipment matching a query. Without `confirm` this is a dry run
+/// that returns the matches and a single-use confirmation token valid for
+/// [`CONFIRMATION_TTL_SECS`](crate::core::operations::delete_query::CONFIRMATION_TTL_SECS);
+/// the delete runs only while the query still matches the same objects, as one commit.
+#[cfg(feature = "agent")]
+pub async fn http_building_delete_by_query(
+    headers: HeaderMap,
+    Query(params): Query<AuthParams>,
+    axum::extract::Path(id): axum::extract::Path<String>,
+    State(state): State<Arc<AgentState>>,
+    Json(req): Json<HttpDeleteByQueryRequest>,
+) -> impl IntoResponse {
+    use crate::agent::feature_flags::FLAG_BULK_DELETE;
+    use crate::core::equipment_types::EquipmentTypeRegistry;
+    use crate::core::operations::{find_matching, mark_deleted, DeleteConfirmations};
+
+    if let Err(response) =
+        require_feature(&headers, params.token.as_deref(), &state, FLAG_BULK_DELETE)
+    {
+        return response;
+    }
+    if let Err(e) = req.query.check() {
+        return error_response(ErrorCode::InvalidParams, e);
+    }
+    let mut building = match load_building_by_id(&state, &id) {
+        Ok(b) => b,
+        Err(response) => return response,
+    };
+    let registry = match EquipmentTypeRegistry::load(&state.repo_root) {
+        Ok(registry) => registry,
+        Err(e) => {
+            state.metrics.record_error();
+            return error_response(ErrorCode::Internal, e);
+        }
+    };
+    let ids = find_matching(&building, &registry, &req.query);
+    let now = chrono::Utc::now();
+    let Some(confirm) = req.confirm else {
+        let issued = DeleteConfirmations::update(&state.repo_root, |confirmations| {
+            Ok(confirmations.issue(&building.id, &ids, now))
+        });
+        return match issued {
+            Ok(token) => Json(serde_json::json!({
+                "dry_run": true,
+                "count": ids.len(),
+                "ids": ids,
+                "confirm": token,
+            }))
+            .into_response(),
+            Err(e) => {
+                state.metrics.record_error();
+                error_response(ErrorCode::Internal, e)
+            }
+        };
+    };
+    let confirmed = DeleteConfirmations::update(&state.repo_root, |confirmations| {
+        Ok(confirmations.redeem(&building.id, &confirm, now))
+    });
+    let confirmed = match confirmed {
+        Ok(Ok(confirmed)) => confirmed,
+        Ok(Err(e)) => return error_response(ErrorCode::Conflict, e),
+        Err(e) => {
+            state.metrics.record_error();
+            return error_response(ErrorCode::Internal, e);
+        }
+    };
+    if confirmed != ids {
+        return error_response(
+            ErrorCode::Conflict,
+            format!(
+                "The query matches {} object(s) now, not the {} confirmed; run it again without `confirm`",
+                ids.len(),
+                confirmed.len()
+            ),
+        );
+    }
+    let deleted = mark_deleted(&mut building, &ids, now);
+    if deleted.is_empty() {
+        return Json(serde_json::json!({ "dry_run": false, "count": 0, "deleted": deleted }))
+            .into_response();
+    }
+    let message = format!("Delete {} equipment by query", deleted.len());
+    match crate::ingest::persist_building_at(&state.repo_root, building, true, Some(&message)) {
+        Ok(_) => Json(serde_json::json!({
+            "dry_run": false,
+            "count": deleted.len(),
+            "deleted": deleted,
+        }))
+        .into_response(),
+        Err(e) => persistence_error_response(&state, "Delete not saved", e),
+    }
+}
+
+#[cfg(feature = "agent")]
+#[derive(Deserialize)]
+pub struct HttpReclassifyRequest {
+    /// Rules to run instead of `.arxos/reclassify.yaml`.
+    #[serde(default)]
+    pub rules: Option<crate::core::operations::ReclassifyRules>,
+    /// Make the changes; by default the response is only a preview.
+    #[serde(default)]
+    pub apply: bool,
+}
+
+/// Relabel equipment types by ordered rules, first match wins. A preview unless
+/// `apply` is set; applied changes are one commit.
+#[cfg(feature = "agent")]
+pub async fn http_building_reclassify(
+    headers: HeaderMap,
+    Query(params): Query<AuthParams>,
+    axum::extract::Path(id): axum::extract::Path<String>,
+    State(state): State<Arc<AgentState>>,
+    Json(req): Json<HttpReclassifyRequest>,
+) -> impl IntoResponse {
+    use crate::core::equipment_types::EquipmentTypeRegistry;
+    use crate::core::operations::{apply_reclassification, plan_reclassification, ReclassifyRules};
+
+    if !check_auth(&headers, params.token.as_deref(), &state) {
+        state.metrics.record_error();
+        return error_response(ErrorCode::Unauthorized, "Unauthorized");
+    }
+    let rules = match req.rules {
+        Some(rules) => rules,
+        None => match ReclassifyRules::load(&state.repo_root) {
+            Ok(rules) => rules,
+            Err(e) => return error_response(ErrorCode::Validation, e),
+        },
+    };
+    let mut building = match load_building_by_id(&state, &id) {
+        Ok(b) => b,
+        Err(response) => return response,
+    };
+    let registry = match EquipmentTypeRegistry::load(&state.repo_root) {
+        Ok(registry) => registry,
+        Err(e) => {
+            state.metrics.record_error();
+            return error_response(ErrorCode::Internal, e);
+        }
+    };
+    let changes = match plan_reclassification(&building, &registry, &rules) {
+        Ok(changes) => changes,
+        Err(e) => return error_response(ErrorCode::InvalidParams, e),
+    };
+    if !req.apply || changes.is_empty() {
+        return Json(serde_json::json!({ "applied": false, "changes": changes })).into_response();
+    }
+    let applied = apply_reclassification(&mut building, &changes);
+    let message = format!("Reclassify {} equipment by rule", applied);
+    match crate::ingest::persist_building_at(&state.repo_root, building, true, Some(&message)) {
+        Ok(_) => Json(serde_json::json!({ "applied": true, "changes": changes })).into_response(),
+        Err(e) => persistence_error_response(&state, "Reclassification not saved", e),
+    }
+}
+
+/// Rules to validate against: `"starter"` or an inline rule set.
+#[cfg(feature = "agent")]
+#[derive(Deserialize)]
+#[serde(untagged)]
+pub enum HttpValidateRules {
+    Named(String),
+    Inline(crate::validation::RuleSet),
+}
+
+#[cfg(feature = "agent")]
+#[derive(Deserialize)]
+pub struct HttpValidateRequest {
+    /// Project `.arxos/rules.yaml` when omitted.
+    #[serde(default)]
+    pub rules: Option<HttpValidateRules>,
+}
+
+/// Run the built-in checks, a rule set, property schemas and floor bounds, and
+/// return the violations with their severity and offending object.
+#[cfg(feature = "agent")]
+pub async fn http_building_validate(
+    headers: HeaderMap,
+    Query(params): Query<AuthParams>,
+    axum::extract::Path(id): axum::extract::Path<String>,
+    State(state): State<Arc<AgentState>>,
+    Json(req): Json<HttpValidateRequest>,
+) -> impl IntoResponse {
+    use crate::validation::ruleset::{resolve_ruleset, starter_ruleset, validate_project};
+
+    if !check_auth(&headers, params.token.as_deref(), &state) {
+        state.metrics.record_error();
+        return error_response(ErrorCode::Unauthorized, "Unauthorized");
+    }
+    let rules = match req.rules {
+        Some(HttpValidateRules::Named(name)) if name == "starter" => Some(starter_ruleset()),
+        Some(HttpValidateRules::Named(name)) => {
+            return error_response(
+                ErrorCode::InvalidParams,
+                format!(
+                    "Unknown rule set '{}'; pass \"starter\" or inline rules",
+                    name
+                ),
+            )
+        }
+        Some(HttpValidateRules::Inline(set)) => match set.check() {
+            Ok(()) => Some(set),
+            Err(e) => return error_response(ErrorCode::InvalidParams, e),
+        },
+        None => match resolve_ruleset(&state.repo_root, None) {
+            Ok(set) => set,
+            Err(e) => {
+                state.metrics.record_error();
+                return error_response(ErrorCode::Validation, e);
+            }
+        },
+    };
+    let building = match load_building_by_id(&state, &id) {
+        Ok(b) => b,
+        Err(response) => return response,
+    };
+    match validate_project(&state.repo_root, &building, rules.as_ref()) {
+        Ok(report) => Json(serde_json::json!({
+            "valid": !report.has_errors(),
+            "violations": report.results,
+        }))
+        .into_response(),
+        Err(e) => {
+            state.metrics.record_error();
+            error_response(ErrorCode::Validation, e)
+        }
+    }
+}
+
+/// Per-cell equipment counts for a heatmap overlay, one grid per floor plan.
+#[cfg(feature = "agent")]
+pub async fn http_building_heatmap(
+    headers: HeaderMap,
+    Query(params): Query<AuthParams>,
+    axum::extract::Path(id): axum::extract::Path<String>,
+    State(state): State<Arc<AgentState>>,
+    Json(req): Json<crate::core::operations::HeatmapRequest>,
+) -> impl IntoResponse {
+    use crate::core::equipment_types::EquipmentTypeRegistry;
+
+    if !check_auth(&headers, params.token.as_deref(), &state) {
+        state.metrics.record_error();
+        return error_response(ErrorCode::Unauthorized, "Unauthorized");
+    }
+    if let Err(e) = req.check() {
+        return error_response(ErrorCode::InvalidParams, e);
+    }
+    let building = match load_building_by_id(&state, &id) {
+        Ok(b) => b,
+        Err(response) => return response,
+    };
+    let registry = match EquipmentTypeRegistry::load(&state.repo_root) {
+        Ok(registry) => registry,
+        Err(e) => {
+            state.metrics.record_error();
+            return error_response(ErrorCode::Internal, e);
+        }
+    };
+    match crate::core::operations::heatmap(&building, &registry, &req) {
+        Ok(map) => Json(map).into_response(),
+        Err(e) => error_response(ErrorCode::InvalidParams, e),
+    }
+}
+
+#[cfg(feature = "agent")]
+#[derive(Deserialize)]
+pub struct HttpTransformRequest {
+    pub selection: crate::core::operations::TransformSelection,
+    pub transform: crate::core::operations::AffineTransform,
+}
+
+#[cfg(feature = "agent")]
+#[derive(Deserialize)]
+pub struct HttpTransformUndoRequest {
+    /// Operation to undo; the building's latest when omitted.
+    #[serde(default)]
+    pub operation: Option<String>,
+}
+
+/// Move, rotate or scale a selection of rooms and equipment in one commit, and
+/// record the operation so `transform/undo` can reverse it.
+#[cfg(feature = "agent")]
+pub async fn http_building_transform(
+    headers: HeaderMap,
+    Query(params): Query<AuthParams>,
+    axum::extract::Path(id): axum::extract::Path<String>,
+    State(state): State<Arc<AgentState>>,
+    Json(req): Json<HttpTransformRequest>,
+) -> impl IntoResponse {
+    use crate::core::operations::{transform_entities, TransformLog};
+
+    if !check_auth(&headers, params.token.as_deref(), &state) {
+        state.metrics.record_error();
+        return error_response(ErrorCode::Unauthorized, "Unauthorized");
+    }
+    if req.transform.is_identity() {
+        return error_response(
+            ErrorCode::InvalidParams,
+            "Nothing to do: set a translation, rotation or scale",
+        );
+    }
+    let mut building = match load_building_by_id(&state, &id) {
+        Ok(b) => b,
+        Err(response) => return response,
+    };
+    let report = match transform_entities(&mut building, &req.selection, &req.transform) {
+        Ok(report) if report.total() == 0 => {
+            return error_response(
+                ErrorCode::NotFound,
+                "No rooms or equipment matched the selection",
+            )
+        }
+        Ok(report) => report,
+        Err(e) => return error_response(ErrorCode::InvalidParams, e),
+    };
+    let message = format!(
+        "Move {} room(s), {} equipment",
+        report.rooms_moved.len(),
+        report.equipment_moved.len()
+    );
+    if let Err(e) =
+        crate::ingest::persist_building_at(&state.repo_root, building, true, Some(&message))
+    {
+        return persistence_error_response(&state, "Transform not saved", e);
+    }
+    let record = TransformLog::load(&state.repo_root).and_then(|mut log| {
+        let record = log.record(&id, &req.transform, &report);
+        log.save(&state.repo_root).map(|_| record)
+    });
+    match record {
+        Ok(record) => Json(serde_json::json!({
+            "operation": record.id,
+            "report": report,
+        }))
+        .into_response(),
+        Err(e) => {
+            state.metrics.record_error();
+            error_response(
+                ErrorCode::Internal,
+                format!("Transform saved but not recorded for undo: {}", e),
+            )
+        }
+    }
+}
+
+/// Reverse a recorded transform, moving exactly the objects it moved.
+#[cfg(feature = "agent")]
+pub async fn http_building_transform_undo(
+    headers: HeaderMap,
+    Query(params): Query<AuthParams>,
+    axum::extract::Path(id): axum::extract::Path<String>,
+    State(state): State<Arc<AgentState>>,
+    Json(req): Json<HttpTransformUndoRequest>,
+) -> impl IntoResponse {
+    use crate::core::operations::{undo_transform, TransformLog};
+
+    if !check_auth(&headers, params.token.as_deref(), &state) {
+        state.metrics.record_error();
+        return error_response(ErrorCode::Unauthorized, "Unauthorized");
+    }
+    let mut log = match TransformLog::load(&state.repo_root) {
+        Ok(log) => log,
+        Err(e) => {
+            state.metrics.record_error();
+            return error_response(ErrorCode::Internal, e);
+        }
+    };
+    let record = match log.undoable(&id, req.operation.as_deref()) {
+        Ok(record) => record.clone(),
+        Err(e) => return error_response(ErrorCode::Conflict, e),
+    };
+    let mut building = match load_building_by_id(&state, &id) {
+        Ok(b) => b,
+        Err(response) => return response,
+    };
+    let report = match undo_transform(&mut building, &record) {
+        Ok(report) => report,
+        Err(e) => return error_response(ErrorCode::InvalidParams, e),
+    };
+    let message = format!("Undo move {}", record.id);
+    if let Err(e) =
+        crate::ingest::persist_building_at(&state.repo_root, building, true, Some(&message))
+    {
+        return persistence_error_response(&state, "Undo not saved", e);
+    }
+    log.mark_undone(&record.id);
+    if let Err(e) = log.save(&state.repo_root) {
+        state.metrics.record_error();
+        return error_response(ErrorCode::Internal, e);
+    }
+    Json(serde_json::json!({
+        "operation": record.id,
+        "undone": true,
+        "report": report,
+    }))
+    .into_response()
+}
+
+#[cfg(feature = "agent")]
+#[derive(Deserialize)]
+pub struct HttpChangesQuery {
+    pub token: Option<String>,
+    /// Cursor from the previous pull; everything when omitted.
+    pub since: Option<String>,
+}
+
+#[cfg(feature = "agent")]
+#[derive(Deserialize)]
+pub struct HttpChangesPush {
+    pub changes: Vec<crate::ingest::delta::ObjectChange>,
+}
+
+/// Objects created, updated and deleted since a sync cursor, for offline clients.
+#[cfg(feature = "agent")]
+pub async fn http_building_changes(
+    headers: HeaderMap,
+    Query(params): Query<HttpChangesQuery>,
+    axum::extract::Path(id): axum::extract::Path<String>,
+    State(state): State<Arc<AgentState>>,
+) -> impl IntoResponse {
+    if !check_auth(&headers, params.token.as_deref(), &state) {
+        state.metrics.record_error();
+        return error_response(ErrorCode::Unauthorized, "Unauthorized");
+    }
+    let building = match load_building_by_id(&state, &id) {
+        Ok(b) => b,
+        Err(response) => return response,
+    };
+    let since = params.since.as_deref();
+    match crate::ingest::delta::pull_changes(&state.repo_root, &building, since) {
+        Ok(changes) => Json(changes).into_response(),
+        Err(e) => error_response(ErrorCode::InvalidParams, e),
+    }
+}
+
+/// Apply changes made offline; changes whose base version is stale come back as
+/// conflicts with the server's copy.
+#[cfg(feature = "agent")]
+pub async fn http_building_changes_push(
+    headers: HeaderMap,
+    Query(params): Query<AuthParams>,
+    axum::extract::Path(id): axum::extract::Path<String>,
+    State(state): State<Arc<AgentState>>,
+    Json(req): Json<HttpChangesPush>,
+) -> impl IntoResponse {
+    if !check_auth(&headers, params.token.as_deref(), &state) {
+        state.metrics.record_error();
+        return error_response(ErrorCode::Unauthorized, "Unauthorized");
+    }
+    let mut building = match load_building_by_id(&state, &id) {
+        Ok(b) => b,
+        Err(response) => return response,
+    };
+    let outcome = crate::ingest::delta::push_changes(&mut building, &req.changes);
+    if outcome.has_changes() {
+        let message = format!(
+            "Sync {} change(s) from offline client",
+            outcome.applied.len()
+        );
+        if let Err(e) =
+            crate::ingest::persist_building_at(&state.repo_root, building, true, Some(&message))
+        {
+            return persistence_error_response(&state, "Changes not saved", e);
+        }
+    }
+    Json(outcome).into_response()
+}
+
+/// Feature flags as they apply to the caller's organization, so clients can hide
+/// disabled features.
+#[cfg(feature = "agent")]
+pub async fn http_features(
+    headers: HeaderMap,
+    Query(params): Query<AuthParams>,
+    State(state): State<Arc<AgentState>>,
+) -> impl IntoResponse {
+    let organization = match caller_organization(&headers, params.token.as_deref(), &state) {
+        Ok(organization) => organization,
+        Err(response) => return response,
+    };
+    match crate::agent::feature_flags::FeatureFlags::load(&state.repo_root) {
+        Ok(flags) => Json(serde_json::json!({
+            "organization": organization,
+            "features": flags.resolved(organization.as_deref()),
+        }))
+        .into_response(),
+        Err(e) => {
+            state.metrics.record_error();
+            error_response(ErrorCode::Internal, e)
+        }
+    }
+}
+
+/// Custom field definitions visible to the caller's organization.
+#[cfg(feature = "agent")]
+pub async fn http_custom_fields_list(
+    headers: HeaderMap,
+    Query(params): Query<AuthParams>,
+    State(state): State<Arc<AgentState>>,
+) -> impl IntoResponse {
+    let organization = match caller_organization(&headers, params.token.as_deref(), &state) {
+        Ok(organization) => organization,
+        Err(response) => return response,
+    };
+    match crate::validation::CustomFields::load(&state.repo_root) {
+        Ok(fields) => Json(serde_json::json!({
+            "organization": organization,
+            "fields": fields.definitions(organization.as_deref()),
+        }))
+        .into_response(),
+        Err(e) => {
+            state.metrics.record_error();
+            error_response(ErrorCode::Internal, e)
+        }
+    }
+}
+
+/// Create or replace custom field `name` for the caller's organization; the root
+/// token defines it for every organization.
+#[cfg(feature = "agent")]
+pub async fn http_custom_field_put(
+    headers: HeaderMap,
+    Query(params): Query<AuthParams>,
+    axum::extract::Path(name): axum::extract::Path<String>,
+    State(state): State<Arc<AgentState>>,
+    Json(definition): Json<crate::validation::FieldDefinition>,
+) -> impl IntoResponse {
+    let Some((_, capabilities)) = identify(&headers, params.token.as_deref(), &state) else {
+        state.metrics.record_error();
+        return error_response(ErrorCode::Unauthorized, "Unauthorized");
+    };
+    if !capabilities.iter().any(|c| c == "auth.manage") {
+        return error_response(ErrorCode::Forbidden, "auth.manage required");
+    }
+    let organization = match caller_organization(&headers, params.token.as_deref(), &state) {
+        Ok(organization) => organization,
+        Err(response) => return response,
+    };
+    let mut fields = match crate::validation::CustomFields::load(&state.repo_root) {
+        Ok(fields) => fields,
+        Err(e) => {
+            state.metrics.record_error();
+            return error_response(ErrorCode::Internal, e);
+        }
+    };
+    if let Err(e) = fields.define(organization.as_deref(), &name, definition) {
+        return error_response(ErrorCode::Validation, e);
+    }
+    if let Err(e) = fields.save(&state.repo_root) {
+        state.metrics.record_error();
+        return error_response(ErrorCode::Internal, e);
+    }
+    Json(serde_json::json!({
+        "organization": organization,
+        "fields": fields.definitions(organization.as_deref()),
+    }))
+    .into_response()
+}
+
+/// The building with its review summary and derived values, as `building.get`.
+#[cfg(feature = "agent")]
+pub async fn http_building_get(
+    headers: HeaderMap,
+    Query(params): Query<AuthParams>,
+    axum::extract::Path(id): axum::extract::Path<String>,
+    State(state): State<Arc<AgentState>>,
+) -> impl IntoResponse {
+    let Some((actor, _)) = identify(&headers, params.token.as_deref(), &state) else {
+        state.metrics.record_error();
+        return error_response(ErrorCode::Unauthorized, "Unauthorized");
+    };
+    match crate::agent::building::get_building(&state.repo_root) {
+        Ok(result) if result.building.id == id => {
+            audit_read(&state, actor, "building.get", id);
+            Json(result).into_response()
+        }
+        Ok(_) => error_response(ErrorCode::NotFound, format!("Building '{}' not found", id)),
+        Err(e) => match e.downcast::<AgentError>() {
+            Ok(agent_err) => agent_error_response(agent_err),
+            Err(e) => {
+                state.metrics.record_error();
+                error_response(ErrorCode::Internal, e.to_string())
+            }
+        },
+    }
+}
+
+#[cfg(feature = "agent")]
+#[derive(Deserialize)]
+pub struct HttpBuildingsQuery {
+    pub token: Option<String>,
+    /// Custom field to filter on; `value` must be set with it.
+    pub field: Option<String>,
+    pub value: Option<String>,
+}
+
+/// Buildings served by this agent with their custom field values, optionally only
+/// those whose custom `field` equals `value`.
+#[cfg(feature = "agent")]
+pub async fn http_buildings_list(
+    headers: HeaderMap,
+    Query(params): Query<HttpBuildingsQuery>,
+    State(state): State<Arc<AgentState>>,
+) -> impl IntoResponse {
+    use crate::validation::custom_fields::building_values;
+    use crate::validation::{CustomFields, FieldScope};
+
+    let (actor, organization) = match caller(&headers, params.token.as_deref(), &state) {
+        Ok(caller) => caller,
+        Err(response) => return response,
+    };
+    let fields = match CustomFields::load(&state.repo_root) {
+        Ok(fields) => fields,
+        Err(e) => {
+            state.metrics.record_error();
+            return error_response(ErrorCode::Internal, e);
+        }
+    };
+    let buildings = match crate::persistence::load_building_at(&state.repo_root) {
+        Ok(building) => vec![building],
+        Err(e) => return persistence_error_response(&state, "Failed to load building", e),
+    };
+    let matched: Vec<&crate::core::Building> = match (&params.field, &params.value) {
+        (Some(field), Some(value)) => {
+            fields.filter_buildings(organization.as_deref(), &buildings, field, value)
+        }
+        (None, None) => buildings.iter().collect(),
+        _ => {
+            return error_response(
+                ErrorCode::InvalidParams,
+                "field and value must be given together",
+            )
+        }
+    };
+    let listed: Vec<serde_json::Value> = matched
+        .into_iter()
+        .map(|b| {
+            audit_read(&state, actor.clone(), "building.list", b.id.clone());
+            serde_json::json!({
+                "id": b.id,
+                "name": b.name,
+                "custom_fields": fields.typed_values(
+                    organization.as_deref(),
+                    FieldScope::Building,
+                    &building_values(b),
+                ),
+            })
+        })
+        .collect();
+    Json(serde_json::json!({ "buildings": listed })).into_response()
+}
+
+/// Custom field values of the building, its floors and rooms.
+#[cfg(feature = "agent")]
+pub async fn http_building_custom_fields(
+    headers: HeaderMap,
+    Query(params): Query<AuthParams>,
+    axum::extract::Path(id): axum::extract::Path<String>,
+    State(state): State<Arc<AgentState>>,
+) -> impl IntoResponse {
+    use crate::validation::custom_fields::building_values;
+    use crate::validation::{CustomFields, FieldScope};
+
+    let (actor, organization) = match caller(&headers, params.token.as_deref(), &state) {
+        Ok(caller) => caller,
+        Err(response) => return response,
+    };
+    let building = match load_building_by_id(&state, &id) {
+        Ok(b) => b,
+        Err(response) => return response,
+    };
+    audit_read(&state, actor, "building.custom_fields", id);
+    let fields = match CustomFields::load(&state.repo_root) {
+        Ok(fields) => fields,
+        Err(e) => {
+            state.metrics.record_error();
+            return error_response(ErrorCode::Internal, e);
+        }
+    };
+    let org = organization.as_deref();
+    let floors: Vec<serde_json::Value> = building
+        .floors
+        .iter()
+        .map(|f| {
+            serde_json::json!({
+                "level": f.level,
+                "custom_fields": fields.typed_values(org, FieldScope::Floor, &f.properties),
+            })
+        })
+        .collect();
+    let rooms: Vec<serde_json::Value> = building
+        .get_all_rooms()
+        .into_iter()
+        .map(|r| {
+            serde_json::json!({
+                "id": r.id,
+                "custom_fields": fields.typed_values(org, FieldScope::Room, &r.properties),
+            })
+        })
+        .collect();
+    Json(serde_json::json!({
+        "custom_fields": fields.typed_values(org, FieldScope::Building, &building_values(&building)),
+        "floors": floors,
+        "rooms": rooms,
+    }))
+    .into_response()
+}
+
+#[cfg(feature = "agent")]
+#[derive(Deserialize)]
+pub struct HttpCustomFieldsPatch {
+    /// Floor level to set values on; the building when neither this nor `room` is set.
+    #[serde(default)]
+    pub floor: Option<i32>,
+    #[serde(default)]
+    pub room: Option<String>,
+    /// New values; `null` removes a value.
+    pub values: std::collections::BTreeMap<String, serde_json::Value>,
+}
+
+/// Set or remove custom field values on the building, a floor or a room. Only
+/// defined, unreserved fields of the object's kind can be written. The object's
+/// defined fields are validated after the change, and a room against its property
+/// schema; the write is refused with per-field errors when any does not conform.
+#[cfg(feature = "agent")]
+pub async fn http_building_custom_fields_patch(
+    headers: HeaderMap,
+    Query(params): Query<AuthParams>,
+    axum::extract::Path(id): axum::extract::Path<String>,
+    State(state): State<Arc<AgentState>>,
+    Json(req): Json<HttpCustomFieldsPatch>,
+) -> impl IntoResponse {
+    use crate::validation::{CustomFields, FieldScope, PropertySchemas};
+
+    let organization = match caller_organization(&headers, params.token.as_deref(), &state) {
+        Ok(organization) => organization,
+        Err(response) => return response,
+    };
+    let mut building = match load_building_by_id(&state, &id) {
+        Ok(b) => b,
+        Err(response) => return response,
+    };
+    let fields = match CustomFields::load(&state.repo_root) {
+        Ok(fields) => fields,
+        Err(e) => {
+            state.metrics.record_error();
+            return error_response(ErrorCode::Internal, e);
+        }
+    };
+    let scope = match (req.floor, req.room.as_deref()) {
+        (Some(_), Some(_)) => {
+            return error_response(ErrorCode::InvalidParams, "Set floor or room, not both")
+        }
+        (Some(_), None) => FieldScope::Floor,
+        (None, Some(_)) => FieldScope::Room,
+        (None, None) => FieldScope::Building,
+    };
+    let mut errors = FieldErrors::new();
+    for key in req.values.keys() {
+        if let Err(message) = fields.check_writable(organization.as_deref(), scope, key) {
+            errors.add(key, message);
+        }
+    }
+    if let Err(e) = errors.into_result() {
+        return agent_error_response(e);
+    }
+    let (target, values) = match (req.floor, req.room.as_deref()) {
+        (Some(level), _) => match building.find_floor_mut(level) {
+            Some(floor) => (format!("floor {}", level), &mut floor.properties),
+            None => {
+                return error_response(ErrorCode::NotFound, format!("Floor {} not found", level))
+            }
+        },
+        (None, Some(room_id)) => match building.find_room_mut(room_id) {
+            Some(room) => (format!("room {}", room.name), &mut room.properties),
+            None => {
+                return error_response(ErrorCode::NotFound, format!("Room '{}' not found", room_id))
+            }
+        },
+        (None, None) => ("building".to_string(), building.metadata_properties_mut()),
+    };
+    for (key, value) in &req.values {
+        match value {
+            serde_json::Value::Null => {
+                values.remove(key);
+            }
+            serde_json::Value::String(s) => {
+                values.insert(key.clone(), s.clone());
+            }
+            other => {
+                values.insert(key.clone(), other.to_string());
+            }
+        }
+    }
+    let mut errors = FieldErrors::new();
+    for violation in fields.check_values(organization.as_deref(), scope, values) {
+        errors.add(&violation.property, violation.message);
+    }
+    if let Err(e) = errors.into_result() {
+        return agent_error_response(e);
+    }
+    let typed = fields.typed_values(organization.as_deref(), scope, values);
+    if let Some(room) = req.room.as_deref().and_then(|id| building.find_room(id)) {
+        let schemas = match PropertySchemas::load(&state.repo_root) {
+            Ok(schemas) => schemas,
+            Err(e) => {
+                state.metrics.record_error();
+                return error_response(ErrorCode::Internal, e);
+            }
+        };
+        if let Err(e) = schemas.gate_room(room) {
+            return error_response(ErrorCode::Validation, e);
+        }
+    }
+    let message = format!("Update custom fields of {}", target);
+    building.updated_at = chrono::Utc::now();
+    match crate::ingest::persist_building_at(&state.repo_root, building, true, Some(&message)) {
+        Ok(_) => Json(serde_json::json!({ "custom_fields": typed })).into_response(),
+        Err(e) => persistence_error_response(&state, "Custom fields not saved", e),
+    }
+}
+
+/// Rooms and equipment outside the building extent, per `.arxos/bounds.yaml`.
+#[cfg(feature = "agent")]
+pub async fn http_building_out_of_bounds(
+    headers: HeaderMap,
+    Query(params): Query<AuthParams>,
+    axum::extract::Path(id): axum::extract::Path<String>,
+    State(state): State<Arc<AgentState>>,
+) -> impl IntoResponse {
+    if !check_auth(&headers, params.token.as_deref(), &state) {
+        state.metrics.record_error();
+        return error_response(ErrorCode::Unauthorized, "Unauthorized");
+    }
+    let building = match load_building_by_id(&state, &id) {
+        Ok(b) => b,
+        Err(response) => return response,
+    };
+    let config = match crate::validation::BoundsConfig::load(&state.repo_root) {
+        Ok(config) => config,
+        Err(e) => return error_response(ErrorCode::Validation, e),
+    };
+    let extent = config.extent(&building);
+    let report = config.evaluate(&building);
+    Json(serde_json::json!({
+        "extent": extent,
+        "margin_m": config.margin_m,
+        "findings": report.results,
+    }))
+    .into_response()
+}
+
+#[cfg(feature = "agent")]
+pub async fn http_templates_list(
+    headers: HeaderMap,
+    Query(params): Query<AuthParams>,
+    State(state): State<Arc<AgentState>>,
+) -> impl IntoResponse {
+    if !check_auth(&headers, params.token.as_deref(), &state) {
+        state.metrics.record_error();
+        return error_response(ErrorCode::Unauthorized, "Unauthorized");
+    }
+    let root = crate::persistence::templates::project_templates_dir(&state.repo_root);
+    match crate::persistence::templates::list_templates(&root) {
+        Ok(templates) => Json(serde_json::json!({ "templates": templates })).into_response(),
+        Err(e) => {
+            state.metrics.record_error();
+            error_response(ErrorCode::Internal, format!("Failed to list templates: {}", e))
+        }
+    }
+}
+
+/// Built-in equipment types and the project's custom ones.
+#[cfg(feature = "agent")]
+pub async fn http_equipment_types_list(
+    headers: HeaderMap,
+    Query(params): Query<AuthParams>,
+    State(state): State<Arc<AgentState>>,
+) -> impl IntoResponse {
+    use crate::core::equipment_types::{EquipmentTypeRegistry, BUILTIN_EQUIPMENT_TYPES};
+
+    if !check_auth(&headers, params.token.as_deref(), &state) {
+        state.metrics.record_error();
+        return error_response(ErrorCode::Unauthorized, "Unauthorized");
+    }
+    match EquipmentTypeRegistry::load(&state.repo_root) {
+        Ok(registry) => Json(serde_json::json!({
+            "builtin": BUILTIN_EQUIPMENT_TYPES,
+            "custom": registry.types.values().collect::<Vec<_>>(),
+        }))
+        .into_response(),
+        Err(e) => {
+            state.metrics.record_error();
+            error_response(ErrorCode::Internal, format!("Failed to load equipment types: {}", e))
+        }
+    }
+}
+
+/// Create or replace the custom equipment type `name`.
+#[cfg(feature = "agent")]
+pub async fn http_equipment_type_put(
+    headers: HeaderMap,
+    Query(params): Query<AuthParams>,
+    axum::extract::Path(name): axum::extract::Path<String>,
+    State(state): State<Arc<AgentState>>,
+    Json(custom): Json<crate::core::equipment_types::CustomEquipmentType>,
+) -> impl IntoResponse {
+    use crate::core::equipment_types::EquipmentTypeRegistry;
+
+    if !check_auth(&headers, params.token.as_deref(), &state) {
+        state.metrics.record_error();
+        return error_response(ErrorCode::Unauthorized, "Unauthorized");
+    }
+    let mut errors = FieldErrors::new();
+    if !custom.name.trim().eq_ignore_ascii_case(name.trim()) {
+        errors.add("name", format!("'{}' does not match the path '{}'", custom.name, name));
+    }
+    for (field, message) in EquipmentTypeRegistry::type_errors(&custom) {
+        errors.add(field, message);
+    }
+    if let Err(e) = errors.into_result() {
+        return agent_error_response(e);
+    }
+    let mut registry = match EquipmentTypeRegistry::load(&state.repo_root) {
+        Ok(registry) => registry,
+        Err(e) => {
+            state.metrics.record_error();
+            return error_response(ErrorCode::Internal, e);
+        }
+    };
+    if let Err(e) = registry.upsert(custom) {
+        return error_response(ErrorCode::Validation, e);
+    }
+    if let Err(e) = registry.save(&state.repo_root) {
+        state.metrics.record_error();
+        return error_response(ErrorCode::Internal, e);
+    }
+    Json(serde_json::json!({ "type": registry.get(&name) })).into_response()
+}
+
+/// Remove the custom equipment type `name`; 409 while equipment still uses it.
+#[cfg(feature = "agent")]
+pub async fn http_equipment_type_delete(
+    headers: HeaderMap,
+    Query(params): Query<AuthParams>,
+    axum::extract::Path(name): axum::extract::Path<String>,
+    State(state): State<Arc<AgentState>>,
+) -> impl IntoResponse {
+    use crate::core::equipment_types::EquipmentTypeRegistry;
+
+    if !check_auth(&headers, params.token.as_deref(), &state) {
+        state.metrics.record_error();
+        return error_response(ErrorCode::Unauthorized, "Unauthorized");
+    }
+    let mut registry = match EquipmentTypeRegistry::load(&state.repo_root) {
+        Ok(registry) => registry,
+        Err(e) => {
+            state.metrics.record_error();
+            return error_response(ErrorCode::Internal, e);
+        }
+    };
+    if registry.get(&name).is_none() {
+        return error_response(
+            ErrorCode::NotFound,
+            format!("Equipment type '{}' not found", name),
+        );
+    }
+    let building = match crate::persistence::load_building_at(&state.repo_root) {
+        Ok(building) => building,
+        Err(e) => return persistence_error_response(&state, "Failed to load building", e),
+    };
+    let removed = match registry.remove(&name, &building) {
+        Ok(removed) => removed,
+        Err(e) => return error_response(ErrorCode::Conflict, e),
+    };
+    if let Err(e) = registry.save(&state.repo_root) {
+        state.metrics.record_error();
+        return error_response(ErrorCode::Internal, e);
+    }
+    Json(serde_json::json!({ "removed": removed })).into_response()
+}
+
+#[cfg(feature = "agent")]
+#[derive(Deserialize)]
+pub struct HttpAccessLogParams {
+    pub token: Option<String>,
+    pub actor: Option<String>,
+    pub object: Option<String>,
+    /// RFC 3339 or Unix seconds.
+    pub since: Option<String>,
+    pub limit: Option<usize>,
+    /// `json` (default) or `csv` for export.
+    pub format: Option<String>,
+}
+
+/// Audited reads, newest first; requires `auth.manage`. Expired entries are pruned
+/// hourly by the agent, not here.
+#[cfg(feature = "agent")]
+pub async fn http_access_log(
+    headers: HeaderMap,
+    Query(params): Query<HttpAccessLogParams>,
+    State(state): State<Arc<AgentState>>,
+) -> impl IntoResponse {
+    use crate::agent::access_log::{entries_csv, AccessLog, AccessQuery};
+
+    let Some((_, capabilities)) = identify(&headers, params.token.as_deref(), &state) else {
+        state.metrics.record_error();
+        return error_response(ErrorCode::Unauthorized, "Unauthorized");
+    };
+    if !capabilities.iter().any(|c| c == "auth.manage") {
+        return error_response(ErrorCode::Forbidden, "auth.manage required");
+    }
+    let since = match params.since.as_deref() {
+        None => None,
+        Some(raw) => match crate::ingest::history::parse_timestamp(raw) {
+            Some(ts) => Some(ts),
+            None => {
+                return error_response(
+                    ErrorCode::InvalidParams,
+                    format!("Invalid since '{}': expected RFC 3339 or Unix seconds", raw),
+                )
+            }
+        },
+    };
+
+    let entries = AccessLog::open(&state.repo_root).and_then(|log| {
+        log.query(&AccessQuery {
+            actor: params.actor,
+            object: params.object,
+            since,
+            limit: params.limit,
+        })
+    });
+    let entries = match entries {
+        Ok(entries) => entries,
+        Err(e) => {
+            state.metrics.record_error();
+            return error_response(ErrorCode::Internal, format!("Failed to read access log: {}", e));
+        }
+    };
+    match params.format.as_deref() {
+        Some("csv") => (
+            [(axum::http::header::CONTENT_TYPE, "text/csv")],
+            entries_csv(&entries),
+        )
+            .into_response(),
+        None | Some("json") => Json(serde_json::json!({ "entries": entries })).into_response(),
+        Some(other) => error_response(
+            ErrorCode::InvalidParams,
+            format!("Unknown format '{}' (json|csv)", other),
+        ),
+    }
+}
+
+#[cfg(feature = "agent")]
+async fn ws_handler(
+    ws: WebSocketUpgrade,
+    headers: HeaderMap,
+    Query(params): Query<AuthParams>,
+    State(state): State<Arc<AgentState>>,
+) -> impl IntoResponse {
+    let Some((actor, capabilities)) = identify(&headers, params.token.as_deref(), &state) else {
+        return error_response(ErrorCode::Unauthorized, "Invalid or missing token");
+    };
+
+    ws.on_upgrade(|socket| handle_socket(socket, state, actor, capabilities))
+}
+
+#[cfg(feature = "agent")]
+async fn rpc_handler(
+    headers: HeaderMap,
+    Query(params): Query<AuthParams>,
+    State(state): State<Arc<AgentState>>,
+    Json(body): Json<serde_json::Value>,
+) -> impl IntoResponse {
+    let Some((actor, capabilities)) = identify(&headers, params.token.as_deref(), &state) else {
+        return error_response(ErrorCode::Unauthorized, "Invalid or missing token");
+    };
+
+    let request: JsonRpcRequest = match body {
+        serde_json::Value::Array(items) => {
+            // Batch items run unkeyed; refuse rather than let a retry repeat them.
+            if idempotency_key(&headers).is_some() {
+                return error_response(
+                    ErrorCode::InvalidParams,
+                    "Idempotency-Key is not supported on batches; send the call on its own",
+                );
+            }
+            if items.is_empty() || items.len() > MAX_BATCH_SIZE {
+                return error_response(
+                    ErrorCode::InvalidParams,
+                    format!("Batch must contain 1 to {} requests", MAX_BATCH_SIZE),
+                );
+            }
+            let reads: Vec<_> = items
+                .iter()
+                .map(|item| {
+                    serde_json::from_value::<JsonRpcRequest>(item.clone())
+                        .ok()
+                        .and_then(|request| rpc_read(&request))
+                })
+                .collect();
+            let responses = dispatch_batch(state.clone(), items, &capabilities).await;
+            for (read, response) in reads.into_iter().zip(&responses) {
+                audit_rpc_read(&state, &actor, read, response);
+            }
+            return Json(responses).into_response();
+        }
+        body => match serde_json::from_value(body) {
+            Ok(request) => request,
+            Err(e) => {
+                return Json(JsonRpcResponse::error(
+                    None,
+                    INVALID_REQUEST,
+                    format!("Invalid request: {}", e),
+                    None,
+                ))
+                .into_response();
+            }
+        },
+    };
+
+    let Some(key) = idempotency_key(&headers).filter(|_| is_non_idempotent(&request.method)) else {
+        let read = rpc_read(&request);
+        let response = dispatch_with_capabilities(state.clone(), request, &capabilities).await;
+        audit_rpc_read(&state, &actor, read, &response);
+        return Json(response).into_response();
+    };
+    if key.len() > MAX_IDEMPOTENCY_KEY_LEN {
+        return error_response(ErrorCode::InvalidParams, "Idempotency-Key is too long");
+    }
+
+    let principal = request_token(&headers, params.token.as_deref()).unwrap_or_default();
+    let scope = IdempotencyStore::scope(&principal, &request.method, key);
+    let fingerprint = IdempotencyStore::fingerprint(request.params.as_ref());
+    let claim = match state.idempotency.begin(&scope, &fingerprint) {
+        IdempotencyCheck::New(claim) => claim,
+        IdempotencyCheck::Replay(response) => {
+            return ([("Idempotent-Replayed", "true")], Json(response)).into_response();
+        }
+        IdempotencyCheck::Mismatch => {
+            return error_response(
+                ErrorCode::Validation,
+                "Idempotency-Key was already used with different params",
+            );
+        }
+        IdempotencyCheck::InFlight => {
+            return error_response(
+                ErrorCode::Conflict,
+                "A request with this Idempotency-Key is still in progress",
+            );
+        }
+        IdempotencyCheck::Full => {
+            return error_response(
+                ErrorCode::Conflict,
+                "Too many requests with an Idempotency-Key are in progress",
+            );
+        }
+    };
+
+    // Dropping the claim (client gone before the response) releases the key.
+    let response = dispatch_with_capabilities(state.clone(), request, &capabilities).await;
+    claim.complete(&response);
+    Json(response).into_response()
+}
+
+#[cfg(feature = "agent")]
+async fn handle_socket(
+    mut socket: WebSocket,
+    state: Arc<AgentState>,
+    actor: String,
+    capabilities: Vec<String>,
+) {
+    struct WsGuard(Arc<AgentState>);
+    impl Drop for WsGuard {
+        fn drop(&mut self) {
+            self.0.metrics.active_ws_clients.fetch_sub(1, Ordering::SeqCst);
+            tracing::info!(active = self.0.metrics.active_ws_clients.load(Ordering::SeqCst), "WebSocket client disconnected");
+        }
+    }
+    state.metrics.active_ws_clients.fetch_add(1, Ordering::SeqCst);
+    let _guard = WsGuard(state.clone());
+    tracing::info!(active = state.metrics.active_ws_clients.load(Ordering::SeqCst), "WebSocket client connected");
+
+    while let Some(msg) = socket.recv().await {
+        let msg = match msg {
+            Ok(msg) => msg,
+            Err(e) => {
+                tracing::error!(error = %e, "WebSocket socket error");
+                return;
+            }
+        };
+
+        match msg {
+            Message::Text(text) => {
+                // Parse JSON-RPC Request
+                let response = match serde_json::from_str::<JsonRpcRequest>(&text) {
+                    Ok(request) => {
+                        let read = rpc_read(&request);
+                        let response =
+                            dispatch_with_capabilities(state.clone(), request, &capabilities)
+                                .await;
+                        audit_rpc_read(&state, &actor, read, &response);
+                        response
+                    }
+                    Err(e) => JsonRpcResponse::error(
+                        None,
+                        PARSE_ERROR,
+                        format!("Invalid JSON: {}", e),
+                        None,
+                    ),
+                };
+
+                // Send Response
+                if let Ok(resp_text) = serde_json::to_string(&response) {
+                    if let Err(e) = socket.send(Message::Text(resp_text)).await {
+                        tracing::error!(error = %e, "Failed to send WebSocket response");
+                        return;
+                    }
+                }
+            }
+            Message::Close(_) => {
+                return;
+            }
+            _ => {}
+        }
+    }
+}
+
+#[cfg(feature = "agent")]
+#[derive(serde::Serialize)]
+struct AgentStatusDto {
+    status: String,
+    uptime_seconds: u64,
+    active_ws_clients: usize,
+    cache_stats: CacheStatsDto,
+}
+
+#[cfg(feature = "agent")]
+#[derive(serde::Serialize)]
+struct CacheStatsDto {
+    warm_cache_size: usize,
+}
+
+/// Liveness: the process is up and serving, even while a migration runs.
+#[cfg(feature = "agent")]
+pub async fn http_health() -> impl IntoResponse {
+    Json(serde_json::json!({ "status": "ok" }))
+}
+
+/// Readiness: 503 while a migration is running, pending or was interrupted, or the
+/// repository is not readable, so load balancers hold traffic until the model is
+/// safe to serve.
+#[cfg(feature = "agent")]
+pub async fn http_ready(State(state): State<Arc<AgentState>>) -> impl IntoResponse {
+    let runner = crate::persistence::MigrationRunner::new(&state.repo_root);
+    match runner.readiness() {
+        Ok(()) => (StatusCode::OK, Json(serde_json::json!({ "ready": true }))),
+        Err(reason) => (
+            StatusCode::SERVICE_UNAVAILABLE,
+            Json(serde_json::json!({ "ready": false, "reason": reason })),
+        ),
+    }
+}
+
+#[cfg(feature = "agent")]
+pub async fn http_agent_status(
+    headers: HeaderMap,
+    Query(params): Query<AuthParams>,
+    State(state): State<Arc<AgentState>>,
+) -> impl IntoResponse {
+    if !check_auth(&headers, params.token.as_deref(), &state) {
+        state.metrics.record_error();
+        return error_response(ErrorCode::Unauthorized, "Unauthorized");
+    }
+
+    let uptime = state.metrics.start_time.elapsed().as_secs();
+    let cache_size = 0; // Static placeholder or simple count of active connections
+
+    let status = AgentStatusDto {
+        status: "healthy".to_string(),
+        uptime_seconds: uptime,
+        active_ws_clients: state.metrics.active_ws_clients.load(Ordering::SeqCst),
+        cache_stats: CacheStatsDto {
+            warm_cache_size: cache_size,
+        },
+    };
+
+    Json(status).into_response()
+}
+
+#[cfg(feature = "agent")]
+#[derive(serde::Serialize)]
+struct ClaimsStatusDto {
+    claims_processed: usize,
+    claims_approved: usize,
     claims_rejected: usize,
     rewards_distributed_axd: f64,
 }
@@ -548,7 +2872,7 @@ pub async fn http_claims_status(
 ) -> impl IntoResponse {
     if !check_auth(&headers, params.token.as_deref(), &state) {
         state.metrics.record_error();
-        return (StatusCode::UNAUTHORIZED, "Unauthorized").into_response();
+        return error_response(ErrorCode::Unauthorized, "Unauthorized");
     }
 
     let axd = if let Ok(val) = state.metrics.rewards_distributed_axd.lock() {
@@ -575,7 +2899,7 @@ pub async fn http_prometheus_metrics(
 ) -> impl IntoResponse {
     if !check_auth(&headers, params.token.as_deref(), &state) {
         state.metrics.record_error();
-        return (StatusCode::UNAUTHORIZED, "Unauthorized").into_response();
+        return error_response(ErrorCode::Unauthorized, "Unauthorized");
     }
 
     let axd = if let Ok(val) = state.metrics.rewards_distributed_axd.lock() {
@@ -617,6 +2941,8 @@ pub async fn http_prometheus_metrics(
         state.metrics.errors_encountered.load(Ordering::SeqCst)
     );
 
+    let metrics_text = metrics_text + &state.metrics.imports.render_prometheus();
+
     (
         [(axum::http::header::CONTENT_TYPE, "text/plain; version=0.0.4")],
         metrics_text
@@ -678,17 +3004,145 @@ async fn run_auto_import_watcher(state: Arc<AgentState>) -> Result<(), Box<dyn s
             println!("🏗️  Detected new/modified IFC: {}", changed_path.display());
             println!("🔄 Auto-importing using native engine...");
 
+            let started = std::time::Instant::now();
             match crate::agent::ifc::import_ifc_local(&state.repo_root, &changed_path) {
                 Ok(result) => {
+                    state.metrics.imports.record_success(
+                        "ifc_watch",
+                        started.elapsed(),
+                        result.rooms + result.equipment,
+                    );
                     println!(
                         "✅ Auto-import complete: {} ({} floors, {} equipment)",
                         result.building_name, result.floors, result.equipment
                     );
                 }
                 Err(e) => {
+                    state.metrics.imports.record_failure(
+                        "ifc_watch",
+                        started.elapsed(),
+                        &e.to_string(),
+                    );
                     eprintln!("❌ Auto-import failed: {}", e);
                 }
             }
         }
     }
 }
+
+#[cfg(all(test, feature = "agent"))]
+mod tests {
+    use super::*;
+    use crate::agent::service_tokens::ServiceTokenStore;
+    use tempfile::TempDir;
+
+    fn state(root: &std::path::Path) -> AgentState {
+        AgentState {
+            repo_root: root.to_path_buf(),
+            token: Arc::new(Mutex::new(TokenState::new(
+                "root".into(),
+                vec!["building.get".into(), "building.sync".into(), "auth.manage".into()],
+            ))),
+            metrics: Arc::new(crate::agent::observability::AgentMetrics::new()),
+            reload_handle: None,
+            idempotency: Default::default(),
+            http_idempotency: Default::default(),
+        }
+    }
+
+    fn bearer(token: Option<&str>) -> HeaderMap {
+        let mut headers = HeaderMap::new();
+        if let Some(token) = token {
+            headers.insert(
+                "Authorization",
+                format!("Bearer {}", token).parse().unwrap(),
+            );
+        }
+        headers
+    }
+
+    #[test]
+    fn rest_routes_enforce_token_scope() {
+        let temp = TempDir::new().unwrap();
+        let mut store = ServiceTokenStore::load(temp.path()).unwrap();
+        let (_, git_only) = store
+            .create("ci", "acme", &["git.status".into()], None)
+            .unwrap();
+        let (_, viewer) = store
+            .create("viewer", "acme", &["building.get".into()], None)
+            .unwrap();
+        store.save().unwrap();
+        let state = state(temp.path());
+        let status = |token: Option<&str>, method: &str, route: &str| {
+            match authorize_route(&bearer(token), None, &state, method, route) {
+                Ok(()) => StatusCode::OK,
+                Err(response) => response.status(),
+            }
+        };
+
+        assert_eq!(status(None, "GET", "/health"), StatusCode::OK);
+        assert_eq!(
+            status(None, "GET", "/api/v1/buildings/:id/duplicates"),
+            StatusCode::UNAUTHORIZED
+        );
+        let writes = [
+            ("POST", "/api/claims/:id/approve"),
+            ("POST", "/api/v1/floors/reorder"),
+            ("POST", "/api/v1/buildings/:id/clone"),
+            ("POST", "/api/v1/buildings/import/json"),
+            ("POST", "/api/v1/buildings/:id/merge"),
+            ("POST", "/api/v1/buildings/:id/objects/delete-by-query"),
+            ("POST", "/api/v1/buildings/:id/reclassify"),
+            ("PATCH", "/api/v1/buildings/:id/custom-fields"),
+        ];
+        for (method, route) in writes {
+            assert_eq!(status(Some(&git_only), method, route), StatusCode::FORBIDDEN);
+            assert_eq!(status(Some(&viewer), method, route), StatusCode::FORBIDDEN);
+            assert_eq!(status(Some("root"), method, route), StatusCode::OK);
+        }
+        assert_eq!(
+            status(Some(&git_only), "GET", "/api/v1/buildings/:id/duplicates"),
+            StatusCode::FORBIDDEN
+        );
+        assert_eq!(
+            status(Some(&viewer), "GET", "/api/v1/buildings/:id/duplicates"),
+            StatusCode::OK
+        );
+        // Definitions are root-only: service tokens never hold auth.manage.
+        for (method, route) in [
+            ("PUT", "/api/v1/equipment-types/:name"),
+            ("DELETE", "/api/v1/equipment-types/:name"),
+            ("PUT", "/api/v1/custom-fields/:name"),
+        ] {
+            assert_eq!(status(Some(&viewer), method, route), StatusCode::FORBIDDEN);
+            assert_eq!(status(Some("root"), method, route), StatusCode::OK);
+        }
+        // /rpc admits any valid token; the dispatcher checks each method.
+        assert_eq!(status(Some(&git_only), "POST", "/rpc"), StatusCode::OK);
+    }
+
+    #[test]
+    fn persistence_errors_keep_their_status() {
+        use crate::persistence::PersistenceError;
+
+        let temp = TempDir::new().unwrap();
+        let state = state(temp.path());
+        let load_status = || load_building_by_id(&state, "hq").unwrap_err().status();
+        assert_eq!(load_status(), StatusCode::NOT_FOUND);
+        // A building that exists but cannot be read is a server error, not a 404.
+        let yaml = temp.path().join(crate::persistence::BUILDING_YAML);
+        std::fs::write(yaml, "floors: [").unwrap();
+        assert_eq!(load_status(), StatusCode::INTERNAL_SERVER_ERROR);
+
+        let invalid = PersistenceError::ValidationError("rooms: overlap".into());
+        let io = PersistenceError::IoError(std::io::ErrorKind::PermissionDenied.into());
+        assert_eq!(
+            persistence_error_response(&state, "Not saved", invalid.into()).status(),
+            StatusCode::UNPROCESSABLE_ENTITY
+        );
+        assert_eq!(
+            persistence_error_response(&state, "Not saved", io.into()).status(),
+            StatusCode::INTERNAL_SERVER_ERROR
+        );
+    }
+}
@@ -0,0 +1,455 @@
+//! Organization-scoped service-account tokens for non-interactive agent clients.
+//!
+//! Tokens are issued by a principal holding `auth.manage`, carry a subset of the
+//! agent capabilities as their scope, and are stored hashed in
+//! `.arxos/service_tokens.yaml`. The plaintext secret is returned exactly once at
+//! creation time. Revocation takes effect on the next request because every
+//! authentication re-reads the store.
+//!
+//! Authentication only reads the token file. Changes to it go through
+//! [`ServiceTokenStore::update`], which serializes them within the agent and
+//! replaces the file atomically. Last-used times are kept in memory and flushed to
+//! a separate `.arxos/service_token_usage.yaml` at most once a minute per token, so
+//! a usage update can never write back a token that was revoked meanwhile.
+
+use std::collections::{BTreeMap, HashMap};
+use std::path::{Path, PathBuf};
+use std::sync::Mutex;
+
+use anyhow::{anyhow, Context, Result};
+use chrono::{DateTime, Duration, Utc};
+use serde::{Deserialize, Serialize};
+use sha2::{Digest, Sha256};
+use uuid::Uuid;
+
+use crate::agent::protocol::FieldErrors;
+
+/// Prefix for service-account secrets, so they are recognisable in logs and configs.
+pub const SERVICE_TOKEN_PREFIX: &str = "arx_sat_";
+
+/// How often a token's last-used time is written to the usage file, at most.
+pub const USAGE_FLUSH_INTERVAL_SECS: i64 = 60;
+
+/// Serializes read-modify-write cycles on token files within the process.
+static STORE_LOCK: Mutex<()> = Mutex::new(());
+
+/// Last-used times not yet flushed, and when each was last flushed, keyed by usage
+/// file and token id.
+static USAGE: Mutex<Option<HashMap<(PathBuf, String), DateTime<Utc>>>> = Mutex::new(None);
+
+/// Capabilities a service token may be scoped to.
+///
+/// `auth.manage` is deliberately absent: service accounts cannot mint or revoke tokens.
+/// Neither is `collab.config`, which the root token does not hold either.
+pub const SERVICE_TOKEN_CAPABILITIES: &[&str] = &[
+    "git.status",
+    "git.diff",
+    "git.commit",
+    "files.read",
+    "building.get",
+    "building.sync",
+    "ifc.import",
+    "ifc.export",
+    "collab.sync",
+];
+
+#[derive(Debug, Clone, Serialize, Deserialize, PartialEq)]
+pub struct ServiceToken {
+    pub id: String,
+    pub name: String,
+    pub organization: String,
+    pub capabilities: Vec<String>,
+    /// Hex SHA-256 of the secret; the secret itself is never stored.
+    pub token_hash: String,
+    pub created_at: DateTime<Utc>,
+    #[serde(default)]
+    pub expires_at: Option<DateTime<Utc>>,
+    #[serde(default)]
+    pub revoked_at: Option<DateTime<Utc>>,
+    /// Filled from the usage file on load; never written to the token file.
+    #[serde(default, skip_serializing)]
+    pub last_used_at: Option<DateTime<Utc>>,
+}
+
+impl ServiceToken {
+    pub fn is_revoked(&self) -> bool {
+        self.revoked_at.is_some()
+    }
+
+    pub fn is_expired(&self, now: DateTime<Utc>) -> bool {
+        self.expires_at.map(|exp| exp <= now).unwrap_or(false)
+    }
+
+    pub fn is_active(&self, now: DateTime<Utc>) -> bool {
+        !self.is_revoked() && !self.is_expired(now)
+    }
+}
+
+/// Identity resolved from a valid service token.
+#[derive(Debug, Clone, PartialEq)]
+pub struct ServiceTokenGrant {
+    pub token_id: String,
+    pub organization: String,
+    pub capabilities: Vec<String>,
+}
+
+#[derive(Debug, Default, Serialize, Deserialize)]
+struct ServiceTokenFile {
+    #[serde(default)]
+    tokens: Vec<ServiceToken>,
+}
+
+/// File-backed store of service tokens rooted at a repository.
+pub struct ServiceTokenStore {
+    path: PathBuf,
+    tokens: Vec<ServiceToken>,
+}
+
+impl ServiceTokenStore {
+    pub fn store_path(repo_root: &Path) -> PathBuf {
+        repo_root.join(".arxos/service_tokens.yaml")
+    }
+
+    pub fn usage_path(repo_root: &Path) -> PathBuf {
+        repo_root.join(".arxos/service_token_usage.yaml")
+    }
+
+    /// Load the store for `repo_root`; a missing file yields an empty store.
+    pub fn load(repo_root: &Path) -> Result<Self> {
+        let path = Self::store_path(repo_root);
+        let mut tokens = if path.exists() {
+            let content = std::fs::read_to_string(&path)
+                .with_context(|| format!("Failed to read {}", path.display()))?;
+            let file: ServiceTokenFile = serde_yaml::from_str(&content)
+                .with_context(|| format!("Failed to parse {}", path.display()))?;
+            file.tokens
+        } else {
+            Vec::new()
+        };
+        let usage = load_usage(&Self::usage_path(repo_root));
+        for token in &mut tokens {
+            token.last_used_at = usage.get(&token.id).copied();
+        }
+        Ok(Self { path, tokens })
+    }
+
+    /// Load, modify and save the store as one step, so concurrent creates and
+    /// revokes within the agent do not overwrite each other.
+    pub fn update<T>(repo_root: &Path, f: impl FnOnce(&mut Self) -> Result<T>) -> Result<T> {
+        let _guard = STORE_LOCK.lock().unwrap_or_else(|e| e.into_inner());
+        let mut store = Self::load(repo_root)?;
+        let value = f(&mut store)?;
+        store.save()?;
+        Ok(value)
+    }
+
+    /// Replace the token file atomically; readers see the old or the new file.
+    pub fn save(&self) -> Result<()> {
+        let file = ServiceTokenFile {
+            tokens: self.tokens.clone(),
+        };
+        write_atomic(&self.path, &serde_yaml::to_string(&file)?)
+    }
+
+    pub fn tokens(&self) -> &[ServiceToken] {
+        &self.tokens
+    }
+
+    /// Issue a new token. Returns the stored record and the plaintext secret.
+    pub fn create(
+        &mut self,
+        name: &str,
+        organization: &str,
+        capabilities: &[String],
+        ttl: Option<Duration>,
+    ) -> Result<(ServiceToken, String)> {
+        check_token_request(name, organization, capabilities, ttl).into_result()?;
+        let name = name.trim();
+        let organization = organization.trim();
+
+        let now = Utc::now();
+        let secret = format!("{}{}", SERVICE_TOKEN_PREFIX, Uuid::new_v4().simple());
+        let mut caps = capabilities.to_vec();
+        caps.sort();
+        caps.dedup();
+
+        let token = ServiceToken {
+            id: Uuid::new_v4().to_string(),
+            name: name.to_string(),
+            organization: organization.to_string(),
+            capabilities: caps,
+            token_hash: hash_secret(&secret),
+            created_at: now,
+            expires_at: ttl.map(|ttl| now + ttl),
+            revoked_at: None,
+            last_used_at: None,
+        };
+        self.tokens.push(token.clone());
+        Ok((token, secret))
+    }
+
+    /// Revoke a token by id. Revoking an already revoked token is a no-op.
+    pub fn revoke(&mut self, id: &str) -> Result<()> {
+        let token = self
+            .tokens
+            .iter_mut()
+            .find(|t| t.id == id)
+            .ok_or_else(|| anyhow!("Service token '{}' not found", id))?;
+        if token.revoked_at.is_none() {
+            token.revoked_at = Some(Utc::now());
+        }
+        Ok(())
+    }
+
+    /// Resolve a secret to its grant, recording `last_used_at` on success.
+    pub fn authenticate(&mut self, secret: &str) -> Option<ServiceTokenGrant> {
+        if !secret.starts_with(SERVICE_TOKEN_PREFIX) {
+            return None;
+        }
+        let hash = hash_secret(secret);
+        let now = Utc::now();
+        let token = self.tokens.iter_mut().find(|t| t.token_hash == hash)?;
+        if !token.is_active(now) {
+            return None;
+        }
+        token.last_used_at = Some(now);
+        Some(ServiceTokenGrant {
+            token_id: token.id.clone(),
+            organization: token.organization.clone(),
+            capabilities: token.capabilities.clone(),
+        })
+    }
+}
+
+/// Authenticate `secret` against the store under `repo_root` and record usage.
+/// The token file is only read.
+///
+/// Store errors are treated as authentication failures.
+pub fn authenticate_service_token(repo_root: &Path, secret: &str) -> Option<ServiceTokenGrant> {
+    if !secret.starts_with(SERVICE_TOKEN_PREFIX) {
+        return None;
+    }
+    let mut store = match ServiceTokenStore::load(repo_root) {
+        Ok(store) => store,
+        Err(e) => {
+            tracing::warn!(error = %e, "Failed to load service token store");
+            return None;
+        }
+    };
+    let grant = store.authenticate(secret)?;
+    if let Err(e) = record_usage(repo_root, &grant.token_id, Utc::now()) {
+        tracing::warn!(error = %e, "Failed to record service token usage");
+    }
+    tracing::info!(
+        token_id = %grant.token_id,
+        organization = %grant.organization,
+        "Service token authenticated"
+    );
+    Some(grant)
+}
+
+/// Note that token `id` was used at `now`, writing the usage file when the token's
+/// last flush is older than [`USAGE_FLUSH_INTERVAL_SECS`].
+fn record_usage(repo_root: &Path, id: &str, now: DateTime<Utc>) -> Result<()> {
+    let path = ServiceTokenStore::usage_path(repo_root);
+    let mut flushed = USAGE.lock().unwrap_or_else(|e| e.into_inner());
+    let flushed = flushed.get_or_insert_with(HashMap::new);
+    let key = (path.clone(), id.to_string());
+    if flushed
+        .get(&key)
+        .is_some_and(|at| now - *at < Duration::seconds(USAGE_FLUSH_INTERVAL_SECS))
+    {
+        return Ok(());
+    }
+    let mut usage = load_usage(&path);
+    usage.insert(id.to_string(), now);
+    write_atomic(&path, &serde_yaml::to_string(&usage)?)?;
+    flushed.insert(key, now);
+    Ok(())
+}
+
+/// Last-used times by token id; an unreadable file counts as empty.
+fn load_usage(path: &Path) -> BTreeMap<String, DateTime<Utc>> {
+    std::fs::read_to_string(path)
+        .ok()
+        .and_then(|content| serde_yaml::from_str(&content).ok())
+        .unwrap_or_default()
+}
+
+fn write_atomic(path: &Path, content: &str) -> Result<()> {
+    if let Some(parent) = path.parent() {
+        std::fs::create_dir_all(parent)?;
+    }
+    let tmp = path.with_extension(format!("yaml.{}.tmp", Uuid::new_v4().simple()));
+    std::fs::write(&tmp, content).with_context(|| format!("Failed to write {}", tmp.display()))?;
+    std::fs::rename(&tmp, path).with_context(|| format!("Failed to replace {}", path.display()))?;
+    Ok(())
+}
+
+fn hash_secret(secret: &str) -> String {
+    let mut hasher = Sha256::new();
+    hasher.update(secret.as_bytes());
+    hasher
+        .finalize()
+        .iter()
+        .map(|b| format!("{:02x}", b))
+        .collect()
+}
+
+/// Every problem with a token request, keyed by the `auth.tokens.create` param name.
+pub fn check_token_request(
+    name: &str,
+    organization: &str,
+    capabilities: &[String],
+    ttl: Option<Duration>,
+) -> FieldErrors {
+    let mut errors = FieldErrors::new();
+    if name.trim().is_empty() {
+        errors.add("name", "must not be empty");
+    }
+    if organization.trim().is_empty() {
+        errors.add("organization", "must not be empty");
+    }
+    if capabilities.is_empty() {
+        errors.add("capabilities", "at least one capability is required");
+    }
+    for cap in capabilities
+        .iter()
+        .filter(|cap| !SERVICE_TOKEN_CAPABILITIES.contains(&cap.as_str()))
+    {
+        errors.add(
+            "capabilities",
+            format!("'{}' cannot be granted to a service token", cap),
+        );
+    }
+    if ttl.is_some_and(|ttl| ttl <= Duration::zero()) {
+        errors.add("ttlHours", "must be positive");
+    }
+    errors
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use tempfile::TempDir;
+
+    #[test]
+    fn create_stores_hash_not_secret() {
+        let temp = TempDir::new().unwrap();
+        let mut store = ServiceTokenStore::load(temp.path()).unwrap();
+        let (token, secret) = store
+            .create("ci", "acme", &["git.status".into()], None)
+            .unwrap();
+        store.save().unwrap();
+
+        assert!(secret.starts_with(SERVICE_TOKEN_PREFIX));
+        assert_ne!(token.token_hash, secret);
+        let on_disk = std::fs::read_to_string(ServiceTokenStore::store_path(temp.path())).unwrap();
+        assert!(!on_disk.contains(&secret));
+    }
+
+    #[test]
+    fn create_rejects_auth_manage_scope() {
+        let temp = TempDir::new().unwrap();
+        let mut store = ServiceTokenStore::load(temp.path()).unwrap();
+        assert!(store
+            .create("ci", "acme", &["auth.manage".into()], None)
+            .is_err());
+        assert!(store
+            .create("ci", "acme", &["collab.config".into()], None)
+            .is_err());
+        assert!(store.create("ci", "acme", &[], None).is_err());
+
+        let errors = check_token_request(
+            " ",
+            "acme",
+            &["auth.manage".into()],
+            Some(Duration::hours(-1)),
+        );
+        let fields: Vec<&str> = errors.errors().iter().map(|e| e.field.as_str()).collect();
+        assert_eq!(fields, vec!["name", "capabilities", "ttlHours"]);
+    }
+
+    #[test]
+    fn authenticate_resolves_org_and_scopes() {
+        let temp = TempDir::new().unwrap();
+        let mut store = ServiceTokenStore::load(temp.path()).unwrap();
+        let (token, secret) = store
+            .create(
+                "ci",
+                "acme",
+                &["git.diff".into(), "git.status".into()],
+                None,
+            )
+            .unwrap();
+        store.save().unwrap();
+
+        let grant = authenticate_service_token(temp.path(), &secret).unwrap();
+        assert_eq!(grant.token_id, token.id);
+        assert_eq!(grant.organization, "acme");
+        assert_eq!(grant.capabilities, vec!["git.diff", "git.status"]);
+
+        let reloaded = ServiceTokenStore::load(temp.path()).unwrap();
+        assert!(reloaded.tokens()[0].last_used_at.is_some());
+        assert!(authenticate_service_token(temp.path(), "arx_sat_wrong").is_none());
+    }
+
+    #[test]
+    fn revoked_and_expired_tokens_are_rejected() {
+        let temp = TempDir::new().unwrap();
+        let mut store = ServiceTokenStore::load(temp.path()).unwrap();
+        let (revoked, revoked_secret) = store
+            .create("old", "acme", &["git.status".into()], None)
+            .unwrap();
+        let (_, expired_secret) = store
+            .create(
+                "short",
+                "acme",
+                &["git.status".into()],
+                Some(Duration::seconds(1)),
+            )
+            .unwrap();
+        store.revoke(&revoked.id).unwrap();
+        store.tokens[1].expires_at = Some(Utc::now() - Duration::seconds(1));
+        store.save().unwrap();
+
+        assert!(authenticate_service_token(temp.path(), &revoked_secret).is_none());
+        assert!(authenticate_service_token(temp.path(), &expired_secret).is_none());
+        assert!(store.revoke("missing").is_err());
+    }
+
+    #[test]
+    fn usage_never_undoes_a_revocation() {
+        let temp = TempDir::new().unwrap();
+        let (token, secret) = ServiceTokenStore::update(temp.path(), |store| {
+            store.create("ci", "acme", &["git.status".into()], None)
+        })
+        .unwrap();
+
+        assert!(authenticate_service_token(temp.path(), &secret).is_some());
+
+        // Requests keep authenticating while the token is revoked.
+        let root = temp.path().to_path_buf();
+        let readers: Vec<_> = (0..4)
+            .map(|_| {
+                let (root, secret) = (root.clone(), secret.clone());
+                std::thread::spawn(move || {
+                    for _ in 0..50 {
+                        authenticate_service_token(&root, &secret);
+                    }
+                })
+            })
+            .collect();
+        ServiceTokenStore::update(temp.path(), |store| store.revoke(&token.id)).unwrap();
+        for reader in readers {
+            reader.join().unwrap();
+        }
+
+        let store = ServiceTokenStore::load(temp.path()).unwrap();
+        assert!(store.tokens()[0].is_revoked());
+        assert!(store.tokens()[0].last_used_at.is_some());
+        assert!(authenticate_service_token(temp.path(), &secret).is_none());
+        let on_disk = std::fs::read_to_string(ServiceTokenStore::store_path(temp.path())).unwrap();
+        assert!(!on_disk.contains("last_used_at"));
+    }
+}
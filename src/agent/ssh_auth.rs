@@ -2,23 +2,103 @@ use anyhow::{Context, Result};
 use russh_keys::key::PublicKey;
 use russh_keys::PublicKeyBase64;
 use serde::Deserialize;
-use std::collections::HashMap;
+use std::collections::{HashMap, HashSet};
 use std::path::Path;
 
-#[derive(Debug, Deserialize, Clone)]
+#[derive(Debug, Deserialize, Clone, Default)]
 pub struct Role {
+    #[serde(default)]
     pub permissions: Vec<String>,
+    /// Roles whose permissions this role also grants.
+    #[serde(default)]
+    pub inherits: Vec<String>,
 }
 
 #[derive(Debug, Deserialize, Default, Clone)]
 pub struct PermissionsConfig {
+    #[serde(default)]
     pub roles: HashMap<String, Role>,
+    #[serde(default)]
     pub users: HashMap<String, UserConfig>,
+    /// Organization-defined custom roles, keyed by organization name.
+    #[serde(default)]
+    pub organizations: HashMap<String, OrganizationConfig>,
+    /// Fall back to [`builtin_role`] for roles the config does not define. Off by
+    /// default, so an undefined role grants nothing.
+    #[serde(default)]
+    pub builtin_roles: bool,
+}
+
+#[derive(Debug, Deserialize, Default, Clone)]
+pub struct OrganizationConfig {
+    #[serde(default)]
+    pub roles: HashMap<String, Role>,
 }
 
 #[derive(Debug, Deserialize, Clone)]
 pub struct UserConfig {
     pub role: String,
+    /// Organization whose custom roles are consulted first for this user.
+    #[serde(default)]
+    pub organization: Option<String>,
+}
+
+/// Built-in definitions of the standard roles, used when `builtin_roles` is set and
+/// neither the organization nor the global config defines the role.
+pub fn builtin_role(name: &str) -> Option<Role> {
+    let (permissions, inherits): (&[&str], &[&str]) = match name {
+        "analyst" => (&["connect", "read"], &[]),
+        "technician" => (&["write"], &["analyst"]),
+        "maintenance" => (&["review"], &["technician"]),
+        "editor" => (&["write", "review"], &["analyst"]),
+        "admin" => (&["admin.users"], &["editor", "maintenance"]),
+        "owner" => (&["admin.organization"], &["admin"]),
+        _ => return None,
+    };
+    Some(Role {
+        permissions: permissions.iter().map(|p| p.to_string()).collect(),
+        inherits: inherits.iter().map(|r| r.to_string()).collect(),
+    })
+}
+
+impl PermissionsConfig {
+    /// Look up a role, preferring the organization's custom definition, then the
+    /// global `roles` table, then the built-in defaults if enabled.
+    pub fn resolve_role(&self, organization: Option<&str>, role_name: &str) -> Option<Role> {
+        organization
+            .and_then(|org| self.organizations.get(org))
+            .and_then(|org| org.roles.get(role_name))
+            .or_else(|| self.roles.get(role_name))
+            .cloned()
+            .or_else(|| builtin_role(role_name).filter(|_| self.builtin_roles))
+    }
+
+    /// All permissions granted by `role_name`, including inherited roles.
+    pub fn role_permissions(&self, organization: Option<&str>, role_name: &str) -> HashSet<String> {
+        let mut granted = HashSet::new();
+        let mut visited = HashSet::new();
+        let mut pending = vec![role_name.to_string()];
+
+        while let Some(name) = pending.pop() {
+            if !visited.insert(name.clone()) {
+                continue;
+            }
+            if let Some(role) = self.resolve_role(organization, &name) {
+                granted.extend(role.permissions);
+                pending.extend(role.inherits);
+            }
+        }
+        granted
+    }
+
+    /// Whether `username` holds `permission` through their role.
+    pub fn can(&self, username: &str, permission: &str) -> bool {
+        let Some(user) = self.users.get(username) else {
+            return false;
+        };
+        let perms = self.role_permissions(user.organization.as_deref(), &user.role);
+        perms.contains("*") || perms.contains(permission)
+    }
 }
 
 pub struct SshAuthenticator {
@@ -88,23 +168,23 @@ impl SshAuthenticator {
 
     /// Check if a user has a specific permission
     pub fn check_permission(&self, username: &str, permission: &str) -> bool {
-        if let Some(role_name) = self.get_user_role(username) {
-            if let Some(perms) = self.get_role_permissions(role_name) {
-                return perms.iter().any(|p| p == permission || p == "*");
-            }
-        }
-        false
+        self.permissions.can(username, permission)
     }
 
     pub fn get_user_role(&self, user_id: &str) -> Option<&String> {
         self.permissions.users.get(user_id).map(|u| &u.role)
     }
 
-    pub fn get_role_permissions(&self, role_name: &str) -> Option<&Vec<String>> {
-        self.permissions
-            .roles
-            .get(role_name)
-            .map(|r| &r.permissions)
+    /// Effective permissions of a role (global or built-in), including inherited roles.
+    pub fn get_role_permissions(&self, role_name: &str) -> Option<Vec<String>> {
+        self.permissions.resolve_role(None, role_name)?;
+        let mut perms: Vec<String> = self
+            .permissions
+            .role_permissions(None, role_name)
+            .into_iter()
+            .collect();
+        perms.sort();
+        Some(perms)
     }
 }
 
@@ -155,4 +235,66 @@ users:
         assert!(!auth.check_permission("test_user", "write"));
         assert!(!auth.check_permission("non_existent_user", "connect"));
     }
+
+    #[test]
+    fn builtin_roles_apply_only_when_enabled() {
+        let mut config = PermissionsConfig::default();
+        for (user, role) in [
+            ("alice", "editor"),
+            ("dan", "technician"),
+            ("erin", "admin"),
+        ] {
+            config.users.insert(
+                user.into(),
+                UserConfig {
+                    role: role.into(),
+                    organization: None,
+                },
+            );
+        }
+        assert!(!config.can("alice", "connect"));
+        assert!(!config.can("erin", "connect"));
+
+        config.builtin_roles = true;
+        assert!(config.can("alice", "connect"));
+        assert!(config.can("alice", "write"));
+        assert!(!config.can("alice", "admin.users"));
+        assert!(config.can("dan", "write"));
+        assert!(!config.can("dan", "review"));
+        assert!(config.can("erin", "admin.users"));
+        assert!(!config.can("erin", "admin.organization"));
+        assert!(builtin_role("viewer").is_none());
+    }
+
+    #[test]
+    fn custom_org_role_grants_subset() {
+        let config: PermissionsConfig = serde_yaml::from_str(
+            r#"
+roles:
+  auditor:
+    permissions: ["connect", "read", "write"]
+organizations:
+  acme:
+    roles:
+      auditor:
+        permissions: ["read"]
+        inherits: ["gate"]
+      gate:
+        permissions: ["connect"]
+        inherits: ["auditor"]
+users:
+  bob:
+    role: auditor
+    organization: acme
+  carol:
+    role: auditor
+"#,
+        )
+        .unwrap();
+
+        assert!(config.can("bob", "connect"));
+        assert!(config.can("bob", "read"));
+        assert!(!config.can("bob", "write"));
+        assert!(config.can("carol", "write"));
+    }
 }
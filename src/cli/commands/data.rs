@@ -6,7 +6,10 @@
 use super::Command;
 use crate::cli::subcommands::{EquipmentCommands, RoomCommands, SpatialCommands};
 use crate::core::domain::ArxAddress;
+use crate::core::equipment_types::EquipmentTypeRegistry;
+use crate::core::id_template::{IdTemplates, ID_TEMPLATES_FILE};
 use crate::core::{Dimensions, Position, SpatialProperties};
+use crate::validation::{BoundsConfig, PropertySchemas};
 use crate::core::{
     Equipment, EquipmentHealthStatus, EquipmentStatus, EquipmentType, Room, RoomType,
 };
@@ -31,14 +34,17 @@ fn save_building_to_path(
     commit: bool,
     message: &str,
 ) -> Result<(), Box<dyn Error>> {
-    let base = path
-        .parent()
-        .filter(|p| !p.as_os_str().is_empty())
-        .unwrap_or_else(|| Path::new("."));
-    crate::ingest::persist_building_at(base, building, commit, Some(message))?;
+    crate::ingest::persist_building_at(project_root(path), building, commit, Some(message))?;
     Ok(())
 }
 
+/// Project root for a `building.yaml` path (its parent, or `.`).
+fn project_root(path: &Path) -> &Path {
+    path.parent()
+        .filter(|p| !p.as_os_str().is_empty())
+        .unwrap_or_else(|| Path::new("."))
+}
+
 fn parse_dimensions(input: &str) -> Result<Dimensions, Box<dyn Error>> {
     let cleaned = input.replace('X', "x");
     let parts: Vec<&str> = cleaned.split('x').collect();
@@ -79,6 +85,35 @@ fn parse_position(input: &str, coordinate_system: &str) -> Result<Position, Box<
     })
 }
 
+fn parse_xy(input: &str) -> Result<(f64, f64), Box<dyn Error>> {
+    let parts: Vec<&str> = input
+        .split([',', ' '])
+        .filter(|s| !s.trim().is_empty())
+        .collect();
+    if parts.len() != 2 {
+        return Err("Point must be in format x,y".into());
+    }
+    Ok((parts[0].trim().parse()?, parts[1].trim().parse()?))
+}
+
+fn parse_bbox(
+    input: &str,
+) -> Result<(crate::core::spatial::Point3D, crate::core::spatial::Point3D), Box<dyn Error>> {
+    use crate::core::spatial::Point3D;
+    let parts: Vec<f64> = input
+        .split([',', ' '])
+        .filter(|s| !s.trim().is_empty())
+        .map(|s| s.trim().parse::<f64>())
+        .collect::<Result<_, _>>()?;
+    if parts.len() != 4 {
+        return Err("Bounding box must be in format minx,miny,maxx,maxy".into());
+    }
+    Ok((
+        Point3D::new(parts[0].min(parts[2]), parts[1].min(parts[3]), 0.0),
+        Point3D::new(parts[0].max(parts[2]), parts[1].max(parts[3]), 0.0),
+    ))
+}
+
 fn parse_properties(props: &[String]) -> Result<HashMap<String, String>, Box<dyn Error>> {
     let mut map = HashMap::new();
     for prop in props {
@@ -90,19 +125,9 @@ fn parse_properties(props: &[String]) -> Result<HashMap<String, String>, Box<dyn
     Ok(map)
 }
 
-fn parse_equipment_type(input: &str) -> Result<EquipmentType, Box<dyn Error>> {
-    let normalized = input.trim().to_lowercase();
-    let eq_type = match normalized.as_str() {
-        "hvac" => EquipmentType::HVAC,
-        "electrical" => EquipmentType::Electrical,
-        "av" => EquipmentType::AV,
-        "furniture" => EquipmentType::Furniture,
-        "safety" => EquipmentType::Safety,
-        "plumbing" => EquipmentType::Plumbing,
-        "network" => EquipmentType::Network,
-        _ => EquipmentType::Other(input.to_string()),
-    };
-    Ok(eq_type)
+/// Built-in type, or a custom type from the project registry under its registered name.
+fn parse_equipment_type(path: &Path, input: &str) -> Result<EquipmentType, Box<dyn Error>> {
+    Ok(EquipmentTypeRegistry::load(project_root(path))?.resolve(input))
 }
 
 fn parse_equipment_status(input: &str) -> Result<EquipmentStatus, Box<dyn Error>> {
@@ -166,6 +191,7 @@ fn apply_room_updates(room: &mut Room, props: &[String]) -> Result<(), Box<dyn E
 }
 
 fn apply_equipment_updates(
+    path: &Path,
     equipment: &mut Equipment,
     props: &[String],
     position_override: Option<&str>,
@@ -177,7 +203,7 @@ fn apply_equipment_updates(
 
         match key.trim().to_lowercase().as_str() {
             "name" => equipment.name = value.trim().to_string(),
-            "equipment_type" => equipment.equipment_type = parse_equipment_type(value)?,
+            "equipment_type" => equipment.equipment_type = parse_equipment_type(path, value)?,
             "status" => equipment.status = parse_equipment_status(value)?,
             "health_status" => equipment.health_status = Some(parse_health_status(value)?),
             "room" | "room_id" => equipment.room_id = Some(value.trim().to_string()),
@@ -260,6 +286,7 @@ impl Command for RoomCommand {
                 }
 
                 room.spatial_properties = SpatialProperties::new(pos, dims, coordinate_system);
+                BoundsConfig::load(project_root(&path))?.gate_room(&model, &room)?;
 
                 let floor_ref = if let Some(floor_ref) = model.find_floor_mut(*floor) {
                     floor_ref
@@ -282,6 +309,7 @@ impl Command for RoomCommand {
                     .find(|w| w.name == *wing)
                     .ok_or_else(|| format!("Failed to find wing '{}'", wing))?;
 
+                PropertySchemas::load(project_root(&path))?.gate_room(&room)?;
                 wing_ref.rooms.push(room.clone());
 
                 save_building_to_path(&path, model, *commit, &format!("Add room: {}", room.name))?;
@@ -348,7 +376,7 @@ impl Command for RoomCommand {
                 Ok(())
             }
             RoomCommands::Show { room, equipment } => {
-                let (_path, model) = load_building_from_dir()?;
+                let (path, model) = load_building_from_dir()?;
 
                 let mut found_room: Option<&Room> = None;
                 for floor_ref in &model.floors {
@@ -371,6 +399,11 @@ impl Command for RoomCommand {
                 println!("   Type: {}", room_ref.room_type);
                 println!("   Equipment count: {}", room_ref.equipment.len());
 
+                let derived = crate::core::derived::DerivedConfig::load(project_root(&path))?;
+                for (name, value) in derived.room_values(room_ref)? {
+                    println!("   {}: {:.2}", name, value);
+                }
+
                 if *equipment {
                     for eq in &room_ref.equipment {
                         println!("   - {} ({})", eq.name, eq.equipment_type);
@@ -403,6 +436,8 @@ impl Command for RoomCommand {
                 }
 
                 let updated = updated_room.ok_or_else(|| format!("Room '{}' not found", room))?;
+                PropertySchemas::load(project_root(&path))?.gate_room(&updated)?;
+                BoundsConfig::load(project_root(&path))?.gate_room(&model, &updated)?;
 
                 save_building_to_path(
                     &path,
@@ -476,17 +511,29 @@ impl Command for EquipmentCommand {
                 position,
                 at,
                 property,
+                id,
                 commit,
             } => {
                 let (path, mut model) = load_building_from_dir()?;
 
-                let eq_type = parse_equipment_type(equipment_type)?;
+                let eq_type = parse_equipment_type(&path, equipment_type)?;
                 let mut equipment = Equipment::new(
                     name.clone(),
                     at.clone().unwrap_or_else(|| "/".to_string()),
-                    eq_type,
+                    eq_type.clone(),
                 );
 
+                if let Some(id) = id {
+                    if model.get_all_equipment().iter().any(|e| e.id == *id) {
+                        return Err(format!("Equipment ID '{}' already exists", id).into());
+                    }
+                    equipment.id = id.clone();
+                } else if let Some(next) = IdTemplates::load(project_root(&path))?
+                    .next_for_room(&model, room, &eq_type.to_string())?
+                {
+                    equipment.id = next;
+                }
+
                 if let Some(addr) = at {
                     let parsed = ArxAddress::from_path(addr)?;
                     parsed.validate()?;
@@ -523,6 +570,8 @@ impl Command for EquipmentCommand {
                 if !added {
                     return Err(format!("Room '{}' not found", room).into());
                 }
+                PropertySchemas::load(project_root(&path))?.gate_equipment(&equipment)?;
+                BoundsConfig::load(project_root(&path))?.gate_equipment(&model, &equipment)?;
 
                 save_building_to_path(
                     &path,
@@ -531,9 +580,31 @@ impl Command for EquipmentCommand {
                     &format!("Add equipment: {}", equipment.name),
                 )?;
 
-                println!("✅ Added equipment: {}", equipment.name);
+                println!("✅ Added equipment: {} ({})", equipment.name, equipment.id);
                 Ok(())
             }
+            EquipmentCommands::NextId {
+                room,
+                equipment_type,
+            } => {
+                let (path, model) = load_building_from_dir()?;
+                let eq_type = parse_equipment_type(&path, equipment_type)?;
+                match IdTemplates::load(project_root(&path))?.next_for_room(
+                    &model,
+                    room,
+                    &eq_type.to_string(),
+                )? {
+                    Some(id) => {
+                        println!("{}", id);
+                        Ok(())
+                    }
+                    None => Err(format!(
+                        "No ID template for equipment type '{}' (see {})",
+                        eq_type, ID_TEMPLATES_FILE
+                    )
+                    .into()),
+                }
+            }
             EquipmentCommands::List {
                 room,
                 equipment_type,
@@ -544,7 +615,12 @@ impl Command for EquipmentCommand {
                     return Err("Interactive equipment browser requires --features tui".into());
                 }
 
-                let (_path, model) = load_building_from_dir()?;
+                let (path, model) = load_building_from_dir()?;
+                let derived = if *verbose {
+                    Some(crate::core::derived::DerivedConfig::load(project_root(&path))?)
+                } else {
+                    None
+                };
                 let all = model.get_all_equipment();
                 let items: Vec<&Equipment> = all
                     .into_iter()
@@ -595,6 +671,11 @@ impl Command for EquipmentCommand {
                         if let Some(addr) = &eq.address {
                             println!("  address: {}", addr.path);
                         }
+                        if let Some(ref derived) = derived {
+                            for (name, value) in derived.equipment_values(eq)? {
+                                println!("  {}: {:.2}", name, value);
+                            }
+                        }
                     } else {
                         println!("- {}", eq.name);
                     }
@@ -611,7 +692,7 @@ impl Command for EquipmentCommand {
                 let (path, mut model) = load_building_from_dir()?;
 
                 let updated = if let Some(eq) = model.find_equipment_mut(equipment) {
-                    apply_equipment_updates(eq, property, position.as_deref())?;
+                    apply_equipment_updates(&path, eq, property, position.as_deref())?;
                     Some(eq.clone())
                 } else {
                     None
@@ -619,6 +700,8 @@ impl Command for EquipmentCommand {
 
                 let updated_eq =
                     updated.ok_or_else(|| format!("Equipment '{}' not found", equipment))?;
+                PropertySchemas::load(project_root(&path))?.gate_equipment(&updated_eq)?;
+                BoundsConfig::load(project_root(&path))?.gate_equipment(&model, &updated_eq)?;
 
                 save_building_to_path(
                     &path,
@@ -734,6 +817,101 @@ impl Command for SpatialCommand {
                 println!("{}", msg);
                 Ok(())
             }
+            SpatialCommands::Move {
+                ids,
+                floor,
+                bbox,
+                translate,
+                rotate,
+                pivot,
+                scale,
+                commit,
+            } => {
+                use crate::core::operations::transform::{
+                    transform_entities, AffineTransform, TransformLog, TransformSelection,
+                };
+                use crate::core::spatial::Point3D;
+
+                let selection = TransformSelection {
+                    ids: ids.clone(),
+                    floor: *floor,
+                    bbox: bbox.as_deref().map(parse_bbox).transpose()?,
+                };
+                let translation = match translate {
+                    Some(t) => {
+                        let p = parse_position(t, "building_local")?;
+                        Point3D::new(p.x, p.y, p.z)
+                    }
+                    None => Point3D::new(0.0, 0.0, 0.0),
+                };
+                let pivot = match pivot {
+                    Some(p) => {
+                        let (x, y) = parse_xy(p)?;
+                        Point3D::new(x, y, 0.0)
+                    }
+                    None => Point3D::new(0.0, 0.0, 0.0),
+                };
+                let transform = AffineTransform {
+                    translation,
+                    rotation_degrees: *rotate,
+                    pivot,
+                    scale: *scale,
+                };
+                if transform.is_identity() {
+                    return Err("Nothing to do: pass --translate, --rotate, or --scale".into());
+                }
+
+                let (path, mut model) = load_building_from_dir()?;
+                let report = transform_entities(&mut model, &selection, &transform)?;
+                if report.total() == 0 {
+                    return Err("No rooms or equipment matched the selection".into());
+                }
+                for warning in &report.warnings {
+                    println!("⚠️  {}", warning);
+                }
+
+                let message = format!(
+                    "Move {} room(s), {} equipment",
+                    report.rooms_moved.len(),
+                    report.equipment_moved.len()
+                );
+                let building_id = model.id.clone();
+                save_building_to_path(&path, model, *commit, &message)?;
+                println!(
+                    "✅ Moved {} room(s) and {} equipment",
+                    report.rooms_moved.len(),
+                    report.equipment_moved.len()
+                );
+                let root = project_root(&path);
+                let mut log = TransformLog::load(root)?;
+                let record = log.record(&building_id, &transform, &report);
+                log.save(root)?;
+                println!("   Undo with: arx spatial undo-move --id {}", record.id);
+                Ok(())
+            }
+            SpatialCommands::UndoMove { id, commit } => {
+                use crate::core::operations::transform::{undo_transform, TransformLog};
+
+                let (path, mut model) = load_building_from_dir()?;
+                let root = project_root(&path).to_path_buf();
+                let mut log = TransformLog::load(&root)?;
+                let record = log.undoable(&model.id, id.as_deref())?.clone();
+                let report = undo_transform(&mut model, &record)?;
+                for warning in &report.warnings {
+                    println!("⚠️  {}", warning);
+                }
+
+                let message = format!("Undo move {}", record.id);
+                save_building_to_path(&path, model, *commit, &message)?;
+                log.mark_undone(&record.id);
+                log.save(&root)?;
+                println!(
+                    "✅ Moved {} room(s) and {} equipment back",
+                    report.rooms_moved.len(),
+                    report.equipment_moved.len()
+                );
+                Ok(())
+            }
             SpatialCommands::Validate { entity, tolerance } => {
                 let building = load_building_at(Path::new("."))
                     .map_err(|e| format!("load building.yaml: {}", e))?;
@@ -854,6 +1032,7 @@ mod tests {
                 position: Some("5,6,2.5".to_string()),
                 at: Some("/usa/ny/brooklyn/ps-118/floor-02/conference/projector-01".to_string()),
                 property: vec!["brand=Epson".to_string()],
+                id: None,
                 commit: false,
             },
         };
@@ -21,6 +21,8 @@ impl Command for StatusCommand {
                     token: std::sync::Arc::new(std::sync::Mutex::new(token_state)),
                     metrics: std::sync::Arc::new(crate::agent::observability::AgentMetrics::new()),
                     reload_handle: None,
+                    idempotency: Default::default(),
+                    http_idempotency: Default::default(),
                 });
 
                 let rt = tokio::runtime::Runtime::new()?;
@@ -0,0 +1,98 @@
+use crate::cli::commands::Command;
+use crate::ingest::checkpoint::{import_with_checkpoint, ImportCheckpointStore};
+use crate::ingest::{ImportOptions, ImporterRegistry, MergeStrategy};
+use crate::persistence::{save_building_at, BUILDING_YAML};
+use anyhow::anyhow;
+use std::error::Error;
+use std::path::Path;
+
+/// Import through the importer registry; merges into building.yaml when present.
+pub struct ImportFileCommand {
+    pub file: String,
+    pub content_type: Option<String>,
+    pub dry_run: bool,
+    pub resume: bool,
+    pub checkpoint_ttl_hours: i64,
+    /// `replace`, `keep-higher-confidence` or `keep-validated`.
+    pub merge_strategy: String,
+}
+
+impl Command for ImportFileCommand {
+    fn execute(&self) -> Result<(), Box<dyn Error>> {
+        let repo_root = Path::new(".");
+        let path = Path::new(&self.file);
+        let registry = ImporterRegistry::default();
+        let merge_strategy = MergeStrategy::parse(&self.merge_strategy).ok_or_else(|| {
+            format!(
+                "Unknown merge strategy '{}' (expected one of: {})",
+                self.merge_strategy,
+                MergeStrategy::NAMES.join(", ")
+            )
+        })?;
+
+        let importer = registry
+            .select(path, self.content_type.as_deref())
+            .ok_or_else(|| {
+                format!(
+                    "No importer for {} (supported: {})",
+                    self.content_type.as_deref().unwrap_or(&self.file),
+                    registry.names().join(", ")
+                )
+            })?;
+        println!(
+            "Importing {} with '{}' importer",
+            self.file,
+            importer.name()
+        );
+        if self.dry_run {
+            println!("Dry run mode enabled - no changes will be written");
+        }
+
+        let building_yaml = repo_root.join(BUILDING_YAML);
+        let options = ImportOptions {
+            existing_yaml: Some(building_yaml.as_path()).filter(|p| p.exists()),
+            validate: true,
+            merge_strategy,
+        };
+        let result = if self.resume {
+            let store = ImportCheckpointStore::new(repo_root)
+                .with_ttl(chrono::Duration::hours(self.checkpoint_ttl_hours));
+            store.prune();
+            import_with_checkpoint(importer, path, &options, &store)
+        } else {
+            importer.import(path, &options)
+        }
+        .map_err(|e| format!("{} import failed: {}", importer.name(), e))?;
+
+        if result.validation.has_errors() {
+            for line in result.summary_lines() {
+                println!("  {}", line);
+            }
+            return Err("Import validation failed; refusing to write building.yaml".into());
+        }
+
+        if self.dry_run {
+            println!("Parsed successfully (dry-run):");
+            println!("  Building: {}", result.building.name);
+            println!("  Equipment: {}", result.building.get_all_equipment().len());
+            for line in result.summary_lines() {
+                println!("  {}", line);
+            }
+            return Ok(());
+        }
+
+        save_building_at(repo_root, &result.building)
+            .map_err(|e| anyhow!("Failed to write {}: {}", BUILDING_YAML, e))?;
+
+        println!("Imported successfully to {}", BUILDING_YAML);
+        for line in result.summary_lines() {
+            println!("  {}", line);
+        }
+
+        Ok(())
+    }
+
+    fn name(&self) -> &'static str {
+        "import-file"
+    }
+}
@@ -1,15 +1,23 @@
-//! One-shot migration: fill missing durable `ArxAddress` on equipment.
+//! Versioned migrations of the Building SSOT (`arx migrate`).
+//!
+//! Migration 1 backfills missing durable `ArxAddress` values on equipment.
 
 use super::Command;
-use crate::core::operations::backfill_equipment_addresses;
-use crate::ingest::persist_building_at;
-use crate::persistence::{load_building_at, BUILDING_YAML};
+use crate::persistence::{MigrationRunner, BUILDING_YAML};
 use std::error::Error;
 use std::path::PathBuf;
 
-/// Backfill missing equipment addresses on the Building SSOT.
+/// Apply, list, or roll back versioned building migrations.
 pub struct MigrateCommand {
     pub dry_run: bool,
+    /// List applied and pending migrations without running anything
+    pub status: bool,
+    /// Roll back the most recently applied migration
+    pub rollback: bool,
+    /// Roll back even if building.yaml changed after the migration
+    pub force: bool,
+    /// Apply pending migrations up to this version (default: all)
+    pub to: Option<u32>,
     /// Project root containing building.yaml (default: cwd)
     pub path: Option<PathBuf>,
 }
@@ -17,42 +25,50 @@ pub struct MigrateCommand {
 impl Command for MigrateCommand {
     fn execute(&self) -> Result<(), Box<dyn Error>> {
         let base = self.path.clone().unwrap_or_else(|| PathBuf::from("."));
+        let runner = MigrationRunner::new(&base);
 
-        let mut building = load_building_at(&base).map_err(|e| {
-            format!(
-                "Failed to load {} under {}: {}",
-                BUILDING_YAML,
-                base.display(),
-                e
-            )
-        })?;
-
-        let updated = backfill_equipment_addresses(&mut building);
-        println!("🔄 Address backfill: {} equipment updated", updated);
-
-        if updated == 0 {
-            println!("✅ Nothing to migrate — all equipment already has addresses");
+        if self.status {
+            for applied in runner.applied()? {
+                println!(
+                    "  ✅ {:04} {} (applied {})",
+                    applied.version,
+                    applied.name,
+                    applied.applied_at.format("%Y-%m-%d %H:%M UTC")
+                );
+            }
+            for pending in runner.pending()? {
+                println!("  ⏳ {:04} {} (pending)", pending.version, pending.name);
+            }
             return Ok(());
         }
 
-        if self.dry_run {
-            println!("Dry run — not writing {}", BUILDING_YAML);
-            for eq in building.get_all_equipment() {
-                if let Some(addr) = &eq.address {
-                    println!("  would keep/set {} → {}", eq.name, addr.path);
-                }
+        if self.rollback {
+            match runner.down(self.force)? {
+                Some(rolled_back) => println!(
+                    "↩️  Rolled back {:04} {}; restored {}",
+                    rolled_back.version, rolled_back.name, BUILDING_YAML
+                ),
+                None => println!("Nothing to roll back — no migrations applied"),
             }
             return Ok(());
         }
 
-        // Path-aware persist — does not mutate process cwd (Track I10).
-        persist_building_at(
-            &base,
-            building,
-            false,
-            Some("migrate: backfill equipment ArxAddress"),
-        )?;
-        println!("✅ Wrote addresses to {}", BUILDING_YAML);
+        let steps = runner.up(self.to, self.dry_run)?;
+        if steps.is_empty() {
+            println!("✅ Nothing to migrate — {} is up to date", BUILDING_YAML);
+            return Ok(());
+        }
+        for step in &steps {
+            println!(
+                "🔄 {:04} {}: {} object(s) updated",
+                step.version, step.name, step.changed
+            );
+        }
+        if self.dry_run {
+            println!("Dry run — not writing {}", BUILDING_YAML);
+        } else {
+            println!("✅ Applied {} migration(s) to {}", steps.len(), BUILDING_YAML);
+        }
         Ok(())
     }
 
@@ -8,11 +8,16 @@ pub mod edit;
 pub mod export;
 pub mod git;
 pub mod import;
+pub mod import_file;
 pub mod import_lidar;
 pub mod init;
 pub mod merge;
 pub mod migrate;
+pub mod quality;
 pub mod query;
+pub mod reconcile;
+pub mod repair;
+pub mod template;
 
 #[cfg(feature = "tui")]
 pub mod search;
@@ -25,6 +30,9 @@ pub use import::ImportCommand;
 pub use init::InitCommand;
 pub use merge::MergeCommand;
 pub use migrate::MigrateCommand;
+pub use quality::QualityCommand;
+pub use repair::RepairCommand;
+pub use template::TemplateCommand;
 
 #[cfg(feature = "tui")]
 pub use search::SearchCommand;
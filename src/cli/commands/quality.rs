@@ -0,0 +1,84 @@
+//! Data-quality score for the Building SSOT.
+
+use super::Command;
+use crate::cli::subcommands::QualityCommands;
+use crate::persistence::{load_building_at, BUILDING_YAML};
+use crate::validation::ruleset::{resolve_ruleset, starter_ruleset};
+use crate::validation::{score_building, QualityWeights};
+use std::error::Error;
+use std::path::PathBuf;
+
+pub struct QualityCommand {
+    pub subcommand: QualityCommands,
+}
+
+impl Command for QualityCommand {
+    fn execute(&self) -> Result<(), Box<dyn Error>> {
+        match &self.subcommand {
+            QualityCommands::Score {
+                path,
+                format,
+                weights,
+                rules,
+            } => {
+                let base = path
+                    .as_deref()
+                    .map(PathBuf::from)
+                    .unwrap_or_else(|| PathBuf::from("."));
+                let building = load_building_at(&base).map_err(|e| {
+                    format!(
+                        "Failed to load {} under {}: {}",
+                        BUILDING_YAML,
+                        base.display(),
+                        e
+                    )
+                })?;
+                let weights = QualityWeights::resolve(&base, weights.as_deref())?;
+                let rules =
+                    resolve_ruleset(&base, rules.as_deref())?.unwrap_or_else(starter_ruleset);
+
+                let score = score_building(&building, &rules, &weights);
+
+                match format.as_str() {
+                    "json" => println!("{}", serde_json::to_string_pretty(&score)?),
+                    "text" => {
+                        println!(
+                            "📊 Data quality for '{}': {:.1}/100",
+                            building.name, score.score
+                        );
+                        for factor in &score.factors {
+                            println!(
+                                "  {:<20} {:>5.1}%  weight {:>4.2}  -{:<5.1} {}",
+                                factor.name,
+                                factor.score * 100.0,
+                                factor.weight,
+                                factor.deduction,
+                                factor.detail
+                            );
+                        }
+                        let weakest = score.weakest();
+                        if !weakest.is_empty() {
+                            println!("Weakest areas:");
+                            for factor in weakest.iter().take(3) {
+                                println!(
+                                    "  {} (-{:.1} pts): {}",
+                                    factor.name, factor.deduction, factor.detail
+                                );
+                            }
+                        }
+                    }
+                    other => {
+                        return Err(
+                            format!("Unsupported format '{}' (use text or json)", other).into()
+                        )
+                    }
+                }
+                Ok(())
+            }
+        }
+    }
+
+    fn name(&self) -> &'static str {
+        "quality"
+    }
+}
@@ -0,0 +1,103 @@
+//! Reconcile building equipment against a CMMS asset export.
+
+use super::Command;
+use crate::ingest::persist_building_at;
+use crate::ingest::reconcile::{apply_reconciliation, extracted_only_csv, reconcile};
+use crate::ingest::schedule::parse_schedule_csv;
+use crate::persistence::{load_building_at, BUILDING_YAML};
+use std::error::Error;
+use std::path::Path;
+
+pub struct ReconcileCommand {
+    pub file: String,
+    pub min_score: f64,
+    pub apply: bool,
+    pub create_missing: bool,
+    pub export_missing: Option<String>,
+    pub format: String,
+    pub commit: bool,
+}
+
+impl Command for ReconcileCommand {
+    fn execute(&self) -> Result<(), Box<dyn Error>> {
+        if !(0.0..=1.0).contains(&self.min_score) {
+            return Err("--min-score must be between 0 and 1".into());
+        }
+        let base = Path::new(".");
+        let content = std::fs::read_to_string(&self.file)
+            .map_err(|e| format!("Failed to read {}: {}", self.file, e))?;
+        let rows = parse_schedule_csv(&content)?;
+        let mut building = load_building_at(base)
+            .map_err(|e| format!("Failed to load {}: {}", BUILDING_YAML, e))?;
+
+        let report = reconcile(&building, &rows, self.min_score);
+
+        match self.format.as_str() {
+            "json" => println!("{}", serde_json::to_string_pretty(&report)?),
+            "text" => {
+                println!(
+                    "🔗 {} matched, {} only in building, {} only in CMMS",
+                    report.matched.len(),
+                    report.extracted_only.len(),
+                    report.cmms_only.len()
+                );
+                for pair in &report.matched {
+                    println!(
+                        "  = {} ({}) <-> line {}: {} [{:?} {:.2}]",
+                        pair.equipment_name,
+                        pair.equipment_id,
+                        pair.row_line,
+                        pair.row_name,
+                        pair.basis,
+                        pair.score
+                    );
+                }
+                for eq in &report.extracted_only {
+                    println!("  + building only: {} ({})", eq.name, eq.id);
+                }
+                for row in &report.cmms_only {
+                    println!("  - CMMS only: line {}: {}", row.line, row.name);
+                }
+            }
+            other => return Err(format!("Unknown format '{}' (text|json)", other).into()),
+        }
+
+        if let Some(out) = &self.export_missing {
+            std::fs::write(out, extracted_only_csv(&report))?;
+            println!(
+                "Wrote {} building-only asset(s) to {}",
+                report.extracted_only.len(),
+                out
+            );
+        }
+
+        if self.apply {
+            let applied = apply_reconciliation(&mut building, &report, &rows, self.create_missing);
+            for msg in &applied.skipped {
+                println!("  ⚠️  {}", msg);
+            }
+            if applied.linked + applied.created == 0 {
+                println!("Nothing to apply");
+                return Ok(());
+            }
+            persist_building_at(
+                base,
+                building,
+                self.commit,
+                Some(&format!(
+                    "reconcile: link {} and create {} CMMS asset(s)",
+                    applied.linked, applied.created
+                )),
+            )?;
+            println!(
+                "✅ Linked {}, created {} in {}",
+                applied.linked, applied.created, BUILDING_YAML
+            );
+        }
+        Ok(())
+    }
+
+    fn name(&self) -> &'static str {
+        "reconcile"
+    }
+}
@@ -0,0 +1,89 @@
+//! Model integrity repair: equipment room references and placement.
+
+use super::Command;
+use crate::cli::subcommands::RepairCommands;
+use crate::core::operations::{check_hierarchy, repair_hierarchy};
+use crate::ingest::persist_building_at;
+use crate::persistence::{load_building_at, BUILDING_YAML};
+use std::error::Error;
+use std::path::PathBuf;
+
+pub struct RepairCommand {
+    pub subcommand: RepairCommands,
+}
+
+impl Command for RepairCommand {
+    fn execute(&self) -> Result<(), Box<dyn Error>> {
+        match &self.subcommand {
+            RepairCommands::Hierarchy { path, fix, commit } => {
+                let base = path
+                    .as_deref()
+                    .map(PathBuf::from)
+                    .unwrap_or_else(|| PathBuf::from("."));
+                let mut building = load_building_at(&base).map_err(|e| {
+                    format!(
+                        "Failed to load {} under {}: {}",
+                        BUILDING_YAML,
+                        base.display(),
+                        e
+                    )
+                })?;
+
+                let report = if *fix {
+                    repair_hierarchy(&mut building)
+                } else {
+                    check_hierarchy(&building)
+                };
+
+                if report.is_clean() {
+                    println!("✅ Hierarchy OK — no dangling or misfiled references");
+                    return Ok(());
+                }
+
+                println!("🔍 {} hierarchy issue(s):", report.issues.len());
+                for issue in &report.issues {
+                    println!("  [{}] {} ({})", issue.kind, issue.message, issue.location);
+                }
+
+                if !*fix {
+                    return Err(format!(
+                        "{} hierarchy issue(s) found; re-run with --fix to repair",
+                        report.issues.len()
+                    )
+                    .into());
+                }
+
+                if !report.changes.is_empty() {
+                    println!("🔧 Applied {} change(s):", report.changes.len());
+                    for change in &report.changes {
+                        println!("  {}", change);
+                    }
+                    persist_building_at(
+                        &base,
+                        building,
+                        *commit,
+                        Some(&format!(
+                            "repair: fix {} hierarchy reference(s)",
+                            report.changes.len()
+                        )),
+                    )?;
+                    println!("✅ Wrote repairs to {}", BUILDING_YAML);
+                }
+
+                let unfixable = report.unfixable().count();
+                if unfixable > 0 {
+                    return Err(format!(
+                        "{} issue(s) need manual resolution (duplicate room ids)",
+                        unfixable
+                    )
+                    .into());
+                }
+                Ok(())
+            }
+        }
+    }
+
+    fn name(&self) -> &'static str {
+        "repair"
+    }
+}
@@ -0,0 +1,85 @@
+//! Building clone and template registry.
+
+use super::Command;
+use crate::cli::subcommands::TemplateCommands;
+use crate::core::operations::{clone_building, CloneOptions};
+use crate::core::Building;
+use crate::persistence::templates::{
+    default_templates_dir, instantiate_template, list_templates, save_template,
+};
+use crate::persistence::{load_building_at, save_building_at, BUILDING_YAML};
+use std::error::Error;
+use std::path::Path;
+
+pub struct TemplateCommand {
+    pub subcommand: TemplateCommands,
+}
+
+/// Write `building` to `{to}/building.yaml`, refusing to overwrite an existing model.
+fn write_new_building(to: &str, building: &Building) -> Result<(), Box<dyn Error>> {
+    let dir = Path::new(to);
+    if dir.join(BUILDING_YAML).exists() {
+        return Err(format!("{} already contains {}", dir.display(), BUILDING_YAML).into());
+    }
+    std::fs::create_dir_all(dir)?;
+    save_building_at(dir, building)?;
+    println!(
+        "📄 Created {} ({}, id={}, {} floor(s), {} room(s), {} equipment)",
+        dir.join(BUILDING_YAML).display(),
+        building.name,
+        building.id,
+        building.floors.len(),
+        building.get_all_rooms().len(),
+        building.get_all_equipment().len()
+    );
+    Ok(())
+}
+
+impl Command for TemplateCommand {
+    fn execute(&self) -> Result<(), Box<dyn Error>> {
+        let load_current = || {
+            load_building_at(".").map_err(|e| format!("Failed to load {}: {}", BUILDING_YAML, e))
+        };
+        match &self.subcommand {
+            TemplateCommands::Clone { to, name, clean } => {
+                let source = load_current()?;
+                let cloned = clone_building(
+                    &source,
+                    &CloneOptions {
+                        name: name.clone(),
+                        clean: *clean,
+                    },
+                );
+                write_new_building(to, &cloned.building)
+            }
+            TemplateCommands::Save { name } => {
+                let source = load_current()?;
+                let dir = save_template(&default_templates_dir(), name, &source)?;
+                println!("✅ Saved template '{}' to {}", name, dir.display());
+                Ok(())
+            }
+            TemplateCommands::List => {
+                let root = default_templates_dir();
+                let templates = list_templates(&root)?;
+                if templates.is_empty() {
+                    println!("No templates in {}", root.display());
+                }
+                for t in &templates {
+                    println!(
+                        "  {}  {} floor(s), {} room(s), {} equipment",
+                        t.name, t.floors, t.rooms, t.equipment
+                    );
+                }
+                Ok(())
+            }
+            TemplateCommands::New { template, to, name } => {
+                let building = instantiate_template(&default_templates_dir(), template, name)?;
+                write_new_building(to, &building)
+            }
+        }
+    }
+
+    fn name(&self) -> &'static str {
+        "template"
+    }
+}
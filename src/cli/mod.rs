@@ -18,7 +18,7 @@ use commands::{
     data::{EquipmentCommand, RoomCommand, SpatialCommand},
     git::{CommitCommand, DiffCommand, StageCommand, StatusCommand, UnstageCommand},
     AccessCommand, Command, ContributeCommand, ExportCommand, ImportCommand, InitCommand,
-    MigrateCommand,
+    MigrateCommand, QualityCommand, RepairCommand, TemplateCommand,
 };
 
 #[derive(Parser)]
@@ -87,6 +87,44 @@ impl Cli {
                     };
                     Ok(cmd.execute()?)
                 }
+                ImportSubcommand::File {
+                    file,
+                    content_type,
+                    dry_run,
+                    resume,
+                    checkpoint_ttl_hours,
+                    merge_strategy,
+                } => {
+                    let cmd = commands::import_file::ImportFileCommand {
+                        file,
+                        content_type,
+                        dry_run,
+                        resume,
+                        checkpoint_ttl_hours,
+                        merge_strategy,
+                    };
+                    Ok(cmd.execute()?)
+                }
+                ImportSubcommand::Reconcile {
+                    file,
+                    min_score,
+                    apply,
+                    create_missing,
+                    export_missing,
+                    format,
+                    commit,
+                } => {
+                    let cmd = commands::reconcile::ReconcileCommand {
+                        file,
+                        min_score,
+                        apply,
+                        create_missing,
+                        export_missing,
+                        format,
+                        commit,
+                    };
+                    Ok(cmd.execute()?)
+                }
                 ImportSubcommand::Text {
                     script,
                     building,
@@ -293,13 +331,41 @@ impl Cli {
                 format,
                 verbose,
             } => commands::query::run_address_query(&pattern, &format, verbose),
-            Commands::Migrate { dry_run } => {
+            Commands::Migrate {
+                dry_run,
+                status,
+                rollback,
+                force,
+                to,
+            } => {
                 let cmd = MigrateCommand {
                     dry_run,
+                    status,
+                    rollback,
+                    force,
+                    to,
                     path: None,
                 };
                 Ok(cmd.execute()?)
             }
+            Commands::Repair { command } => {
+                let cmd = RepairCommand {
+                    subcommand: command,
+                };
+                cmd.execute()
+            }
+            Commands::Quality { command } => {
+                let cmd = QualityCommand {
+                    subcommand: command,
+                };
+                cmd.execute()
+            }
+            Commands::Template { command } => {
+                let cmd = TemplateCommand {
+                    subcommand: command,
+                };
+                cmd.execute()
+            }
             Commands::Room { command } => {
                 let cmd = RoomCommand {
                     subcommand: command,
@@ -376,13 +442,19 @@ impl Cli {
                         ))),
                         metrics: std::sync::Arc::new(crate::agent::observability::AgentMetrics::new()),
                         reload_handle: None,
+                        idempotency: Default::default(),
+                        http_idempotency: Default::default(),
                     });
 
                     crate::tui::dashboard::run_dashboard(state).await
                 })?;
                 Ok(())
             }
-            Commands::Validate { path, strict_addresses } => {
+            Commands::Validate {
+                path,
+                strict_addresses,
+                rules,
+            } => {
                 use crate::persistence::{load_building_at, BUILDING_YAML};
                 use crate::validation::{validate_building, STRICT_ADDRESSES};
                 use std::sync::atomic::Ordering;
@@ -407,7 +479,35 @@ impl Cli {
                 for line in report.summary_lines() {
                     println!("{}", line);
                 }
-                if report.has_errors() {
+                let rule_report = crate::validation::ruleset::resolve_ruleset(&base, rules.as_deref())?
+                    .map(|set| set.evaluate(&building));
+                if let Some(ref rule_report) = rule_report {
+                    println!("Rule set:");
+                    for line in rule_report.summary_lines() {
+                        println!("{}", line);
+                    }
+                }
+                let schemas = crate::validation::PropertySchemas::load(&base)?;
+                let schema_report = (!schemas.is_empty()).then(|| schemas.evaluate(&building));
+                if let Some(ref schema_report) = schema_report {
+                    println!("Property schemas:");
+                    for line in schema_report.summary_lines() {
+                        println!("{}", line);
+                    }
+                }
+                let bounds_report =
+                    crate::validation::BoundsConfig::load(&base)?.evaluate(&building);
+                if !bounds_report.results.is_empty() {
+                    println!("Coordinate bounds:");
+                    for line in bounds_report.summary_lines() {
+                        println!("{}", line);
+                    }
+                }
+                if report.has_errors()
+                    || rule_report.is_some_and(|r| r.has_errors())
+                    || schema_report.is_some_and(|r| r.has_errors())
+                    || bounds_report.has_errors()
+                {
                     Err("Building validation failed".into())
                 } else {
                     println!("✅ Validation completed successfully");
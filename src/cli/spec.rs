@@ -7,7 +7,10 @@ use clap::Subcommand;
 
 #[cfg(feature = "agent")]
 use crate::cli::commands::RemoteCommand;
-use crate::cli::subcommands::{EquipmentCommands, RoomCommands, SpatialCommands};
+use crate::cli::subcommands::{
+    EquipmentCommands, QualityCommands, RepairCommands, RoomCommands, SpatialCommands,
+    TemplateCommands,
+};
 
 /// Top-level `arx` subcommands (order = `--help` order).
 #[derive(Subcommand)]
@@ -64,6 +67,10 @@ pub enum Commands {
         /// Enable strict address prefix checking
         #[arg(long)]
         strict_addresses: bool,
+        /// Object rule set: `starter` (built-in code checks) or a YAML/JSON file.
+        /// Defaults to .arxos/rules.yaml under the project root when present.
+        #[arg(long)]
+        rules: Option<String>,
     },
     /// Export building SSOT (IFC is the compiler interchange spine)
     ///
@@ -119,11 +126,38 @@ Use --path to select a project root without changing cwd.")]
         #[arg(long)]
         verbose: bool,
     },
-    /// Backfill missing ArxAddress fields on equipment
+    /// Apply versioned building.yaml migrations (e.g. ArxAddress backfill)
     Migrate {
         /// Preview changes without writing
         #[arg(long)]
         dry_run: bool,
+        /// List applied and pending migrations
+        #[arg(long, conflicts_with_all = ["rollback", "dry_run", "to"])]
+        status: bool,
+        /// Roll back the most recently applied migration
+        #[arg(long, conflicts_with_all = ["dry_run", "to"])]
+        rollback: bool,
+        /// With --rollback: discard edits made after the migration
+        #[arg(long, requires = "rollback")]
+        force: bool,
+        /// Apply pending migrations up to this version
+        #[arg(long)]
+        to: Option<u32>,
+    },
+    /// Check and repair model integrity (read-only unless --fix)
+    Repair {
+        #[command(subcommand)]
+        command: RepairCommands,
+    },
+    /// Data-quality score and breakdown
+    Quality {
+        #[command(subcommand)]
+        command: QualityCommands,
+    },
+    /// Clone this building or instantiate it from a reusable template
+    Template {
+        #[command(subcommand)]
+        command: TemplateCommands,
     },
 
     // ── Model CRUD ──────────────────────────────────────────────────────
@@ -137,7 +171,7 @@ Use --path to select a project root without changing cwd.")]
         #[command(subcommand)]
         command: EquipmentCommands,
     },
-    /// Spatial query / transform / validate (implemented verbs only)
+    /// Spatial query / transform / move / validate (implemented verbs only)
     Spatial {
         #[command(subcommand)]
         command: SpatialCommands,
@@ -435,6 +469,53 @@ pub enum ImportSubcommand {
         #[arg(long)]
         building: Option<String>,
     },
+    /// Import any supported file, choosing the importer by content type or extension
+    /// (IFC, LiDAR, CSV equipment schedules)
+    File {
+        /// Path to the file
+        file: String,
+        /// MIME type override, e.g. `text/csv` (default: detect from extension)
+        #[arg(long)]
+        content_type: Option<String>,
+        /// Show what would be imported without writing
+        #[arg(long)]
+        dry_run: bool,
+        /// Checkpoint the parse stage under .arxos/import_checkpoints so a retry of
+        /// the same file resumes instead of re-parsing
+        #[arg(long)]
+        resume: bool,
+        /// Hours before a checkpoint expires (with --resume)
+        #[arg(long, default_value = "24")]
+        checkpoint_ttl_hours: i64,
+        /// Whose record wins when an imported object matches an existing one:
+        /// replace, keep-higher-confidence, or keep-validated
+        #[arg(long, default_value = "replace")]
+        merge_strategy: String,
+    },
+    /// Reconcile building equipment against a CMMS asset export (CSV, same columns as
+    /// equipment schedules): report matched, building-only and CMMS-only assets
+    Reconcile {
+        /// CMMS export CSV
+        file: String,
+        /// Minimum score (0-1) for a name-based match
+        #[arg(long, default_value = "0.6")]
+        min_score: f64,
+        /// Link matched pairs by writing the CMMS id to each equipment's cmms_id property
+        #[arg(long)]
+        apply: bool,
+        /// With --apply, also add CMMS-only assets to the building
+        #[arg(long, requires = "apply")]
+        create_missing: bool,
+        /// Write building-only equipment to this CSV for import into the CMMS
+        #[arg(long)]
+        export_missing: Option<String>,
+        /// Output format: text or json
+        #[arg(long, default_value = "text")]
+        format: String,
+        /// Commit changes to Git (with --apply)
+        #[arg(long, requires = "apply")]
+        commit: bool,
+    },
     /// Apply a text / AR command script (same as `arx edit`)
     Text {
         /// Script file path, or "-" for stdin
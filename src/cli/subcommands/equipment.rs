@@ -26,10 +26,23 @@ pub enum EquipmentCommands {
         /// Equipment properties (key=value)
         #[arg(long)]
         property: Vec<String>,
+        /// Equipment ID. If not provided, the next ID from the type's template in
+        /// .arxos/id_templates.yaml is used, or a UUID when no template applies.
+        #[arg(long)]
+        id: Option<String>,
         /// Commit changes to Git
         #[arg(long)]
         commit: bool,
     },
+    /// Preview the ID the next added equipment would get
+    NextId {
+        /// Room ID or name
+        #[arg(long)]
+        room: String,
+        /// Equipment type
+        #[arg(long)]
+        equipment_type: String,
+    },
     /// List equipment
     List {
         /// Room ID or name
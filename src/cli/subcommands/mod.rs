@@ -1,9 +1,15 @@
 //! CLI sub-command definitions for the Building compiler surface.
 
 pub mod equipment;
+pub mod quality;
+pub mod repair;
 pub mod room;
 pub mod spatial;
+pub mod template;
 
 pub use equipment::EquipmentCommands;
+pub use quality::QualityCommands;
+pub use repair::RepairCommands;
 pub use room::RoomCommands;
 pub use spatial::SpatialCommands;
+pub use template::TemplateCommands;
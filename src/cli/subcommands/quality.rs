@@ -0,0 +1,23 @@
+//! Data-quality reporting commands.
+
+use clap::Subcommand;
+
+#[derive(Subcommand)]
+pub enum QualityCommands {
+    /// Score data completeness and trustworthiness (0–100) with a factor breakdown
+    Score {
+        /// Project root containing building.yaml (default: cwd)
+        #[arg(long)]
+        path: Option<String>,
+        /// Output format (text, json)
+        #[arg(long, default_value = "text")]
+        format: String,
+        /// Factor weights YAML (default: .arxos/quality.yaml when present)
+        #[arg(long)]
+        weights: Option<String>,
+        /// Rule set for required properties: `starter` or a YAML/JSON file.
+        /// Defaults to .arxos/rules.yaml when present, else `starter`.
+        #[arg(long)]
+        rules: Option<String>,
+    },
+}
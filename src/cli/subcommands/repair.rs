@@ -0,0 +1,19 @@
+//! Model integrity repair commands.
+
+use clap::Subcommand;
+
+#[derive(Subcommand)]
+pub enum RepairCommands {
+    /// Check equipment room references and placement; repair with --fix
+    Hierarchy {
+        /// Project root containing building.yaml (default: cwd)
+        #[arg(long)]
+        path: Option<String>,
+        /// Apply repairs (default is a read-only report)
+        #[arg(long)]
+        fix: bool,
+        /// Commit repairs to Git
+        #[arg(long, requires = "fix")]
+        commit: bool,
+    },
+}
@@ -31,6 +31,42 @@ pub enum SpatialCommands {
         #[arg(long)]
         entity: String,
     },
+    /// Move, rotate, or scale a selection of rooms and equipment in place
+    Move {
+        /// Room or equipment IDs/names (comma-separated)
+        #[arg(long, value_delimiter = ',')]
+        ids: Vec<String>,
+        /// Restrict to a floor level
+        #[arg(long)]
+        floor: Option<i32>,
+        /// Select entities positioned inside an XY window (minx,miny,maxx,maxy)
+        #[arg(long)]
+        bbox: Option<String>,
+        /// Translation (dx,dy,dz)
+        #[arg(long, allow_hyphen_values = true)]
+        translate: Option<String>,
+        /// Counter-clockwise rotation in degrees about --pivot
+        #[arg(long, default_value = "0", allow_hyphen_values = true)]
+        rotate: f64,
+        /// Rotation/scale pivot (x,y)
+        #[arg(long, allow_hyphen_values = true)]
+        pivot: Option<String>,
+        /// Uniform scale factor about --pivot
+        #[arg(long, default_value = "1")]
+        scale: f64,
+        /// Commit changes to Git
+        #[arg(long)]
+        commit: bool,
+    },
+    /// Undo a move recorded by `spatial move`
+    UndoMove {
+        /// Operation to undo (default: the latest)
+        #[arg(long)]
+        id: Option<String>,
+        /// Commit changes to Git
+        #[arg(long)]
+        commit: bool,
+    },
     /// Validate spatial data
     Validate {
         /// Entity to validate
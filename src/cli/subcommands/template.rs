@@ -0,0 +1,35 @@
+//! Building clone and template registry commands.
+
+use clap::Subcommand;
+
+#[derive(Subcommand)]
+pub enum TemplateCommands {
+    /// Copy this building into a new directory under fresh ids
+    Clone {
+        /// Directory for the new building.yaml (created if missing)
+        to: String,
+        /// Name of the new building
+        #[arg(long)]
+        name: String,
+        /// Drop field-validation state, sensor bindings and CMMS links
+        #[arg(long)]
+        clean: bool,
+    },
+    /// Register this building as a reusable template (always a clean copy)
+    Save {
+        /// Template name (letters, digits, '-' and '_')
+        name: String,
+    },
+    /// List registered templates
+    List,
+    /// Create a new building from a registered template
+    New {
+        /// Template name
+        template: String,
+        /// Directory for the new building.yaml (created if missing)
+        to: String,
+        /// Name of the new building
+        #[arg(long)]
+        name: String,
+    },
+}
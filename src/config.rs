@@ -114,6 +114,12 @@ pub struct BuildingConfig {
     /// Validate on import
     #[serde(default = "default_validate_on_import")]
     pub validate_on_import: bool,
+    /// Decimal places for lengths (meters) written to building.yaml and sync payloads (0-12)
+    #[serde(default = "default_length_decimals")]
+    pub length_decimals: u32,
+    /// Decimal places for angles (radians) written to building.yaml and sync payloads (0-12)
+    #[serde(default = "default_angle_decimals")]
+    pub angle_decimals: u32,
 }
 
 /// Performance configuration
@@ -151,6 +157,9 @@ pub struct UiConfig {
     /// Show detailed help
     #[serde(default)]
     pub detailed_help: bool,
+    /// Agent dashboard auto-refresh interval in seconds (0 = manual refresh only)
+    #[serde(default = "default_dashboard_refresh_secs")]
+    pub dashboard_refresh_secs: u64,
 }
 
 fn default_commit_template() -> String {
@@ -206,6 +215,14 @@ fn default_validate_on_import() -> bool {
     true
 }
 
+fn default_length_decimals() -> u32 {
+    crate::core::precision::DEFAULT_LENGTH_DECIMALS
+}
+
+fn default_angle_decimals() -> u32 {
+    crate::core::precision::DEFAULT_ANGLE_DECIMALS
+}
+
 fn default_max_threads() -> usize {
     4
 }
@@ -238,6 +255,10 @@ fn default_color_scheme() -> String {
     "Auto".to_string()
 }
 
+fn default_dashboard_refresh_secs() -> u64 {
+    30
+}
+
 impl Default for ArxConfig {
     fn default() -> Self {
         Self {
@@ -285,6 +306,8 @@ impl Default for BuildingConfig {
             auto_commit: default_auto_commit(),
             naming_pattern: default_naming_pattern(),
             validate_on_import: default_validate_on_import(),
+            length_decimals: default_length_decimals(),
+            angle_decimals: default_angle_decimals(),
         }
     }
 }
@@ -308,6 +331,7 @@ impl Default for UiConfig {
             verbosity: default_verbosity(),
             color_scheme: default_color_scheme(),
             detailed_help: false,
+            dashboard_refresh_secs: default_dashboard_refresh_secs(),
         }
     }
 }
@@ -457,6 +481,11 @@ impl ConfigManager {
         if let Ok(val) = env::var("ARX_COLOR_SCHEME") {
             config.ui.color_scheme = val;
         }
+        if let Ok(val) = env::var("ARX_DASHBOARD_REFRESH_SECS") {
+            if let Ok(num) = val.parse() {
+                config.ui.dashboard_refresh_secs = num;
+            }
+        }
     }
 
     /// Validate configuration
@@ -477,6 +506,22 @@ impl ConfigManager {
             });
         }
 
+        // Validate output precision
+        for (field, value) in [
+            ("building.length_decimals", config.building.length_decimals),
+            ("building.angle_decimals", config.building.angle_decimals),
+        ] {
+            if value > crate::core::precision::MAX_DECIMALS {
+                return Err(ConfigError::ValidationFailed {
+                    field: field.to_string(),
+                    message: format!(
+                        "Decimal places must be between 0 and {}",
+                        crate::core::precision::MAX_DECIMALS
+                    ),
+                });
+            }
+        }
+
         // Validate thread count
         if config.performance.max_parallel_threads == 0
             || config.performance.max_parallel_threads > 64
@@ -522,8 +522,14 @@ impl Building {
 
     /// Add a property to the building metadata
     pub fn add_metadata_property(&mut self, key: String, value: String) {
-        if self.metadata.is_none() {
-            self.metadata = Some(BuildingMetadata {
+        self.metadata_properties_mut().insert(key, value);
+    }
+
+    /// Metadata properties, creating the metadata block if the building has none
+    pub fn metadata_properties_mut(&mut self) -> &mut HashMap<String, String> {
+        &mut self
+            .metadata
+            .get_or_insert_with(|| BuildingMetadata {
                 source_file: None,
                 parser_version: "native-1.0".to_string(),
                 total_entities: 0,
@@ -532,11 +538,8 @@ impl Building {
                 units: "meters".to_string(),
                 tags: Vec::new(),
                 properties: HashMap::new(),
-            });
-        }
-        if let Some(meta) = &mut self.metadata {
-            meta.properties.insert(key, value);
-        }
+            })
+            .properties
     }
 
     /// Get all anchors in the building tree.
@@ -0,0 +1,519 @@
+//! Derived (read-only) geometry properties for rooms and equipment.
+//!
+//! Values are computed from the current geometry every time they are read, so
+//! they can never go stale after a move or resize. Projects can add their own
+//! derived values as arithmetic expressions in `.arxos/derived.yaml`:
+//!
+//! ```yaml
+//! room:
+//!   occupancy: floor(area / 9.3)
+//!   wall_area: perimeter * height
+//! equipment:
+//!   service_area: (width + 2 * clearance) * (depth + 2 * clearance)
+//! ```
+//!
+//! Expressions support `+ - * /`, parentheses, numbers, the variables listed by
+//! [`room_variables`] / [`equipment_variables`], and `min`, `max`, `sqrt`,
+//! `abs`, `floor`, `ceil`, `round`.
+
+use std::collections::BTreeMap;
+use std::path::Path;
+
+use serde::{Deserialize, Serialize};
+
+use super::spatial::{BoundingBox3D, Point3D};
+use super::{Building, Equipment, Room};
+
+/// Project file holding custom derived-property expressions.
+pub const DERIVED_CONFIG_FILE: &str = ".arxos/derived.yaml";
+
+/// Clearance margin (metres) used when equipment has no `clearance` property.
+pub const DEFAULT_CLEARANCE_M: f64 = 0.5;
+
+/// Footprint area (m²) of a room, from its bounding box.
+pub fn room_area(room: &Room) -> f64 {
+    let (w, d, _) = room_extent(room);
+    w * d
+}
+
+/// Footprint perimeter (m) of a room, from its bounding box.
+pub fn room_perimeter(room: &Room) -> f64 {
+    let (w, d, _) = room_extent(room);
+    2.0 * (w + d)
+}
+
+/// Enclosed volume (m³) of a room, from its bounding box.
+pub fn room_volume(room: &Room) -> f64 {
+    let (w, d, h) = room_extent(room);
+    w * d * h
+}
+
+fn room_extent(room: &Room) -> (f64, f64, f64) {
+    let bbox = &room.spatial_properties.bounding_box;
+    let w = (bbox.max.x - bbox.min.x).abs();
+    let d = (bbox.max.y - bbox.min.y).abs();
+    let h = (bbox.max.z - bbox.min.z).abs();
+    if w > 0.0 && d > 0.0 {
+        (w, d, h)
+    } else {
+        let dims = &room.spatial_properties.dimensions;
+        (dims.width, dims.depth, dims.height)
+    }
+}
+
+/// Clearance margin for a piece of equipment (`clearance` property, metres).
+pub fn equipment_clearance(equipment: &Equipment) -> f64 {
+    equipment
+        .properties
+        .get("clearance")
+        .and_then(|v| v.trim().trim_end_matches('m').trim().parse::<f64>().ok())
+        .filter(|v| v.is_finite() && *v >= 0.0)
+        .unwrap_or(DEFAULT_CLEARANCE_M)
+}
+
+/// Physical extent of equipment: mesh bounds when present, else its position.
+pub fn equipment_bounds(equipment: &Equipment) -> BoundingBox3D {
+    if let Some(mesh) = equipment.mesh.as_ref().filter(|m| !m.vertices.is_empty()) {
+        let mut min = mesh.vertices[0];
+        let mut max = mesh.vertices[0];
+        for v in &mesh.vertices {
+            min = Point3D::new(min.x.min(v.x), min.y.min(v.y), min.z.min(v.z));
+            max = Point3D::new(max.x.max(v.x), max.y.max(v.y), max.z.max(v.z));
+        }
+        BoundingBox3D::new(min, max)
+    } else {
+        let p = Point3D::new(
+            equipment.position.x,
+            equipment.position.y,
+            equipment.position.z,
+        );
+        BoundingBox3D::new(p, p)
+    }
+}
+
+/// Service clearance envelope: the equipment bounds grown by its clearance margin
+/// in plan and upward.
+pub fn equipment_clearance_envelope(equipment: &Equipment) -> BoundingBox3D {
+    let bounds = equipment_bounds(equipment);
+    let c = equipment_clearance(equipment);
+    BoundingBox3D::new(
+        Point3D::new(bounds.min.x - c, bounds.min.y - c, bounds.min.z),
+        Point3D::new(bounds.max.x + c, bounds.max.y + c, bounds.max.z + c),
+    )
+}
+
+/// Variables available to room expressions.
+pub fn room_variables(room: &Room) -> BTreeMap<String, f64> {
+    let (w, d, h) = room_extent(room);
+    let pos = &room.spatial_properties.position;
+    BTreeMap::from([
+        ("width".to_string(), w),
+        ("depth".to_string(), d),
+        ("height".to_string(), h),
+        ("area".to_string(), room_area(room)),
+        ("perimeter".to_string(), room_perimeter(room)),
+        ("volume".to_string(), room_volume(room)),
+        ("x".to_string(), pos.x),
+        ("y".to_string(), pos.y),
+        ("z".to_string(), pos.z),
+    ])
+}
+
+/// Variables available to equipment expressions.
+pub fn equipment_variables(equipment: &Equipment) -> BTreeMap<String, f64> {
+    let bounds = equipment_bounds(equipment);
+    let envelope = equipment_clearance_envelope(equipment);
+    BTreeMap::from([
+        ("width".to_string(), bounds.max.x - bounds.min.x),
+        ("depth".to_string(), bounds.max.y - bounds.min.y),
+        ("height".to_string(), bounds.max.z - bounds.min.z),
+        ("clearance".to_string(), equipment_clearance(equipment)),
+        ("clearance_volume".to_string(), envelope.volume()),
+        ("x".to_string(), equipment.position.x),
+        ("y".to_string(), equipment.position.y),
+        ("z".to_string(), equipment.position.z),
+    ])
+}
+
+/// Custom derived-property expressions, keyed by output name.
+#[derive(Debug, Clone, Default, Serialize, Deserialize)]
+pub struct DerivedConfig {
+    #[serde(default)]
+    pub room: BTreeMap<String, String>,
+    #[serde(default)]
+    pub equipment: BTreeMap<String, String>,
+}
+
+impl DerivedConfig {
+    /// Load `.arxos/derived.yaml` under `base`; a missing file yields an empty config.
+    pub fn load(base: &Path) -> Result<Self, String> {
+        let path = base.join(DERIVED_CONFIG_FILE);
+        if !path.exists() {
+            return Ok(Self::default());
+        }
+        let content = std::fs::read_to_string(&path)
+            .map_err(|e| format!("read {}: {}", path.display(), e))?;
+        let config: DerivedConfig = serde_yaml::from_str(&content)
+            .map_err(|e| format!("parse {}: {}", path.display(), e))?;
+        config.check()?;
+        Ok(config)
+    }
+
+    /// Parse every expression once so typos surface at load time.
+    pub fn check(&self) -> Result<(), String> {
+        for (name, expr) in self.room.iter().chain(self.equipment.iter()) {
+            Expr::parse(expr).map_err(|e| format!("derived '{}': {}", name, e))?;
+        }
+        Ok(())
+    }
+
+    /// Built-in plus custom derived values for a room.
+    pub fn room_values(&self, room: &Room) -> Result<BTreeMap<String, f64>, String> {
+        let vars = room_variables(room);
+        let mut out: BTreeMap<String, f64> = ["area", "perimeter", "volume"]
+            .iter()
+            .map(|k| (k.to_string(), vars[*k]))
+            .collect();
+        for (name, expr) in &self.room {
+            out.insert(name.clone(), evaluate(expr, &vars)?);
+        }
+        Ok(out)
+    }
+
+    /// Built-in plus custom derived values for equipment.
+    pub fn equipment_values(&self, equipment: &Equipment) -> Result<BTreeMap<String, f64>, String> {
+        let vars = equipment_variables(equipment);
+        let mut out: BTreeMap<String, f64> = ["clearance", "clearance_volume"]
+            .iter()
+            .map(|k| (k.to_string(), vars[*k]))
+            .collect();
+        for (name, expr) in &self.equipment {
+            out.insert(name.clone(), evaluate(expr, &vars)?);
+        }
+        Ok(out)
+    }
+}
+
+/// Derived values of every room and equipment in a building, keyed by id, as
+/// served alongside the building in the API.
+#[derive(Debug, Clone, Default, Serialize)]
+pub struct BuildingDerivedValues {
+    pub rooms: BTreeMap<String, BTreeMap<String, f64>>,
+    pub equipment: BTreeMap<String, BTreeMap<String, f64>>,
+    /// Objects whose custom expressions failed; they keep their built-in values.
+    #[serde(skip_serializing_if = "Vec::is_empty")]
+    pub errors: Vec<String>,
+}
+
+impl DerivedConfig {
+    /// Derived values for the whole building. A failing custom expression only
+    /// drops that object's custom values.
+    pub fn building_values(&self, building: &Building) -> BuildingDerivedValues {
+        let builtin = DerivedConfig::default();
+        let mut out = BuildingDerivedValues::default();
+        for room in building.get_all_rooms() {
+            let values = self.room_values(room).unwrap_or_else(|e| {
+                out.errors.push(format!("room {}: {}", room.id, e));
+                builtin.room_values(room).unwrap_or_default()
+            });
+            out.rooms.insert(room.id.clone(), values);
+        }
+        for equipment in building.get_all_equipment() {
+            let values = self.equipment_values(equipment).unwrap_or_else(|e| {
+                out.errors
+                    .push(format!("equipment {}: {}", equipment.id, e));
+                builtin.equipment_values(equipment).unwrap_or_default()
+            });
+            out.equipment.insert(equipment.id.clone(), values);
+        }
+        out
+    }
+}
+
+/// Evaluate an arithmetic expression against named variables.
+pub fn evaluate(expr: &str, vars: &BTreeMap<String, f64>) -> Result<f64, String> {
+    Expr::parse(expr)?.eval(vars)
+}
+
+#[derive(Debug, Clone, PartialEq)]
+enum Expr {
+    Num(f64),
+    Var(String),
+    Neg(Box<Expr>),
+    Bin(char, Box<Expr>, Box<Expr>),
+    Call(String, Vec<Expr>),
+}
+
+impl Expr {
+    fn parse(input: &str) -> Result<Expr, String> {
+        let mut parser = Parser {
+            chars: input.chars().collect(),
+            pos: 0,
+        };
+        let expr = parser.expr()?;
+        parser.skip_ws();
+        if parser.pos < parser.chars.len() {
+            return Err(format!(
+                "unexpected '{}' at {}",
+                parser.chars[parser.pos], parser.pos
+            ));
+        }
+        Ok(expr)
+    }
+
+    fn eval(&self, vars: &BTreeMap<String, f64>) -> Result<f64, String> {
+        Ok(match self {
+            Expr::Num(n) => *n,
+            Expr::Var(name) => *vars
+                .get(name)
+                .ok_or_else(|| format!("unknown variable '{}'", name))?,
+            Expr::Neg(e) => -e.eval(vars)?,
+            Expr::Bin(op, l, r) => {
+                let (l, r) = (l.eval(vars)?, r.eval(vars)?);
+                match op {
+                    '+' => l + r,
+                    '-' => l - r,
+                    '*' => l * r,
+                    _ => {
+                        if r == 0.0 {
+                            return Err("division by zero".to_string());
+                        }
+                        l / r
+                    }
+                }
+            }
+            Expr::Call(name, args) => {
+                let args: Vec<f64> = args
+                    .iter()
+                    .map(|a| a.eval(vars))
+                    .collect::<Result<_, _>>()?;
+                match (name.as_str(), args.as_slice()) {
+                    ("min", [a, b]) => a.min(*b),
+                    ("max", [a, b]) => a.max(*b),
+                    ("sqrt", [a]) => a.sqrt(),
+                    ("abs", [a]) => a.abs(),
+                    ("floor", [a]) => a.floor(),
+                    ("ceil", [a]) => a.ceil(),
+                    ("round", [a]) => a.round(),
+                    _ => return Err(format!("unknown function {}/{}", name, args.len())),
+                }
+            }
+        })
+    }
+}
+
+struct Parser {
+    chars: Vec<char>,
+    pos: usize,
+}
+
+impl Parser {
+    fn skip_ws(&mut self) {
+        while self.pos < self.chars.len() && self.chars[self.pos].is_whitespace() {
+            self.pos += 1;
+        }
+    }
+
+    fn peek(&mut self) -> Option<char> {
+        self.skip_ws();
+        self.chars.get(self.pos).copied()
+    }
+
+    fn expect(&mut self, c: char) -> Result<(), String> {
+        if self.peek() == Some(c) {
+            self.pos += 1;
+            Ok(())
+        } else {
+            Err(format!("expected '{}' at {}", c, self.pos))
+        }
+    }
+
+    // expr := term (('+' | '-') term)*
+    fn expr(&mut self) -> Result<Expr, String> {
+        let mut lhs = self.term()?;
+        while let Some(op @ ('+' | '-')) = self.peek() {
+            self.pos += 1;
+            lhs = Expr::Bin(op, Box::new(lhs), Box::new(self.term()?));
+        }
+        Ok(lhs)
+    }
+
+    // term := unary (('*' | '/') unary)*
+    fn term(&mut self) -> Result<Expr, String> {
+        let mut lhs = self.unary()?;
+        while let Some(op @ ('*' | '/')) = self.peek() {
+            self.pos += 1;
+            lhs = Expr::Bin(op, Box::new(lhs), Box::new(self.unary()?));
+        }
+        Ok(lhs)
+    }
+
+    fn unary(&mut self) -> Result<Expr, String> {
+        if self.peek() == Some('-') {
+            self.pos += 1;
+            return Ok(Expr::Neg(Box::new(self.unary()?)));
+        }
+        self.atom()
+    }
+
+    fn atom(&mut self) -> Result<Expr, String> {
+        match self.peek() {
+            Some('(') => {
+                self.pos += 1;
+                let e = self.expr()?;
+                self.expect(')')?;
+                Ok(e)
+            }
+            Some(c) if c.is_ascii_digit() || c == '.' => {
+                let start = self.pos;
+                while self.pos < self.chars.len()
+                    && (self.chars[self.pos].is_ascii_digit() || self.chars[self.pos] == '.')
+                {
+                    self.pos += 1;
+                }
+                let text: String = self.chars[start..self.pos].iter().collect();
+                text.parse::<f64>()
+                    .map(Expr::Num)
+                    .map_err(|_| format!("invalid number '{}'", text))
+            }
+            Some(c) if c.is_ascii_alphabetic() || c == '_' => {
+                let start = self.pos;
+                while self.pos < self.chars.len()
+                    && (self.chars[self.pos].is_ascii_alphanumeric() || self.chars[self.pos] == '_')
+                {
+                    self.pos += 1;
+                }
+                let name: String = self.chars[start..self.pos].iter().collect();
+                if self.peek() == Some('(') {
+                    self.pos += 1;
+                    let mut args = vec![self.expr()?];
+                    while self.peek() == Some(',') {
+                        self.pos += 1;
+                        args.push(self.expr()?);
+                    }
+                    self.expect(')')?;
+                    Ok(Expr::Call(name, args))
+                } else {
+                    Ok(Expr::Var(name))
+                }
+            }
+            Some(c) => Err(format!("unexpected '{}' at {}", c, self.pos)),
+            None => Err("unexpected end of expression".to_string()),
+        }
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::core::{Dimensions, EquipmentType, Position, RoomType, SpatialProperties};
+
+    fn room(w: f64, d: f64, h: f64) -> Room {
+        let mut room = Room::new("R".into(), RoomType::Office);
+        room.spatial_properties = SpatialProperties::new(
+            Position {
+                x: 0.0,
+                y: 0.0,
+                z: 0.0,
+                coordinate_system: "building_local".into(),
+            },
+            Dimensions {
+                width: w,
+                height: h,
+                depth: d,
+            },
+            "building_local".into(),
+        );
+        room
+    }
+
+    #[test]
+    fn room_values_track_geometry_changes() {
+        let mut r = room(4.0, 5.0, 3.0);
+        assert_eq!(room_area(&r), 20.0);
+        assert_eq!(room_perimeter(&r), 18.0);
+        assert_eq!(room_volume(&r), 60.0);
+
+        r.spatial_properties = SpatialProperties::new(
+            r.spatial_properties.position.clone(),
+            Dimensions {
+                width: 6.0,
+                height: 2.5,
+                depth: 5.0,
+            },
+            "building_local".into(),
+        );
+        assert_eq!(room_area(&r), 30.0);
+        assert_eq!(room_perimeter(&r), 22.0);
+        assert_eq!(room_volume(&r), 75.0);
+    }
+
+    #[test]
+    fn clearance_envelope_uses_property_or_default() {
+        let mut eq = Equipment::new("AHU".into(), "/eq/ahu".into(), EquipmentType::HVAC);
+        eq.position = Position {
+            x: 10.0,
+            y: 5.0,
+            z: 0.0,
+            coordinate_system: "building_local".into(),
+        };
+        let env = equipment_clearance_envelope(&eq);
+        assert_eq!(env.min, Point3D::new(9.5, 4.5, 0.0));
+        assert_eq!(env.max, Point3D::new(10.5, 5.5, 0.5));
+
+        eq.properties.insert("clearance".into(), "1m".into());
+        let env = equipment_clearance_envelope(&eq);
+        assert_eq!(env.max, Point3D::new(11.0, 6.0, 1.0));
+        assert_eq!(env.volume(), 4.0);
+    }
+
+    #[test]
+    fn custom_expressions_evaluate() {
+        let mut config = DerivedConfig::default();
+        config
+            .room
+            .insert("wall_area".into(), "perimeter * height".into());
+        config
+            .room
+            .insert("occupancy".into(), "floor(area / 9.3)".into());
+        config.check().unwrap();
+
+        let values = config.room_values(&room(10.0, 10.0, 3.0)).unwrap();
+        assert_eq!(values["area"], 100.0);
+        assert_eq!(values["wall_area"], 120.0);
+        assert_eq!(values["occupancy"], 10.0);
+
+        let vars = BTreeMap::from([("a".to_string(), 2.0)]);
+        assert_eq!(
+            evaluate("-(a + 1) * 2 - max(a, 5) / 5", &vars).unwrap(),
+            -7.0
+        );
+        assert!(evaluate("a +", &vars).is_err());
+        assert!(evaluate("b", &vars).is_err());
+        assert!(evaluate("nope(a)", &vars).is_err());
+    }
+
+    #[test]
+    fn building_values_keep_builtins_when_an_expression_fails() {
+        use crate::core::{Floor, Wing};
+
+        let mut building = Building::new("HQ".into(), "/hq".into());
+        let mut floor = Floor::new("F0".into(), 0);
+        let mut wing = Wing::new("Main".into());
+        let office = room(4.0, 5.0, 3.0);
+        let closet = room(0.0, 0.0, 0.0);
+        let (office_id, closet_id) = (office.id.clone(), closet.id.clone());
+        wing.add_room(office);
+        wing.add_room(closet);
+        floor.add_wing(wing);
+        building.add_floor(floor);
+        let mut config = DerivedConfig::default();
+        config.room.insert("aspect".into(), "width / depth".into());
+
+        let values = config.building_values(&building);
+        assert_eq!(values.rooms[&office_id]["aspect"], 0.8);
+        assert_eq!(values.rooms[&closet_id]["area"], 0.0);
+        assert!(!values.rooms[&closet_id].contains_key("aspect"));
+        assert_eq!(values.errors.len(), 1);
+    }
+}
@@ -0,0 +1,302 @@
+//! Custom equipment types.
+//!
+//! The built-in [`EquipmentType`] variants cover the common systems; anything else
+//! is stored as `Other(name)`. A project can make such names first-class by
+//! registering them in `.arxos/equipment_types.yaml`:
+//!
+//! ```yaml
+//! types:
+//!   fume hood:
+//!     name: Fume Hood
+//!     system: hvac
+//!     symbol: FH
+//!     schema:
+//!       required: [face_velocity]
+//!       properties:
+//!         face_velocity: { type: number, min: 0.3, max: 0.7 }
+//! ```
+//!
+//! `system` is the built-in type the custom type belongs to, and `schema` joins the
+//! project's property schemas (an entry for the same type in
+//! `.arxos/property_schemas.yaml` takes precedence). Names are matched
+//! case-insensitively, and a type cannot be removed while equipment still uses it.
+
+use std::collections::BTreeMap;
+use std::path::Path;
+
+use serde::{Deserialize, Serialize};
+
+use super::{Building, EquipmentType};
+use crate::validation::schema::TypeSchema;
+
+/// Project file holding custom equipment types.
+pub const EQUIPMENT_TYPES_FILE: &str = ".arxos/equipment_types.yaml";
+
+/// Built-in type names, lowercase.
+pub const BUILTIN_EQUIPMENT_TYPES: &[&str] = &[
+    "hvac",
+    "electrical",
+    "av",
+    "furniture",
+    "safety",
+    "plumbing",
+    "network",
+];
+
+/// The built-in type called `name` (case-insensitive).
+pub fn builtin_equipment_type(name: &str) -> Option<EquipmentType> {
+    match name.trim().to_lowercase().as_str() {
+        "hvac" => Some(EquipmentType::HVAC),
+        "electrical" => Some(EquipmentType::Electrical),
+        "av" => Some(EquipmentType::AV),
+        "furniture" => Some(EquipmentType::Furniture),
+        "safety" => Some(EquipmentType::Safety),
+        "plumbing" => Some(EquipmentType::Plumbing),
+        "network" => Some(EquipmentType::Network),
+        _ => None,
+    }
+}
+
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct CustomEquipmentType {
+    /// Display name, stored as the equipment's `Other(name)`.
+    pub name: String,
+    /// Built-in type this one belongs to (`hvac`, `electrical`, ...).
+    pub system: String,
+    /// Short label for plans and the UI.
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub symbol: Option<String>,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub description: Option<String>,
+    #[serde(default)]
+    pub schema: TypeSchema,
+}
+
+#[derive(Debug, Clone, Default, Serialize, Deserialize)]
+pub struct EquipmentTypeRegistry {
+    /// Keyed by lowercase type name.
+    #[serde(default)]
+    pub types: BTreeMap<String, CustomEquipmentType>,
+}
+
+impl EquipmentTypeRegistry {
+    /// Load `.arxos/equipment_types.yaml` under `base`; a missing file yields no types.
+    pub fn load(base: &Path) -> Result<Self, String> {
+        let path = base.join(EQUIPMENT_TYPES_FILE);
+        if !path.exists() {
+            return Ok(Self::default());
+        }
+        let content = std::fs::read_to_string(&path)
+            .map_err(|e| format!("read {}: {}", path.display(), e))?;
+        let registry: EquipmentTypeRegistry = serde_yaml::from_str(&content)
+            .map_err(|e| format!("parse {}: {}", path.display(), e))?;
+        registry.check()?;
+        Ok(registry)
+    }
+
+    pub fn save(&self, base: &Path) -> Result<(), String> {
+        let path = base.join(EQUIPMENT_TYPES_FILE);
+        if let Some(parent) = path.parent() {
+            std::fs::create_dir_all(parent)
+                .map_err(|e| format!("create {}: {}", parent.display(), e))?;
+        }
+        let content = serde_yaml::to_string(self).map_err(|e| e.to_string())?;
+        std::fs::write(&path, content).map_err(|e| format!("write {}: {}", path.display(), e))
+    }
+
+    pub fn check(&self) -> Result<(), String> {
+        for (key, custom) in &self.types {
+            if key != &custom.name.trim().to_lowercase() {
+                return Err(format!(
+                    "type '{}' is registered under key '{}'",
+                    custom.name, key
+                ));
+            }
+            Self::check_type(custom)?;
+        }
+        Ok(())
+    }
+
+    fn check_type(custom: &CustomEquipmentType) -> Result<(), String> {
+        match Self::type_errors(custom).first() {
+            None => Ok(()),
+            Some((field, message)) => Err(format!("{} {}: {}", custom.name.trim(), field, message)),
+        }
+    }
+
+    /// Every problem with `custom` as `(field, message)`, empty when it can be registered.
+    pub fn type_errors(custom: &CustomEquipmentType) -> Vec<(&'static str, String)> {
+        let mut errors = Vec::new();
+        let name = custom.name.trim();
+        if name.is_empty() {
+            errors.push(("name", "must not be empty".to_string()));
+        } else if builtin_equipment_type(name).is_some() {
+            errors.push(("name", format!("'{}' is a built-in type", name)));
+        }
+        if builtin_equipment_type(&custom.system).is_none() {
+            errors.push((
+                "system",
+                format!(
+                    "'{}' is not one of {}",
+                    custom.system,
+                    BUILTIN_EQUIPMENT_TYPES.join(", ")
+                ),
+            ));
+        }
+        if let Err(e) = custom.schema.validate() {
+            errors.push(("schema", e));
+        }
+        errors
+    }
+
+    pub fn get(&self, name: &str) -> Option<&CustomEquipmentType> {
+        self.types.get(&name.trim().to_lowercase())
+    }
+
+    /// Type for `name`: built-in, registered (under its registered spelling), or
+    /// an unregistered `Other`.
+    pub fn resolve(&self, name: &str) -> EquipmentType {
+        if let Some(builtin) = builtin_equipment_type(name) {
+            return builtin;
+        }
+        match self.get(name) {
+            Some(custom) => EquipmentType::Other(custom.name.clone()),
+            None => EquipmentType::Other(name.trim().to_string()),
+        }
+    }
+
+    /// System of `equipment_type`: itself when built-in, the registered system for a
+    /// custom type, `None` for an unregistered name.
+    pub fn system_of(&self, equipment_type: &EquipmentType) -> Option<EquipmentType> {
+        match equipment_type {
+            EquipmentType::Other(name) => self
+                .get(name)
+                .and_then(|custom| builtin_equipment_type(&custom.system)),
+            builtin => Some(builtin.clone()),
+        }
+    }
+
+    /// Create or replace a custom type.
+    pub fn upsert(&mut self, custom: CustomEquipmentType) -> Result<(), String> {
+        Self::check_type(&custom)?;
+        let custom = CustomEquipmentType {
+            name: custom.name.trim().to_string(),
+            system: custom.system.trim().to_lowercase(),
+            ..custom
+        };
+        self.types.insert(custom.name.to_lowercase(), custom);
+        Ok(())
+    }
+
+    /// Ids of equipment in `building` whose type is `name`.
+    pub fn usage(building: &Building, name: &str) -> Vec<String> {
+        let name = name.trim().to_lowercase();
+        building
+            .get_all_equipment()
+            .into_iter()
+            .filter(|e| {
+                matches!(&e.equipment_type, EquipmentType::Other(t) if t.to_lowercase() == name)
+            })
+            .map(|e| e.id.clone())
+            .collect()
+    }
+
+    /// Remove a custom type that no equipment in `building` uses.
+    pub fn remove(
+        &mut self,
+        name: &str,
+        building: &Building,
+    ) -> Result<CustomEquipmentType, String> {
+        let key = name.trim().to_lowercase();
+        if !self.types.contains_key(&key) {
+            return Err(format!("Equipment type '{}' not found", name));
+        }
+        let used_by = Self::usage(building, &key);
+        if !used_by.is_empty() {
+            return Err(format!(
+                "Equipment type '{}' is used by {} equipment ({})",
+                name,
+                used_by.len(),
+                used_by.join(", ")
+            ));
+        }
+        Ok(self.types.remove(&key).expect("checked above"))
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::core::{Equipment, Floor};
+    use crate::validation::schema::{PropertySchemas, PropertySpec, ValueKind};
+
+    fn fume_hood() -> CustomEquipmentType {
+        let mut schema = TypeSchema {
+            required: vec!["face_velocity".into()],
+            ..Default::default()
+        };
+        schema.properties.insert(
+            "face_velocity".into(),
+            PropertySpec {
+                kind: Some(ValueKind::Number),
+                min: Some(0.3),
+                max: Some(0.7),
+                ..Default::default()
+            },
+        );
+        CustomEquipmentType {
+            name: "Fume Hood".into(),
+            system: "HVAC".into(),
+            symbol: Some("FH".into()),
+            description: None,
+            schema,
+        }
+    }
+
+    #[test]
+    fn custom_type_is_resolved_validated_and_protected_while_used() {
+        let dir = tempfile::tempdir().unwrap();
+        let mut registry = EquipmentTypeRegistry::default();
+        registry.upsert(fume_hood()).unwrap();
+        assert!(registry
+            .upsert(CustomEquipmentType {
+                name: "hvac".into(),
+                ..fume_hood()
+            })
+            .is_err());
+        assert!(registry
+            .upsert(CustomEquipmentType {
+                name: "Server Rack".into(),
+                system: "computing".into(),
+                ..fume_hood()
+            })
+            .is_err());
+        registry.save(dir.path()).unwrap();
+
+        let registry = EquipmentTypeRegistry::load(dir.path()).unwrap();
+        let hood_type = registry.resolve("fume hood");
+        assert_eq!(hood_type, EquipmentType::Other("Fume Hood".into()));
+        assert_eq!(registry.system_of(&hood_type), Some(EquipmentType::HVAC));
+        assert_eq!(registry.resolve("Plumbing"), EquipmentType::Plumbing);
+
+        let mut hood = Equipment::new("FH-1".into(), "/fh-1".into(), hood_type);
+        let schemas = PropertySchemas::load(dir.path()).unwrap();
+        assert_eq!(schemas.check_equipment(&hood).len(), 1);
+        hood.add_property("face_velocity".into(), "0.5".into());
+        assert!(schemas.check_equipment(&hood).is_empty());
+
+        let mut building = Building::new("Lab".into(), "/lab".into());
+        let mut floor = Floor::new("Ground".into(), 0);
+        floor.equipment.push(hood);
+        building.add_floor(floor);
+        let mut registry = registry;
+        let err = registry.remove("Fume Hood", &building).unwrap_err();
+        assert!(err.contains("used by 1 equipment"), "{}", err);
+        building.floors[0].equipment.clear();
+        assert_eq!(
+            registry.remove("FUME HOOD", &building).unwrap().name,
+            "Fume Hood"
+        );
+        assert!(registry.types.is_empty());
+    }
+}
@@ -0,0 +1,386 @@
+//! Equipment ID templates.
+//!
+//! Facilities name equipment by convention (`RTU-2-03`, `PANEL-HQ-B`). Projects can
+//! describe those conventions in `.arxos/id_templates.yaml`; equipment created
+//! without an explicit id then gets the next id its template produces:
+//!
+//! ```yaml
+//! default: "{type}-{seq:3}"
+//! types:
+//!   hvac: "RTU-{floor}-{seq:2}"
+//!   electrical: "PANEL-{building}-{letter}"
+//! ```
+//!
+//! Tokens: `{building}`, `{floor}` (level), `{room}`, `{type}`, plus exactly one
+//! sequence token — `{seq}` / `{seq:N}` (zero-padded to N digits) or `{letter}`
+//! (A…Z, AA…). The sequence is not stored anywhere: the next value is one past the
+//! highest already used by ids matching the template, so deleted ids are not reused
+//! and a hand-assigned id is never overwritten.
+
+use std::collections::{BTreeMap, HashSet};
+use std::path::Path;
+
+use regex::Regex;
+use serde::{Deserialize, Serialize};
+
+use super::Building;
+
+/// Project file holding equipment ID templates.
+pub const ID_TEMPLATES_FILE: &str = ".arxos/id_templates.yaml";
+
+/// Values substituted for the context tokens of a template.
+#[derive(Debug, Clone, Default, PartialEq, Eq)]
+pub struct IdContext {
+    pub building: String,
+    pub floor: String,
+    pub room: String,
+    pub equipment_type: String,
+}
+
+impl IdContext {
+    /// `{type}` is the equipment type name upper-cased (`HVAC`, `ELECTRICAL`).
+    pub fn new(building: &str, floor_level: i32, room: &str, equipment_type: &str) -> Self {
+        Self {
+            building: building.to_string(),
+            floor: floor_level.to_string(),
+            room: room.to_string(),
+            equipment_type: equipment_type.to_uppercase(),
+        }
+    }
+}
+
+#[derive(Debug, Clone, PartialEq, Eq)]
+enum Segment {
+    Literal(String),
+    Building,
+    Floor,
+    Room,
+    Type,
+    Seq(usize),
+    Letter,
+}
+
+/// A parsed ID template.
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub struct IdTemplate {
+    source: String,
+    segments: Vec<Segment>,
+}
+
+impl IdTemplate {
+    pub fn parse(template: &str) -> Result<Self, String> {
+        let mut segments = Vec::new();
+        let mut literal = String::new();
+        let mut chars = template.chars();
+        while let Some(c) = chars.next() {
+            if c != '{' {
+                literal.push(c);
+                continue;
+            }
+            let mut token = String::new();
+            loop {
+                match chars.next() {
+                    Some('}') => break,
+                    Some(c) => token.push(c),
+                    None => return Err(format!("template '{}': unclosed '{{'", template)),
+                }
+            }
+            if !literal.is_empty() {
+                segments.push(Segment::Literal(std::mem::take(&mut literal)));
+            }
+            let segment = match token.as_str() {
+                "building" => Segment::Building,
+                "floor" => Segment::Floor,
+                "room" => Segment::Room,
+                "type" => Segment::Type,
+                "seq" => Segment::Seq(0),
+                "letter" => Segment::Letter,
+                other => match other.strip_prefix("seq:") {
+                    Some(width) => Segment::Seq(width.parse().map_err(|_| {
+                        format!("template '{}': bad sequence width '{}'", template, width)
+                    })?),
+                    None => {
+                        return Err(format!(
+                            "template '{}': unknown token '{{{}}}'",
+                            template, other
+                        ))
+                    }
+                },
+            };
+            segments.push(segment);
+        }
+        if !literal.is_empty() {
+            segments.push(Segment::Literal(literal));
+        }
+
+        let sequences = segments
+            .iter()
+            .filter(|s| matches!(s, Segment::Seq(_) | Segment::Letter))
+            .count();
+        if sequences != 1 {
+            return Err(format!(
+                "template '{}': needs exactly one {{seq}} or {{letter}} token",
+                template
+            ));
+        }
+        Ok(Self {
+            source: template.to_string(),
+            segments,
+        })
+    }
+
+    pub fn as_str(&self) -> &str {
+        &self.source
+    }
+
+    /// The id for sequence number `seq` (1-based).
+    pub fn expand(&self, ctx: &IdContext, seq: u64) -> String {
+        self.segments
+            .iter()
+            .map(|segment| match segment {
+                Segment::Seq(width) => format!("{:0width$}", seq, width = *width),
+                Segment::Letter => letters(seq),
+                fixed => fixed_value(fixed, ctx),
+            })
+            .collect()
+    }
+
+    /// Matches ids this template produced for `ctx`, capturing the sequence.
+    fn matcher(&self, ctx: &IdContext) -> Regex {
+        let pattern: String = self
+            .segments
+            .iter()
+            .map(|segment| match segment {
+                Segment::Seq(_) => r"(\d+)".to_string(),
+                Segment::Letter => "([A-Z]+)".to_string(),
+                fixed => regex::escape(&fixed_value(fixed, ctx)),
+            })
+            .collect();
+        Regex::new(&format!("^{}$", pattern)).expect("escaped template is a valid regex")
+    }
+
+    /// Next free id: one past the highest sequence among `existing`, skipping any
+    /// expansion that is already taken.
+    pub fn next_id<'a, I>(&self, ctx: &IdContext, existing: I) -> String
+    where
+        I: IntoIterator<Item = &'a str>,
+    {
+        let matcher = self.matcher(ctx);
+        let taken: HashSet<&str> = existing.into_iter().collect();
+        let highest = taken
+            .iter()
+            .filter_map(|id| matcher.captures(id))
+            .filter_map(|caps| {
+                let value = caps.get(1)?.as_str();
+                if value.starts_with(|c: char| c.is_ascii_digit()) {
+                    value.parse::<u64>().ok()
+                } else {
+                    letters_value(value)
+                }
+            })
+            .max()
+            .unwrap_or(0);
+
+        let mut seq = highest + 1;
+        loop {
+            let id = self.expand(ctx, seq);
+            if !taken.contains(id.as_str()) {
+                return id;
+            }
+            seq += 1;
+        }
+    }
+}
+
+/// Templates per equipment type, with an optional fallback.
+#[derive(Debug, Clone, Default, Serialize, Deserialize)]
+pub struct IdTemplates {
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub default: Option<String>,
+    /// Keyed by lowercase equipment type (`hvac`, `electrical`, or a custom type).
+    #[serde(default)]
+    pub types: BTreeMap<String, String>,
+}
+
+impl IdTemplates {
+    /// Load `.arxos/id_templates.yaml` under `base`; a missing file yields no templates.
+    pub fn load(base: &Path) -> Result<Self, String> {
+        let path = base.join(ID_TEMPLATES_FILE);
+        if !path.exists() {
+            return Ok(Self::default());
+        }
+        let content = std::fs::read_to_string(&path)
+            .map_err(|e| format!("read {}: {}", path.display(), e))?;
+        let templates: IdTemplates = serde_yaml::from_str(&content)
+            .map_err(|e| format!("parse {}: {}", path.display(), e))?;
+        templates.check()?;
+        Ok(templates)
+    }
+
+    /// Parse every template once so typos surface at load time.
+    pub fn check(&self) -> Result<(), String> {
+        for template in self.default.iter().chain(self.types.values()) {
+            IdTemplate::parse(template)?;
+        }
+        Ok(())
+    }
+
+    /// Template for the named equipment type (case-insensitive), falling back to `default`.
+    pub fn template_for(&self, equipment_type: &str) -> Option<IdTemplate> {
+        self.types
+            .get(&equipment_type.to_lowercase())
+            .or(self.default.as_ref())
+            .and_then(|t| IdTemplate::parse(t).ok())
+    }
+
+    /// Next id for new equipment of `equipment_type` in `room` (name or id).
+    ///
+    /// `Ok(None)` when no template applies; an error when the room does not exist.
+    pub fn next_for_room(
+        &self,
+        building: &Building,
+        room: &str,
+        equipment_type: &str,
+    ) -> Result<Option<String>, String> {
+        let Some(template) = self.template_for(equipment_type) else {
+            return Ok(None);
+        };
+        let (floor, room) = building
+            .floors
+            .iter()
+            .flat_map(|f| {
+                f.wings
+                    .iter()
+                    .flat_map(move |w| w.rooms.iter().map(move |r| (f, r)))
+            })
+            .find(|(_, r)| r.name == room || r.id == room)
+            .ok_or_else(|| format!("Room '{}' not found", room))?;
+        let ctx = IdContext::new(&building.name, floor.level, &room.name, equipment_type);
+        let equipment = building.get_all_equipment();
+        Ok(Some(
+            template.next_id(&ctx, equipment.iter().map(|e| e.id.as_str())),
+        ))
+    }
+}
+
+fn fixed_value(segment: &Segment, ctx: &IdContext) -> String {
+    match segment {
+        Segment::Literal(text) => text.clone(),
+        Segment::Building => token_value(&ctx.building),
+        Segment::Floor => token_value(&ctx.floor),
+        Segment::Room => token_value(&ctx.room),
+        Segment::Type => token_value(&ctx.equipment_type),
+        Segment::Seq(_) | Segment::Letter => String::new(),
+    }
+}
+
+/// Context values are used verbatim except that whitespace becomes `-`.
+fn token_value(value: &str) -> String {
+    value.split_whitespace().collect::<Vec<_>>().join("-")
+}
+
+/// 1 → A, 26 → Z, 27 → AA.
+fn letters(mut n: u64) -> String {
+    let mut out = Vec::new();
+    while n > 0 {
+        n -= 1;
+        out.push(b'A' + (n % 26) as u8);
+        n /= 26;
+    }
+    out.reverse();
+    String::from_utf8(out).unwrap_or_default()
+}
+
+fn letters_value(s: &str) -> Option<u64> {
+    s.bytes().try_fold(0u64, |acc, b| {
+        acc.checked_mul(26)?.checked_add((b - b'A' + 1) as u64)
+    })
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::core::{Equipment, EquipmentType, Floor, Room, RoomType, Wing};
+
+    fn ctx() -> IdContext {
+        IdContext::new("HQ", 2, "Mech Room", "HVAC")
+    }
+
+    #[test]
+    fn expands_tokens_and_padding() {
+        let template = IdTemplate::parse("RTU-{floor}-{seq:3}").unwrap();
+        assert_eq!(template.expand(&ctx(), 7), "RTU-2-007");
+
+        let template = IdTemplate::parse("{type}/{building}/{room}/{letter}").unwrap();
+        assert_eq!(template.expand(&ctx(), 1), "HVAC/HQ/Mech-Room/A");
+        assert_eq!(template.expand(&ctx(), 28), "HVAC/HQ/Mech-Room/AB");
+
+        assert!(IdTemplate::parse("RTU-{floor}").is_err());
+        assert!(IdTemplate::parse("RTU-{seq}-{letter}").is_err());
+        assert!(IdTemplate::parse("RTU-{wing}-{seq}").is_err());
+        assert!(IdTemplate::parse("RTU-{seq").is_err());
+    }
+
+    #[test]
+    fn sequence_follows_highest_existing_id() {
+        let template = IdTemplate::parse("RTU-{floor}-{seq:2}").unwrap();
+        assert_eq!(template.next_id(&ctx(), []), "RTU-2-01");
+        assert_eq!(
+            template.next_id(&ctx(), ["RTU-2-01", "RTU-2-04", "RTU-3-09", "other"]),
+            "RTU-2-05"
+        );
+
+        let panels = IdTemplate::parse("PANEL-{building}-{letter}").unwrap();
+        assert_eq!(
+            panels.next_id(&ctx(), ["PANEL-HQ-A", "PANEL-HQ-B"]),
+            "PANEL-HQ-C"
+        );
+    }
+
+    #[test]
+    fn never_collides_with_existing_ids() {
+        // Hand-typed ids without padding, or past the width, still count.
+        let template = IdTemplate::parse("RTU-{floor}-{seq:2}").unwrap();
+        assert_eq!(template.next_id(&ctx(), ["RTU-2-7"]), "RTU-2-08");
+        assert_eq!(
+            template.next_id(&ctx(), ["RTU-2-99", "RTU-2-100"]),
+            "RTU-2-101"
+        );
+
+        let templates = IdTemplates {
+            default: Some("{type}-{seq:3}".into()),
+            types: [("electrical".to_string(), "PANEL-{letter}".to_string())].into(),
+        };
+        assert_eq!(
+            templates.template_for("Electrical").unwrap().as_str(),
+            "PANEL-{letter}"
+        );
+
+        let mut building = Building::new("HQ".into(), "/hq".into());
+        let mut floor = Floor::new("Ground".into(), 0);
+        let mut wing = Wing::new("Main".into());
+        let mut room = Room::new("Plant".into(), RoomType::Mechanical);
+        let mut pump = Equipment::new("Pump".into(), "/pump".into(), EquipmentType::Plumbing);
+        pump.id = "PLUMBING-001".into();
+        room.equipment.push(pump);
+        wing.rooms.push(room);
+        floor.wings.push(wing);
+        building.add_floor(floor);
+
+        assert_eq!(
+            templates
+                .next_for_room(&building, "Plant", "plumbing")
+                .unwrap(),
+            Some("PLUMBING-002".to_string())
+        );
+        assert!(templates
+            .next_for_room(&building, "Nowhere", "plumbing")
+            .is_err());
+        assert_eq!(
+            IdTemplates::default()
+                .next_for_room(&building, "Plant", "plumbing")
+                .unwrap(),
+            None
+        );
+    }
+}
@@ -6,15 +6,20 @@
 // Core modules
 mod anchor;
 mod building;
+pub mod derived;
 pub mod domain;
 mod equipment;
+pub mod equipment_types;
 mod floor;
+pub mod id_template;
 pub mod identity;
 pub mod operations;
+pub mod precision;
 pub mod review;
 mod room;
 mod serde_helpers;
 pub mod spatial;
+pub mod suggestions;
 mod types;
 mod wing;
 
@@ -0,0 +1,283 @@
+//! Building clone and template instantiation
+//!
+//! A clone deep-copies the hierarchy under fresh ids and rewrites every reference
+//! that points inside the building (equipment `room_id`, pending ids, anchor poses)
+//! to the new ids. Addresses and IFC GlobalIds identify the source site, so they are
+//! dropped. A clean clone is suitable as a template: it also drops field-review
+//! status, LiDAR evidence, sensor bindings, and CMMS links.
+
+use std::collections::HashMap;
+
+use chrono::Utc;
+use uuid::Uuid;
+
+use crate::core::review::PROP_REVIEW_STATUS;
+use crate::core::{Anchor, Building, Equipment, Room};
+
+/// Site-specific properties removed by a clean clone.
+const SITE_PROPERTIES: &[&str] = &[PROP_REVIEW_STATUS, "cmms_id"];
+
+#[derive(Debug, Clone)]
+pub struct CloneOptions {
+    pub name: String,
+    /// Strip field-validation state and site bindings.
+    pub clean: bool,
+}
+
+/// Result of [`clone_building`]: the new building and the old → new id map.
+#[derive(Debug, Clone)]
+pub struct ClonedBuilding {
+    pub building: Building,
+    pub id_map: HashMap<String, String>,
+}
+
+struct Cloner {
+    clean: bool,
+    id_map: HashMap<String, String>,
+}
+
+impl Cloner {
+    fn fresh(&mut self, id: &mut String) {
+        let new = Uuid::new_v4().to_string();
+        self.id_map.insert(std::mem::replace(id, new.clone()), new);
+    }
+
+    fn strip_properties(&self, properties: &mut HashMap<String, String>) {
+        if self.clean {
+            properties.retain(|k, _| !SITE_PROPERTIES.contains(&k.as_str()));
+        }
+    }
+
+    fn anchor(&mut self, anchor: &mut Anchor) {
+        self.fresh(&mut anchor.id);
+        anchor.address = None;
+        self.strip_properties(&mut anchor.properties);
+        if self.clean {
+            anchor.recalibration_count = 0;
+            anchor.last_recalibrated_at = None;
+        }
+    }
+
+    fn equipment(&mut self, equipment: &mut Equipment) {
+        self.fresh(&mut equipment.id);
+        equipment.address = None;
+        equipment.ifc_global_id = None;
+        self.strip_properties(&mut equipment.properties);
+        if self.clean {
+            equipment.lidar_enrichment = None;
+            equipment.sensor_mappings = None;
+            equipment.health_status = None;
+        }
+    }
+
+    fn room(&mut self, room: &mut Room) {
+        self.fresh(&mut room.id);
+        room.ifc_global_id = None;
+        room.address = None;
+        self.strip_properties(&mut room.properties);
+        if self.clean {
+            room.lidar_enrichment = None;
+        }
+        room.anchors.iter_mut().for_each(|a| self.anchor(a));
+        room.equipment.iter_mut().for_each(|e| self.equipment(e));
+    }
+
+    /// Point references at the new ids. References to ids outside the building are kept.
+    fn remap(&self, id: &mut String) {
+        if let Some(new) = self.id_map.get(id.as_str()) {
+            *id = new.clone();
+        }
+    }
+
+    fn remap_anchor(&self, anchor: &mut Anchor) {
+        for pose in &mut anchor.relative_poses {
+            self.remap(&mut pose.target_id);
+        }
+    }
+}
+
+/// Deep-copy `source` under fresh ids with references remapped.
+pub fn clone_building(source: &Building, options: &CloneOptions) -> ClonedBuilding {
+    let mut building = source.clone();
+    let mut cloner = Cloner {
+        clean: options.clean,
+        id_map: HashMap::new(),
+    };
+
+    cloner.fresh(&mut building.id);
+    building.name = options.name.clone();
+    building.path = format!("/{}", options.name.replace(' ', "-").to_lowercase());
+    building.address = None;
+    building.ifc_global_id = None;
+    let now = Utc::now();
+    building.created_at = now;
+    building.updated_at = now;
+    if let Some(metadata) = building.metadata.as_mut() {
+        cloner.strip_properties(&mut metadata.properties);
+    }
+    building.anchors.iter_mut().for_each(|a| cloner.anchor(a));
+
+    for floor in &mut building.floors {
+        cloner.fresh(&mut floor.id);
+        floor.ifc_global_id = None;
+        floor.address = None;
+        cloner.strip_properties(&mut floor.properties);
+        floor.anchors.iter_mut().for_each(|a| cloner.anchor(a));
+        floor.equipment.iter_mut().for_each(|e| cloner.equipment(e));
+        for wing in &mut floor.wings {
+            cloner.fresh(&mut wing.id);
+            wing.address = None;
+            cloner.strip_properties(&mut wing.properties);
+            wing.anchors.iter_mut().for_each(|a| cloner.anchor(a));
+            wing.equipment.iter_mut().for_each(|e| cloner.equipment(e));
+            wing.rooms.iter_mut().for_each(|r| cloner.room(r));
+        }
+    }
+
+    // Second pass: every id is now known, so references can be rewritten.
+    building
+        .pending_anchor_ids
+        .iter_mut()
+        .for_each(|id| cloner.remap(id));
+    building
+        .anchors
+        .iter_mut()
+        .for_each(|a| cloner.remap_anchor(a));
+    for floor in &mut building.floors {
+        floor
+            .pending_equipment_ids
+            .iter_mut()
+            .chain(floor.pending_anchor_ids.iter_mut())
+            .for_each(|id| cloner.remap(id));
+        floor
+            .anchors
+            .iter_mut()
+            .for_each(|a| cloner.remap_anchor(a));
+        for wing in &mut floor.wings {
+            wing.pending_equipment_ids
+                .iter_mut()
+                .chain(wing.pending_anchor_ids.iter_mut())
+                .for_each(|id| cloner.remap(id));
+            wing.anchors.iter_mut().for_each(|a| cloner.remap_anchor(a));
+            for room in &mut wing.rooms {
+                room.pending_equipment_ids
+                    .iter_mut()
+                    .chain(room.pending_anchor_ids.iter_mut())
+                    .for_each(|id| cloner.remap(id));
+                room.anchors.iter_mut().for_each(|a| cloner.remap_anchor(a));
+            }
+        }
+    }
+    for equipment in building.get_all_equipment_mut() {
+        if let Some(room_id) = equipment.room_id.as_mut() {
+            cloner.remap(room_id);
+        }
+    }
+
+    ClonedBuilding {
+        building,
+        id_map: cloner.id_map,
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::core::anchor::{PoseType, RelativePose};
+    use crate::core::{EquipmentType, Floor, RoomType, Wing};
+
+    fn source() -> Building {
+        let mut building = Building::new("Store 1".into(), "/store-1".into());
+        let mut floor = Floor::new("Sales".into(), 0);
+        let mut wing = Wing::new("Front".into());
+        let mut room = Room::new("Register".into(), RoomType::Office);
+        let mut pos = Equipment::new("POS-1".into(), "/pos-1".into(), EquipmentType::Network);
+        pos.room_id = Some(room.id.clone());
+        pos.properties
+            .insert(PROP_REVIEW_STATUS.into(), "accepted".into());
+        pos.properties.insert("model".into(), "T-100".into());
+        pos.ifc_global_id = Some("2O2Fr$t4X7Zf8NOew3FLOH".into());
+        room.pending_equipment_ids.push(pos.id.clone());
+        let mut anchor = Anchor::new("Door".into(), pos.position.clone(), 0.9);
+        anchor.relative_poses.push(RelativePose {
+            target_id: room.id.clone(),
+            pose_type: PoseType::AnchorToRoom,
+            x: 1.0,
+            y: 0.0,
+            z: 0.0,
+            roll: 0.0,
+            pitch: 0.0,
+            yaw: 0.0,
+        });
+        room.anchors.push(anchor);
+        room.equipment.push(pos);
+        wing.rooms.push(room);
+        floor.wings.push(wing);
+        building.add_floor(floor);
+        building
+    }
+
+    #[test]
+    fn clone_has_same_structure_with_new_ids() {
+        let source = source();
+        let cloned = clone_building(
+            &source,
+            &CloneOptions {
+                name: "Store 2".into(),
+                clean: false,
+            },
+        );
+        let building = &cloned.building;
+
+        assert_eq!(building.name, "Store 2");
+        assert_eq!(building.path, "/store-2");
+        assert_ne!(building.id, source.id);
+        assert_eq!(building.floors.len(), source.floors.len());
+        assert_eq!(building.get_all_rooms().len(), source.get_all_rooms().len());
+        assert_eq!(
+            building.get_all_equipment().len(),
+            source.get_all_equipment().len()
+        );
+
+        let old_room = &source.floors[0].wings[0].rooms[0];
+        let new_room = &building.floors[0].wings[0].rooms[0];
+        let old_eq = &old_room.equipment[0];
+        let new_eq = &new_room.equipment[0];
+        assert_eq!(new_room.name, old_room.name);
+        assert_ne!(new_room.id, old_room.id);
+        assert_ne!(new_eq.id, old_eq.id);
+        assert_eq!(cloned.id_map[&old_eq.id], new_eq.id);
+
+        // References follow the new ids.
+        assert_eq!(new_eq.room_id.as_deref(), Some(new_room.id.as_str()));
+        assert_eq!(new_room.pending_equipment_ids, vec![new_eq.id.clone()]);
+        assert_ne!(new_room.anchors[0].id, old_room.anchors[0].id);
+        assert_eq!(new_room.anchors[0].relative_poses[0].target_id, new_room.id);
+        assert!(crate::core::operations::check_hierarchy(building).is_clean());
+
+        // Site identity is dropped; other properties are kept.
+        assert_eq!(new_eq.ifc_global_id, None);
+        assert_eq!(
+            new_eq.properties.get("model").map(String::as_str),
+            Some("T-100")
+        );
+        assert!(new_eq.properties.contains_key(PROP_REVIEW_STATUS));
+    }
+
+    #[test]
+    fn clean_clone_strips_field_validation() {
+        let cloned = clone_building(
+            &source(),
+            &CloneOptions {
+                name: "Template".into(),
+                clean: true,
+            },
+        );
+        let eq = &cloned.building.get_all_equipment()[0].clone();
+        assert!(!eq.properties.contains_key(PROP_REVIEW_STATUS));
+        assert_eq!(
+            eq.properties.get("model").map(String::as_str),
+            Some("T-100")
+        );
+    }
+}
@@ -0,0 +1,428 @@
+//! Bulk equipment deletion by query
+//!
+//! Cleaning up a bad import means deleting many objects at once. A query selects
+//! equipment by type, system, source and confidence; there is no tag filter, since
+//! equipment carries no tags in this model. A dry run issues a random,
+//! single-use token ([`DeleteConfirmations::issue`]) bound to the matched ids, and a
+//! delete only runs when the caller echoes it before it expires. If the building
+//! changed in between, the query no longer matches those ids and the delete is refused.
+//!
+//! Deletion is soft: objects are marked with [`PROP_DELETED_AT`] and stay in the
+//! stored model, but no query matches them again, and read, export, validation and
+//! history paths drop them ([`strip_deleted`], [`without_deleted`]), so history shows
+//! the delete as a tombstone. Removing the mark (or `git revert` of the delete
+//! commit, which is committed like any other edit) restores them.
+
+use std::borrow::Cow;
+use std::collections::HashSet;
+use std::path::Path;
+use std::sync::Mutex;
+
+use chrono::{DateTime, Duration, Utc};
+use serde::{Deserialize, Serialize};
+use sha2::{Digest, Sha256};
+
+use crate::core::equipment_types::{builtin_equipment_type, EquipmentTypeRegistry};
+use crate::core::review::{equipment_review_status, ReviewStatus};
+use crate::core::{Building, Equipment};
+
+/// Equipment property marking a soft-deleted object (RFC 3339).
+pub const PROP_DELETED_AT: &str = "deleted_at";
+
+/// Project file holding confirmation tokens issued by dry runs.
+pub const DELETE_CONFIRMATIONS_FILE: &str = ".arxos/delete_confirmations.yaml";
+
+/// How long a dry run's confirmation token stays valid.
+pub const CONFIRMATION_TTL_SECS: i64 = 10 * 60;
+
+/// Serializes read-modify-write of the confirmations file within this process.
+static CONFIRMATIONS_LOCK: Mutex<()> = Mutex::new(());
+
+/// Where an object came from, as far as the model records it.
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Serialize, Deserialize)]
+#[serde(rename_all = "lowercase")]
+pub enum ObjectSource {
+    /// Has an IFC GlobalId.
+    Ifc,
+    /// LiDAR-detected (no IFC id).
+    Lidar,
+    /// Neither: entered by hand.
+    Manual,
+}
+
+impl ObjectSource {
+    pub fn of(equipment: &Equipment) -> Self {
+        if equipment.ifc_global_id.is_some() {
+            ObjectSource::Ifc
+        } else if equipment.lidar_enrichment.is_some() {
+            ObjectSource::Lidar
+        } else {
+            ObjectSource::Manual
+        }
+    }
+}
+
+/// Equipment filter; every set field must match.
+#[derive(Debug, Clone, Default, Serialize, Deserialize)]
+pub struct EquipmentQuery {
+    /// Type name (`hvac`, or a custom type), case-insensitive.
+    #[serde(default, rename = "type", skip_serializing_if = "Option::is_none")]
+    pub equipment_type: Option<String>,
+    /// Built-in system; custom types match through their registered system.
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub system: Option<String>,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub source: Option<ObjectSource>,
+    /// Matches equipment whose LiDAR confidence is below this value; equipment
+    /// without a confidence never matches.
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub confidence_below: Option<f64>,
+    /// `proposed`, `accepted` or `rejected`.
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub review_status: Option<String>,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub floor_level: Option<i32>,
+}
+
+impl EquipmentQuery {
+    /// Reject empty and malformed queries; an empty one would match everything.
+    pub fn check(&self) -> Result<(), String> {
+        if self.equipment_type.is_none()
+            && self.system.is_none()
+            && self.source.is_none()
+            && self.confidence_below.is_none()
+            && self.review_status.is_none()
+            && self.floor_level.is_none()
+        {
+            return Err("Query must set at least one filter".into());
+        }
+        if let Some(system) = &self.system {
+            if builtin_equipment_type(system).is_none() {
+                return Err(format!("Unknown system '{}'", system));
+            }
+        }
+        if let Some(threshold) = self.confidence_below {
+            if !threshold.is_finite() || !(0.0..=1.0).contains(&threshold) {
+                return Err("confidence_below must be between 0 and 1".into());
+            }
+        }
+        if let Some(status) = &self.review_status {
+            if ReviewStatus::parse(status).is_none() {
+                return Err(format!("Unknown review status '{}'", status));
+            }
+        }
+        Ok(())
+    }
+
+    /// Whether `eq` matches; soft-deleted equipment never does.
+    pub(super) fn matches(&self, registry: &EquipmentTypeRegistry, eq: &Equipment) -> bool {
+        if is_deleted(eq) {
+            return false;
+        }
+        if let Some(name) = &self.equipment_type {
+            if eq.equipment_type != registry.resolve(name) {
+                return false;
+            }
+        }
+        if let Some(system) = &self.system {
+            if registry.system_of(&eq.equipment_type) != builtin_equipment_type(system) {
+                return false;
+            }
+        }
+        if let Some(source) = self.source {
+            if ObjectSource::of(eq) != source {
+                return false;
+            }
+        }
+        if let Some(threshold) = self.confidence_below {
+            match &eq.lidar_enrichment {
+                Some(l) if l.confidence_score < threshold => {}
+                _ => return false,
+            }
+        }
+        if let Some(status) = &self.review_status {
+            if equipment_review_status(eq) != ReviewStatus::parse(status) {
+                return false;
+            }
+        }
+        true
+    }
+}
+
+/// Ids of equipment matching `query`, sorted.
+pub fn find_matching(
+    building: &Building,
+    registry: &EquipmentTypeRegistry,
+    query: &EquipmentQuery,
+) -> Vec<String> {
+    let mut ids: Vec<String> = building
+        .floors
+        .iter()
+        .filter(|f| query.floor_level.map_or(true, |level| f.level == level))
+        .flat_map(|f| {
+            f.equipment.iter().chain(f.wings.iter().flat_map(|w| {
+                w.equipment
+                    .iter()
+                    .chain(w.rooms.iter().flat_map(|r| r.equipment.iter()))
+            }))
+        })
+        .filter(|eq| query.matches(registry, eq))
+        .map(|eq| eq.id.clone())
+        .collect();
+    ids.sort();
+    ids
+}
+
+pub fn is_deleted(eq: &Equipment) -> bool {
+    eq.properties.contains_key(PROP_DELETED_AT)
+}
+
+/// Drop soft-deleted equipment, and pending references to it, from a building about
+/// to be served or exported. Returns how many objects were dropped. Never persist
+/// the result: the stored model keeps deleted objects so a delete can be undone.
+pub fn strip_deleted(building: &mut Building) -> usize {
+    let deleted: HashSet<String> = building
+        .get_all_equipment()
+        .into_iter()
+        .filter(|eq| is_deleted(eq))
+        .map(|eq| eq.id.clone())
+        .collect();
+    if deleted.is_empty() {
+        return 0;
+    }
+    let live = |eq: &Equipment| !deleted.contains(&eq.id);
+    let referenced = |id: &String| !deleted.contains(id);
+    for floor in &mut building.floors {
+        floor.equipment.retain(live);
+        floor.pending_equipment_ids.retain(referenced);
+        for wing in &mut floor.wings {
+            wing.equipment.retain(live);
+            wing.pending_equipment_ids.retain(referenced);
+            for room in &mut wing.rooms {
+                room.equipment.retain(live);
+                room.pending_equipment_ids.retain(referenced);
+            }
+        }
+    }
+    deleted.len()
+}
+
+/// `building` without soft-deleted equipment; borrowed when nothing is deleted.
+pub fn without_deleted(building: &Building) -> Cow<'_, Building> {
+    if !building.get_all_equipment().into_iter().any(is_deleted) {
+        return Cow::Borrowed(building);
+    }
+    let mut live = building.clone();
+    strip_deleted(&mut live);
+    Cow::Owned(live)
+}
+
+/// Mark equipment `ids` deleted at `at`. Returns the ids that were marked; already
+/// deleted and unknown ids are skipped.
+pub fn mark_deleted(building: &mut Building, ids: &[String], at: DateTime<Utc>) -> Vec<String> {
+    let mut marked = Vec::new();
+    for eq in building.get_all_equipment_mut() {
+        if ids.contains(&eq.id) && !is_deleted(eq) {
+            eq.properties
+                .insert(PROP_DELETED_AT.to_string(), at.to_rfc3339());
+            marked.push(eq.id.clone());
+        }
+    }
+    if !marked.is_empty() {
+        building.updated_at = at;
+    }
+    marked.sort();
+    marked
+}
+
+#[derive(Debug, Clone, Serialize, Deserialize)]
+struct PendingDelete {
+    /// SHA-256 of the token; the token itself is only returned to the caller.
+    token_hash: String,
+    building_id: String,
+    ids: Vec<String>,
+    expires_at: DateTime<Utc>,
+}
+
+/// Confirmation tokens issued by dry runs and not yet used or expired.
+#[derive(Debug, Clone, Default, Serialize, Deserialize)]
+pub struct DeleteConfirmations {
+    #[serde(default)]
+    pending: Vec<PendingDelete>,
+}
+
+fn token_hash(token: &str) -> String {
+    Sha256::digest(token.as_bytes())
+        .iter()
+        .map(|b| format!("{:02x}", b))
+        .collect()
+}
+
+impl DeleteConfirmations {
+    /// Load `.arxos/delete_confirmations.yaml` under `base`; a missing file holds none.
+    pub fn load(base: &Path) -> Result<Self, String> {
+        let path = base.join(DELETE_CONFIRMATIONS_FILE);
+        if !path.exists() {
+            return Ok(Self::default());
+        }
+        let content = std::fs::read_to_string(&path)
+            .map_err(|e| format!("read {}: {}", path.display(), e))?;
+        serde_yaml::from_str(&content).map_err(|e| format!("parse {}: {}", path.display(), e))
+    }
+
+    pub fn save(&self, base: &Path) -> Result<(), String> {
+        let path = base.join(DELETE_CONFIRMATIONS_FILE);
+        if let Some(parent) = path.parent() {
+            std::fs::create_dir_all(parent)
+                .map_err(|e| format!("create {}: {}", parent.display(), e))?;
+        }
+        let content = serde_yaml::to_string(self).map_err(|e| e.to_string())?;
+        std::fs::write(&path, content).map_err(|e| format!("write {}: {}", path.display(), e))
+    }
+
+    /// Load, apply `f` and save under a process-wide lock.
+    pub fn update<T>(
+        base: &Path,
+        f: impl FnOnce(&mut Self) -> Result<T, String>,
+    ) -> Result<T, String> {
+        let _guard = CONFIRMATIONS_LOCK.lock().unwrap_or_else(|e| e.into_inner());
+        let mut confirmations = Self::load(base)?;
+        let value = f(&mut confirmations)?;
+        confirmations.save(base)?;
+        Ok(value)
+    }
+
+    /// Issue a random token confirming the deletion of exactly `ids` from the building.
+    pub fn issue(&mut self, building_id: &str, ids: &[String], now: DateTime<Utc>) -> String {
+        self.pending.retain(|p| p.expires_at > now);
+        let token = uuid::Uuid::new_v4().simple().to_string();
+        self.pending.push(PendingDelete {
+            token_hash: token_hash(&token),
+            building_id: building_id.to_string(),
+            ids: ids.to_vec(),
+            expires_at: now + Duration::seconds(CONFIRMATION_TTL_SECS),
+        });
+        token
+    }
+
+    /// Use up `token`, returning the ids it was issued for. Unknown, expired and
+    /// already used tokens, and tokens of another building, are refused.
+    pub fn redeem(
+        &mut self,
+        building_id: &str,
+        token: &str,
+        now: DateTime<Utc>,
+    ) -> Result<Vec<String>, String> {
+        self.pending.retain(|p| p.expires_at > now);
+        let hash = token_hash(token);
+        let index = self
+            .pending
+            .iter()
+            .position(|p| p.token_hash == hash && p.building_id == building_id)
+            .ok_or("Confirmation token is unknown, used or expired; run a dry run again")?;
+        Ok(self.pending.remove(index).ids)
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::core::types::LidarEnrichment;
+    use crate::core::{EquipmentType, Floor};
+
+    fn building() -> Building {
+        let mut building = Building::new("HQ".into(), "/hq".into());
+        let mut floor = Floor::new("Ground".into(), 0);
+        for (name, confidence) in [("scan-1", 0.2), ("scan-2", 0.4), ("scan-3", 0.9)] {
+            let mut eq = Equipment::new(name.into(), format!("/{}", name), EquipmentType::HVAC);
+            eq.lidar_enrichment = Some(LidarEnrichment {
+                point_count: 10,
+                confidence_score: confidence,
+                last_scan_timestamp: None,
+                classification_heuristic: None,
+            });
+            floor.equipment.push(eq);
+        }
+        let mut ifc = Equipment::new("AHU-1".into(), "/ahu-1".into(), EquipmentType::HVAC);
+        ifc.ifc_global_id = Some("2O2Fr$t4X7Zf8NOew3FLOH".into());
+        floor.equipment.push(ifc);
+        floor
+            .pending_equipment_ids
+            .push(floor.equipment[0].id.clone());
+        building.add_floor(floor);
+        building
+    }
+
+    #[test]
+    fn delete_requires_a_live_confirmation() {
+        let dir = tempfile::tempdir().unwrap();
+        let mut building = building();
+        let registry = EquipmentTypeRegistry::default();
+        assert!(EquipmentQuery::default().check().is_err());
+
+        let query = EquipmentQuery {
+            source: Some(ObjectSource::Lidar),
+            confidence_below: Some(0.5),
+            ..Default::default()
+        };
+        query.check().unwrap();
+
+        // Dry run: two low-confidence scans; the IFC object and the good scan stay.
+        let ids = find_matching(&building, &registry, &query);
+        assert_eq!(ids.len(), 2);
+        let now = Utc::now();
+        let token =
+            DeleteConfirmations::update(dir.path(), |c| Ok(c.issue(&building.id, &ids, now)))
+                .unwrap();
+        let other =
+            DeleteConfirmations::update(dir.path(), |c| Ok(c.issue(&building.id, &ids, now)))
+                .unwrap();
+        assert_ne!(token, other);
+
+        // Tokens are single-use, per building, and expire.
+        let mut confirmations = DeleteConfirmations::load(dir.path()).unwrap();
+        assert!(confirmations.redeem("other", &token, now).is_err());
+        let expired = now + Duration::seconds(CONFIRMATION_TTL_SECS);
+        assert!(confirmations
+            .clone()
+            .redeem(&building.id, &token, expired)
+            .is_err());
+        assert_eq!(
+            confirmations.redeem(&building.id, &token, now).unwrap(),
+            ids
+        );
+        assert!(confirmations.redeem(&building.id, &token, now).is_err());
+
+        let before = building.clone();
+        let marked = mark_deleted(&mut building, &ids, now);
+        assert_eq!(marked, ids);
+        assert!(mark_deleted(&mut building, &ids, now).is_empty());
+        // Soft-deleted objects stay in the model but no longer match any query.
+        assert_eq!(
+            building.floors[0].equipment.len(),
+            before.floors[0].equipment.len()
+        );
+        assert_eq!(building.floors[0].pending_equipment_ids.len(), 1);
+        assert!(find_matching(&building, &registry, &query).is_empty());
+        let all_lidar = EquipmentQuery {
+            source: Some(ObjectSource::Lidar),
+            ..Default::default()
+        };
+        let names: Vec<&str> = find_matching(&building, &registry, &all_lidar)
+            .iter()
+            .map(|id| building.find_equipment(id).unwrap().name.as_str())
+            .collect();
+        assert_eq!(names, vec!["scan-3"]);
+        for id in &ids {
+            let eq = building.find_equipment(id).unwrap();
+            assert_eq!(eq.properties[PROP_DELETED_AT], now.to_rfc3339());
+        }
+
+        // Served and exported copies leave them out, pending references included.
+        let live = without_deleted(&building).into_owned();
+        assert_eq!(live.floors[0].equipment.len(), 2);
+        assert!(live.floors[0].pending_equipment_ids.is_empty());
+        assert!(ids.iter().all(|id| live.find_equipment(id).is_none()));
+        assert!(matches!(without_deleted(&before), Cow::Borrowed(_)));
+    }
+}
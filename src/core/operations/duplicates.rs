@@ -0,0 +1,364 @@
+//! Duplicate equipment detection and merge
+//!
+//! Repeated imports and overlapping scans produce the same fixture twice. Equipment
+//! of the same type on the same floor closer than a tolerance is clustered
+//! (transitively) as one object; [`merge_equipment`] folds a cluster into a single
+//! survivor and points every reference at it. Merges are committed like any other
+//! edit, so `git revert` of the merge commit undoes one.
+
+use std::collections::HashMap;
+
+use serde::Serialize;
+
+use super::delete_query::is_deleted;
+use crate::core::review::{
+    equipment_needs_review, PROP_PHOTO_REF, PROP_REVIEW_STATUS, PROP_VALIDATED_AT,
+    PROP_VALIDATED_BY,
+};
+use crate::core::{Building, Equipment};
+
+/// Default clustering tolerance (meters).
+pub const DEFAULT_DUPLICATE_TOLERANCE_M: f64 = 0.5;
+/// Survivor property listing the ids merged into it (comma-separated).
+pub const PROP_MERGED_FROM: &str = "merged_from";
+/// Review bookkeeping describes one record, so it is never copied onto the survivor.
+const NOT_MERGED: &[&str] = &[
+    PROP_REVIEW_STATUS,
+    PROP_VALIDATED_BY,
+    PROP_VALIDATED_AT,
+    PROP_PHOTO_REF,
+    PROP_MERGED_FROM,
+];
+
+/// Equipment ids that look like one object, best-ranked first.
+#[derive(Debug, Clone, PartialEq, Serialize)]
+pub struct DuplicateCluster {
+    pub equipment_type: String,
+    pub floor_level: i32,
+    pub ids: Vec<String>,
+    pub names: Vec<String>,
+    /// Largest distance between a member and the first (suggested survivor).
+    pub spread_m: f64,
+}
+
+#[derive(Debug, Clone, PartialEq, Serialize)]
+pub struct MergeOutcome {
+    pub survivor: String,
+    pub merged: Vec<String>,
+    /// Anchor poses and pending-id entries pointed at the survivor.
+    pub references_rewired: usize,
+}
+
+fn distance(a: &Equipment, b: &Equipment) -> f64 {
+    let (p, q) = (&a.position, &b.position);
+    ((p.x - q.x).powi(2) + (p.y - q.y).powi(2) + (p.z - q.z).powi(2)).sqrt()
+}
+
+/// Rank for choosing a survivor: reviewed before proposed, then LiDAR confidence,
+/// then the richer property set. Ties keep the smaller id.
+fn rank(a: &Equipment, b: &Equipment) -> std::cmp::Ordering {
+    let confidence = |e: &Equipment| {
+        e.lidar_enrichment
+            .as_ref()
+            .map_or(0.0, |l| l.confidence_score)
+    };
+    equipment_needs_review(a)
+        .cmp(&equipment_needs_review(b))
+        .then(confidence(b).total_cmp(&confidence(a)))
+        .then(b.properties.len().cmp(&a.properties.len()))
+        .then(a.id.cmp(&b.id))
+}
+
+fn find(parent: &mut [usize], i: usize) -> usize {
+    let mut root = i;
+    while parent[root] != root {
+        root = parent[root];
+    }
+    parent[i] = root;
+    root
+}
+
+/// Clusters of likely-duplicate equipment, largest first. Soft-deleted equipment
+/// is left out.
+pub fn find_duplicates(building: &Building, tolerance_m: f64) -> Vec<DuplicateCluster> {
+    let mut clusters = Vec::new();
+    for floor in &building.floors {
+        let equipment: Vec<&Equipment> = floor
+            .equipment
+            .iter()
+            .chain(floor.wings.iter().flat_map(|w| {
+                w.equipment
+                    .iter()
+                    .chain(w.rooms.iter().flat_map(|r| r.equipment.iter()))
+            }))
+            .filter(|eq| !is_deleted(eq))
+            .collect();
+
+        let mut parent: Vec<usize> = (0..equipment.len()).collect();
+        for i in 0..equipment.len() {
+            for j in i + 1..equipment.len() {
+                if equipment[i].equipment_type == equipment[j].equipment_type
+                    && distance(equipment[i], equipment[j]) <= tolerance_m
+                {
+                    let (a, b) = (find(&mut parent, i), find(&mut parent, j));
+                    parent[a.max(b)] = a.min(b);
+                }
+            }
+        }
+
+        let mut groups: HashMap<usize, Vec<&Equipment>> = HashMap::new();
+        for i in 0..equipment.len() {
+            let root = find(&mut parent, i);
+            groups.entry(root).or_default().push(equipment[i]);
+        }
+        for (_, mut members) in groups {
+            if members.len() < 2 {
+                continue;
+            }
+            members.sort_by(|a, b| rank(a, b));
+            let spread_m = members
+                .iter()
+                .map(|m| distance(m, members[0]))
+                .fold(0.0, f64::max);
+            clusters.push(DuplicateCluster {
+                equipment_type: members[0].equipment_type.to_string(),
+                floor_level: floor.level,
+                ids: members.iter().map(|m| m.id.clone()).collect(),
+                names: members.iter().map(|m| m.name.clone()).collect(),
+                spread_m,
+            });
+        }
+    }
+    clusters.sort_by(|a, b| b.ids.len().cmp(&a.ids.len()).then(a.ids.cmp(&b.ids)));
+    clusters
+}
+
+/// Remove equipment `id` from wherever it is nested.
+fn take_equipment(building: &mut Building, id: &str) -> Option<Equipment> {
+    fn take(list: &mut Vec<Equipment>, id: &str) -> Option<Equipment> {
+        let i = list.iter().position(|e| e.id == id)?;
+        Some(list.remove(i))
+    }
+    for floor in &mut building.floors {
+        if let Some(eq) = take(&mut floor.equipment, id) {
+            return Some(eq);
+        }
+        for wing in &mut floor.wings {
+            if let Some(eq) = take(&mut wing.equipment, id) {
+                return Some(eq);
+            }
+            for room in &mut wing.rooms {
+                if let Some(eq) = take(&mut room.equipment, id) {
+                    return Some(eq);
+                }
+            }
+        }
+    }
+    None
+}
+
+fn rewire_ids(ids: &mut Vec<String>, merged: &[String], survivor: &str) -> usize {
+    let before = ids.iter().filter(|id| merged.contains(id)).count();
+    if before == 0 {
+        return 0;
+    }
+    let has_survivor = ids.iter().any(|id| id == survivor);
+    ids.retain(|id| !merged.contains(id));
+    if !has_survivor {
+        ids.push(survivor.to_string());
+    }
+    before
+}
+
+/// Merge equipment `ids` into one. The best-ranked id survives; others' properties
+/// fill its gaps (the survivor wins conflicts) and references are rewired to it.
+pub fn merge_equipment(building: &mut Building, ids: &[String]) -> Result<MergeOutcome, String> {
+    if ids.len() < 2 {
+        return Err("Merge needs at least two equipment ids".into());
+    }
+    let mut members: Vec<&Equipment> = Vec::new();
+    for id in ids {
+        let eq = building
+            .find_equipment(id)
+            .ok_or_else(|| format!("Equipment '{}' not found", id))?;
+        if members.iter().any(|m| m.id == eq.id) {
+            return Err(format!("Equipment '{}' listed twice", id));
+        }
+        if let Some(first) = members.first() {
+            if first.equipment_type != eq.equipment_type {
+                return Err(format!(
+                    "Cannot merge '{}' ({}) with '{}' ({})",
+                    eq.name, eq.equipment_type, first.name, first.equipment_type
+                ));
+            }
+        }
+        members.push(eq);
+    }
+    members.sort_by(|a, b| rank(a, b));
+    let survivor_id = members[0].id.clone();
+    let merged_ids: Vec<String> = members[1..].iter().map(|m| m.id.clone()).collect();
+
+    let absorbed: Vec<Equipment> = merged_ids
+        .iter()
+        .filter_map(|id| take_equipment(building, id))
+        .collect();
+    let survivor = building
+        .find_equipment_mut(&survivor_id)
+        .expect("survivor is not removed");
+    for other in &absorbed {
+        for (key, value) in &other.properties {
+            if NOT_MERGED.contains(&key.as_str()) {
+                continue;
+            }
+            survivor
+                .properties
+                .entry(key.clone())
+                .or_insert_with(|| value.clone());
+        }
+        if survivor.ifc_global_id.is_none() {
+            survivor.ifc_global_id = other.ifc_global_id.clone();
+        }
+        if let Some(mappings) = &other.sensor_mappings {
+            let list = survivor.sensor_mappings.get_or_insert_with(Vec::new);
+            for m in mappings {
+                if !list.iter().any(|s| s.sensor_id == m.sensor_id) {
+                    list.push(m.clone());
+                }
+            }
+        }
+    }
+    let mut merged_from: Vec<String> = survivor
+        .properties
+        .get(PROP_MERGED_FROM)
+        .map(|v| v.split(',').map(str::to_string).collect())
+        .unwrap_or_default();
+    merged_from.extend(merged_ids.iter().cloned());
+    survivor
+        .properties
+        .insert(PROP_MERGED_FROM.into(), merged_from.join(","));
+
+    let mut rewired = 0;
+    for anchor in building.get_all_anchors_mut() {
+        for pose in &mut anchor.relative_poses {
+            if merged_ids.contains(&pose.target_id) {
+                pose.target_id = survivor_id.clone();
+                rewired += 1;
+            }
+        }
+    }
+    for floor in &mut building.floors {
+        rewired += rewire_ids(&mut floor.pending_equipment_ids, &merged_ids, &survivor_id);
+        for wing in &mut floor.wings {
+            rewired += rewire_ids(&mut wing.pending_equipment_ids, &merged_ids, &survivor_id);
+            for room in &mut wing.rooms {
+                rewired += rewire_ids(&mut room.pending_equipment_ids, &merged_ids, &survivor_id);
+            }
+        }
+    }
+    building.updated_at = chrono::Utc::now();
+
+    Ok(MergeOutcome {
+        survivor: survivor_id,
+        merged: merged_ids,
+        references_rewired: rewired,
+    })
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::core::anchor::{PoseType, RelativePose};
+    use crate::core::review::mark_proposed;
+    use crate::core::{Anchor, EquipmentType, Floor, Position, Room, RoomType, Wing};
+
+    fn at(x: f64, y: f64) -> Position {
+        Position {
+            x,
+            y,
+            z: 0.0,
+            coordinate_system: "building_local".into(),
+        }
+    }
+
+    fn equipment(name: &str, kind: EquipmentType, x: f64) -> Equipment {
+        let mut eq = Equipment::new(name.into(), format!("/{}", name), kind);
+        eq.position = at(x, 0.0);
+        eq
+    }
+
+    #[test]
+    fn clusters_same_type_neighbors_and_merges_with_rewiring() {
+        let mut building = Building::new("HQ".into(), "/hq".into());
+        let mut floor = Floor::new("Ground".into(), 0);
+        let mut wing = Wing::new("East".into());
+        let mut room = Room::new("Office".into(), RoomType::Office);
+
+        let mut kept = equipment("Outlet A", EquipmentType::Electrical, 0.0);
+        kept.properties.insert("circuit".into(), "P1-4".into());
+        let mut scan = equipment("Outlet A (scan)", EquipmentType::Electrical, 0.3);
+        mark_proposed(&mut scan.properties);
+        scan.properties.insert("circuit".into(), "P1-9".into());
+        scan.properties.insert("amperage".into(), "20".into());
+        // Chained through the scan copy: 0.6 m from the original, 0.3 m from the scan.
+        let mut rescan = equipment("Outlet A (rescan)", EquipmentType::Electrical, 0.6);
+        mark_proposed(&mut rescan.properties);
+        // Same spot, different type: not a duplicate.
+        let data = equipment("Data jack", EquipmentType::Network, 0.1);
+        let far = equipment("Outlet B", EquipmentType::Electrical, 5.0);
+        let (kept_id, scan_id, rescan_id) = (kept.id.clone(), scan.id.clone(), rescan.id.clone());
+
+        room.pending_equipment_ids.push(scan_id.clone());
+        let mut anchor = Anchor::new("Door".into(), at(1.0, 1.0), 0.9);
+        anchor.relative_poses.push(RelativePose {
+            target_id: rescan_id.clone(),
+            pose_type: PoseType::AnchorToEquipment,
+            x: 0.0,
+            y: 0.0,
+            z: 0.0,
+            roll: 0.0,
+            pitch: 0.0,
+            yaw: 0.0,
+        });
+        room.anchors.push(anchor);
+        room.equipment.extend([kept, data, far]);
+        wing.equipment.push(rescan);
+        wing.rooms.push(room);
+        floor.equipment.push(scan);
+        floor.wings.push(wing);
+        building.add_floor(floor);
+
+        let clusters = find_duplicates(&building, DEFAULT_DUPLICATE_TOLERANCE_M);
+        assert_eq!(clusters.len(), 1);
+        assert_eq!(clusters[0].ids[0], kept_id);
+        let mut ids = clusters[0].ids.clone();
+        ids.sort();
+        let mut expected = vec![kept_id.clone(), scan_id.clone(), rescan_id.clone()];
+        expected.sort();
+        assert_eq!(ids, expected);
+
+        let outcome = merge_equipment(&mut building, &clusters[0].ids).unwrap();
+        assert_eq!(outcome.survivor, kept_id);
+        assert_eq!(outcome.references_rewired, 2);
+        assert_eq!(building.get_all_equipment().len(), 3);
+        let survivor = building.find_equipment(&kept_id).unwrap();
+        assert_eq!(survivor.properties["circuit"], "P1-4");
+        assert_eq!(survivor.properties["amperage"], "20");
+        assert!(survivor.properties[PROP_MERGED_FROM].contains(&rescan_id));
+        assert!(!survivor.properties.contains_key(PROP_REVIEW_STATUS));
+
+        let room = &building.get_all_rooms()[0];
+        assert_eq!(room.pending_equipment_ids, vec![kept_id.clone()]);
+        assert_eq!(room.anchors[0].relative_poses[0].target_id, kept_id);
+        assert!(find_duplicates(&building, DEFAULT_DUPLICATE_TOLERANCE_M).is_empty());
+
+        let data_id = building
+            .get_all_equipment()
+            .into_iter()
+            .find(|e| e.name == "Data jack")
+            .unwrap()
+            .id
+            .clone();
+        assert!(merge_equipment(&mut building, &[kept_id.clone(), data_id]).is_err());
+        assert!(merge_equipment(&mut building, &[kept_id]).is_err());
+    }
+}
@@ -6,6 +6,7 @@
 use crate::core::Equipment;
 use crate::ingest::persist_building_at;
 use crate::persistence::{load_building_data_from_dir, PersistenceManager};
+use crate::validation::{BoundsConfig, PropertySchemas};
 use std::collections::HashMap;
 
 /// Add equipment to a room or floor
@@ -19,6 +20,8 @@ pub fn add_equipment(
     let base = persistence.base_path().to_path_buf();
     let mut building = persistence.load_building_data()?;
     let eq_name = equipment.name.clone();
+    PropertySchemas::load(&base)?.gate_equipment(&equipment)?;
+    BoundsConfig::load(&base)?.gate_equipment(&building, &equipment)?;
 
     if let Some(room_name) = room_name {
         let mut added = false;
@@ -87,6 +90,7 @@ pub fn update_equipment_impl(
         equipment.properties.insert(key.clone(), value.clone());
     }
     let updated = equipment.clone();
+    PropertySchemas::load(&base)?.gate_equipment(&updated)?;
 
     let building = persist_building_at(
         base,
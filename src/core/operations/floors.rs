@@ -0,0 +1,236 @@
+//! Floor numbering and reordering
+//!
+//! A floor's `level` is its ordinal in the building: 0 is ground, negative levels
+//! are basements, positive levels count up from ground. Display labels follow the
+//! usual convention (`B2`, `B1`, `G`, `1`, `2`); a floor that does not fit it, such
+//! as a mezzanine, sets [`PROP_FLOOR_LABEL`] (e.g. `M`).
+//!
+//! Rooms and equipment belong to a floor by nesting, not by level, so renumbering
+//! never detaches them.
+
+use std::collections::HashSet;
+
+use serde::Serialize;
+
+use crate::core::Building;
+use crate::core::Floor;
+
+/// Floor property overriding the conventional display label.
+pub const PROP_FLOOR_LABEL: &str = "floor_label";
+
+/// One floor's level change from [`reorder_floors`].
+#[derive(Debug, Clone, PartialEq, Serialize)]
+pub struct FloorRenumber {
+    pub floor_id: String,
+    pub name: String,
+    pub old_level: i32,
+    pub new_level: i32,
+}
+
+/// Conventional label for `level`: `B1` below ground, `G` at ground, `1` above.
+pub fn level_label(level: i32) -> String {
+    match level {
+        0 => "G".to_string(),
+        l if l < 0 => format!("B{}", -l),
+        l => l.to_string(),
+    }
+}
+
+/// Display label of `floor`, honoring [`PROP_FLOOR_LABEL`].
+pub fn floor_label(floor: &Floor) -> String {
+    floor
+        .properties
+        .get(PROP_FLOOR_LABEL)
+        .filter(|l| !l.trim().is_empty())
+        .cloned()
+        .unwrap_or_else(|| level_label(floor.level))
+}
+
+/// Level for a conventional label (`B1`, `G`, `GF`, `L3`, `3`); case-insensitive.
+pub fn parse_floor_label(label: &str) -> Option<i32> {
+    let label = label.trim().to_ascii_uppercase();
+    match label.as_str() {
+        "G" | "GF" => Some(0),
+        _ => {
+            if let Some(n) = label.strip_prefix('B') {
+                n.parse::<i32>().ok().filter(|n| *n > 0).map(|n| -n)
+            } else {
+                label
+                    .strip_prefix('L')
+                    .unwrap_or(&label)
+                    .parse::<i32>()
+                    .ok()
+            }
+        }
+    }
+}
+
+/// Floor ids from lowest to highest elevation; `None` if any floor lacks one.
+pub fn floors_by_elevation(building: &Building) -> Option<Vec<String>> {
+    let mut floors: Vec<(&Floor, f64)> = building
+        .floors
+        .iter()
+        .map(|f| f.elevation.map(|e| (f, e)))
+        .collect::<Option<_>>()?;
+    floors.sort_by(|a, b| a.1.total_cmp(&b.1).then(a.0.level.cmp(&b.0.level)));
+    Some(floors.into_iter().map(|(f, _)| f.id.clone()).collect())
+}
+
+/// Renumber floors so `order` (floor ids, bottom to top) is their stacking order and
+/// `ground` is level 0. `order` must name every floor exactly once.
+pub fn reorder_floors(
+    building: &mut Building,
+    order: &[String],
+    ground: &str,
+) -> Result<Vec<FloorRenumber>, String> {
+    let mut seen = HashSet::new();
+    for id in order {
+        if !seen.insert(id.as_str()) {
+            return Err(format!("Floor '{}' appears more than once", id));
+        }
+        if !building.floors.iter().any(|f| &f.id == id) {
+            return Err(format!("Floor '{}' not found", id));
+        }
+    }
+    if let Some(missing) = building
+        .floors
+        .iter()
+        .find(|f| !seen.contains(f.id.as_str()))
+    {
+        return Err(format!(
+            "Floor '{}' ({}) is missing from the order",
+            missing.id, missing.name
+        ));
+    }
+    let ground_index = order
+        .iter()
+        .position(|id| id == ground)
+        .ok_or_else(|| format!("Ground floor '{}' is not in the order", ground))?;
+
+    let mut changes = Vec::new();
+    for (index, id) in order.iter().enumerate() {
+        let new_level = index as i32 - ground_index as i32;
+        let floor = building
+            .floors
+            .iter_mut()
+            .find(|f| &f.id == id)
+            .expect("checked above");
+        if floor.level != new_level {
+            changes.push(FloorRenumber {
+                floor_id: floor.id.clone(),
+                name: floor.name.clone(),
+                old_level: floor.level,
+                new_level,
+            });
+            floor.level = new_level;
+        }
+    }
+    building.floors.sort_by_key(|f| f.level);
+    if !changes.is_empty() {
+        building.updated_at = chrono::Utc::now();
+    }
+    Ok(changes)
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::core::{Equipment, EquipmentType, Room, RoomType, Wing};
+    use crate::validation::validate_building;
+
+    fn building() -> Building {
+        let mut building = Building::new("HQ".into(), "/hq".into());
+        // Imported with the plant room as level 0 and everything else above it.
+        for (name, level, elevation) in [("Plant", 0, -4.0), ("Lobby", 1, 0.0), ("Office", 2, 4.0)]
+        {
+            let mut floor = Floor::new(name.into(), level);
+            floor.elevation = Some(elevation);
+            let mut wing = Wing::new("Main".into());
+            let mut room = Room::new(format!("{} Room", name), RoomType::Office);
+            room.equipment.push(Equipment::new(
+                format!("{}-AHU", name),
+                format!("/{}-ahu", name.to_lowercase()),
+                EquipmentType::HVAC,
+            ));
+            wing.rooms.push(room);
+            floor.wings.push(wing);
+            building.add_floor(floor);
+        }
+        building
+    }
+
+    #[test]
+    fn labels_follow_convention() {
+        assert_eq!(level_label(-2), "B2");
+        assert_eq!(level_label(0), "G");
+        assert_eq!(level_label(3), "3");
+        for level in [-3, -1, 0, 1, 12] {
+            assert_eq!(parse_floor_label(&level_label(level)), Some(level));
+        }
+        assert_eq!(parse_floor_label("gf"), Some(0));
+        assert_eq!(parse_floor_label("L2"), Some(2));
+        assert_eq!(parse_floor_label("B0"), None);
+        assert_eq!(parse_floor_label("roof"), None);
+
+        let mut mezzanine = Floor::new("Mezzanine".into(), 1);
+        assert_eq!(floor_label(&mezzanine), "1");
+        mezzanine
+            .properties
+            .insert(PROP_FLOOR_LABEL.into(), "M".into());
+        assert_eq!(floor_label(&mezzanine), "M");
+    }
+
+    #[test]
+    fn reorder_renumbers_and_keeps_contents() {
+        let mut building = building();
+        let order = floors_by_elevation(&building).unwrap();
+        let lobby = building.floors[1].id.clone();
+
+        let changes = reorder_floors(&mut building, &order, &lobby).unwrap();
+        assert_eq!(changes.len(), 3);
+        let labels: Vec<(String, String)> = building
+            .floors
+            .iter()
+            .map(|f| (floor_label(f), f.name.clone()))
+            .collect();
+        assert_eq!(
+            labels,
+            vec![
+                ("B1".to_string(), "Plant".to_string()),
+                ("G".to_string(), "Lobby".to_string()),
+                ("1".to_string(), "Office".to_string()),
+            ]
+        );
+        for floor in &building.floors {
+            let room = &floor.wings[0].rooms[0];
+            assert_eq!(room.name, format!("{} Room", floor.name));
+            assert_eq!(room.equipment[0].name, format!("{}-AHU", floor.name));
+        }
+        assert!(!validate_building(&building).has_errors());
+        assert!(reorder_floors(&mut building, &order, &lobby)
+            .unwrap()
+            .is_empty());
+    }
+
+    #[test]
+    fn rejects_incomplete_or_duplicate_orders() {
+        let mut building = building();
+        let ids: Vec<String> = building.floors.iter().map(|f| f.id.clone()).collect();
+        let before = building.clone();
+
+        let duplicate = vec![ids[0].clone(), ids[0].clone(), ids[1].clone()];
+        assert!(reorder_floors(&mut building, &duplicate, &ids[0]).is_err());
+        assert!(reorder_floors(&mut building, &ids[..2], &ids[0]).is_err());
+        assert!(reorder_floors(&mut building, &ids, "nope").is_err());
+        assert_eq!(
+            building.floors.iter().map(|f| f.level).collect::<Vec<_>>(),
+            before.floors.iter().map(|f| f.level).collect::<Vec<_>>()
+        );
+
+        building.floors[2].level = 1;
+        let report = validate_building(&building);
+        assert!(report
+            .errors()
+            .any(|r| r.rule_id == "floor.level.duplicate"));
+    }
+}
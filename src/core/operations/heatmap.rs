@@ -0,0 +1,318 @@
+//! Spatial heatmap aggregation
+//!
+//! Bins equipment into a square grid over each floor plan (x/y, metres) and counts,
+//! per cell, the objects a metric selects: all of them (`density`), LiDAR objects
+//! below a confidence threshold (`low-confidence`), or objects awaiting review
+//! (`pending-review`). Cells are aligned to multiples of the cell size, so the same
+//! object lands in the same cell whatever the filter, and only non-empty cells are
+//! returned. Every floor gets its own grid, so objects stacked on different levels
+//! never share a cell; equipment can be narrowed to one floor, a type and a system.
+
+use serde::{Deserialize, Serialize};
+
+use super::delete_query::EquipmentQuery;
+use crate::core::equipment_types::EquipmentTypeRegistry;
+use crate::core::review::{equipment_review_status, ReviewStatus};
+use crate::core::{Building, Equipment};
+
+/// LiDAR confidence below which an object counts as low-confidence by default.
+pub const DEFAULT_LOW_CONFIDENCE: f64 = 0.5;
+
+/// Most cells a heatmap may span, to keep a tiny cell size from exploding.
+pub const MAX_HEATMAP_CELLS: u64 = 1_000_000;
+
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Serialize, Deserialize)]
+#[serde(rename_all = "kebab-case")]
+pub enum HeatmapMetric {
+    /// Every object.
+    Density,
+    /// Objects with a LiDAR confidence below the threshold.
+    LowConfidence,
+    /// Objects awaiting review (`proposed`, as LiDAR objects are until reviewed).
+    PendingReview,
+}
+
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct HeatmapRequest {
+    pub metric: HeatmapMetric,
+    /// Cell edge length in metres.
+    pub cell_size_m: f64,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub floor_level: Option<i32>,
+    /// Type name (`hvac`, or a custom type), case-insensitive.
+    #[serde(default, rename = "type", skip_serializing_if = "Option::is_none")]
+    pub equipment_type: Option<String>,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub system: Option<String>,
+    /// Threshold of `low-confidence`; [`DEFAULT_LOW_CONFIDENCE`] when unset.
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub confidence_below: Option<f64>,
+}
+
+/// One non-empty grid cell; `x`/`y` is its minimum corner.
+#[derive(Debug, Clone, PartialEq, Serialize)]
+pub struct HeatmapCell {
+    pub column: i64,
+    pub row: i64,
+    pub x: f64,
+    pub y: f64,
+    pub value: u32,
+}
+
+/// The grid of one floor.
+#[derive(Debug, Clone, Serialize)]
+pub struct FloorHeatmap {
+    pub floor_level: i32,
+    /// Cells with a non-zero value, by row then column.
+    pub cells: Vec<HeatmapCell>,
+    pub max_value: u32,
+    pub total: u32,
+}
+
+#[derive(Debug, Clone, Serialize)]
+pub struct Heatmap {
+    pub metric: HeatmapMetric,
+    pub cell_size_m: f64,
+    /// One grid per floor by level; only the requested floor when `floor_level` is set.
+    pub floors: Vec<FloorHeatmap>,
+    /// Largest cell value on any floor, so floors share one colour ramp.
+    pub max_value: u32,
+    /// Objects counted across all floors.
+    pub total: u32,
+}
+
+impl HeatmapRequest {
+    fn filter(&self) -> EquipmentQuery {
+        EquipmentQuery {
+            equipment_type: self.equipment_type.clone(),
+            system: self.system.clone(),
+            floor_level: self.floor_level,
+            ..Default::default()
+        }
+    }
+
+    pub fn check(&self) -> Result<(), String> {
+        if !self.cell_size_m.is_finite() || self.cell_size_m <= 0.0 {
+            return Err("cell_size_m must be positive".into());
+        }
+        if let Some(threshold) = self.confidence_below {
+            if !threshold.is_finite() || !(0.0..=1.0).contains(&threshold) {
+                return Err("confidence_below must be between 0 and 1".into());
+            }
+        }
+        let filter = self.filter();
+        if filter.equipment_type.is_some() || filter.system.is_some() {
+            filter.check()?;
+        }
+        Ok(())
+    }
+
+    fn counts(&self, eq: &Equipment) -> bool {
+        match self.metric {
+            HeatmapMetric::Density => true,
+            HeatmapMetric::LowConfidence => {
+                let threshold = self.confidence_below.unwrap_or(DEFAULT_LOW_CONFIDENCE);
+                eq.lidar_enrichment
+                    .as_ref()
+                    .is_some_and(|l| l.confidence_score < threshold)
+            }
+            HeatmapMetric::PendingReview => {
+                equipment_review_status(eq) == Some(ReviewStatus::Proposed)
+            }
+        }
+    }
+}
+
+/// Aggregate the building's equipment into grid cells per `request`.
+pub fn heatmap(
+    building: &Building,
+    registry: &EquipmentTypeRegistry,
+    request: &HeatmapRequest,
+) -> Result<Heatmap, String> {
+    request.check()?;
+    let filter = request.filter();
+    let size = request.cell_size_m;
+    let mut floors = Vec::new();
+    for floor in &building.floors {
+        if request
+            .floor_level
+            .is_some_and(|level| floor.level != level)
+        {
+            continue;
+        }
+        let mut counts: std::collections::BTreeMap<(i64, i64), u32> = Default::default();
+        let equipment = floor
+            .equipment
+            .iter()
+            .chain(floor.wings.iter().flat_map(|w| {
+                w.equipment
+                    .iter()
+                    .chain(w.rooms.iter().flat_map(|r| r.equipment.iter()))
+            }));
+        for eq in equipment {
+            if !filter.matches(registry, eq) || !request.counts(eq) {
+                continue;
+            }
+            let column = (eq.position.x / size).floor() as i64;
+            let row = (eq.position.y / size).floor() as i64;
+            *counts.entry((row, column)).or_default() += 1;
+        }
+        if let (Some(first), Some(last)) = (counts.keys().next(), counts.keys().next_back()) {
+            let columns = counts.keys().map(|(_, c)| *c);
+            let (min_c, max_c) =
+                columns.fold((i64::MAX, i64::MIN), |(lo, hi), c| (lo.min(c), hi.max(c)));
+            let spanned = (last.0 - first.0 + 1) as u64 * (max_c - min_c + 1) as u64;
+            if spanned > MAX_HEATMAP_CELLS {
+                return Err(format!(
+                    "cell_size_m {} gives {} cells on floor {}; the limit is {}",
+                    size, spanned, floor.level, MAX_HEATMAP_CELLS
+                ));
+            }
+        }
+        let cells: Vec<HeatmapCell> = counts
+            .into_iter()
+            .map(|((row, column), value)| HeatmapCell {
+                column,
+                row,
+                x: column as f64 * size,
+                y: row as f64 * size,
+                value,
+            })
+            .collect();
+        floors.push(FloorHeatmap {
+            floor_level: floor.level,
+            max_value: cells.iter().map(|c| c.value).max().unwrap_or(0),
+            total: cells.iter().map(|c| c.value).sum(),
+            cells,
+        });
+    }
+    floors.sort_by_key(|f| f.floor_level);
+    Ok(Heatmap {
+        metric: request.metric,
+        cell_size_m: size,
+        max_value: floors.iter().map(|f| f.max_value).max().unwrap_or(0),
+        total: floors.iter().map(|f| f.total).sum(),
+        floors,
+    })
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::core::review::PROP_REVIEW_STATUS;
+    use crate::core::types::LidarEnrichment;
+    use crate::core::{EquipmentType, Floor};
+
+    fn place(floor: &mut Floor, name: &str, kind: EquipmentType, x: f64, y: f64) -> usize {
+        let mut eq = Equipment::new(name.into(), format!("/{}", name), kind);
+        eq.position.x = x;
+        eq.position.y = y;
+        floor.equipment.push(eq);
+        floor.equipment.len() - 1
+    }
+
+    fn request(metric: HeatmapMetric) -> HeatmapRequest {
+        HeatmapRequest {
+            metric,
+            cell_size_m: 10.0,
+            floor_level: None,
+            equipment_type: None,
+            system: None,
+            confidence_below: None,
+        }
+    }
+
+    /// `(level, column, row, value)` of every cell.
+    fn summary(map: &Heatmap) -> Vec<(i32, i64, i64, u32)> {
+        map.floors
+            .iter()
+            .flat_map(|f| {
+                f.cells
+                    .iter()
+                    .map(|c| (f.floor_level, c.column, c.row, c.value))
+            })
+            .collect()
+    }
+
+    #[test]
+    fn counts_objects_per_cell() {
+        let mut building = Building::new("HQ".into(), "/hq".into());
+        let mut ground = Floor::new("Ground".into(), 0);
+        // Cell (0, 0) holds three; an object on a cell edge belongs to the cell above it.
+        place(&mut ground, "ahu-1", EquipmentType::HVAC, 1.0, 1.0);
+        place(&mut ground, "ahu-2", EquipmentType::HVAC, 9.5, 9.5);
+        place(&mut ground, "panel-1", EquipmentType::Electrical, 5.0, 2.0);
+        place(&mut ground, "panel-2", EquipmentType::Electrical, 10.0, 0.0);
+        // Cell (2, 3) and a negative cell (-1, 0).
+        place(&mut ground, "vav-1", EquipmentType::HVAC, 25.0, 31.0);
+        place(&mut ground, "vav-2", EquipmentType::HVAC, -0.5, 4.0);
+        for (i, confidence) in [(0, 0.2), (1, 0.9), (4, 0.3)] {
+            ground.equipment[i].lidar_enrichment = Some(LidarEnrichment {
+                point_count: 10,
+                confidence_score: confidence,
+                last_scan_timestamp: None,
+                classification_heuristic: None,
+            });
+        }
+        // LiDAR objects are proposed until reviewed; two of the three were accepted.
+        ground.equipment[2]
+            .properties
+            .insert(PROP_REVIEW_STATUS.into(), "proposed".into());
+        for i in [1, 4] {
+            ground.equipment[i]
+                .properties
+                .insert(PROP_REVIEW_STATUS.into(), "accepted".into());
+        }
+        let mut first = Floor::new("First".into(), 1);
+        place(&mut first, "ahu-3", EquipmentType::HVAC, 1.0, 1.0);
+        building.add_floor(ground);
+        building.add_floor(first);
+        let registry = EquipmentTypeRegistry::default();
+
+        // Each floor has its own grid: ahu-3 upstairs does not join cell (0, 0) below.
+        let density = heatmap(&building, &registry, &request(HeatmapMetric::Density)).unwrap();
+        assert_eq!(
+            summary(&density),
+            vec![
+                (0, -1, 0, 1),
+                (0, 0, 0, 3),
+                (0, 1, 0, 1),
+                (0, 2, 3, 1),
+                (1, 0, 0, 1)
+            ]
+        );
+        assert_eq!((density.total, density.max_value), (7, 3));
+        assert_eq!((density.floors[0].total, density.floors[1].total), (6, 1));
+        let corner = &density.floors[0].cells[0];
+        assert_eq!((corner.x, corner.y), (-10.0, 0.0));
+
+        let low = heatmap(&building, &registry, &request(HeatmapMetric::LowConfidence)).unwrap();
+        assert_eq!(summary(&low), vec![(0, 0, 0, 1), (0, 2, 3, 1)]);
+        let pending =
+            heatmap(&building, &registry, &request(HeatmapMetric::PendingReview)).unwrap();
+        assert_eq!(summary(&pending), vec![(0, 0, 0, 2)]);
+
+        // Filters narrow before binning; a coarser grid merges cells.
+        let mut hvac_ground = request(HeatmapMetric::Density);
+        hvac_ground.floor_level = Some(0);
+        hvac_ground.system = Some("hvac".into());
+        let filtered = heatmap(&building, &registry, &hvac_ground).unwrap();
+        assert_eq!(filtered.floors.len(), 1);
+        assert_eq!(
+            summary(&filtered),
+            vec![(0, -1, 0, 1), (0, 0, 0, 2), (0, 2, 3, 1)]
+        );
+        let mut coarse = request(HeatmapMetric::Density);
+        coarse.cell_size_m = 50.0;
+        assert_eq!(
+            summary(&heatmap(&building, &registry, &coarse).unwrap()),
+            vec![(0, -1, 0, 1), (0, 0, 0, 5), (1, 0, 0, 1)]
+        );
+
+        let mut bad = request(HeatmapMetric::Density);
+        bad.cell_size_m = 0.0;
+        assert!(heatmap(&building, &registry, &bad).is_err());
+        bad.cell_size_m = 0.0001;
+        assert!(heatmap(&building, &registry, &bad).is_err());
+    }
+}
@@ -0,0 +1,479 @@
+//! Hierarchy integrity check and repair
+//!
+//! The Building → Floor → Wing → Room → Equipment nesting cannot form cycles, but
+//! equipment also carries a `room_id` back-reference that can drift from its
+//! placement (hand edits, `equipment update --property room=…`, partial merges).
+//! This module finds dangling or mismatched references, equipment filed outside the
+//! room it names, and equipment ids that appear in more than one place. Repair is
+//! opt-in and records every change for audit.
+
+use std::collections::HashMap;
+use std::fmt;
+
+use crate::core::{Building, Equipment};
+
+/// Kind of hierarchy problem found.
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub enum HierarchyIssueKind {
+    /// `room_id` names a room that does not exist.
+    DanglingRoomRef,
+    /// Equipment nested in one room carries another room's id.
+    MismatchedRoomRef,
+    /// Equipment outside any room whose `room_id` names an existing room.
+    Unplaced,
+    /// The same equipment id appears in more than one place.
+    DuplicateEquipment,
+    /// The same room id appears in more than one place. Reported only.
+    DuplicateRoom,
+}
+
+impl HierarchyIssueKind {
+    pub fn as_str(&self) -> &'static str {
+        match self {
+            HierarchyIssueKind::DanglingRoomRef => "equipment.room_id.dangling",
+            HierarchyIssueKind::MismatchedRoomRef => "equipment.room_id.mismatch",
+            HierarchyIssueKind::Unplaced => "equipment.unplaced",
+            HierarchyIssueKind::DuplicateEquipment => "equipment.id.duplicate",
+            HierarchyIssueKind::DuplicateRoom => "room.id.duplicate",
+        }
+    }
+
+    pub fn is_fixable(&self) -> bool {
+        !matches!(self, HierarchyIssueKind::DuplicateRoom)
+    }
+}
+
+impl fmt::Display for HierarchyIssueKind {
+    fn fmt(&self, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+        f.write_str(self.as_str())
+    }
+}
+
+#[derive(Debug, Clone)]
+pub struct HierarchyIssue {
+    pub kind: HierarchyIssueKind,
+    /// Id of the offending room or equipment.
+    pub id: String,
+    /// Human-readable placement, e.g. `Ground/East/Room 101`.
+    pub location: String,
+    pub message: String,
+}
+
+#[derive(Debug, Clone, Default)]
+pub struct HierarchyReport {
+    pub issues: Vec<HierarchyIssue>,
+    /// Audit log of changes applied by [`repair_hierarchy`]; empty for a check.
+    pub changes: Vec<String>,
+}
+
+impl HierarchyReport {
+    pub fn is_clean(&self) -> bool {
+        self.issues.is_empty()
+    }
+
+    pub fn unfixable(&self) -> impl Iterator<Item = &HierarchyIssue> {
+        self.issues.iter().filter(|i| !i.kind.is_fixable())
+    }
+}
+
+/// Position of one equipment entry in the nesting.
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+struct Slot {
+    floor: usize,
+    wing: Option<usize>,
+    room: Option<usize>,
+    index: usize,
+}
+
+impl Slot {
+    fn depth(&self) -> usize {
+        self.wing.is_some() as usize + self.room.is_some() as usize
+    }
+
+    fn container_room_id<'a>(&self, building: &'a Building) -> Option<&'a str> {
+        let room = self.room?;
+        Some(&building.floors[self.floor].wings[self.wing?].rooms[room].id)
+    }
+
+    fn equipment<'a>(&self, building: &'a Building) -> &'a Equipment {
+        let floor = &building.floors[self.floor];
+        match (self.wing, self.room) {
+            (Some(w), Some(r)) => &floor.wings[w].rooms[r].equipment[self.index],
+            (Some(w), None) => &floor.wings[w].equipment[self.index],
+            _ => &floor.equipment[self.index],
+        }
+    }
+
+    fn container_mut<'a>(&self, building: &'a mut Building) -> &'a mut Vec<Equipment> {
+        let floor = &mut building.floors[self.floor];
+        match (self.wing, self.room) {
+            (Some(w), Some(r)) => &mut floor.wings[w].rooms[r].equipment,
+            (Some(w), None) => &mut floor.wings[w].equipment,
+            _ => &mut floor.equipment,
+        }
+    }
+
+    fn describe(&self, building: &Building) -> String {
+        let floor = &building.floors[self.floor];
+        let mut parts = vec![floor.name.clone()];
+        if let Some(w) = self.wing {
+            parts.push(floor.wings[w].name.clone());
+            if let Some(r) = self.room {
+                parts.push(floor.wings[w].rooms[r].name.clone());
+            }
+        }
+        parts.join("/")
+    }
+}
+
+fn equipment_slots(building: &Building) -> Vec<Slot> {
+    let mut slots = Vec::new();
+    for (f, floor) in building.floors.iter().enumerate() {
+        for (w, wing) in floor.wings.iter().enumerate() {
+            for (r, room) in wing.rooms.iter().enumerate() {
+                slots.extend((0..room.equipment.len()).map(|index| Slot {
+                    floor: f,
+                    wing: Some(w),
+                    room: Some(r),
+                    index,
+                }));
+            }
+            slots.extend((0..wing.equipment.len()).map(|index| Slot {
+                floor: f,
+                wing: Some(w),
+                room: None,
+                index,
+            }));
+        }
+        slots.extend((0..floor.equipment.len()).map(|index| Slot {
+            floor: f,
+            wing: None,
+            room: None,
+            index,
+        }));
+    }
+    slots
+}
+
+/// First placement of each room id: (floor, wing, room).
+fn room_index(building: &Building) -> HashMap<&str, (usize, usize, usize)> {
+    let mut index = HashMap::new();
+    for (f, floor) in building.floors.iter().enumerate() {
+        for (w, wing) in floor.wings.iter().enumerate() {
+            for (r, room) in wing.rooms.iter().enumerate() {
+                index.entry(room.id.as_str()).or_insert((f, w, r));
+            }
+        }
+    }
+    index
+}
+
+/// Report hierarchy problems without changing the building.
+pub fn check_hierarchy(building: &Building) -> HierarchyReport {
+    let mut report = HierarchyReport::default();
+    let rooms = room_index(building);
+
+    let mut seen_rooms: HashMap<&str, String> = HashMap::new();
+    for floor in &building.floors {
+        for wing in &floor.wings {
+            for room in &wing.rooms {
+                let location = format!("{}/{}/{}", floor.name, wing.name, room.name);
+                if let Some(first) = seen_rooms.get(room.id.as_str()) {
+                    report.issues.push(HierarchyIssue {
+                        kind: HierarchyIssueKind::DuplicateRoom,
+                        id: room.id.clone(),
+                        message: format!("Room id '{}' also used at {}", room.id, first),
+                        location,
+                    });
+                } else {
+                    seen_rooms.insert(room.id.as_str(), location);
+                }
+            }
+        }
+    }
+
+    let slots = equipment_slots(building);
+    let mut by_id: HashMap<&str, Vec<Slot>> = HashMap::new();
+    for slot in &slots {
+        by_id
+            .entry(slot.equipment(building).id.as_str())
+            .or_default()
+            .push(*slot);
+    }
+
+    for slot in &slots {
+        let eq = slot.equipment(building);
+        let location = slot.describe(building);
+
+        let copies = &by_id[eq.id.as_str()];
+        if copies.len() > 1 && copies[0] != *slot {
+            report.issues.push(HierarchyIssue {
+                kind: HierarchyIssueKind::DuplicateEquipment,
+                id: eq.id.clone(),
+                message: format!(
+                    "Equipment '{}' also placed at {}",
+                    eq.name,
+                    copies[0].describe(building)
+                ),
+                location: location.clone(),
+            });
+        }
+
+        let Some(room_ref) = eq.room_id.as_deref() else {
+            continue;
+        };
+        let container = slot.container_room_id(building);
+        if container == Some(room_ref) {
+            continue;
+        }
+        let (kind, message) = if !rooms.contains_key(room_ref) {
+            (
+                HierarchyIssueKind::DanglingRoomRef,
+                format!(
+                    "Equipment '{}' references missing room '{}'",
+                    eq.name, room_ref
+                ),
+            )
+        } else if container.is_some() {
+            (
+                HierarchyIssueKind::MismatchedRoomRef,
+                format!(
+                    "Equipment '{}' is in room '{}' but references room '{}'",
+                    eq.name,
+                    container.unwrap_or_default(),
+                    room_ref
+                ),
+            )
+        } else {
+            (
+                HierarchyIssueKind::Unplaced,
+                format!(
+                    "Equipment '{}' references room '{}' but is filed at {}",
+                    eq.name, room_ref, location
+                ),
+            )
+        };
+        report.issues.push(HierarchyIssue {
+            kind,
+            id: eq.id.clone(),
+            location,
+            message,
+        });
+    }
+
+    report
+}
+
+/// Repair fixable problems in place.
+///
+/// - Duplicate equipment: the copy placed deepest (room over wing over floor; first
+///   wins a tie) is kept and the others are removed.
+/// - Unplaced equipment is moved into the room its `room_id` names.
+/// - A mismatched `room_id` is reset to the containing room; a dangling one is reset
+///   to the containing room, or cleared when the equipment is not in a room.
+///
+/// Duplicate room ids are reported but left for a human to resolve. The returned
+/// report lists the issues found before repair and every change made.
+pub fn repair_hierarchy(building: &mut Building) -> HierarchyReport {
+    let mut report = check_hierarchy(building);
+    if report.issues.iter().all(|i| !i.kind.is_fixable()) {
+        return report;
+    }
+
+    // Duplicates: keep the deepest copy of each id.
+    let slots = equipment_slots(building);
+    let mut keep: HashMap<String, Slot> = HashMap::new();
+    for slot in &slots {
+        let id = slot.equipment(building).id.clone();
+        match keep.get(&id) {
+            Some(kept) if kept.depth() >= slot.depth() => {}
+            _ => {
+                keep.insert(id, *slot);
+            }
+        }
+    }
+    let mut drop: Vec<Slot> = slots
+        .iter()
+        .filter(|s| keep[&s.equipment(building).id] != **s)
+        .copied()
+        .collect();
+    drop.sort_by(|a, b| b.index.cmp(&a.index));
+    for slot in drop {
+        let location = slot.describe(building);
+        let removed = slot.container_mut(building).remove(slot.index);
+        report.changes.push(format!(
+            "Removed duplicate of equipment '{}' ({}) from {}",
+            removed.name, removed.id, location
+        ));
+    }
+
+    // Unplaced equipment: move into the referenced room.
+    let rooms: HashMap<String, (usize, usize, usize)> = room_index(building)
+        .into_iter()
+        .map(|(id, at)| (id.to_string(), at))
+        .collect();
+    let mut moves: Vec<(Slot, (usize, usize, usize))> = equipment_slots(building)
+        .into_iter()
+        .filter(|s| s.room.is_none())
+        .filter_map(|s| {
+            let target = s.equipment(building).room_id.as_ref()?;
+            rooms.get(target).map(|at| (s, *at))
+        })
+        .collect();
+    moves.sort_by(|a, b| b.0.index.cmp(&a.0.index));
+    for (slot, (f, w, r)) in moves {
+        let from = slot.describe(building);
+        let eq = slot.container_mut(building).remove(slot.index);
+        let room = &mut building.floors[f].wings[w].rooms[r];
+        report.changes.push(format!(
+            "Moved equipment '{}' ({}) from {} into room '{}'",
+            eq.name, eq.id, from, room.name
+        ));
+        room.equipment.push(eq);
+    }
+
+    // Remaining references follow placement.
+    for slot in equipment_slots(building) {
+        let container = slot.container_room_id(building).map(str::to_string);
+        let eq = slot.equipment(building);
+        let current = eq.room_id.clone();
+        let target = match (&container, &current) {
+            (Some(c), Some(r)) if c != r => container.clone(),
+            (None, Some(r)) if !rooms.contains_key(r) => None,
+            _ => continue,
+        };
+        report.changes.push(format!(
+            "Equipment '{}' ({}) room_id {} -> {}",
+            eq.name,
+            eq.id,
+            current.as_deref().unwrap_or("none"),
+            target.as_deref().unwrap_or("none")
+        ));
+        slot.container_mut(building)[slot.index].room_id = target;
+    }
+
+    report
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::core::{EquipmentType, Floor, Room, RoomType, Wing};
+
+    fn equipment(id: &str, room_id: Option<&str>) -> Equipment {
+        let mut eq = Equipment::new(id.to_uppercase(), format!("/{}", id), EquipmentType::HVAC);
+        eq.id = id.to_string();
+        eq.room_id = room_id.map(str::to_string);
+        eq
+    }
+
+    fn room(id: &str, name: &str) -> Room {
+        let mut room = Room::new(name.to_string(), RoomType::Office);
+        room.id = id.to_string();
+        room
+    }
+
+    fn sample_building() -> Building {
+        let mut building = Building::new("Repair".into(), "/repair".into());
+        let mut floor = Floor::new("Ground".into(), 0);
+        let mut wing = Wing::new("East".into());
+
+        let mut office = room("room-a", "Office A");
+        office.equipment.push(equipment("ahu-1", Some("room-a")));
+        office.equipment.push(equipment("vav-1", Some("room-gone")));
+        office.equipment.push(equipment("vav-2", Some("room-b")));
+        office.equipment.push(equipment("dup-1", Some("room-a")));
+        wing.rooms.push(office);
+        wing.rooms.push(room("room-b", "Office B"));
+
+        floor.equipment.push(equipment("pump-1", Some("room-b")));
+        floor.equipment.push(equipment("fan-1", Some("room-gone")));
+        floor.equipment.push(equipment("dup-1", Some("room-a")));
+        floor.wings.push(wing);
+        building.add_floor(floor);
+        building
+    }
+
+    fn kinds(report: &HierarchyReport, id: &str) -> Vec<HierarchyIssueKind> {
+        report
+            .issues
+            .iter()
+            .filter(|i| i.id == id)
+            .map(|i| i.kind)
+            .collect()
+    }
+
+    #[test]
+    fn detects_dangling_mismatched_unplaced_and_duplicates() {
+        let mut building = sample_building();
+        building.floors[0].wings[0]
+            .rooms
+            .push(room("room-a", "Copy"));
+        let report = check_hierarchy(&building);
+
+        assert!(kinds(&report, "ahu-1").is_empty());
+        assert_eq!(
+            kinds(&report, "vav-1"),
+            vec![HierarchyIssueKind::DanglingRoomRef]
+        );
+        assert_eq!(
+            kinds(&report, "vav-2"),
+            vec![HierarchyIssueKind::MismatchedRoomRef]
+        );
+        assert_eq!(kinds(&report, "pump-1"), vec![HierarchyIssueKind::Unplaced]);
+        assert_eq!(
+            kinds(&report, "fan-1"),
+            vec![HierarchyIssueKind::DanglingRoomRef]
+        );
+        assert!(kinds(&report, "dup-1").contains(&HierarchyIssueKind::DuplicateEquipment));
+        assert_eq!(
+            kinds(&report, "room-a"),
+            vec![HierarchyIssueKind::DuplicateRoom]
+        );
+        assert_eq!(report.unfixable().count(), 1);
+        assert!(report.changes.is_empty());
+    }
+
+    #[test]
+    fn repair_produces_consistent_tree() {
+        let mut building = sample_building();
+        let report = repair_hierarchy(&mut building);
+        assert!(!report.changes.is_empty());
+
+        assert!(check_hierarchy(&building).is_clean());
+        assert_eq!(
+            building
+                .get_all_equipment()
+                .iter()
+                .filter(|e| e.id == "dup-1")
+                .count(),
+            1
+        );
+
+        let rooms = &building.floors[0].wings[0].rooms;
+        let in_room = |r: &Room, id: &str| r.equipment.iter().find(|e| e.id == id).cloned();
+        assert_eq!(
+            in_room(&rooms[0], "dup-1").unwrap().room_id.as_deref(),
+            Some("room-a")
+        );
+        assert_eq!(
+            in_room(&rooms[0], "vav-1").unwrap().room_id.as_deref(),
+            Some("room-a")
+        );
+        assert_eq!(
+            in_room(&rooms[0], "vav-2").unwrap().room_id.as_deref(),
+            Some("room-a")
+        );
+        assert_eq!(
+            in_room(&rooms[1], "pump-1").unwrap().room_id.as_deref(),
+            Some("room-b")
+        );
+
+        let floor_eq = &building.floors[0].equipment;
+        assert_eq!(floor_eq.len(), 1);
+        assert_eq!(floor_eq[0].id, "fan-1");
+        assert_eq!(floor_eq[0].room_id, None);
+
+        assert!(repair_hierarchy(&mut building).changes.is_empty());
+    }
+}
@@ -8,6 +8,14 @@
 //! - `room` - Room CRUD operations
 //! - `equipment` - Equipment CRUD operations
 //! - `spatial` - Spatial queries and validation
+//! - `transform` - Bulk translate/rotate/scale of a selection
+//! - `hierarchy` - Room reference integrity check and repair
+//! - `clone` - Deep copy of a building under fresh ids (templates)
+//! - `duplicates` - Duplicate equipment detection and merge
+//! - `delete_query` - Bulk equipment soft deletion by query with confirmation
+//! - `floors` - Floor labels (B1/G/1) and renumbering
+//! - `reclassify` - Rule-driven bulk type changes
+//! - `heatmap` - Per-cell equipment counts for heatmap overlays
 //!
 //! # Usage
 //!
@@ -25,13 +33,32 @@
 //! ```
 
 pub mod address;
+pub mod clone;
+pub mod delete_query;
+pub mod duplicates;
 pub mod equipment;
+pub mod floors;
+pub mod heatmap;
+pub mod hierarchy;
+pub mod reclassify;
 pub mod room;
 pub mod spatial;
+pub mod transform;
 #[cfg(test)]
 mod spatial_tests;
 
 pub use address::backfill_equipment_addresses;
+pub use clone::{clone_building, CloneOptions, ClonedBuilding};
+pub use delete_query::{
+    find_matching, is_deleted, mark_deleted, strip_deleted, without_deleted, DeleteConfirmations,
+    EquipmentQuery, ObjectSource,
+};
+pub use duplicates::{find_duplicates, merge_equipment, DuplicateCluster, MergeOutcome};
+pub use floors::{floor_label, parse_floor_label, reorder_floors, FloorRenumber};
+pub use heatmap::{heatmap, FloorHeatmap, Heatmap, HeatmapCell, HeatmapMetric, HeatmapRequest};
+pub use reclassify::{
+    apply_reclassification, plan_reclassification, Reclassification, ReclassifyRules,
+};
 
 // Re-export room operations
 pub use room::{
@@ -49,3 +76,14 @@ pub use spatial::{
     set_spatial_relationship, spatial_query, transform_coordinates, validate_spatial,
     SpatialValidationIssue, SpatialValidationResult,
 };
+
+// Re-export bulk transform operations
+pub use transform::{
+    transform_entities, undo_transform, AffineTransform, TransformLog, TransformRecord,
+    TransformReport, TransformSelection,
+};
+
+// Re-export hierarchy integrity operations
+pub use hierarchy::{
+    check_hierarchy, repair_hierarchy, HierarchyIssue, HierarchyIssueKind, HierarchyReport,
+};
@@ -0,0 +1,381 @@
+//! Rule-driven bulk reclassification
+//!
+//! Objects imported before the type registry improved keep their old types. Ordered
+//! rules in `.arxos/reclassify.yaml` relabel them in bulk; the first rule that
+//! matches an object decides its type and later rules are not consulted:
+//!
+//! ```yaml
+//! rules:
+//!   - name: fume hoods imported as generic HVAC
+//!     when:
+//!       type: hvac
+//!       name_pattern: "^FH-"
+//!       property: { key: face_velocity }
+//!       near: { type: plumbing, within_m: 3 }
+//!     set: fume hood
+//! ```
+//!
+//! Every condition set on a rule must hold. `set` is resolved through the equipment
+//! type registry, so a custom type carries its system with it. [`plan_reclassification`]
+//! only reports changes; [`apply_reclassification`] makes them.
+
+use std::path::Path;
+
+use regex::Regex;
+use serde::{Deserialize, Serialize};
+
+use crate::core::equipment_types::EquipmentTypeRegistry;
+use crate::core::{Building, Equipment, EquipmentType};
+
+/// Project file holding reclassification rules.
+pub const RECLASSIFY_FILE: &str = ".arxos/reclassify.yaml";
+
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct PropertyMatch {
+    pub key: String,
+    /// Exact value (case-insensitive); any value when unset.
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub value: Option<String>,
+}
+
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct NeighborMatch {
+    #[serde(rename = "type")]
+    pub equipment_type: String,
+    pub within_m: f64,
+}
+
+/// Conditions of one rule; unset conditions always hold.
+#[derive(Debug, Clone, Default, Serialize, Deserialize)]
+pub struct RuleMatch {
+    /// Current type name.
+    #[serde(default, rename = "type", skip_serializing_if = "Option::is_none")]
+    pub equipment_type: Option<String>,
+    /// Regex over the equipment name (the drawing symbol or tag).
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub name_pattern: Option<String>,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub property: Option<PropertyMatch>,
+    /// Equipment of this type on the same floor within the distance.
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub near: Option<NeighborMatch>,
+}
+
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct ReclassifyRule {
+    pub name: String,
+    #[serde(default)]
+    pub when: RuleMatch,
+    /// Type to assign.
+    pub set: String,
+}
+
+#[derive(Debug, Clone, Default, Serialize, Deserialize)]
+pub struct ReclassifyRules {
+    #[serde(default)]
+    pub rules: Vec<ReclassifyRule>,
+}
+
+/// One planned type change.
+#[derive(Debug, Clone, PartialEq, Serialize)]
+pub struct Reclassification {
+    pub equipment_id: String,
+    pub name: String,
+    pub from: EquipmentType,
+    pub to: EquipmentType,
+    pub rule: String,
+}
+
+struct CompiledRule<'a> {
+    rule: &'a ReclassifyRule,
+    name_pattern: Option<Regex>,
+    current: Option<EquipmentType>,
+    near: Option<(EquipmentType, f64)>,
+    target: EquipmentType,
+}
+
+fn distance(a: &Equipment, b: &Equipment) -> f64 {
+    let (p, q) = (&a.position, &b.position);
+    ((p.x - q.x).powi(2) + (p.y - q.y).powi(2) + (p.z - q.z).powi(2)).sqrt()
+}
+
+/// Type equality, ignoring case between unregistered `Other` names.
+fn same_type(a: &EquipmentType, b: &EquipmentType) -> bool {
+    match (a, b) {
+        (EquipmentType::Other(x), EquipmentType::Other(y)) => x.eq_ignore_ascii_case(y),
+        _ => a == b,
+    }
+}
+
+impl CompiledRule<'_> {
+    fn matches(&self, eq: &Equipment, floor: &[&Equipment]) -> bool {
+        if self
+            .current
+            .as_ref()
+            .is_some_and(|t| !same_type(t, &eq.equipment_type))
+        {
+            return false;
+        }
+        if self
+            .name_pattern
+            .as_ref()
+            .is_some_and(|re| !re.is_match(&eq.name))
+        {
+            return false;
+        }
+        if let Some(property) = &self.rule.when.property {
+            match eq.properties.get(&property.key) {
+                None => return false,
+                Some(value) => {
+                    if property
+                        .value
+                        .as_ref()
+                        .is_some_and(|want| !want.eq_ignore_ascii_case(value.trim()))
+                    {
+                        return false;
+                    }
+                }
+            }
+        }
+        if let Some((near_type, within_m)) = &self.near {
+            let found = floor.iter().any(|other| {
+                other.id != eq.id
+                    && same_type(&other.equipment_type, near_type)
+                    && distance(eq, other) <= *within_m
+            });
+            if !found {
+                return false;
+            }
+        }
+        true
+    }
+}
+
+impl ReclassifyRules {
+    /// Load `.arxos/reclassify.yaml` under `base`; a missing file yields no rules.
+    pub fn load(base: &Path) -> Result<Self, String> {
+        let path = base.join(RECLASSIFY_FILE);
+        if !path.exists() {
+            return Ok(Self::default());
+        }
+        let content = std::fs::read_to_string(&path)
+            .map_err(|e| format!("read {}: {}", path.display(), e))?;
+        let rules: ReclassifyRules = serde_yaml::from_str(&content)
+            .map_err(|e| format!("parse {}: {}", path.display(), e))?;
+        rules.check()?;
+        Ok(rules)
+    }
+
+    pub fn check(&self) -> Result<(), String> {
+        self.compile(&EquipmentTypeRegistry::default()).map(|_| ())
+    }
+
+    fn compile<'a>(
+        &'a self,
+        registry: &EquipmentTypeRegistry,
+    ) -> Result<Vec<CompiledRule<'a>>, String> {
+        self.rules
+            .iter()
+            .map(|rule| {
+                if rule.set.trim().is_empty() {
+                    return Err(format!("rule '{}': set must not be empty", rule.name));
+                }
+                let name_pattern = rule
+                    .when
+                    .name_pattern
+                    .as_deref()
+                    .map(Regex::new)
+                    .transpose()
+                    .map_err(|e| format!("rule '{}': name_pattern: {}", rule.name, e))?;
+                let near = match &rule.when.near {
+                    Some(near) if !near.within_m.is_finite() || near.within_m <= 0.0 => {
+                        return Err(format!(
+                            "rule '{}': near.within_m must be positive",
+                            rule.name
+                        ));
+                    }
+                    Some(near) => Some((registry.resolve(&near.equipment_type), near.within_m)),
+                    None => None,
+                };
+                Ok(CompiledRule {
+                    rule,
+                    name_pattern,
+                    current: rule
+                        .when
+                        .equipment_type
+                        .as_deref()
+                        .map(|t| registry.resolve(t)),
+                    near,
+                    target: registry.resolve(&rule.set),
+                })
+            })
+            .collect()
+    }
+}
+
+/// Type changes `rules` would make, first matching rule per object. Objects already
+/// of the matched rule's type are not listed.
+pub fn plan_reclassification(
+    building: &Building,
+    registry: &EquipmentTypeRegistry,
+    rules: &ReclassifyRules,
+) -> Result<Vec<Reclassification>, String> {
+    let compiled = rules.compile(registry)?;
+    let mut changes = Vec::new();
+    for floor in &building.floors {
+        let equipment: Vec<&Equipment> = floor
+            .equipment
+            .iter()
+            .chain(floor.wings.iter().flat_map(|w| {
+                w.equipment
+                    .iter()
+                    .chain(w.rooms.iter().flat_map(|r| r.equipment.iter()))
+            }))
+            .collect();
+        for eq in &equipment {
+            let Some(rule) = compiled.iter().find(|r| r.matches(eq, &equipment)) else {
+                continue;
+            };
+            if !same_type(&rule.target, &eq.equipment_type) {
+                changes.push(Reclassification {
+                    equipment_id: eq.id.clone(),
+                    name: eq.name.clone(),
+                    from: eq.equipment_type.clone(),
+                    to: rule.target.clone(),
+                    rule: rule.rule.name.clone(),
+                });
+            }
+        }
+    }
+    Ok(changes)
+}
+
+/// Apply planned changes; returns how many objects changed type.
+pub fn apply_reclassification(building: &mut Building, changes: &[Reclassification]) -> usize {
+    let mut applied = 0;
+    for change in changes {
+        if let Some(eq) = building.find_equipment_mut(&change.equipment_id) {
+            if eq.equipment_type == change.from {
+                eq.equipment_type = change.to.clone();
+                applied += 1;
+            }
+        }
+    }
+    if applied > 0 {
+        building.updated_at = chrono::Utc::now();
+    }
+    applied
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::core::Floor;
+
+    fn rule(name: &str, when: RuleMatch, set: &str) -> ReclassifyRule {
+        ReclassifyRule {
+            name: name.into(),
+            when,
+            set: set.into(),
+        }
+    }
+
+    #[test]
+    fn first_matching_rule_wins_and_plan_changes_nothing() {
+        let mut building = Building::new("Lab".into(), "/lab".into());
+        let mut floor = Floor::new("Ground".into(), 0);
+        let mut hood = Equipment::new("FH-1".into(), "/fh-1".into(), EquipmentType::HVAC);
+        hood.add_property("face_velocity".into(), "0.5".into());
+        let mut sink = Equipment::new("Sink".into(), "/sink".into(), EquipmentType::Plumbing);
+        sink.position.x = 1.0;
+        let ahu = Equipment::new("FH-2".into(), "/fh-2".into(), EquipmentType::HVAC);
+        let mut far = Equipment::new(
+            "AP-9".into(),
+            "/ap-9".into(),
+            EquipmentType::Other("WAP".into()),
+        );
+        far.position.x = 40.0;
+        floor.equipment.extend([hood, sink, ahu, far]);
+        building.add_floor(floor);
+
+        let rules = ReclassifyRules {
+            rules: vec![
+                rule(
+                    "hood by sink",
+                    RuleMatch {
+                        equipment_type: Some("hvac".into()),
+                        name_pattern: Some("^FH-".into()),
+                        property: Some(PropertyMatch {
+                            key: "face_velocity".into(),
+                            value: None,
+                        }),
+                        near: Some(NeighborMatch {
+                            equipment_type: "plumbing".into(),
+                            within_m: 3.0,
+                        }),
+                    },
+                    "Fume Hood",
+                ),
+                // Also matches FH-1, but the first rule already decided it.
+                rule(
+                    "any FH tag",
+                    RuleMatch {
+                        name_pattern: Some("^FH-".into()),
+                        ..Default::default()
+                    },
+                    "safety",
+                ),
+                rule(
+                    "access points",
+                    RuleMatch {
+                        equipment_type: Some("wap".into()),
+                        ..Default::default()
+                    },
+                    "network",
+                ),
+            ],
+        };
+        let registry = EquipmentTypeRegistry::default();
+        let before = building.clone();
+        let plan = plan_reclassification(&building, &registry, &rules).unwrap();
+        let summary: Vec<(&str, String, &str)> = plan
+            .iter()
+            .map(|c| (c.name.as_str(), c.to.to_string(), c.rule.as_str()))
+            .collect();
+        assert_eq!(
+            summary,
+            vec![
+                ("FH-1", "Fume Hood".to_string(), "hood by sink"),
+                ("FH-2", "Safety".to_string(), "any FH tag"),
+                ("AP-9", "Network".to_string(), "access points"),
+            ]
+        );
+        // Planning is a dry run.
+        for (a, b) in building
+            .get_all_equipment()
+            .iter()
+            .zip(before.get_all_equipment())
+        {
+            assert_eq!(a.equipment_type, b.equipment_type);
+        }
+
+        assert_eq!(apply_reclassification(&mut building, &plan), 3);
+        assert!(plan_reclassification(&building, &registry, &rules)
+            .unwrap()
+            .iter()
+            .all(|c| c.name != "AP-9"));
+        assert_eq!(apply_reclassification(&mut building, &plan), 0);
+
+        let bad = ReclassifyRules {
+            rules: vec![rule(
+                "broken",
+                RuleMatch {
+                    name_pattern: Some("(".into()),
+                    ..Default::default()
+                },
+                "hvac",
+            )],
+        };
+        assert!(bad.check().is_err());
+    }
+}
@@ -6,6 +6,7 @@
 use crate::core::Room;
 use crate::ingest::persist_building_at;
 use crate::persistence::{load_building_data_from_dir, PersistenceManager};
+use crate::validation::{BoundsConfig, PropertySchemas};
 use std::collections::HashMap;
 
 /// Create a room in a building
@@ -20,6 +21,8 @@ pub fn create_room(
     let base = persistence.base_path().to_path_buf();
     let mut building = persistence.load_building_data()?;
     let room_name = room.name.clone();
+    PropertySchemas::load(&base)?.gate_room(&room)?;
+    BoundsConfig::load(&base)?.gate_room(&building, &room)?;
 
     let floor = if let Some(floor) = building.find_floor_mut(floor_level) {
         floor
@@ -115,6 +118,7 @@ pub fn update_room_impl(
         room.properties.insert(key.clone(), value.clone());
     }
     let updated_room = room.clone();
+    PropertySchemas::load(&base)?.gate_room(&updated_room)?;
 
     let building = persist_building_at(
         base,
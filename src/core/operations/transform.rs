@@ -0,0 +1,759 @@
+//! Bulk repositioning of rooms and equipment
+//!
+//! Applies a planar affine transform (uniform scale and rotation about a pivot,
+//! then translation) to a selection of entities. Used to realign a mislaid import
+//! against an existing floor without re-importing.
+//!
+//! Applied transforms are recorded in `.arxos/transforms.yaml` with the IDs of the
+//! entities they moved; [`undo_transform`] moves exactly those entities back.
+
+use std::collections::HashSet;
+use std::path::Path;
+
+use chrono::{DateTime, Utc};
+use serde::{Deserialize, Serialize};
+
+use crate::core::spatial::Point3D;
+use crate::core::types::{BoundingBox, Position};
+use crate::core::{Building, Equipment, Room};
+
+/// Project file recording applied transforms.
+pub const TRANSFORM_LOG_FILE: &str = ".arxos/transforms.yaml";
+
+/// Most operations kept in the transform log; older ones can no longer be undone.
+pub const MAX_TRANSFORM_LOG: usize = 100;
+
+/// Affine transform in the XY plane with an optional Z offset.
+///
+/// Points are scaled and rotated (counter-clockwise, degrees) about `pivot`,
+/// then translated by `translation`.
+#[derive(Debug, Clone, PartialEq, Serialize, Deserialize)]
+#[serde(default)]
+pub struct AffineTransform {
+    pub translation: Point3D,
+    pub rotation_degrees: f64,
+    pub pivot: Point3D,
+    pub scale: f64,
+}
+
+impl Default for AffineTransform {
+    fn default() -> Self {
+        Self {
+            translation: Point3D::new(0.0, 0.0, 0.0),
+            rotation_degrees: 0.0,
+            pivot: Point3D::new(0.0, 0.0, 0.0),
+            scale: 1.0,
+        }
+    }
+}
+
+impl AffineTransform {
+    pub fn validate(&self) -> Result<(), String> {
+        if !self.scale.is_finite() || self.scale <= 0.0 {
+            return Err(format!(
+                "Scale must be a positive number, got {}",
+                self.scale
+            ));
+        }
+        if !self.rotation_degrees.is_finite() {
+            return Err("Rotation must be a finite number of degrees".to_string());
+        }
+        Ok(())
+    }
+
+    pub fn is_identity(&self) -> bool {
+        self.translation == Point3D::new(0.0, 0.0, 0.0)
+            && self.rotation_degrees % 360.0 == 0.0
+            && self.scale == 1.0
+    }
+
+    /// Whether the rotation is an odd multiple of 90°, which swaps width and depth.
+    fn is_quarter_turn(&self) -> bool {
+        let turns = self.rotation_degrees / 90.0;
+        (turns - turns.round()).abs() < 1e-9 && turns.round().rem_euclid(2.0) == 1.0
+    }
+
+    /// Apply the transform to a single point.
+    pub fn apply(&self, x: f64, y: f64, z: f64) -> (f64, f64, f64) {
+        let (sin, cos) = self.rotation_degrees.to_radians().sin_cos();
+        let dx = (x - self.pivot.x) * self.scale;
+        let dy = (y - self.pivot.y) * self.scale;
+        (
+            self.pivot.x + dx * cos - dy * sin + self.translation.x,
+            self.pivot.y + dx * sin + dy * cos + self.translation.y,
+            z + self.translation.z,
+        )
+    }
+
+    /// Transform that undoes this one.
+    pub fn inverse(&self) -> Self {
+        // Undo translation first: the pivot moves with it.
+        Self {
+            translation: Point3D::new(
+                -self.translation.x,
+                -self.translation.y,
+                -self.translation.z,
+            ),
+            rotation_degrees: -self.rotation_degrees,
+            pivot: Point3D::new(
+                self.pivot.x + self.translation.x,
+                self.pivot.y + self.translation.y,
+                self.pivot.z + self.translation.z,
+            ),
+            scale: 1.0 / self.scale,
+        }
+    }
+
+    fn apply_position(&self, position: &mut Position) {
+        let (x, y, z) = self.apply(position.x, position.y, position.z);
+        position.x = x;
+        position.y = y;
+        position.z = z;
+    }
+
+    fn apply_point(&self, point: &mut Point3D) {
+        let (x, y, z) = self.apply(point.x, point.y, point.z);
+        point.x = x;
+        point.y = y;
+        point.z = z;
+    }
+
+    /// Transform an axis-aligned box; rotated corners are re-enclosed.
+    fn apply_bounding_box(&self, bbox: &mut BoundingBox) {
+        let corners = [
+            (bbox.min.x, bbox.min.y),
+            (bbox.max.x, bbox.min.y),
+            (bbox.min.x, bbox.max.y),
+            (bbox.max.x, bbox.max.y),
+        ];
+        let moved: Vec<(f64, f64, f64)> = corners
+            .iter()
+            .map(|(x, y)| self.apply(*x, *y, 0.0))
+            .collect();
+        bbox.min.x = moved.iter().map(|p| p.0).fold(f64::INFINITY, f64::min);
+        bbox.min.y = moved.iter().map(|p| p.1).fold(f64::INFINITY, f64::min);
+        bbox.max.x = moved.iter().map(|p| p.0).fold(f64::NEG_INFINITY, f64::max);
+        bbox.max.y = moved.iter().map(|p| p.1).fold(f64::NEG_INFINITY, f64::max);
+        bbox.min.z += self.translation.z;
+        bbox.max.z += self.translation.z;
+    }
+}
+
+/// Which entities a transform applies to. Criteria are combined with AND;
+/// an empty selection matches nothing.
+#[derive(Debug, Clone, Default, Serialize, Deserialize)]
+#[serde(default)]
+pub struct TransformSelection {
+    /// Room or equipment IDs (or names)
+    pub ids: Vec<String>,
+    /// Restrict to one floor level
+    pub floor: Option<i32>,
+    /// XY window `(min, max)`; entities whose position lies inside are selected
+    pub bbox: Option<(Point3D, Point3D)>,
+}
+
+impl TransformSelection {
+    fn is_empty(&self) -> bool {
+        self.ids.is_empty() && self.floor.is_none() && self.bbox.is_none()
+    }
+
+    fn matches(&self, id: &str, name: &str, position: &Position) -> bool {
+        if !self.ids.is_empty() && !self.ids.iter().any(|s| s == id || s == name) {
+            return false;
+        }
+        if let Some((min, max)) = &self.bbox {
+            let inside = position.x >= min.x
+                && position.x <= max.x
+                && position.y >= min.y
+                && position.y <= max.y;
+            if !inside {
+                return false;
+            }
+        }
+        true
+    }
+}
+
+/// Outcome of [`transform_entities`].
+#[derive(Debug, Clone, Default, Serialize)]
+pub struct TransformReport {
+    pub rooms_moved: Vec<String>,
+    pub equipment_moved: Vec<String>,
+    /// Entities that ended up outside their floor's bounding box
+    pub warnings: Vec<String>,
+}
+
+impl TransformReport {
+    pub fn total(&self) -> usize {
+        self.rooms_moved.len() + self.equipment_moved.len()
+    }
+}
+
+/// Apply `transform` to every room and equipment matched by `selection`.
+///
+/// Equipment inside a selected room moves with it and is bounds-checked like any
+/// other moved equipment. The building is mutated in place; callers persist through `persist_building_at` and then add the
+/// operation to the [`TransformLog`] so it can be undone.
+pub fn transform_entities(
+    building: &mut Building,
+    selection: &TransformSelection,
+    transform: &AffineTransform,
+) -> Result<TransformReport, String> {
+    transform.validate()?;
+    if selection.is_empty() {
+        return Err("Selection is empty: pass IDs, a floor, or a bounding box".to_string());
+    }
+
+    let mut report = TransformReport::default();
+    let mut moved: HashSet<String> = HashSet::new();
+
+    for floor in &mut building.floors {
+        if selection.floor.is_some_and(|level| level != floor.level) {
+            continue;
+        }
+        let floor_bounds = floor.bounding_box.clone();
+
+        for wing in &mut floor.wings {
+            for room in &mut wing.rooms {
+                if selection.matches(&room.id, &room.name, &room.spatial_properties.position) {
+                    transform_room(room, transform);
+                    moved.insert(room.id.clone());
+                    report.rooms_moved.push(room.id.clone());
+                    let position = &room.spatial_properties.position;
+                    check_bounds(&floor_bounds, &room.name, position, &mut report);
+                    for equipment in &mut room.equipment {
+                        if moved.insert(equipment.id.clone()) {
+                            move_equipment(equipment, transform, &floor_bounds, &mut report);
+                        }
+                    }
+                } else {
+                    for equipment in &mut room.equipment {
+                        if selection.matches(&equipment.id, &equipment.name, &equipment.position)
+                            && moved.insert(equipment.id.clone())
+                        {
+                            move_equipment(equipment, transform, &floor_bounds, &mut report);
+                        }
+                    }
+                }
+            }
+            for equipment in &mut wing.equipment {
+                if selection.matches(&equipment.id, &equipment.name, &equipment.position)
+                    && moved.insert(equipment.id.clone())
+                {
+                    move_equipment(equipment, transform, &floor_bounds, &mut report);
+                }
+            }
+        }
+
+        for equipment in &mut floor.equipment {
+            if selection.matches(&equipment.id, &equipment.name, &equipment.position)
+                && moved.insert(equipment.id.clone())
+            {
+                move_equipment(equipment, transform, &floor_bounds, &mut report);
+            }
+        }
+    }
+
+    Ok(report)
+}
+
+/// Move the rooms and equipment `record` moved back to where they were.
+///
+/// Only the recorded entities move, each at most once, so equipment placed in a
+/// room afterwards stays put. Entities deleted since are reported as warnings.
+pub fn undo_transform(
+    building: &mut Building,
+    record: &TransformRecord,
+) -> Result<TransformReport, String> {
+    let inverse = record.transform.inverse();
+    inverse.validate()?;
+    let rooms: HashSet<&str> = record.rooms.iter().map(String::as_str).collect();
+    let equipment: HashSet<&str> = record.equipment.iter().map(String::as_str).collect();
+    let mut report = TransformReport::default();
+    let mut moved: HashSet<String> = HashSet::new();
+
+    for floor in &mut building.floors {
+        let floor_bounds = floor.bounding_box.clone();
+        for wing in &mut floor.wings {
+            for room in &mut wing.rooms {
+                if rooms.contains(room.id.as_str()) && moved.insert(room.id.clone()) {
+                    transform_room(room, &inverse);
+                    report.rooms_moved.push(room.id.clone());
+                    let position = &room.spatial_properties.position;
+                    check_bounds(&floor_bounds, &room.name, position, &mut report);
+                }
+                for eq in &mut room.equipment {
+                    if equipment.contains(eq.id.as_str()) && moved.insert(eq.id.clone()) {
+                        move_equipment(eq, &inverse, &floor_bounds, &mut report);
+                    }
+                }
+            }
+            for eq in &mut wing.equipment {
+                if equipment.contains(eq.id.as_str()) && moved.insert(eq.id.clone()) {
+                    move_equipment(eq, &inverse, &floor_bounds, &mut report);
+                }
+            }
+        }
+        for eq in &mut floor.equipment {
+            if equipment.contains(eq.id.as_str()) && moved.insert(eq.id.clone()) {
+                move_equipment(eq, &inverse, &floor_bounds, &mut report);
+            }
+        }
+    }
+
+    let missing = (rooms.len() + equipment.len()).saturating_sub(report.total());
+    if missing > 0 {
+        report
+            .warnings
+            .push(format!("{} moved object(s) no longer exist", missing));
+    }
+    Ok(report)
+}
+
+/// An applied transform and the entities it moved.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct TransformRecord {
+    pub id: String,
+    pub building_id: String,
+    pub applied_at: DateTime<Utc>,
+    pub transform: AffineTransform,
+    #[serde(default)]
+    pub rooms: Vec<String>,
+    #[serde(default)]
+    pub equipment: Vec<String>,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub undone_at: Option<DateTime<Utc>>,
+}
+
+impl TransformRecord {
+    fn moves_any_of(&self, other: &TransformRecord) -> bool {
+        self.rooms.iter().any(|id| other.rooms.contains(id))
+            || self.equipment.iter().any(|id| other.equipment.contains(id))
+    }
+}
+
+/// Applied transforms, oldest first (`.arxos/transforms.yaml`).
+#[derive(Debug, Clone, Default, Serialize, Deserialize)]
+pub struct TransformLog {
+    #[serde(default)]
+    pub operations: Vec<TransformRecord>,
+}
+
+impl TransformLog {
+    /// Load the log under `base`; a missing file is an empty log.
+    pub fn load(base: &Path) -> Result<Self, String> {
+        let path = base.join(TRANSFORM_LOG_FILE);
+        if !path.exists() {
+            return Ok(Self::default());
+        }
+        let content = std::fs::read_to_string(&path)
+            .map_err(|e| format!("read {}: {}", path.display(), e))?;
+        serde_yaml::from_str(&content).map_err(|e| format!("parse {}: {}", path.display(), e))
+    }
+
+    /// Replace the log atomically, so a crash mid-write cannot lose undo history.
+    pub fn save(&self, base: &Path) -> Result<(), String> {
+        let path = base.join(TRANSFORM_LOG_FILE);
+        if let Some(parent) = path.parent() {
+            std::fs::create_dir_all(parent)
+                .map_err(|e| format!("create {}: {}", parent.display(), e))?;
+        }
+        let content = serde_yaml::to_string(self).map_err(|e| e.to_string())?;
+        let tmp = path.with_extension(format!("yaml.{}.tmp", uuid::Uuid::new_v4().simple()));
+        std::fs::write(&tmp, content).map_err(|e| format!("write {}: {}", tmp.display(), e))?;
+        std::fs::rename(&tmp, &path).map_err(|e| format!("replace {}: {}", path.display(), e))
+    }
+
+    /// Add an applied transform, dropping the oldest beyond [`MAX_TRANSFORM_LOG`].
+    pub fn record(
+        &mut self,
+        building_id: &str,
+        transform: &AffineTransform,
+        report: &TransformReport,
+    ) -> TransformRecord {
+        let record = TransformRecord {
+            id: uuid::Uuid::new_v4().to_string(),
+            building_id: building_id.to_string(),
+            applied_at: Utc::now(),
+            transform: transform.clone(),
+            rooms: report.rooms_moved.clone(),
+            equipment: report.equipment_moved.clone(),
+            undone_at: None,
+        };
+        self.operations.push(record.clone());
+        let excess = self.operations.len().saturating_sub(MAX_TRANSFORM_LOG);
+        self.operations.drain(..excess);
+        record
+    }
+
+    /// The operation `id`, or the building's latest one when `None`, if it can be
+    /// undone: not undone yet, and no later operation moved the same entities.
+    pub fn undoable(
+        &self,
+        building_id: &str,
+        id: Option<&str>,
+    ) -> Result<&TransformRecord, String> {
+        let active: Vec<&TransformRecord> = self
+            .operations
+            .iter()
+            .filter(|op| op.building_id == building_id && op.undone_at.is_none())
+            .collect();
+        let index = match id {
+            Some(id) => active
+                .iter()
+                .position(|op| op.id == id)
+                .ok_or_else(|| format!("No transform '{}' to undo", id))?,
+            None => active
+                .len()
+                .checked_sub(1)
+                .ok_or_else(|| "No transform to undo".to_string())?,
+        };
+        let record = active[index];
+        if let Some(later) = active[index + 1..]
+            .iter()
+            .find(|op| op.moves_any_of(record))
+        {
+            return Err(format!(
+                "Transform '{}' moved the same objects later; undo it first",
+                later.id
+            ));
+        }
+        Ok(record)
+    }
+
+    pub fn mark_undone(&mut self, id: &str) {
+        if let Some(op) = self.operations.iter_mut().find(|op| op.id == id) {
+            op.undone_at = Some(Utc::now());
+        }
+    }
+}
+
+fn transform_room(room: &mut Room, transform: &AffineTransform) {
+    let props = &mut room.spatial_properties;
+    transform.apply_position(&mut props.position);
+    transform.apply_bounding_box(&mut props.bounding_box);
+    props.dimensions.width *= transform.scale;
+    props.dimensions.depth *= transform.scale;
+    if transform.is_quarter_turn() {
+        let dims = &mut props.dimensions;
+        std::mem::swap(&mut dims.width, &mut dims.depth);
+    }
+    if let Some(mesh) = &mut props.mesh {
+        mesh.vertices
+            .iter_mut()
+            .for_each(|v| transform.apply_point(v));
+    }
+    room.updated_at = Some(chrono::Utc::now());
+}
+
+fn move_equipment(
+    equipment: &mut Equipment,
+    transform: &AffineTransform,
+    floor_bounds: &Option<crate::core::spatial::BoundingBox3D>,
+    report: &mut TransformReport,
+) {
+    transform_equipment(equipment, transform);
+    report.equipment_moved.push(equipment.id.clone());
+    check_bounds(floor_bounds, &equipment.name, &equipment.position, report);
+}
+
+fn transform_equipment(equipment: &mut Equipment, transform: &AffineTransform) {
+    transform.apply_position(&mut equipment.position);
+    if let Some(mesh) = &mut equipment.mesh {
+        mesh.vertices
+            .iter_mut()
+            .for_each(|v| transform.apply_point(v));
+    }
+}
+
+fn check_bounds(
+    floor_bounds: &Option<crate::core::spatial::BoundingBox3D>,
+    name: &str,
+    position: &Position,
+    report: &mut TransformReport,
+) {
+    if let Some(bounds) = floor_bounds {
+        let inside = position.x >= bounds.min.x
+            && position.x <= bounds.max.x
+            && position.y >= bounds.min.y
+            && position.y <= bounds.max.y;
+        if !inside {
+            report.warnings.push(format!(
+                "'{}' moved outside floor bounds to ({:.2}, {:.2})",
+                name, position.x, position.y
+            ));
+        }
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::core::{EquipmentType, Floor, RoomType, Wing};
+
+    fn pos(x: f64, y: f64) -> Position {
+        Position {
+            x,
+            y,
+            z: 0.0,
+            coordinate_system: "building_local".to_string(),
+        }
+    }
+
+    fn sample_building() -> Building {
+        let mut building = Building::new("Test".to_string(), "/test".to_string());
+        let mut floor = Floor::new("Ground".to_string(), 0);
+        let mut wing = Wing::new("East".to_string());
+        let mut room = Room::new("Room A".to_string(), RoomType::Office);
+        room.spatial_properties.position = pos(2.0, 1.0);
+        room.spatial_properties.bounding_box = BoundingBox::new(pos(1.0, 0.0), pos(3.0, 2.0));
+        let mut inner = Equipment::new(
+            "VAV-1".to_string(),
+            "/eq/vav".to_string(),
+            EquipmentType::HVAC,
+        );
+        inner.position = pos(2.0, 2.0);
+        room.equipment.push(inner);
+        wing.add_room(room);
+        floor.add_wing(wing);
+        let mut loose = Equipment::new(
+            "Panel".to_string(),
+            "/eq/panel".to_string(),
+            EquipmentType::Electrical,
+        );
+        loose.position = pos(5.0, 5.0);
+        floor.equipment.push(loose);
+        building.add_floor(floor);
+        building
+    }
+
+    fn assert_close(actual: f64, expected: f64) {
+        assert!(
+            (actual - expected).abs() < 1e-9,
+            "{} != {}",
+            actual,
+            expected
+        );
+    }
+
+    #[test]
+    fn rotate_90_about_pivot_moves_room_and_contents() {
+        let mut building = sample_building();
+        let transform = AffineTransform {
+            rotation_degrees: 90.0,
+            pivot: Point3D::new(1.0, 1.0, 0.0),
+            ..Default::default()
+        };
+        let selection = TransformSelection {
+            ids: vec!["Room A".to_string()],
+            ..Default::default()
+        };
+
+        let report = transform_entities(&mut building, &selection, &transform).unwrap();
+        assert_eq!(report.rooms_moved.len(), 1);
+        assert_eq!(report.equipment_moved.len(), 1);
+
+        let room = &building.floors[0].wings[0].rooms[0];
+        assert_close(room.spatial_properties.position.x, 1.0);
+        assert_close(room.spatial_properties.position.y, 2.0);
+        assert_close(room.spatial_properties.bounding_box.min.x, 0.0);
+        assert_close(room.spatial_properties.bounding_box.max.y, 3.0);
+        let vav = &room.equipment[0];
+        assert_close(vav.position.x, 0.0);
+        assert_close(vav.position.y, 2.0);
+
+        let panel = &building.floors[0].equipment[0];
+        assert_close(panel.position.x, 5.0);
+    }
+
+    #[test]
+    fn translate_by_bbox_selection_and_inverse_restores() {
+        let mut building = sample_building();
+        let transform = AffineTransform {
+            translation: Point3D::new(10.0, -2.0, 0.5),
+            ..Default::default()
+        };
+        let selection = TransformSelection {
+            bbox: Some((Point3D::new(4.0, 4.0, 0.0), Point3D::new(6.0, 6.0, 0.0))),
+            ..Default::default()
+        };
+
+        let report = transform_entities(&mut building, &selection, &transform).unwrap();
+        assert_eq!(report.total(), 1);
+        let panel = &building.floors[0].equipment[0];
+        assert_close(panel.position.x, 15.0);
+        assert_close(panel.position.y, 3.0);
+        assert_close(panel.position.z, 0.5);
+
+        let undo = TransformSelection {
+            ids: vec!["Panel".to_string()],
+            ..Default::default()
+        };
+        transform_entities(&mut building, &undo, &transform.inverse()).unwrap();
+        let panel = &building.floors[0].equipment[0];
+        assert_close(panel.position.x, 5.0);
+        assert_close(panel.position.y, 5.0);
+        assert_close(panel.position.z, 0.0);
+    }
+
+    #[test]
+    fn quarter_turn_swaps_dimensions_and_undo_restores() {
+        let mut building = sample_building();
+        let room = &mut building.floors[0].wings[0].rooms[0];
+        room.spatial_properties.dimensions.width = 2.0;
+        room.spatial_properties.dimensions.depth = 4.0;
+        let transform = AffineTransform {
+            translation: Point3D::new(3.0, 0.0, 0.0),
+            rotation_degrees: 90.0,
+            pivot: Point3D::new(1.0, 1.0, 0.0),
+            ..Default::default()
+        };
+        let selection = TransformSelection {
+            ids: vec!["Room A".to_string(), "Panel".to_string()],
+            ..Default::default()
+        };
+
+        let report = transform_entities(&mut building, &selection, &transform).unwrap();
+        let room = &building.floors[0].wings[0].rooms[0];
+        assert_close(room.spatial_properties.dimensions.width, 4.0);
+        assert_close(room.spatial_properties.dimensions.depth, 2.0);
+
+        let temp = tempfile::tempdir().unwrap();
+        let mut log = TransformLog::default();
+        let record = log.record(&building.id, &transform, &report);
+        log.save(temp.path()).unwrap();
+        let mut log = TransformLog::load(temp.path()).unwrap();
+
+        // Equipment placed in the room after the move is not part of the undo.
+        let mut late = Equipment::new(
+            "VAV-2".to_string(),
+            "/eq/vav2".to_string(),
+            EquipmentType::HVAC,
+        );
+        late.position = pos(7.0, 7.0);
+        building.floors[0].wings[0].rooms[0].equipment.push(late);
+
+        let undo = log.undoable(&building.id, None).unwrap().clone();
+        assert_eq!(undo.id, record.id);
+        let undone = undo_transform(&mut building, &undo).unwrap();
+        assert_eq!(undone.total(), report.total());
+        log.mark_undone(&undo.id);
+        assert!(log.undoable(&building.id, None).is_err());
+
+        let room = &building.floors[0].wings[0].rooms[0];
+        let props = &room.spatial_properties;
+        assert_close(props.position.x, 2.0);
+        assert_close(props.position.y, 1.0);
+        assert_close(props.bounding_box.min.x, 1.0);
+        assert_close(props.bounding_box.min.y, 0.0);
+        assert_close(props.bounding_box.max.x, 3.0);
+        assert_close(props.bounding_box.max.y, 2.0);
+        assert_close(props.dimensions.width, 2.0);
+        assert_close(props.dimensions.depth, 4.0);
+        assert_close(room.equipment[0].position.x, 2.0);
+        assert_close(room.equipment[0].position.y, 2.0);
+        assert_close(room.equipment[1].position.x, 7.0);
+        let panel = &building.floors[0].equipment[0];
+        assert_close(panel.position.x, 5.0);
+        assert_close(panel.position.y, 5.0);
+    }
+
+    #[test]
+    fn undo_refuses_when_a_later_transform_moved_the_same_objects() {
+        let building = sample_building();
+        let report = TransformReport {
+            equipment_moved: vec!["panel-id".to_string()],
+            ..Default::default()
+        };
+        let shift = AffineTransform {
+            translation: Point3D::new(1.0, 0.0, 0.0),
+            ..Default::default()
+        };
+        let mut log = TransformLog::default();
+        let first = log.record(&building.id, &shift, &report);
+        let second = log.record(&building.id, &shift, &report);
+
+        assert!(log.undoable(&building.id, Some(&first.id)).is_err());
+        assert!(log.undoable("other-building", None).is_err());
+        log.mark_undone(&second.id);
+        assert_eq!(
+            log.undoable(&building.id, Some(&first.id)).unwrap().id,
+            first.id
+        );
+    }
+
+    #[test]
+    fn warns_when_leaving_floor_bounds() {
+        let mut building = sample_building();
+        building.floors[0].bounding_box = Some(crate::core::spatial::BoundingBox3D::new(
+            Point3D::new(0.0, 0.0, 0.0),
+            Point3D::new(10.0, 10.0, 3.0),
+        ));
+        let transform = AffineTransform {
+            translation: Point3D::new(20.0, 0.0, 0.0),
+            ..Default::default()
+        };
+        let selection = TransformSelection {
+            floor: Some(0),
+            ..Default::default()
+        };
+
+        // The room, the VAV carried with it, and the panel all end up outside.
+        let report = transform_entities(&mut building, &selection, &transform).unwrap();
+        assert_eq!(report.total(), 3);
+        assert_eq!(report.warnings.len(), 3);
+    }
+
+    #[test]
+    fn wing_equipment_moves_and_undo_moves_each_object_once() {
+        let mut building = sample_building();
+        let mut riser = Equipment::new(
+            "Riser".to_string(),
+            "/eq/riser".to_string(),
+            EquipmentType::Plumbing,
+        );
+        riser.position = pos(8.0, 1.0);
+        building.floors[0].wings[0].equipment.push(riser);
+        let transform = AffineTransform {
+            translation: Point3D::new(1.0, 0.0, 0.0),
+            ..Default::default()
+        };
+        let selection = TransformSelection {
+            floor: Some(0),
+            ..Default::default()
+        };
+
+        let report = transform_entities(&mut building, &selection, &transform).unwrap();
+        assert_eq!(report.total(), 4);
+        assert_close(building.floors[0].wings[0].equipment[0].position.x, 9.0);
+
+        // A record listing an object twice still moves it back once.
+        let mut log = TransformLog::default();
+        let mut record = log.record(&building.id, &transform, &report);
+        record.equipment.push(record.equipment[0].clone());
+        let undone = undo_transform(&mut building, &record).unwrap();
+        assert_eq!(undone.total(), 4);
+        assert!(undone.warnings.is_empty());
+        assert_close(building.floors[0].wings[0].equipment[0].position.x, 8.0);
+        assert_close(building.floors[0].equipment[0].position.x, 5.0);
+    }
+
+    #[test]
+    fn rejects_empty_selection_and_bad_scale() {
+        let mut building = sample_building();
+        let transform = AffineTransform::default();
+        assert!(
+            transform_entities(&mut building, &TransformSelection::default(), &transform).is_err()
+        );
+
+        let bad = AffineTransform {
+            scale: 0.0,
+            ..Default::default()
+        };
+        let selection = TransformSelection {
+            floor: Some(0),
+            ..Default::default()
+        };
+        assert!(transform_entities(&mut building, &selection, &bad).is_err());
+    }
+}
@@ -0,0 +1,195 @@
+//! Output precision for coordinates and dimensions.
+//!
+//! Geometry is held at full `f64` precision in memory, but unit conversions and
+//! transforms leave noise such as `0.30000000000000004` that churns diffs and
+//! inflates payloads. Values are rounded (half away from zero, not truncated) when
+//! the model is written to `building.yaml` and when objects are versioned for
+//! delta sync, so values equal within the precision never register as changes.
+//!
+//! Decimal places are configured per unit under `[building]` in config.toml:
+//! `length_decimals` (meters) and `angle_decimals` (radians).
+
+use std::sync::OnceLock;
+
+use super::spatial::{BoundingBox3D, Point3D};
+use super::{Anchor, BoundingBox, Building, Equipment, Position, Room};
+
+/// Default decimal places for lengths in meters (0.1 mm).
+pub const DEFAULT_LENGTH_DECIMALS: u32 = 4;
+/// Default decimal places for angles in radians (~0.0006°).
+pub const DEFAULT_ANGLE_DECIMALS: u32 = 5;
+/// Upper bound; beyond this rounding is a no-op for `f64`.
+pub const MAX_DECIMALS: u32 = 12;
+
+/// Round `value` to `decimals` places, half away from zero.
+pub fn round_to(value: f64, decimals: u32) -> f64 {
+    if !value.is_finite() {
+        return value;
+    }
+    let factor = 10f64.powi(decimals.min(MAX_DECIMALS) as i32);
+    let rounded = (value * factor).round() / factor;
+    // Normalize -0.0 so it serializes the same as 0.0.
+    if rounded == 0.0 {
+        0.0
+    } else {
+        rounded
+    }
+}
+
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub struct CoordinatePrecision {
+    pub length_decimals: u32,
+    pub angle_decimals: u32,
+}
+
+impl Default for CoordinatePrecision {
+    fn default() -> Self {
+        Self {
+            length_decimals: DEFAULT_LENGTH_DECIMALS,
+            angle_decimals: DEFAULT_ANGLE_DECIMALS,
+        }
+    }
+}
+
+impl CoordinatePrecision {
+    /// Precision from the user/project config, read once per process.
+    pub fn configured() -> Self {
+        static CONFIGURED: OnceLock<CoordinatePrecision> = OnceLock::new();
+        *CONFIGURED.get_or_init(|| {
+            crate::config::ConfigManager::new()
+                .map(|m| {
+                    let building = &m.get_config().building;
+                    Self {
+                        length_decimals: building.length_decimals,
+                        angle_decimals: building.angle_decimals,
+                    }
+                })
+                .unwrap_or_default()
+        })
+    }
+
+    pub fn length(&self, value: f64) -> f64 {
+        round_to(value, self.length_decimals)
+    }
+
+    pub fn angle(&self, value: f64) -> f64 {
+        round_to(value, self.angle_decimals)
+    }
+
+    /// Whether two lengths are indistinguishable at this precision.
+    pub fn lengths_equal(&self, a: f64, b: f64) -> bool {
+        self.length(a) == self.length(b)
+    }
+
+    fn position(&self, p: &mut Position) {
+        p.x = self.length(p.x);
+        p.y = self.length(p.y);
+        p.z = self.length(p.z);
+    }
+
+    fn point(&self, p: &mut Point3D) {
+        p.x = self.length(p.x);
+        p.y = self.length(p.y);
+        p.z = self.length(p.z);
+    }
+
+    fn bounding_box(&self, bbox: &mut BoundingBox) {
+        self.position(&mut bbox.min);
+        self.position(&mut bbox.max);
+    }
+
+    fn bounding_box_3d(&self, bbox: &mut BoundingBox3D) {
+        self.point(&mut bbox.min);
+        self.point(&mut bbox.max);
+    }
+
+    fn anchor(&self, anchor: &mut Anchor) {
+        self.position(&mut anchor.position);
+        for pose in &mut anchor.relative_poses {
+            pose.x = self.length(pose.x);
+            pose.y = self.length(pose.y);
+            pose.z = self.length(pose.z);
+            pose.roll = self.angle(pose.roll);
+            pose.pitch = self.angle(pose.pitch);
+            pose.yaw = self.angle(pose.yaw);
+        }
+    }
+
+    pub fn apply_equipment(&self, equipment: &mut Equipment) {
+        self.position(&mut equipment.position);
+    }
+
+    pub fn apply_room(&self, room: &mut Room) {
+        let spatial = &mut room.spatial_properties;
+        self.position(&mut spatial.position);
+        spatial.dimensions.width = self.length(spatial.dimensions.width);
+        spatial.dimensions.depth = self.length(spatial.dimensions.depth);
+        spatial.dimensions.height = self.length(spatial.dimensions.height);
+        self.bounding_box(&mut spatial.bounding_box);
+        room.anchors.iter_mut().for_each(|a| self.anchor(a));
+        room.equipment
+            .iter_mut()
+            .for_each(|e| self.apply_equipment(e));
+    }
+
+    /// Round every coordinate and dimension in the building. Meshes are left as-is.
+    pub fn apply_building(&self, building: &mut Building) {
+        building.anchors.iter_mut().for_each(|a| self.anchor(a));
+        for floor in &mut building.floors {
+            floor.elevation = floor.elevation.map(|e| self.length(e));
+            if let Some(bbox) = floor.bounding_box.as_mut() {
+                self.bounding_box_3d(bbox);
+            }
+            floor.anchors.iter_mut().for_each(|a| self.anchor(a));
+            floor
+                .equipment
+                .iter_mut()
+                .for_each(|e| self.apply_equipment(e));
+            for wing in &mut floor.wings {
+                wing.anchors.iter_mut().for_each(|a| self.anchor(a));
+                wing.equipment
+                    .iter_mut()
+                    .for_each(|e| self.apply_equipment(e));
+                wing.rooms.iter_mut().for_each(|r| self.apply_room(r));
+            }
+        }
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::core::{EquipmentType, Floor};
+
+    #[test]
+    fn rounds_half_away_from_zero() {
+        assert_eq!(round_to(0.1 + 0.2, 4), 0.3);
+        assert_eq!(round_to(1.23456, 4), 1.2346);
+        assert_eq!(round_to(1.23454, 4), 1.2345);
+        assert_eq!(round_to(-2.5, 0), -3.0);
+        assert_eq!(round_to(2.5, 0), 3.0);
+        assert_eq!(round_to(-0.00001, 3).to_string(), "0");
+        assert!(round_to(f64::NAN, 2).is_nan());
+    }
+
+    #[test]
+    fn building_coordinates_are_rounded() {
+        let precision = CoordinatePrecision::default();
+        let mut building = Building::new("P".into(), "/p".into());
+        let mut floor = Floor::new("Ground".into(), 0);
+        floor.elevation = Some(3.000000000000001);
+        let mut eq = Equipment::new("AHU".into(), "/ahu".into(), EquipmentType::HVAC);
+        eq.position.x = 0.1 + 0.2;
+        eq.position.y = 12.345678;
+        floor.equipment.push(eq);
+        building.add_floor(floor);
+
+        precision.apply_building(&mut building);
+        let floor = &building.floors[0];
+        assert_eq!(floor.elevation, Some(3.0));
+        assert_eq!(floor.equipment[0].position.x, 0.3);
+        assert_eq!(floor.equipment[0].position.y, 12.3457);
+        assert!(precision.lengths_equal(0.30000000000000004, 0.3));
+        assert!(!precision.lengths_equal(0.3, 0.3001));
+    }
+}
@@ -5,10 +5,22 @@
 
 use std::collections::HashMap;
 
+use chrono::{DateTime, Utc};
+use serde::{Deserialize, Serialize};
+
 use crate::core::{Building, Equipment, Room};
 
 /// Property key on room/equipment free-form bags.
 pub const PROP_REVIEW_STATUS: &str = "review_status";
+/// Who reviewed the entity in the field.
+pub const PROP_VALIDATED_BY: &str = "validated_by";
+/// When the field review happened (RFC 3339).
+pub const PROP_VALIDATED_AT: &str = "validated_at";
+/// Reference to the photo taken as evidence.
+pub const PROP_PHOTO_REF: &str = "photo_ref";
+
+/// Largest field-validation batch accepted at once.
+pub const MAX_VALIDATION_BATCH: usize = 1000;
 
 /// Review lifecycle for automated structure.
 #[derive(Debug, Clone, Copy, PartialEq, Eq)]
@@ -193,11 +205,12 @@ fn keep_room(room: &Room, approved_only: bool) -> bool {
 
 /// Filter a building for IFC export.
 ///
-/// - Always drops `rejected` LiDAR/auto entities.
+/// - Always drops `rejected` LiDAR/auto entities and soft-deleted equipment.
 /// - When `approved_only`, also drops `proposed` (unreviewed) auto entities.
 /// - Entities without LiDAR/review tags are always kept.
 pub fn filter_building_for_export(building: &Building, approved_only: bool) -> Building {
     let mut out = building.clone();
+    crate::core::operations::strip_deleted(&mut out);
     for floor in &mut out.floors {
         floor
             .equipment
@@ -215,6 +228,97 @@ pub fn filter_building_for_export(building: &Building, approved_only: bool) -> B
     out
 }
 
+/// One field review of a room or equipment, e.g. collected offline on a tablet.
+#[derive(Debug, Clone, Deserialize)]
+pub struct FieldValidation {
+    pub id: String,
+    /// `accepted` or `rejected` (any spelling [`ReviewStatus::parse`] accepts).
+    pub status: String,
+    pub validated_by: String,
+    #[serde(default)]
+    pub photo_ref: Option<String>,
+    /// When the review happened; defaults to when the batch is applied.
+    #[serde(default)]
+    pub validated_at: Option<DateTime<Utc>>,
+}
+
+/// Per-item result of [`apply_field_validations`].
+#[derive(Debug, Clone, PartialEq, Eq, Serialize)]
+pub struct ValidationOutcome {
+    pub id: String,
+    pub ok: bool,
+    #[serde(skip_serializing_if = "Option::is_none")]
+    pub error: Option<String>,
+}
+
+fn record_validation(
+    props: &mut HashMap<String, String>,
+    item: &FieldValidation,
+    status: ReviewStatus,
+    at: DateTime<Utc>,
+) {
+    props.insert(PROP_REVIEW_STATUS.to_string(), status.as_str().to_string());
+    props.insert(
+        PROP_VALIDATED_BY.to_string(),
+        item.validated_by.trim().to_string(),
+    );
+    props.insert(
+        PROP_VALIDATED_AT.to_string(),
+        item.validated_at.unwrap_or(at).to_rfc3339(),
+    );
+    match &item.photo_ref {
+        Some(photo) => props.insert(PROP_PHOTO_REF.to_string(), photo.clone()),
+        None => props.remove(PROP_PHOTO_REF),
+    };
+}
+
+/// Apply a batch of field reviews to `building`.
+///
+/// Items are independent: an unknown id or a bad status fails only that item. The
+/// caller persists the building once for the whole batch, so the batch lands as a
+/// single commit (and a single history version per object).
+pub fn apply_field_validations(
+    building: &mut Building,
+    items: &[FieldValidation],
+    now: DateTime<Utc>,
+) -> Vec<ValidationOutcome> {
+    items
+        .iter()
+        .map(|item| {
+            let result = (|| {
+                let status = ReviewStatus::parse(&item.status)
+                    .filter(|s| *s != ReviewStatus::Proposed)
+                    .ok_or_else(|| {
+                        format!("status '{}' must be accepted or rejected", item.status)
+                    })?;
+                if item.validated_by.trim().is_empty() {
+                    return Err("validated_by must not be empty".to_string());
+                }
+                if let Some(room) = building
+                    .get_all_rooms_mut()
+                    .into_iter()
+                    .find(|r| r.id == item.id)
+                {
+                    record_validation(&mut room.properties, item, status, now);
+                    return Ok(());
+                }
+                match building.find_equipment_mut(&item.id) {
+                    Some(eq) => {
+                        record_validation(&mut eq.properties, item, status, now);
+                        Ok(())
+                    }
+                    None => Err(format!("no room or equipment with id '{}'", item.id)),
+                }
+            })();
+            ValidationOutcome {
+                id: item.id.clone(),
+                ok: result.is_ok(),
+                error: result.err(),
+            }
+        })
+        .collect()
+}
+
 #[cfg(test)]
 mod tests {
     use super::*;
@@ -304,4 +408,54 @@ mod tests {
         assert!(s.has_unreviewed());
         assert_eq!(s.proposed_rooms, vec!["ScanRoom".to_string()]);
     }
+
+    #[test]
+    fn field_validation_batch_reports_per_item() {
+        let mut building = Building::new("V".into(), "/v".into());
+        let mut floor = Floor::new("G".into(), 0);
+        let mut wing = Wing::new("W".into());
+        let mut room = Room::new("Lab".into(), RoomType::Laboratory);
+        room.id = "room-1".into();
+        mark_proposed(&mut room.properties);
+        let mut eq = Equipment::new(
+            "AHU".into(),
+            "/ahu".into(),
+            crate::core::EquipmentType::HVAC,
+        );
+        eq.id = "eq-1".into();
+        room.equipment.push(eq);
+        wing.rooms.push(room);
+        floor.wings.push(wing);
+        building.add_floor(floor);
+
+        let item = |id: &str, status: &str| FieldValidation {
+            id: id.into(),
+            status: status.into(),
+            validated_by: "tech-7".into(),
+            photo_ref: Some(format!("photos/{}.jpg", id)),
+            validated_at: None,
+        };
+        let now = Utc::now();
+        let outcomes = apply_field_validations(
+            &mut building,
+            &[
+                item("room-1", "accepted"),
+                item("eq-1", "reject"),
+                item("missing", "accepted"),
+                item("eq-1", "proposed"),
+            ],
+            now,
+        );
+        let ok: Vec<bool> = outcomes.iter().map(|o| o.ok).collect();
+        assert_eq!(ok, vec![true, true, false, false]);
+        assert!(outcomes[2].error.as_deref().unwrap().contains("missing"));
+
+        let room = &building.floors[0].wings[0].rooms[0];
+        assert_eq!(room_review_status(room), Some(ReviewStatus::Accepted));
+        assert_eq!(room.properties[PROP_VALIDATED_BY], "tech-7");
+        assert_eq!(room.properties[PROP_VALIDATED_AT], now.to_rfc3339());
+        let eq = building.find_equipment("eq-1").unwrap();
+        assert_eq!(equipment_review_status(eq), Some(ReviewStatus::Rejected));
+        assert_eq!(eq.properties[PROP_PHOTO_REF], "photos/eq-1.jpg");
+    }
 }
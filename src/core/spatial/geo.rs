@@ -0,0 +1,195 @@
+//! Building-local ↔ WGS84 conversion.
+//!
+//! Geometry is stored and queried in the building's local Cartesian frame (meters,
+//! `building_local`). The frame is tied to the globe by the site reference the IFC
+//! importer records in building metadata:
+//!
+//! - `geo:latitude`, `geo:longitude` (decimal degrees) and `geo:elevation` (meters):
+//!   the WGS84 position of the local origin;
+//! - `geo:true_north` (degrees, optional): counter-clockwise angle from local +Y to
+//!   true north, 0 when the local axes already point east/north.
+//!
+//! Conversion goes through ECEF and the local east-north-up tangent plane at the
+//! origin, so it is exact up to floating point rather than a flat-earth approximation.
+
+use super::Point3D;
+use crate::core::Building;
+use serde::{Deserialize, Serialize};
+
+pub const GEO_LATITUDE: &str = "geo:latitude";
+pub const GEO_LONGITUDE: &str = "geo:longitude";
+pub const GEO_ELEVATION: &str = "geo:elevation";
+pub const GEO_TRUE_NORTH: &str = "geo:true_north";
+
+/// WGS84 semi-major axis (m).
+const WGS84_A: f64 = 6_378_137.0;
+/// WGS84 flattening.
+const WGS84_F: f64 = 1.0 / 298.257_223_563;
+
+/// A WGS84 position: degrees and meters above the ellipsoid.
+#[derive(Debug, Clone, Copy, PartialEq, Serialize, Deserialize)]
+pub struct GeoPoint {
+    pub latitude: f64,
+    pub longitude: f64,
+    pub altitude: f64,
+}
+
+/// Anchor of a building's local frame on the WGS84 ellipsoid.
+#[derive(Debug, Clone, Copy, PartialEq)]
+pub struct GeoReference {
+    pub origin: GeoPoint,
+    pub true_north_deg: f64,
+}
+
+fn e2() -> f64 {
+    WGS84_F * (2.0 - WGS84_F)
+}
+
+fn to_ecef(p: &GeoPoint) -> [f64; 3] {
+    let (lat, lon) = (p.latitude.to_radians(), p.longitude.to_radians());
+    let n = WGS84_A / (1.0 - e2() * lat.sin().powi(2)).sqrt();
+    [
+        (n + p.altitude) * lat.cos() * lon.cos(),
+        (n + p.altitude) * lat.cos() * lon.sin(),
+        (n * (1.0 - e2()) + p.altitude) * lat.sin(),
+    ]
+}
+
+fn from_ecef([x, y, z]: [f64; 3]) -> GeoPoint {
+    let lon = y.atan2(x);
+    let p = (x * x + y * y).sqrt();
+    let mut lat = z.atan2(p * (1.0 - e2()));
+    let mut alt = 0.0;
+    // Converges to sub-millimeter in a few iterations for terrestrial points.
+    for _ in 0..6 {
+        let n = WGS84_A / (1.0 - e2() * lat.sin().powi(2)).sqrt();
+        alt = p / lat.cos() - n;
+        lat = z.atan2(p * (1.0 - e2() * n / (n + alt)));
+    }
+    GeoPoint {
+        latitude: lat.to_degrees(),
+        longitude: lon.to_degrees(),
+        altitude: alt,
+    }
+}
+
+impl GeoReference {
+    pub fn new(origin: GeoPoint, true_north_deg: f64) -> Self {
+        Self {
+            origin,
+            true_north_deg,
+        }
+    }
+
+    /// The reference recorded in building metadata; `None` without latitude and longitude.
+    pub fn from_building(building: &Building) -> Option<Self> {
+        let props = &building.metadata.as_ref()?.properties;
+        let number = |key: &str| props.get(key).and_then(|v| v.trim().parse::<f64>().ok());
+        Some(Self::new(
+            GeoPoint {
+                latitude: number(GEO_LATITUDE)?,
+                longitude: number(GEO_LONGITUDE)?,
+                altitude: number(GEO_ELEVATION).unwrap_or(0.0),
+            },
+            number(GEO_TRUE_NORTH).unwrap_or(0.0),
+        ))
+    }
+
+    /// Sines and cosines of origin latitude, longitude and true-north rotation.
+    fn trig(&self) -> ([f64; 2], [f64; 2], [f64; 2]) {
+        let lat = self.origin.latitude.to_radians();
+        let lon = self.origin.longitude.to_radians();
+        let rot = self.true_north_deg.to_radians();
+        (
+            [lat.sin(), lat.cos()],
+            [lon.sin(), lon.cos()],
+            [rot.sin(), rot.cos()],
+        )
+    }
+
+    /// WGS84 position of a building-local point.
+    pub fn to_wgs84(&self, local: &Point3D) -> GeoPoint {
+        let ([sin_lat, cos_lat], [sin_lon, cos_lon], [sin_r, cos_r]) = self.trig();
+        // Local axes → east/north/up.
+        let east = local.x * cos_r - local.y * sin_r;
+        let north = local.x * sin_r + local.y * cos_r;
+        let up = local.z;
+
+        let [ox, oy, oz] = to_ecef(&self.origin);
+        from_ecef([
+            ox - sin_lon * east - sin_lat * cos_lon * north + cos_lat * cos_lon * up,
+            oy + cos_lon * east - sin_lat * sin_lon * north + cos_lat * sin_lon * up,
+            oz + cos_lat * north + sin_lat * up,
+        ])
+    }
+
+    /// Building-local point for a WGS84 position.
+    pub fn to_local(&self, point: &GeoPoint) -> Point3D {
+        let ([sin_lat, cos_lat], [sin_lon, cos_lon], [sin_r, cos_r]) = self.trig();
+        let [ox, oy, oz] = to_ecef(&self.origin);
+        let [px, py, pz] = to_ecef(point);
+        let (dx, dy, dz) = (px - ox, py - oy, pz - oz);
+
+        let east = -sin_lon * dx + cos_lon * dy;
+        let north = -sin_lat * cos_lon * dx - sin_lat * sin_lon * dy + cos_lat * dz;
+        let up = cos_lat * cos_lon * dx + cos_lat * sin_lon * dy + sin_lat * dz;
+        Point3D::new(
+            east * cos_r + north * sin_r,
+            -east * sin_r + north * cos_r,
+            up,
+        )
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn reference(true_north_deg: f64) -> GeoReference {
+        GeoReference::new(
+            GeoPoint {
+                latitude: 40.7484,
+                longitude: -73.9857,
+                altitude: 10.0,
+            },
+            true_north_deg,
+        )
+    }
+
+    #[test]
+    fn local_and_wgs84_round_trip() {
+        for rotation in [0.0, 32.5, -90.0] {
+            let geo = reference(rotation);
+            for local in [
+                Point3D::new(0.0, 0.0, 0.0),
+                Point3D::new(12.5, -3.25, 4.0),
+                Point3D::new(-250.0, 480.0, 120.0),
+            ] {
+                let back = geo.to_local(&geo.to_wgs84(&local));
+                assert!((back.x - local.x).abs() < 1e-6, "{:?} vs {:?}", back, local);
+                assert!((back.y - local.y).abs() < 1e-6, "{:?} vs {:?}", back, local);
+                assert!((back.z - local.z).abs() < 1e-6, "{:?} vs {:?}", back, local);
+            }
+        }
+    }
+
+    #[test]
+    fn axes_follow_true_north() {
+        // 111.3 km per degree of latitude near 40°N, so 100 m north ≈ 0.0009 degrees.
+        let north = reference(0.0).to_wgs84(&Point3D::new(0.0, 100.0, 0.0));
+        assert!((north.latitude - 40.7484 - 100.0 / 111_040.0).abs() < 1e-5);
+        assert!((north.longitude + 73.9857).abs() < 1e-9);
+        assert!((north.altitude - 10.0).abs() < 1e-2);
+
+        // Rotated 90° counter-clockwise, local +X points north.
+        let rotated = reference(90.0).to_wgs84(&Point3D::new(100.0, 0.0, 0.0));
+        assert!((rotated.latitude - north.latitude).abs() < 1e-9);
+
+        let mut building = Building::new("Geo".into(), "/geo".into());
+        assert!(GeoReference::from_building(&building).is_none());
+        building.add_metadata_property(GEO_LATITUDE.into(), "40.7484".into());
+        building.add_metadata_property(GEO_LONGITUDE.into(), "-73.9857".into());
+        building.add_metadata_property(GEO_ELEVATION.into(), "10".into());
+        assert_eq!(GeoReference::from_building(&building), Some(reference(0.0)));
+    }
+}
@@ -1,6 +1,7 @@
 // Spatial data processing for ArxOS
 use nalgebra::Point3;
 
+pub mod geo;
 pub mod grid;
 pub mod mesh;
 pub mod types;
@@ -0,0 +1,505 @@
+//! Review suggestions: concrete fixes for objects awaiting review.
+//!
+//! Equipment proposed by LiDAR or another automated source (see [`super::review`])
+//! is compared against the reviewed objects around it. Each suggestion names one
+//! fix and how confident the heuristic is; [`apply_suggestion`] carries it out.
+//!
+//! - `infer_property`: most reviewed equipment of the same type agree on a value
+//!   the object lacks;
+//! - `reject_duplicate`: a trusted object of the same type sits within
+//!   [`DUPLICATE_TOLERANCE_M`], so this one is likely a second detection of it;
+//! - `assign_room`: common-area equipment lies inside a room's footprint;
+//! - `reclassify`: untyped (`Other`) equipment whose nearby reviewed equipment
+//!   mostly share one type.
+
+use std::collections::HashMap;
+
+use serde::{Deserialize, Serialize};
+
+use super::review::{
+    equipment_needs_review, ReviewStatus, PROP_PHOTO_REF, PROP_REVIEW_STATUS, PROP_VALIDATED_AT,
+    PROP_VALIDATED_BY,
+};
+use super::{Building, Equipment, EquipmentType, Room};
+
+/// Same-type equipment closer than this (meters) is treated as one object.
+pub const DUPLICATE_TOLERANCE_M: f64 = 0.5;
+/// Radius (meters) of the neighborhood used to reclassify untyped equipment.
+pub const NEIGHBOR_RADIUS_M: f64 = 3.0;
+/// Reviewed peers needed before a property value is inferred.
+const MIN_PEERS: usize = 3;
+/// Share of peers that must agree on a value.
+const MIN_AGREEMENT: f64 = 0.8;
+/// Per-object bookkeeping that must never be copied between objects.
+const NOT_INFERRED: &[&str] = &[
+    PROP_REVIEW_STATUS,
+    PROP_VALIDATED_BY,
+    PROP_VALIDATED_AT,
+    PROP_PHOTO_REF,
+    "cmms_id",
+];
+
+#[derive(Debug, Clone, PartialEq, Serialize, Deserialize)]
+#[serde(tag = "kind", rename_all = "snake_case")]
+pub enum Fix {
+    InferProperty { key: String, value: String },
+    RejectDuplicate { duplicate_of: String },
+    AssignRoom { room_id: String },
+    Reclassify { equipment_type: EquipmentType },
+}
+
+#[derive(Debug, Clone, PartialEq, Serialize, Deserialize)]
+pub struct Suggestion {
+    pub object_id: String,
+    #[serde(flatten)]
+    pub fix: Fix,
+    /// 0.0 – 1.0.
+    pub confidence: f64,
+    pub reason: String,
+}
+
+struct Placed<'a> {
+    eq: &'a Equipment,
+    floor: i32,
+    room: Option<&'a Room>,
+}
+
+fn placed(building: &Building) -> Vec<Placed<'_>> {
+    let mut out = Vec::new();
+    for floor in &building.floors {
+        let level = floor.level;
+        out.extend(floor.equipment.iter().map(|eq| Placed {
+            eq,
+            floor: level,
+            room: None,
+        }));
+        for wing in &floor.wings {
+            out.extend(wing.equipment.iter().map(|eq| Placed {
+                eq,
+                floor: level,
+                room: None,
+            }));
+            for room in &wing.rooms {
+                out.extend(room.equipment.iter().map(|eq| Placed {
+                    eq,
+                    floor: level,
+                    room: Some(room),
+                }));
+            }
+        }
+    }
+    out
+}
+
+fn distance(a: &Equipment, b: &Equipment) -> f64 {
+    let (p, q) = (&a.position, &b.position);
+    ((p.x - q.x).powi(2) + (p.y - q.y).powi(2) + (p.z - q.z).powi(2)).sqrt()
+}
+
+fn lidar_confidence(eq: &Equipment) -> f64 {
+    eq.lidar_enrichment
+        .as_ref()
+        .map_or(0.0, |l| l.confidence_score)
+}
+
+fn is_rejected(eq: &Equipment) -> bool {
+    eq.properties.get(PROP_REVIEW_STATUS).map(String::as_str)
+        == Some(ReviewStatus::Rejected.as_str())
+}
+
+fn infer_properties(target: &Equipment, all: &[Placed], out: &mut Vec<Suggestion>) {
+    let peers: Vec<&Equipment> = all
+        .iter()
+        .map(|p| p.eq)
+        .filter(|e| e.id != target.id && !equipment_needs_review(e))
+        .filter(|e| e.equipment_type == target.equipment_type)
+        .collect();
+    if peers.len() < MIN_PEERS {
+        return;
+    }
+    let mut values: HashMap<&str, HashMap<&str, usize>> = HashMap::new();
+    for peer in &peers {
+        for (key, value) in &peer.properties {
+            if NOT_INFERRED.contains(&key.as_str()) || target.properties.contains_key(key) {
+                continue;
+            }
+            *values
+                .entry(key.as_str())
+                .or_default()
+                .entry(value.as_str())
+                .or_default() += 1;
+        }
+    }
+    let mut keys: Vec<&&str> = values.keys().collect();
+    keys.sort();
+    for key in keys {
+        let (value, count) = values[*key]
+            .iter()
+            .max_by(|a, b| a.1.cmp(b.1).then(b.0.cmp(a.0)))
+            .expect("non-empty");
+        let share = *count as f64 / peers.len() as f64;
+        if share >= MIN_AGREEMENT {
+            out.push(Suggestion {
+                object_id: target.id.clone(),
+                fix: Fix::InferProperty {
+                    key: key.to_string(),
+                    value: value.to_string(),
+                },
+                confidence: share,
+                reason: format!(
+                    "{} of {} reviewed {} equipment have {} = {}",
+                    count,
+                    peers.len(),
+                    target.equipment_type,
+                    key,
+                    value
+                ),
+            });
+        }
+    }
+}
+
+/// Whether `other` should survive over `target` when both describe one object.
+fn outranks(other: &Equipment, target: &Equipment) -> bool {
+    if !equipment_needs_review(other) {
+        return true;
+    }
+    match lidar_confidence(other).total_cmp(&lidar_confidence(target)) {
+        std::cmp::Ordering::Equal => other.id < target.id,
+        ordering => ordering.is_gt(),
+    }
+}
+
+fn find_duplicate(target: &Placed, all: &[Placed], out: &mut Vec<Suggestion>) {
+    let nearest = all
+        .iter()
+        .filter(|p| p.eq.id != target.eq.id && p.floor == target.floor && !is_rejected(p.eq))
+        .filter(|p| p.eq.equipment_type == target.eq.equipment_type)
+        .map(|p| (p.eq, distance(p.eq, target.eq)))
+        .filter(|(other, d)| *d <= DUPLICATE_TOLERANCE_M && outranks(other, target.eq))
+        .min_by(|a, b| a.1.total_cmp(&b.1));
+    if let Some((other, d)) = nearest {
+        out.push(Suggestion {
+            object_id: target.eq.id.clone(),
+            fix: Fix::RejectDuplicate {
+                duplicate_of: other.id.clone(),
+            },
+            confidence: 1.0 - 0.5 * d / DUPLICATE_TOLERANCE_M,
+            reason: format!(
+                "'{}' is {:.2} m from '{}' of the same type",
+                target.eq.name, d, other.name
+            ),
+        });
+    }
+}
+
+fn assign_room(target: &Placed, building: &Building, out: &mut Vec<Suggestion>) {
+    if target.room.is_some() {
+        return;
+    }
+    let Some(floor) = building.find_floor(target.floor) else {
+        return;
+    };
+    let p = &target.eq.position;
+    let mut containing: Vec<(&Room, f64)> = floor
+        .wings
+        .iter()
+        .flat_map(|w| &w.rooms)
+        .filter_map(|room| {
+            let bbox = &room.spatial_properties.bounding_box;
+            let (w, d) = (bbox.max.x - bbox.min.x, bbox.max.y - bbox.min.y);
+            let inside = w > 0.0
+                && d > 0.0
+                && (bbox.min.x..=bbox.max.x).contains(&p.x)
+                && (bbox.min.y..=bbox.max.y).contains(&p.y);
+            inside.then_some((room, w * d))
+        })
+        .collect();
+    containing.sort_by(|a, b| a.1.total_cmp(&b.1));
+    if let Some((room, _)) = containing.first() {
+        out.push(Suggestion {
+            object_id: target.eq.id.clone(),
+            fix: Fix::AssignRoom {
+                room_id: room.id.clone(),
+            },
+            confidence: if containing.len() == 1 { 0.9 } else { 0.6 },
+            reason: format!(
+                "'{}' lies inside the footprint of room '{}'",
+                target.eq.name, room.name
+            ),
+        });
+    }
+}
+
+fn reclassify(target: &Placed, all: &[Placed], out: &mut Vec<Suggestion>) {
+    if !matches!(target.eq.equipment_type, EquipmentType::Other(_)) {
+        return;
+    }
+    let neighbors: Vec<&EquipmentType> = all
+        .iter()
+        .filter(|p| p.eq.id != target.eq.id && p.floor == target.floor)
+        .filter(|p| !equipment_needs_review(p.eq))
+        .filter(|p| !matches!(p.eq.equipment_type, EquipmentType::Other(_)))
+        .filter(|p| distance(p.eq, target.eq) <= NEIGHBOR_RADIUS_M)
+        .map(|p| &p.eq.equipment_type)
+        .collect();
+    if neighbors.len() < 2 {
+        return;
+    }
+    let mut counts: Vec<(&EquipmentType, usize)> = Vec::new();
+    for t in &neighbors {
+        match counts.iter_mut().find(|(c, _)| c == t) {
+            Some((_, n)) => *n += 1,
+            None => counts.push((t, 1)),
+        }
+    }
+    let (best, count) = counts
+        .into_iter()
+        .max_by_key(|(_, n)| *n)
+        .expect("non-empty");
+    let share = count as f64 / neighbors.len() as f64;
+    if share > 0.5 {
+        out.push(Suggestion {
+            object_id: target.eq.id.clone(),
+            fix: Fix::Reclassify {
+                equipment_type: best.clone(),
+            },
+            confidence: share * 0.8,
+            reason: format!(
+                "{} of {} reviewed equipment within {} m are {}",
+                count,
+                neighbors.len(),
+                NEIGHBOR_RADIUS_M,
+                best
+            ),
+        });
+    }
+}
+
+/// Suggested fixes for every piece of equipment awaiting review, most confident first.
+pub fn suggest_fixes(building: &Building) -> Vec<Suggestion> {
+    let all = placed(building);
+    let mut out = Vec::new();
+    for target in all
+        .iter()
+        .filter(|p| equipment_needs_review(p.eq) && !is_rejected(p.eq))
+    {
+        let before = out.len();
+        find_duplicate(target, &all, &mut out);
+        if out.len() > before {
+            // A duplicate is rejected, not repaired.
+            continue;
+        }
+        infer_properties(target.eq, &all, &mut out);
+        assign_room(target, building, &mut out);
+        reclassify(target, &all, &mut out);
+    }
+    out.sort_by(|a, b| {
+        b.confidence
+            .total_cmp(&a.confidence)
+            .then_with(|| a.object_id.cmp(&b.object_id))
+    });
+    out
+}
+
+/// Remove common-area (floor or wing level) equipment `id` from its container.
+fn take_unplaced(building: &mut Building, id: &str) -> Option<Equipment> {
+    for floor in &mut building.floors {
+        if let Some(i) = floor.equipment.iter().position(|e| e.id == id) {
+            return Some(floor.equipment.remove(i));
+        }
+        for wing in &mut floor.wings {
+            if let Some(i) = wing.equipment.iter().position(|e| e.id == id) {
+                return Some(wing.equipment.remove(i));
+            }
+        }
+    }
+    None
+}
+
+/// Apply one suggestion. Fails if its object (or target room) no longer exists.
+pub fn apply_suggestion(building: &mut Building, suggestion: &Suggestion) -> Result<(), String> {
+    let id = suggestion.object_id.as_str();
+    if building.find_equipment(id).is_none() {
+        return Err(format!("Equipment '{}' not found", id));
+    }
+    match &suggestion.fix {
+        Fix::InferProperty { key, value } => {
+            let eq = building.find_equipment_mut(id).expect("checked above");
+            eq.properties.insert(key.clone(), value.clone());
+        }
+        Fix::RejectDuplicate { duplicate_of } => {
+            if building.find_equipment(duplicate_of).is_none() {
+                return Err(format!("Equipment '{}' not found", duplicate_of));
+            }
+            let eq = building.find_equipment_mut(id).expect("checked above");
+            eq.properties.insert(
+                PROP_REVIEW_STATUS.to_string(),
+                ReviewStatus::Rejected.as_str().to_string(),
+            );
+        }
+        Fix::AssignRoom { room_id } => {
+            if building.find_room(room_id).is_none() {
+                return Err(format!("Room '{}' not found", room_id));
+            }
+            let mut eq = take_unplaced(building, id)
+                .ok_or_else(|| format!("Equipment '{}' is already in a room", id))?;
+            eq.room_id = Some(room_id.clone());
+            building
+                .find_room_mut(room_id)
+                .expect("checked above")
+                .equipment
+                .push(eq);
+        }
+        Fix::Reclassify { equipment_type } => {
+            let eq = building.find_equipment_mut(id).expect("checked above");
+            eq.equipment_type = equipment_type.clone();
+        }
+    }
+    building.updated_at = chrono::Utc::now();
+    Ok(())
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::core::review::mark_proposed;
+    use crate::core::{Dimensions, Floor, Position, SpatialProperties, Wing};
+
+    fn equipment(name: &str, kind: EquipmentType, x: f64, y: f64) -> Equipment {
+        let mut eq = Equipment::new(name.into(), format!("/{}", name), kind);
+        eq.position = Position {
+            x,
+            y,
+            z: 0.0,
+            coordinate_system: "building_local".into(),
+        };
+        eq
+    }
+
+    fn proposed(name: &str, kind: EquipmentType, x: f64, y: f64) -> Equipment {
+        let mut eq = equipment(name, kind, x, y);
+        mark_proposed(&mut eq.properties);
+        eq
+    }
+
+    fn building() -> Building {
+        let mut building = Building::new("HQ".into(), "/hq".into());
+        let mut floor = Floor::new("Ground".into(), 0);
+        let mut wing = Wing::new("East".into());
+        let mut office = crate::core::Room::new("Office".into(), crate::core::RoomType::Office);
+        office.spatial_properties = SpatialProperties::new(
+            Position {
+                x: 5.0,
+                y: 5.0,
+                z: 0.0,
+                coordinate_system: "building_local".into(),
+            },
+            Dimensions {
+                width: 10.0,
+                height: 3.0,
+                depth: 10.0,
+            },
+            "building_local".into(),
+        );
+        for (i, x) in [1.0, 3.0, 5.0].into_iter().enumerate() {
+            let mut light = equipment(&format!("EL-{}", i), EquipmentType::Electrical, x, 1.0);
+            light.properties.insert("voltage".into(), "277".into());
+            office.equipment.push(light);
+        }
+        wing.rooms.push(office);
+        // Second detection of EL-0, and an untyped object among the fixtures.
+        wing.equipment
+            .push(proposed("EL-0b", EquipmentType::Electrical, 1.2, 1.1));
+        wing.equipment.push(proposed(
+            "Fixture?",
+            EquipmentType::Other("Unknown".into()),
+            3.0,
+            2.0,
+        ));
+        floor.wings.push(wing);
+        building.add_floor(floor);
+        building
+    }
+
+    fn find<'a>(suggestions: &'a [Suggestion], name: &str, b: &Building) -> Vec<&'a Fix> {
+        let id = &b
+            .get_all_equipment()
+            .into_iter()
+            .find(|e| e.name == name)
+            .unwrap()
+            .id;
+        suggestions
+            .iter()
+            .filter(|s| &s.object_id == id)
+            .map(|s| &s.fix)
+            .collect()
+    }
+
+    #[test]
+    fn suggests_duplicate_room_and_type_fixes() {
+        let building = building();
+        let suggestions = suggest_fixes(&building);
+        let lt0 = building
+            .get_all_equipment()
+            .into_iter()
+            .find(|e| e.name == "EL-0")
+            .unwrap()
+            .id
+            .clone();
+
+        assert_eq!(
+            find(&suggestions, "EL-0b", &building),
+            vec![&Fix::RejectDuplicate { duplicate_of: lt0 }]
+        );
+        let fixture = find(&suggestions, "Fixture?", &building);
+        assert!(fixture.contains(&&Fix::Reclassify {
+            equipment_type: EquipmentType::Electrical
+        }));
+        let office_id = building.get_all_rooms()[0].id.clone();
+        assert!(fixture.contains(&&Fix::AssignRoom { room_id: office_id }));
+        assert!(suggestions
+            .iter()
+            .all(|s| (0.0..=1.0).contains(&s.confidence)));
+    }
+
+    #[test]
+    fn infers_missing_property_and_applies_fixes() {
+        let mut building = building();
+        let reclassify = suggest_fixes(&building)
+            .into_iter()
+            .find(|s| matches!(s.fix, Fix::Reclassify { .. }))
+            .unwrap();
+        apply_suggestion(&mut building, &reclassify).unwrap();
+
+        // Now typed as electrical, the fixture picks up its neighbors' common voltage.
+        let suggestions = suggest_fixes(&building);
+        let infer = suggestions
+            .iter()
+            .find(|s| matches!(s.fix, Fix::InferProperty { .. }))
+            .unwrap();
+        assert_eq!(
+            infer.fix,
+            Fix::InferProperty {
+                key: "voltage".into(),
+                value: "277".into()
+            }
+        );
+        let assign = suggestions
+            .iter()
+            .find(|s| matches!(s.fix, Fix::AssignRoom { .. }))
+            .unwrap();
+        apply_suggestion(&mut building, infer).unwrap();
+        apply_suggestion(&mut building, assign).unwrap();
+
+        let room = &building.get_all_rooms()[0];
+        let fixture = room
+            .equipment
+            .iter()
+            .find(|e| e.name == "Fixture?")
+            .unwrap();
+        assert_eq!(fixture.room_id.as_ref(), Some(&room.id));
+        assert_eq!(fixture.properties["voltage"], "277");
+        assert!(apply_suggestion(&mut building, assign).is_err());
+    }
+}
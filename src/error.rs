@@ -11,6 +11,61 @@ pub struct ErrorContext {
     pub recovery_steps: Vec<String>,
 }
 
+/// Stable, machine-readable error codes surfaced to API clients.
+///
+/// Messages may change between releases; codes do not. Clients should switch on these.
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Hash)]
+pub enum ErrorCode {
+    NotFound,
+    Validation,
+    Conflict,
+    InvalidParams,
+    Unauthorized,
+    Forbidden,
+    MethodNotFound,
+    Internal,
+}
+
+impl ErrorCode {
+    pub fn as_str(self) -> &'static str {
+        match self {
+            ErrorCode::NotFound => "ARX-NOT-FOUND",
+            ErrorCode::Validation => "ARX-VALIDATION",
+            ErrorCode::Conflict => "ARX-CONFLICT",
+            ErrorCode::InvalidParams => "ARX-INVALID-PARAMS",
+            ErrorCode::Unauthorized => "ARX-UNAUTHORIZED",
+            ErrorCode::Forbidden => "ARX-FORBIDDEN",
+            ErrorCode::MethodNotFound => "ARX-METHOD-NOT-FOUND",
+            ErrorCode::Internal => "ARX-INTERNAL",
+        }
+    }
+
+    /// HTTP status used when the error is returned from a REST endpoint.
+    pub fn http_status(self) -> u16 {
+        match self {
+            ErrorCode::NotFound | ErrorCode::MethodNotFound => 404,
+            ErrorCode::Validation => 422,
+            ErrorCode::Conflict => 409,
+            ErrorCode::InvalidParams => 400,
+            ErrorCode::Unauthorized => 401,
+            ErrorCode::Forbidden => 403,
+            ErrorCode::Internal => 500,
+        }
+    }
+}
+
+impl fmt::Display for ErrorCode {
+    fn fmt(&self, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+        f.write_str(self.as_str())
+    }
+}
+
+impl serde::Serialize for ErrorCode {
+    fn serialize<S: serde::Serializer>(&self, serializer: S) -> Result<S::Ok, S::Error> {
+        serializer.serialize_str(self.as_str())
+    }
+}
+
 /// Core ArxOS error types
 #[derive(Debug)]
 pub enum ArxError {
@@ -188,6 +243,29 @@ impl From<crate::git::manager::GitError> for ArxError {
 }
 
 impl ArxError {
+    /// Stable API code for this error.
+    pub fn code(&self) -> ErrorCode {
+        match self {
+            ArxError::PathInvalid { .. }
+            | ArxError::AddressValidation { .. }
+            | ArxError::Validation { .. }
+            | ArxError::Serialization(_)
+            | ArxError::YamlProcessing { .. }
+            | ArxError::Ifc(_)
+            | ArxError::IfcProcessing { .. }
+            | ArxError::SpatialData { .. } => ErrorCode::Validation,
+            ArxError::Io(err) if err.kind() == std::io::ErrorKind::NotFound => ErrorCode::NotFound,
+            ArxError::Io(_)
+            | ArxError::IoError { .. }
+            | ArxError::Git(_)
+            | ArxError::GitOperation { .. }
+            | ArxError::Config(_)
+            | ArxError::Configuration { .. }
+            | ArxError::General(_)
+            | ArxError::CounterOverflow { .. } => ErrorCode::Internal,
+        }
+    }
+
     /// Get error context with suggestions and recovery steps
     pub fn context(&self) -> ErrorContext {
         match self {
@@ -12,9 +12,17 @@
 //!   existing entities are omitted (counted in stats).
 //! - **Existing** (LiDAR): result starts from existing; unmatched existing kept;
 //!   new entities from the scan are added.
+//!
+//! # Merge strategy
+//!
+//! When equipment matches, [`MergeStrategy`] decides whose record wins. `replace`
+//! (default) takes the incoming fields; `keep-validated` keeps a field-validated
+//! existing object untouched; `keep-higher-confidence` keeps the existing object
+//! when its confidence is higher (field-validated counts as 1.0).
 
 use std::collections::{HashMap, HashSet};
 
+use crate::core::review::{equipment_review_status, ReviewStatus, PROP_VALIDATED_AT};
 use crate::core::{Building, Equipment, EquipmentType, Floor, Position, Room};
 
 use super::prefer_existing_lidar;
@@ -38,11 +46,69 @@ pub enum HierarchyBase {
     Existing,
 }
 
+/// Which record wins when incoming equipment matches an existing object.
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Default)]
+pub enum MergeStrategy {
+    /// Incoming fields replace existing ones (ids and Arx state are kept).
+    #[default]
+    Replace,
+    /// Keep the existing object when it is more confident than the incoming one.
+    KeepHigherConfidence,
+    /// Keep field-validated existing objects as they are.
+    KeepValidated,
+}
+
+impl MergeStrategy {
+    pub const NAMES: &'static [&'static str] =
+        &["replace", "keep-higher-confidence", "keep-validated"];
+
+    pub fn parse(s: &str) -> Option<Self> {
+        match s.trim().to_ascii_lowercase().replace('_', "-").as_str() {
+            "replace" => Some(MergeStrategy::Replace),
+            "keep-higher-confidence" => Some(MergeStrategy::KeepHigherConfidence),
+            "keep-validated" => Some(MergeStrategy::KeepValidated),
+            _ => None,
+        }
+    }
+
+    /// Whether `existing` should be kept instead of merging `incoming` over it.
+    pub fn keeps_existing(self, existing: &Equipment, incoming: &Equipment) -> bool {
+        match self {
+            MergeStrategy::Replace => false,
+            MergeStrategy::KeepValidated => is_validated(existing),
+            MergeStrategy::KeepHigherConfidence => {
+                merge_confidence(existing) > merge_confidence(incoming)
+            }
+        }
+    }
+}
+
+/// Accepted in review or confirmed in the field. A field review stamps
+/// `validated_at` on rejections too, so a rejected object never counts.
+fn is_validated(eq: &Equipment) -> bool {
+    match equipment_review_status(eq) {
+        Some(ReviewStatus::Accepted) => true,
+        Some(ReviewStatus::Rejected) => false,
+        _ => eq.properties.contains_key(PROP_VALIDATED_AT),
+    }
+}
+
+fn merge_confidence(eq: &Equipment) -> f64 {
+    if is_validated(eq) {
+        return 1.0;
+    }
+    eq.lidar_enrichment
+        .as_ref()
+        .map_or(0.0, |l| l.confidence_score)
+}
+
 /// Configurable merge policy for ingest adapters.
 #[derive(Debug, Clone)]
 pub struct MergePolicy {
     pub source: MergeSource,
     pub hierarchy: HierarchyBase,
+    /// Whose record wins when equipment matches.
+    pub strategy: MergeStrategy,
     /// When set, rooms on the same floor match if centroids are within this distance (m).
     pub room_match_radius_m: Option<f64>,
     /// When set, equipment matches if same type and within this distance (m).
@@ -55,6 +121,7 @@ impl MergePolicy {
         Self {
             source: MergeSource::Ifc,
             hierarchy: HierarchyBase::Incoming,
+            strategy: MergeStrategy::Replace,
             room_match_radius_m: None,
             equipment_match_radius_m: None,
         }
@@ -65,10 +132,16 @@ impl MergePolicy {
         Self {
             source: MergeSource::Lidar,
             hierarchy: HierarchyBase::Existing,
+            strategy: MergeStrategy::Replace,
             room_match_radius_m: Some(2.0),
             equipment_match_radius_m: Some(1.5),
         }
     }
+
+    pub fn with_strategy(mut self, strategy: MergeStrategy) -> Self {
+        self.strategy = strategy;
+        self
+    }
 }
 
 /// Result of merging an existing Arx building with an incoming model.
@@ -182,7 +255,9 @@ fn merge_incoming_base(
                 Some((key, old_eq)) => {
                     stats.equipment_matched += 1;
                     matched_eq_keys.insert(key);
-                    merge_equipment_fields(eq, old_eq, policy);
+                    if merge_equipment_fields(eq, old_eq, policy) {
+                        stats.equipment_kept_existing += 1;
+                    }
                 }
                 None => stats.equipment_added += 1,
             }
@@ -196,7 +271,9 @@ fn merge_incoming_base(
                     Some((key, old_eq)) => {
                         stats.equipment_matched += 1;
                         matched_eq_keys.insert(key);
-                        merge_equipment_fields(eq, old_eq, policy);
+                        if merge_equipment_fields(eq, old_eq, policy) {
+                            stats.equipment_kept_existing += 1;
+                        }
                     }
                     None => stats.equipment_added += 1,
                 }
@@ -239,7 +316,9 @@ fn merge_incoming_base(
                         Some((key, old_eq)) => {
                             stats.equipment_matched += 1;
                             matched_eq_keys.insert(key);
-                            merge_equipment_fields(eq, old_eq, policy);
+                            if merge_equipment_fields(eq, old_eq, policy) {
+                                stats.equipment_kept_existing += 1;
+                            }
                             eq.room_id = Some(room.id.clone());
                         }
                         None => {
@@ -379,7 +458,9 @@ fn merge_existing_base(
                                 Some(ei) => {
                                     stats.equipment_matched += 1;
                                     let old_eq = merged_eq[ei].clone();
-                                    merge_equipment_fields(&mut inc_eq, &old_eq, policy);
+                                    if merge_equipment_fields(&mut inc_eq, &old_eq, policy) {
+                                        stats.equipment_kept_existing += 1;
+                                    }
                                     inc_eq.room_id = Some(incoming_room.id.clone());
                                     merged_eq[ei] = inc_eq;
                                 }
@@ -417,7 +498,9 @@ fn merge_existing_base(
                     Some(ei) => {
                         stats.equipment_matched += 1;
                         let old_eq = wings[wing_idx].equipment[ei].clone();
-                        merge_equipment_fields(&mut inc_eq, &old_eq, policy);
+                        if merge_equipment_fields(&mut inc_eq, &old_eq, policy) {
+                            stats.equipment_kept_existing += 1;
+                        }
                         wings[wing_idx].equipment[ei] = inc_eq;
                     }
                     None => {
@@ -444,7 +527,9 @@ fn merge_existing_base(
                 Some(ei) => {
                     stats.equipment_matched += 1;
                     let old_eq = building.floors[floor_idx].equipment[ei].clone();
-                    merge_equipment_fields(&mut inc_eq, &old_eq, policy);
+                    if merge_equipment_fields(&mut inc_eq, &old_eq, policy) {
+                        stats.equipment_kept_existing += 1;
+                    }
                     building.floors[floor_idx].equipment[ei] = inc_eq;
                 }
                 None => {
@@ -552,7 +637,13 @@ fn merge_room_fields(room: &mut Room, old: &Room, _policy: &MergePolicy) {
     }
 }
 
-fn merge_equipment_fields(eq: &mut Equipment, old: &Equipment, _policy: &MergePolicy) {
+/// Merge `old` into matched incoming `eq`. Returns true when the strategy kept `old`
+/// as it was instead.
+fn merge_equipment_fields(eq: &mut Equipment, old: &Equipment, policy: &MergePolicy) -> bool {
+    if policy.strategy.keeps_existing(old, eq) {
+        *eq = old.clone();
+        return true;
+    }
     eq.id = old.id.clone();
     eq.status = old.status;
     eq.health_status = old.health_status;
@@ -575,6 +666,7 @@ fn merge_equipment_fields(eq: &mut Equipment, old: &Equipment, _policy: &MergePo
         merged.insert(k, v);
     }
     eq.properties = merged;
+    false
 }
 
 fn finish_orphan_stats(
@@ -877,6 +969,7 @@ fn equipment_types_compatible(a: &EquipmentType, b: &EquipmentType) -> bool {
 #[cfg(test)]
 mod tests {
     use super::*;
+    use crate::core::review::PROP_REVIEW_STATUS;
     use crate::core::{
         EquipmentStatus, EquipmentType, Floor, LidarEnrichment, Position, Room, RoomType, Wing,
     };
@@ -1066,4 +1159,103 @@ mod tests {
         assert_eq!(e.lidar_enrichment.as_ref().map(|e| e.point_count), Some(80));
         assert!((e.position.x - 2.25).abs() < 1e-9);
     }
+
+    fn scanned(name: &str, gid: &str, confidence: f64, x: f64) -> Equipment {
+        let mut eq = Equipment::new(name.into(), "".into(), EquipmentType::HVAC);
+        eq.ifc_global_id = Some(gid.into());
+        eq.position.x = x;
+        eq.lidar_enrichment = Some(LidarEnrichment {
+            point_count: 10,
+            confidence_score: confidence,
+            last_scan_timestamp: None,
+            classification_heuristic: None,
+        });
+        eq
+    }
+
+    fn reimport(strategy: MergeStrategy) -> (Building, MergeResult) {
+        let mut existing = Building::new("HQ".into(), "/hq".into());
+        let mut floor = Floor::new("F1".into(), 1);
+        let mut validated = scanned("AHU-1", "Gid-A", 0.3, 1.0);
+        validated
+            .properties
+            .insert(PROP_VALIDATED_AT.into(), "2026-10-01T09:00:00Z".into());
+        validated
+            .properties
+            .insert("serial".into(), "field-read".into());
+        floor.equipment.push(validated);
+        floor.equipment.push(scanned("VAV-1", "Gid-B", 0.9, 2.0));
+        floor.equipment.push(scanned("VAV-2", "Gid-C", 0.2, 3.0));
+        // Reviewed in the field and rejected: stamped, but not validated.
+        let mut rejected = scanned("VAV-3", "Gid-D", 0.4, 4.0);
+        for (key, value) in [
+            (PROP_REVIEW_STATUS, "rejected"),
+            (PROP_VALIDATED_AT, "2026-10-02T09:00:00Z"),
+        ] {
+            rejected.properties.insert(key.into(), value.into());
+        }
+        floor.equipment.push(rejected);
+        existing.add_floor(floor);
+
+        // Updated drawing: every object moved, with its own confidence.
+        let mut incoming = Building::new("HQ".into(), "/hq".into());
+        let mut floor = Floor::new("F1".into(), 1);
+        let mut ahu = scanned("AHU-1", "Gid-A", 0.8, 11.0);
+        ahu.properties.insert("serial".into(), "drawing".into());
+        floor.equipment.push(ahu);
+        floor.equipment.push(scanned("VAV-1", "Gid-B", 0.5, 12.0));
+        floor.equipment.push(scanned("VAV-2", "Gid-C", 0.7, 13.0));
+        floor.equipment.push(scanned("VAV-3", "Gid-D", 0.6, 14.0));
+        incoming.add_floor(floor);
+
+        let policy = MergePolicy::ifc().with_strategy(strategy);
+        let result = merge_building_with_policy(&existing, incoming, &policy);
+        (existing, result)
+    }
+
+    fn x_of(building: &Building, name: &str) -> f64 {
+        building.floors[0]
+            .equipment
+            .iter()
+            .find(|e| e.name == name)
+            .unwrap()
+            .position
+            .x
+    }
+
+    #[test]
+    fn merge_strategy_decides_whose_record_wins() {
+        let (_, result) = reimport(MergeStrategy::Replace);
+        assert_eq!(result.stats.equipment_kept_existing, 0);
+        assert_eq!(x_of(&result.building, "AHU-1"), 11.0);
+
+        let (existing_kv, result) = reimport(MergeStrategy::KeepValidated);
+        assert_eq!(result.stats.equipment_matched, 4);
+        assert_eq!(result.stats.equipment_kept_existing, 1);
+        let ahu = &result.building.floors[0].equipment[0];
+        assert_eq!(ahu.id, existing_kv.floors[0].equipment[0].id);
+        assert_eq!(ahu.position.x, 1.0);
+        assert_eq!(ahu.properties["serial"], "field-read");
+        assert_eq!(x_of(&result.building, "VAV-1"), 12.0);
+        assert_eq!(x_of(&result.building, "VAV-2"), 13.0);
+        assert_eq!(x_of(&result.building, "VAV-3"), 14.0);
+
+        // Validated counts as full confidence; otherwise the higher score wins.
+        let (existing, result) = reimport(MergeStrategy::KeepHigherConfidence);
+        assert_eq!(result.stats.equipment_kept_existing, 2);
+        assert_eq!(x_of(&result.building, "AHU-1"), 1.0);
+        assert_eq!(x_of(&result.building, "VAV-1"), 2.0);
+        assert_eq!(x_of(&result.building, "VAV-2"), 13.0);
+        assert_eq!(x_of(&result.building, "VAV-3"), 14.0);
+        assert_eq!(
+            result.building.floors[0].equipment[1].id,
+            existing.floors[0].equipment[1].id
+        );
+
+        assert_eq!(
+            MergeStrategy::parse("keep_validated"),
+            Some(MergeStrategy::KeepValidated)
+        );
+        assert_eq!(MergeStrategy::parse("newest"), None);
+    }
 }
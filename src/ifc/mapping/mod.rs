@@ -28,7 +28,7 @@ pub use lidar::{
 };
 pub use merge::{
     merge_building, merge_building_with_policy, merge_into_report, merge_into_report_with_policy,
-    HierarchyBase, MergePolicy, MergeResult, MergeSource,
+    HierarchyBase, MergePolicy, MergeResult, MergeSource, MergeStrategy,
 };
 pub use properties::{
     normalize_imported_properties, properties_for_export, wing_name_from_properties, PROP_ARX_WING,
@@ -63,6 +63,9 @@ pub struct MergeStats {
     pub existing_rooms_not_in_incoming: usize,
     /// Existing equipment not present in the incoming model
     pub existing_equipment_not_in_incoming: usize,
+    /// Matched equipment left as it was by the merge strategy
+    #[serde(default)]
+    pub equipment_kept_existing: usize,
 }
 
 /// Aggregated result of an IFC import/export mapping operation.
@@ -108,6 +111,12 @@ impl LossReport {
                 "Merge: rooms {} matched / {} added, equipment {} matched / {} added",
                 m.rooms_matched, m.rooms_added, m.equipment_matched, m.equipment_added
             ));
+            if m.equipment_kept_existing > 0 {
+                lines.push(format!(
+                    "Kept existing: {} equipment (merge strategy)",
+                    m.equipment_kept_existing
+                ));
+            }
             if m.existing_rooms_not_in_incoming > 0 || m.existing_equipment_not_in_incoming > 0 {
                 lines.push(format!(
                     "Existing not in IFC: {} rooms, {} equipment (not carried into result)",
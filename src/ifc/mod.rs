@@ -23,6 +23,7 @@ pub use mapping::{
     assign_missing_global_ids, merge_building, merge_building_with_policy, merge_into_report,
     merge_into_report_with_policy, report_export_losses, resolve_product_global_id, FidelityLevel,
     HierarchyBase, LossReport, MappingResult, MergePolicy, MergeResult, MergeSource, MergeStats,
+    MergeStrategy,
 };
 pub use spatial::{SpatialIndex, SpatialQueryResult, SpatialRelationship};
 
@@ -82,9 +83,10 @@ impl IFCProcessor {
         content: &str,
         validate_strict: bool,
     ) -> anyhow::Result<ParsingResult> {
+        let budget = crate::resource_limits::ParseBudget::ifc();
         let lexer = parser::StepLexer::new(content);
         let mut registry = parser::EntityRegistry::new();
-        registry.populate_from_lexer(lexer);
+        registry.populate_within_budget(lexer, &budget)?;
 
         let stats = registry.get_stats();
 
@@ -96,6 +98,7 @@ impl IFCProcessor {
 
         let mut resolver = parser::IfcResolver::new(&mut registry);
         let (building, report) = resolver.resolve_all()?;
+        budget.check(stats.total_entities)?;
         let warnings = report
             .warnings
             .iter()
@@ -5,6 +5,7 @@
 
 use super::lexer::{Param, RawEntity};
 use crate::core::domain::ArxAddress;
+use crate::resource_limits::ParseBudget;
 use std::collections::HashMap;
 
 /// A graph-aware cache for STEP entities and their ArxOS counterparts.
@@ -73,6 +74,24 @@ impl EntityRegistry {
         }
     }
 
+    /// Populate the registry from a lexer, stopping with an error as soon as `budget`
+    /// is exceeded instead of reading the whole document into memory.
+    pub fn populate_within_budget(
+        &mut self,
+        mut lexer: crate::ifc::parser::lexer::StepLexer,
+        budget: &ParseBudget,
+    ) -> anyhow::Result<()> {
+        while let Some(entity) = lexer.next_entity() {
+            self.register(entity);
+            // The clock is read every 1024 entities; the count is compared every time.
+            let count = self.entities.len();
+            if count > budget.max_entities || count % 1024 == 0 {
+                budget.check(count)?;
+            }
+        }
+        budget.check(self.entities.len())
+    }
+
     /// Get all entity IDs that are contained within or aggregated by a specific entity.
     pub fn get_contained(&self, container_id: u64) -> Vec<u64> {
         let mut kids = Vec::new();
@@ -176,4 +195,29 @@ mod tests {
         assert_eq!(stats.spatial_entities, 1);
         assert_eq!(stats.class_counts.get("IFCWALL"), Some(&1));
     }
+
+    #[test]
+    fn test_populate_stops_at_entity_budget() {
+        // Many trivial points: small on disk, large once parsed.
+        let mut content = String::from("ISO-10303-21;\nDATA;\n");
+        for id in 1..=5_000 {
+            content.push_str(&format!("#{}=IFCCARTESIANPOINT((0.,0.,0.));\n", id));
+        }
+        content.push_str("ENDSEC;\nEND-ISO-10303-21;\n");
+
+        let budget = ParseBudget::new("IFC", 1_000, std::time::Duration::from_secs(60));
+        let mut registry = EntityRegistry::new();
+        let err = registry
+            .populate_within_budget(crate::ifc::parser::StepLexer::new(&content), &budget)
+            .unwrap_err();
+        assert!(err.to_string().contains("too complex"), "{}", err);
+        assert_eq!(registry.entity_count(), 1_001);
+
+        let roomy = ParseBudget::new("IFC", 10_000, std::time::Duration::from_secs(60));
+        let mut registry = EntityRegistry::new();
+        registry
+            .populate_within_budget(crate::ifc::parser::StepLexer::new(&content), &roomy)
+            .unwrap();
+        assert_eq!(registry.entity_count(), 5_000);
+    }
 }
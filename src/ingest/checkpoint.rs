@@ -0,0 +1,345 @@
+//! Opt-in checkpoints for long imports.
+//!
+//! After an importer's parse stage completes, the parsed model is written to
+//! `.arxos/import_checkpoints/<importer>-<sha256>.yaml`, keyed by the source file's
+//! content hash and the import options (see [`checkpoint_key`]). A retried import of
+//! the same bytes with the same options resumes from that checkpoint and only
+//! re-runs merge + validation. The checkpoint is removed once the import
+//! finishes, and checkpoints older than the store TTL are ignored and pruned.
+
+use std::path::{Path, PathBuf};
+
+use anyhow::{anyhow, Context, Result};
+use chrono::{DateTime, Duration, Utc};
+use serde::{Deserialize, Serialize};
+use sha2::{Digest, Sha256};
+
+use super::import::{IngestResult, ParsedImport};
+use super::importer::{ImportOptions, Importer};
+use super::LossReport;
+use crate::yaml::BuildingYamlSerializer;
+
+/// Default lifetime of a checkpoint.
+pub const DEFAULT_CHECKPOINT_TTL_HOURS: i64 = 24;
+
+/// Last import stage that completed before the checkpoint was written.
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Serialize, Deserialize)]
+#[serde(rename_all = "snake_case")]
+pub enum ImportStage {
+    Parsed,
+}
+
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct ImportCheckpoint {
+    pub importer: String,
+    /// [`checkpoint_key`] of the source file and options.
+    pub content_hash: String,
+    pub stage: ImportStage,
+    pub created_at: DateTime<Utc>,
+    /// Parsed model as canonical building YAML.
+    pub building_yaml: String,
+    #[serde(default)]
+    pub report: LossReport,
+}
+
+/// Directory of import checkpoints under a repository.
+pub struct ImportCheckpointStore {
+    dir: PathBuf,
+    ttl: Duration,
+}
+
+impl ImportCheckpointStore {
+    pub fn new(repo_root: &Path) -> Self {
+        Self {
+            dir: repo_root.join(".arxos/import_checkpoints"),
+            ttl: Duration::hours(DEFAULT_CHECKPOINT_TTL_HOURS),
+        }
+    }
+
+    pub fn with_ttl(mut self, ttl: Duration) -> Self {
+        self.ttl = ttl;
+        self
+    }
+
+    fn path_for(&self, importer: &str, content_hash: &str) -> PathBuf {
+        self.dir.join(format!("{}-{}.yaml", importer, content_hash))
+    }
+
+    fn is_expired(&self, checkpoint: &ImportCheckpoint) -> bool {
+        checkpoint.created_at + self.ttl <= Utc::now()
+    }
+
+    /// Load a live checkpoint. Expired or unreadable checkpoints are removed.
+    pub fn load(&self, importer: &str, content_hash: &str) -> Option<ImportCheckpoint> {
+        let path = self.path_for(importer, content_hash);
+        let content = std::fs::read_to_string(&path).ok()?;
+        match serde_yaml::from_str::<ImportCheckpoint>(&content) {
+            Ok(checkpoint) if !self.is_expired(&checkpoint) => Some(checkpoint),
+            Ok(_) => {
+                let _ = std::fs::remove_file(&path);
+                None
+            }
+            Err(e) => {
+                log::warn!(
+                    "Discarding unreadable import checkpoint {}: {}",
+                    path.display(),
+                    e
+                );
+                let _ = std::fs::remove_file(&path);
+                None
+            }
+        }
+    }
+
+    pub fn save(&self, checkpoint: &ImportCheckpoint) -> Result<()> {
+        std::fs::create_dir_all(&self.dir)
+            .with_context(|| format!("Failed to create {}", self.dir.display()))?;
+        let path = self.path_for(&checkpoint.importer, &checkpoint.content_hash);
+        let content = serde_yaml::to_string(checkpoint)?;
+        std::fs::write(&path, content)
+            .with_context(|| format!("Failed to write {}", path.display()))?;
+        Ok(())
+    }
+
+    pub fn clear(&self, importer: &str, content_hash: &str) {
+        let _ = std::fs::remove_file(self.path_for(importer, content_hash));
+    }
+
+    /// Remove expired checkpoints; returns how many were removed.
+    pub fn prune(&self) -> usize {
+        let Ok(entries) = std::fs::read_dir(&self.dir) else {
+            return 0;
+        };
+        let mut removed = 0;
+        for entry in entries.flatten() {
+            let path = entry.path();
+            let expired = std::fs::read_to_string(&path)
+                .ok()
+                .and_then(|c| serde_yaml::from_str::<ImportCheckpoint>(&c).ok())
+                .map(|cp| self.is_expired(&cp))
+                .unwrap_or(true);
+            if expired && std::fs::remove_file(&path).is_ok() {
+                removed += 1;
+            }
+        }
+        removed
+    }
+}
+
+/// Hex SHA-256 of the file contents.
+pub fn content_hash(path: &Path) -> Result<String> {
+    let bytes = std::fs::read(path).with_context(|| format!("read {}", path.display()))?;
+    Ok(Sha256::digest(&bytes)
+        .iter()
+        .map(|b| format!("{:02x}", b))
+        .collect())
+}
+
+/// Checkpoint key: hex SHA-256 over the file contents and every import option,
+/// including the contents of the building merged into. A checkpoint parsed under
+/// other options (or against another existing building) is never resumed.
+pub fn checkpoint_key(path: &Path, options: &ImportOptions) -> Result<String> {
+    let mut hasher = Sha256::new();
+    hasher.update(content_hash(path)?.as_bytes());
+    let existing = match options.existing_yaml {
+        Some(existing) => format!("{}:{}", existing.display(), content_hash(existing)?),
+        None => String::new(),
+    };
+    hasher.update(
+        format!(
+            "\0existing={}\0validate={}\0merge={:?}",
+            existing, options.validate, options.merge_strategy
+        )
+        .as_bytes(),
+    );
+    Ok(hasher
+        .finalize()
+        .iter()
+        .map(|b| format!("{:02x}", b))
+        .collect())
+}
+
+/// Run `importer` on `path`, resuming from a checkpoint when one exists for the same
+/// bytes and options.
+pub fn import_with_checkpoint(
+    importer: &dyn Importer,
+    path: &Path,
+    options: &ImportOptions,
+    store: &ImportCheckpointStore,
+) -> Result<IngestResult> {
+    let hash = checkpoint_key(path, options)?;
+
+    let parsed = match store.load(importer.name(), &hash) {
+        Some(checkpoint) => {
+            log::info!(
+                "Resuming {} import from parsed checkpoint {}",
+                importer.name(),
+                hash
+            );
+            let building = BuildingYamlSerializer::deserialize_building(&checkpoint.building_yaml)
+                .map_err(|e| anyhow!("Corrupt import checkpoint: {}", e))?;
+            ParsedImport {
+                source: importer.source(),
+                building,
+                report: checkpoint.report,
+            }
+        }
+        None => {
+            let parsed = importer.parse(path, options)?;
+            let building_yaml = BuildingYamlSerializer::serialize_building(&parsed.building)
+                .map_err(|e| anyhow!("Failed to serialize import checkpoint: {}", e))?;
+            store.save(&ImportCheckpoint {
+                importer: importer.name().to_string(),
+                content_hash: hash.clone(),
+                stage: ImportStage::Parsed,
+                created_at: Utc::now(),
+                building_yaml,
+                report: parsed.report.clone(),
+            })?;
+            parsed
+        }
+    };
+
+    let result = importer.finish(parsed, options)?;
+    store.clear(importer.name(), &hash);
+    Ok(result)
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::core::{Building, Floor};
+    use crate::ifc::mapping::MergeStrategy;
+    use crate::ingest::IngestSource;
+    use std::sync::atomic::{AtomicBool, AtomicUsize, Ordering};
+    use tempfile::TempDir;
+
+    /// Counts parses and fails the first `finish`, standing in for a crash mid-import.
+    struct FlakyImporter {
+        parses: AtomicUsize,
+        crash_on_finish: AtomicBool,
+    }
+
+    impl Importer for FlakyImporter {
+        fn name(&self) -> &'static str {
+            "flaky"
+        }
+
+        fn source(&self) -> IngestSource {
+            IngestSource::Lidar
+        }
+
+        fn can_handle(&self, _path: &Path, _content_type: Option<&str>) -> bool {
+            true
+        }
+
+        fn parse(&self, path: &Path, _options: &ImportOptions) -> Result<ParsedImport> {
+            self.parses.fetch_add(1, Ordering::SeqCst);
+            let name = std::fs::read_to_string(path)?;
+            let mut building = Building::new(name.trim().to_string(), "/scan".into());
+            building.add_floor(Floor::new("Ground".into(), 0));
+            let mut report = LossReport::default();
+            report.warn("flaky.parse", "parsed");
+            Ok(ParsedImport {
+                source: self.source(),
+                building,
+                report,
+            })
+        }
+
+        fn finish(&self, parsed: ParsedImport, options: &ImportOptions) -> Result<IngestResult> {
+            if self.crash_on_finish.swap(false, Ordering::SeqCst) {
+                return Err(anyhow!("simulated crash"));
+            }
+            crate::ingest::finish_import_with_strategy(
+                parsed,
+                options.existing_yaml,
+                options.validate,
+                options.merge_strategy,
+            )
+        }
+    }
+
+    fn setup() -> (TempDir, PathBuf, FlakyImporter) {
+        let temp = TempDir::new().unwrap();
+        let source = temp.path().join("scan.txt");
+        std::fs::write(&source, "Annex").unwrap();
+        let importer = FlakyImporter {
+            parses: AtomicUsize::new(0),
+            crash_on_finish: AtomicBool::new(true),
+        };
+        (temp, source, importer)
+    }
+
+    #[test]
+    fn retry_after_crash_skips_parse() {
+        let (temp, source, importer) = setup();
+        let store = ImportCheckpointStore::new(temp.path());
+        let options = ImportOptions::default();
+
+        assert!(import_with_checkpoint(&importer, &source, &options, &store).is_err());
+        assert_eq!(importer.parses.load(Ordering::SeqCst), 1);
+        let hash = checkpoint_key(&source, &options).unwrap();
+        assert!(store.load("flaky", &hash).is_some());
+
+        let result = import_with_checkpoint(&importer, &source, &options, &store).unwrap();
+        assert_eq!(importer.parses.load(Ordering::SeqCst), 1);
+        assert_eq!(result.building.name, "Annex");
+        assert!(result
+            .report
+            .warnings
+            .iter()
+            .any(|w| w.code == "flaky.parse"));
+        assert!(store.load("flaky", &hash).is_none());
+    }
+
+    #[test]
+    fn expired_or_changed_input_reparses() {
+        let (temp, source, importer) = setup();
+        let options = ImportOptions::default();
+
+        let expired = ImportCheckpointStore::new(temp.path()).with_ttl(Duration::zero());
+        assert!(import_with_checkpoint(&importer, &source, &options, &expired).is_err());
+        assert_eq!(expired.prune(), 1);
+        import_with_checkpoint(&importer, &source, &options, &expired).unwrap();
+        assert_eq!(importer.parses.load(Ordering::SeqCst), 2);
+
+        let store = ImportCheckpointStore::new(temp.path());
+        importer.crash_on_finish.store(true, Ordering::SeqCst);
+        assert!(import_with_checkpoint(&importer, &source, &options, &store).is_err());
+        std::fs::write(&source, "Annex B").unwrap();
+        let result = import_with_checkpoint(&importer, &source, &options, &store).unwrap();
+        assert_eq!(importer.parses.load(Ordering::SeqCst), 4);
+        assert_eq!(result.building.name, "Annex B");
+    }
+
+    #[test]
+    fn changed_options_reparse() {
+        let (temp, source, importer) = setup();
+        let store = ImportCheckpointStore::new(temp.path());
+        let options = ImportOptions::default();
+        assert!(import_with_checkpoint(&importer, &source, &options, &store).is_err());
+
+        let existing = temp.path().join("building.yaml");
+        let mut building = Building::new("Annex".into(), "/scan".into());
+        building.add_floor(Floor::new("Ground".into(), 0));
+        std::fs::write(
+            &existing,
+            BuildingYamlSerializer::serialize_building(&building).unwrap(),
+        )
+        .unwrap();
+        let merged = ImportOptions {
+            existing_yaml: Some(&existing),
+            merge_strategy: MergeStrategy::KeepValidated,
+            ..options
+        };
+        assert_ne!(
+            checkpoint_key(&source, &options).unwrap(),
+            checkpoint_key(&source, &merged).unwrap()
+        );
+        import_with_checkpoint(&importer, &source, &merged, &store).unwrap();
+        assert_eq!(importer.parses.load(Ordering::SeqCst), 2);
+        let key = checkpoint_key(&source, &options).unwrap();
+        assert!(store.load("flaky", &key).is_some());
+    }
+}
@@ -0,0 +1,714 @@
+//! Delta sync for offline clients: pull changes since a cursor, push edits with
+//! conflict detection.
+//!
+//! A version is a short content hash of the object's JSON form, so any edit —
+//! from CLI, IFC re-import, or another device — changes it. A room's form leaves
+//! out its equipment, which syncs as objects of its own.
+//!
+//! The cursor is a sequence number from the sync journal
+//! (`.arxos/sync_journal.yaml`). Each pull compares `building.yaml` with the
+//! journal, gives every object whose version changed the next sequence number and
+//! keeps a tombstone for every object that disappeared; the changes since a cursor
+//! are then the objects and tombstones with a higher number. Once old tombstones
+//! are dropped, cursors from before them are refused and the client starts over.
+//!
+//! ```json
+//! { "cursor": "42", "changes": [
+//!   { "kind": "room", "id": "r1", "op": "updated", "version": "9f2c…", "object": { ... } },
+//!   { "kind": "equipment", "id": "e7", "op": "deleted" }
+//! ] }
+//! ```
+
+use std::collections::BTreeMap;
+use std::path::Path;
+use std::sync::Mutex;
+
+use serde::{Deserialize, Serialize};
+use serde_json::Value;
+use sha2::{Digest, Sha256};
+
+use crate::core::precision::CoordinatePrecision;
+use crate::core::{Building, Equipment, Room};
+
+/// Project file holding the sync journal.
+pub const SYNC_JOURNAL_FILE: &str = ".arxos/sync_journal.yaml";
+
+/// Tombstones kept in the journal; older ones are dropped and expire their cursors.
+pub const MAX_TOMBSTONES: usize = 10_000;
+
+/// Serializes journal updates within the process.
+static JOURNAL_LOCK: Mutex<()> = Mutex::new(());
+
+/// Kind of object carried in a delta.
+#[derive(Debug, Clone, Copy, PartialEq, Eq, PartialOrd, Ord, Serialize, Deserialize)]
+#[serde(rename_all = "lowercase")]
+pub enum SyncObjectKind {
+    Room,
+    Equipment,
+}
+
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Serialize, Deserialize)]
+#[serde(rename_all = "lowercase")]
+pub enum ChangeOp {
+    Created,
+    Updated,
+    /// Tombstone: the object no longer exists.
+    Deleted,
+}
+
+/// One object-level change, used in both pull responses and push requests.
+#[derive(Debug, Clone, PartialEq, Serialize, Deserialize)]
+pub struct ObjectChange {
+    pub kind: SyncObjectKind,
+    pub id: String,
+    pub op: ChangeOp,
+    /// Server version after the change (pull only).
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub version: Option<String>,
+    /// Version the client edited from (push; required for updates and deletes).
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub base_version: Option<String>,
+    /// Full object JSON; absent for tombstones.
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub object: Option<Value>,
+    /// Floor level for newly created rooms / common-area equipment.
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub floor: Option<i32>,
+    /// Wing name for newly created rooms (defaults to the floor's first wing).
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub wing: Option<String>,
+}
+
+/// Result of a pull.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct ChangeSet {
+    pub cursor: String,
+    pub changes: Vec<ObjectChange>,
+}
+
+/// A pushed change that was not applied because the server copy moved on.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct SyncConflict {
+    pub kind: SyncObjectKind,
+    pub id: String,
+    pub reason: String,
+    /// Current server version; `None` when the server deleted the object.
+    pub server_version: Option<String>,
+    pub server_object: Option<Value>,
+}
+
+/// Outcome of a push.
+#[derive(Debug, Clone, Default, Serialize, Deserialize)]
+pub struct PushOutcome {
+    pub applied: Vec<String>,
+    pub conflicts: Vec<SyncConflict>,
+    /// Changes rejected for reasons other than a conflict (bad payload, placement).
+    pub rejected: Vec<String>,
+}
+
+impl PushOutcome {
+    pub fn has_changes(&self) -> bool {
+        !self.applied.is_empty()
+    }
+}
+
+type ObjectKey = (SyncObjectKind, String);
+
+/// Last known version of an object and the sequence numbers that created and last
+/// changed (or deleted) it.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+struct JournalEntry {
+    kind: SyncObjectKind,
+    id: String,
+    version: String,
+    created: u64,
+    changed: u64,
+}
+
+impl JournalEntry {
+    fn key(&self) -> ObjectKey {
+        (self.kind, self.id.clone())
+    }
+}
+
+/// Sequence-numbered versions of every object, and tombstones of deleted ones.
+#[derive(Debug, Clone, Default, Serialize, Deserialize)]
+pub struct SyncJournal {
+    /// Last sequence number handed out; the cursor of a pull.
+    #[serde(default)]
+    pub sequence: u64,
+    /// Highest sequence number of a dropped tombstone; older cursors are expired.
+    #[serde(default)]
+    pub horizon: u64,
+    #[serde(default)]
+    objects: Vec<JournalEntry>,
+    #[serde(default)]
+    tombstones: Vec<JournalEntry>,
+}
+
+impl SyncJournal {
+    /// Load the journal under `base`; a missing file is an empty journal.
+    pub fn load(base: &Path) -> Result<Self, String> {
+        let path = base.join(SYNC_JOURNAL_FILE);
+        if !path.exists() {
+            return Ok(Self::default());
+        }
+        let content = std::fs::read_to_string(&path)
+            .map_err(|e| format!("read {}: {}", path.display(), e))?;
+        serde_yaml::from_str(&content).map_err(|e| format!("parse {}: {}", path.display(), e))
+    }
+
+    pub fn save(&self, base: &Path) -> Result<(), String> {
+        let path = base.join(SYNC_JOURNAL_FILE);
+        if let Some(parent) = path.parent() {
+            std::fs::create_dir_all(parent)
+                .map_err(|e| format!("create {}: {}", parent.display(), e))?;
+        }
+        let content = serde_yaml::to_string(self).map_err(|e| e.to_string())?;
+        std::fs::write(&path, content).map_err(|e| format!("write {}: {}", path.display(), e))
+    }
+
+    /// Number the objects of `objects` that are new or changed since the last
+    /// observation, and tombstone the ones that are gone.
+    fn observe(&mut self, objects: &BTreeMap<ObjectKey, Value>) {
+        let mut known: BTreeMap<ObjectKey, JournalEntry> = std::mem::take(&mut self.objects)
+            .into_iter()
+            .map(|entry| (entry.key(), entry))
+            .collect();
+        for (key, value) in objects {
+            let version = object_version(value);
+            let entry = match known.remove(key) {
+                Some(entry) if entry.version == version => entry,
+                Some(entry) => {
+                    self.sequence += 1;
+                    JournalEntry {
+                        version,
+                        changed: self.sequence,
+                        ..entry
+                    }
+                }
+                None => {
+                    self.sequence += 1;
+                    self.tombstones.retain(|t| t.key() != *key);
+                    JournalEntry {
+                        kind: key.0,
+                        id: key.1.clone(),
+                        version,
+                        created: self.sequence,
+                        changed: self.sequence,
+                    }
+                }
+            };
+            self.objects.push(entry);
+        }
+        for (_, gone) in known {
+            self.sequence += 1;
+            self.tombstones.push(JournalEntry {
+                changed: self.sequence,
+                ..gone
+            });
+        }
+        if self.tombstones.len() > MAX_TOMBSTONES {
+            self.tombstones.sort_by_key(|t| t.changed);
+            let excess = self.tombstones.len() - MAX_TOMBSTONES;
+            for dropped in self.tombstones.drain(..excess) {
+                self.horizon = self.horizon.max(dropped.changed);
+            }
+        }
+    }
+
+    /// Changes after sequence number `since` (everything when 0), given the
+    /// current `objects` the journal has observed.
+    fn changes_since(
+        &self,
+        objects: &BTreeMap<ObjectKey, Value>,
+        since: u64,
+    ) -> Result<Vec<ObjectChange>, String> {
+        if since > self.sequence {
+            return Err("sync cursor is ahead of the server; sync from scratch".to_string());
+        }
+        if since > 0 && since < self.horizon {
+            return Err("sync cursor has expired; sync from scratch".to_string());
+        }
+        let mut changes = Vec::new();
+        for entry in self.objects.iter().filter(|e| e.changed > since) {
+            let op = if entry.created > since {
+                ChangeOp::Created
+            } else {
+                ChangeOp::Updated
+            };
+            changes.push(ObjectChange {
+                kind: entry.kind,
+                id: entry.id.clone(),
+                op,
+                version: Some(entry.version.clone()),
+                base_version: None,
+                object: objects.get(&entry.key()).cloned(),
+                floor: None,
+                wing: None,
+            });
+        }
+        // Objects created and deleted since the cursor were never seen by the client.
+        for tombstone in &self.tombstones {
+            if tombstone.changed > since && tombstone.created <= since {
+                changes.push(ObjectChange {
+                    kind: tombstone.kind,
+                    id: tombstone.id.clone(),
+                    op: ChangeOp::Deleted,
+                    version: None,
+                    base_version: None,
+                    object: None,
+                    floor: None,
+                    wing: None,
+                });
+            }
+        }
+        Ok(changes)
+    }
+}
+
+/// Version string for an object: first 16 hex chars of SHA-256 over its JSON.
+pub fn object_version(object: &Value) -> String {
+    let bytes = serde_json::to_vec(object).unwrap_or_default();
+    let digest = Sha256::digest(&bytes);
+    digest
+        .iter()
+        .take(8)
+        .map(|b| format!("{:02x}", b))
+        .collect()
+}
+
+/// JSON form of a room at output precision, without its equipment list.
+pub(crate) fn room_value(room: &Room) -> Option<Value> {
+    let mut room = room.clone();
+    CoordinatePrecision::configured().apply_room(&mut room);
+    let mut value = serde_json::to_value(room).ok()?;
+    value.as_object_mut()?.remove("equipment");
+    Some(value)
+}
+
+/// JSON form of equipment at output precision.
+pub(crate) fn equipment_value(equipment: &Equipment) -> Option<Value> {
+    let mut equipment = equipment.clone();
+    CoordinatePrecision::configured().apply_equipment(&mut equipment);
+    serde_json::to_value(equipment).ok()
+}
+
+fn snapshot(building: &Building) -> BTreeMap<ObjectKey, Value> {
+    let mut objects = BTreeMap::new();
+    for room in building.get_all_rooms() {
+        if let Some(value) = room_value(room) {
+            objects.insert((SyncObjectKind::Room, room.id.clone()), value);
+        }
+    }
+    for equipment in building.get_all_equipment() {
+        if let Some(value) = equipment_value(equipment) {
+            objects.insert((SyncObjectKind::Equipment, equipment.id.clone()), value);
+        }
+    }
+    objects
+}
+
+fn current_object(building: &Building, kind: SyncObjectKind, id: &str) -> Option<Value> {
+    match kind {
+        SyncObjectKind::Room => building.find_room(id).and_then(room_value),
+        SyncObjectKind::Equipment => building
+            .get_all_equipment()
+            .into_iter()
+            .find(|e| e.id == id)
+            .and_then(equipment_value),
+    }
+}
+
+/// Changes between the sequence number in cursor `since` and `building`, with
+/// the cursor to pass next time. The journal under `base` is updated first.
+///
+/// `since = None` (first sync) returns every object as created.
+pub fn pull_changes(
+    base: &Path,
+    building: &Building,
+    since: Option<&str>,
+) -> Result<ChangeSet, String> {
+    let since = match since.map(str::trim) {
+        Some(cursor) if !cursor.is_empty() => cursor
+            .parse::<u64>()
+            .map_err(|_| format!("invalid sync cursor '{}'", cursor))?,
+        _ => 0,
+    };
+    let objects = snapshot(building);
+    let _guard = JOURNAL_LOCK.lock().unwrap_or_else(|e| e.into_inner());
+    let mut journal = SyncJournal::load(base)?;
+    let before = journal.sequence;
+    journal.observe(&objects);
+    if journal.sequence != before {
+        journal.save(base)?;
+    }
+    Ok(ChangeSet {
+        cursor: journal.sequence.to_string(),
+        changes: journal.changes_since(&objects, since)?,
+    })
+}
+
+/// Apply client changes to `building`, skipping any whose base version is stale.
+///
+/// The caller persists the mutated building through `persist_building_at` so the
+/// usual validation gate applies to synced edits.
+pub fn push_changes(building: &mut Building, changes: &[ObjectChange]) -> PushOutcome {
+    let mut outcome = PushOutcome::default();
+
+    for change in changes {
+        let current = current_object(building, change.kind, &change.id);
+        let current_version = current.as_ref().map(object_version);
+
+        let conflict = match change.op {
+            ChangeOp::Created if current.is_some() => Some("object already exists on server"),
+            ChangeOp::Updated | ChangeOp::Deleted if current.is_none() => {
+                Some("object was deleted on server")
+            }
+            ChangeOp::Updated | ChangeOp::Deleted if change.base_version != current_version => {
+                Some("object was modified on server since base version")
+            }
+            _ => None,
+        };
+        if let Some(reason) = conflict {
+            outcome.conflicts.push(SyncConflict {
+                kind: change.kind,
+                id: change.id.clone(),
+                reason: reason.to_string(),
+                server_version: current_version,
+                server_object: current,
+            });
+            continue;
+        }
+
+        match apply_change(building, change) {
+            Ok(()) => outcome.applied.push(change.id.clone()),
+            Err(e) => outcome.rejected.push(format!("{}: {}", change.id, e)),
+        }
+    }
+
+    outcome
+}
+
+fn apply_change(building: &mut Building, change: &ObjectChange) -> Result<(), String> {
+    let object = || {
+        change
+            .object
+            .clone()
+            .ok_or_else(|| "missing object payload".to_string())
+    };
+
+    match (change.kind, change.op) {
+        (SyncObjectKind::Room, ChangeOp::Deleted) => delete_room(building, &change.id),
+        (SyncObjectKind::Equipment, ChangeOp::Deleted) => delete_equipment(building, &change.id),
+        (SyncObjectKind::Room, op) => {
+            // Rooms sync without their equipment list; equipment names its room.
+            let mut value = object()?;
+            if let Some(fields) = value.as_object_mut() {
+                fields
+                    .entry("equipment")
+                    .or_insert_with(|| Value::Array(Vec::new()));
+            }
+            let mut room: Room = serde_json::from_value(value).map_err(|e| e.to_string())?;
+            if room.id != change.id {
+                return Err(format!("object id '{}' does not match change id", room.id));
+            }
+            room.pending_equipment_ids.clear();
+            room.pending_anchor_ids.clear();
+            if op == ChangeOp::Created {
+                insert_room(building, room, change.floor, change.wing.as_deref())
+            } else {
+                let existing = building.find_room_mut(&change.id).ok_or("room not found")?;
+                room.equipment = std::mem::take(&mut existing.equipment);
+                room.anchors = std::mem::take(&mut existing.anchors);
+                *existing = room;
+                Ok(())
+            }
+        }
+        (SyncObjectKind::Equipment, op) => {
+            let equipment: Equipment =
+                serde_json::from_value(object()?).map_err(|e| e.to_string())?;
+            if equipment.id != change.id {
+                return Err(format!(
+                    "object id '{}' does not match change id",
+                    equipment.id
+                ));
+            }
+            if op == ChangeOp::Created {
+                insert_equipment(building, equipment, change.floor)
+            } else {
+                let existing = building
+                    .get_all_equipment_mut()
+                    .into_iter()
+                    .find(|e| e.id == change.id)
+                    .ok_or("equipment not found")?;
+                *existing = equipment;
+                Ok(())
+            }
+        }
+    }
+}
+
+fn insert_room(
+    building: &mut Building,
+    room: Room,
+    floor: Option<i32>,
+    wing: Option<&str>,
+) -> Result<(), String> {
+    let level = floor.ok_or("new room requires a floor level")?;
+    let floor = building
+        .floors
+        .iter_mut()
+        .find(|f| f.level == level)
+        .ok_or_else(|| format!("floor {} not found", level))?;
+    let wing = match wing {
+        Some(name) => floor.wings.iter_mut().find(|w| w.name == name),
+        None => floor.wings.first_mut(),
+    }
+    .ok_or("wing not found on floor")?;
+    wing.rooms.push(room);
+    Ok(())
+}
+
+fn insert_equipment(
+    building: &mut Building,
+    equipment: Equipment,
+    floor: Option<i32>,
+) -> Result<(), String> {
+    if let Some(room_id) = equipment.room_id.clone() {
+        let room = building
+            .find_room_mut(&room_id)
+            .ok_or_else(|| format!("room '{}' not found", room_id))?;
+        room.equipment.push(equipment);
+        return Ok(());
+    }
+    let level = floor.ok_or("new equipment requires a room_id or floor level")?;
+    let floor = building
+        .floors
+        .iter_mut()
+        .find(|f| f.level == level)
+        .ok_or_else(|| format!("floor {} not found", level))?;
+    floor.equipment.push(equipment);
+    Ok(())
+}
+
+fn delete_room(building: &mut Building, id: &str) -> Result<(), String> {
+    for floor in &mut building.floors {
+        for wing in &mut floor.wings {
+            if let Some(idx) = wing.rooms.iter().position(|r| r.id == id) {
+                if !wing.rooms[idx].equipment.is_empty() {
+                    return Err(format!(
+                        "room still holds {} equipment; delete or move it first",
+                        wing.rooms[idx].equipment.len()
+                    ));
+                }
+                wing.rooms.remove(idx);
+                return Ok(());
+            }
+        }
+    }
+    Err("room not found".to_string())
+}
+
+fn delete_equipment(building: &mut Building, id: &str) -> Result<(), String> {
+    for floor in &mut building.floors {
+        if let Some(idx) = floor.equipment.iter().position(|e| e.id == id) {
+            floor.equipment.remove(idx);
+            return Ok(());
+        }
+        for wing in &mut floor.wings {
+            for room in &mut wing.rooms {
+                if let Some(idx) = room.equipment.iter().position(|e| e.id == id) {
+                    room.equipment.remove(idx);
+                    return Ok(());
+                }
+            }
+        }
+    }
+    Err("equipment not found".to_string())
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::core::{EquipmentType, Floor, RoomType, Wing};
+
+    fn sample_building() -> Building {
+        let mut building = Building::new("HQ".into(), "/hq".into());
+        let mut floor = Floor::new("F0".into(), 0);
+        let mut wing = Wing::new("Main".into());
+        let mut room = Room::new("R1".into(), RoomType::Office);
+        room.id = "room-1".into();
+        wing.add_room(room);
+        floor.add_wing(wing);
+        let mut eq = Equipment::new("AHU".into(), "/eq/ahu".into(), EquipmentType::HVAC);
+        eq.id = "eq-1".into();
+        floor.equipment.push(eq);
+        building.add_floor(floor);
+        building
+    }
+
+    fn find<'a>(set: &'a ChangeSet, id: &str) -> &'a ObjectChange {
+        set.changes.iter().find(|c| c.id == id).expect("change")
+    }
+
+    #[test]
+    fn pull_reports_created_updated_and_tombstones() {
+        let dir = tempfile::tempdir().unwrap();
+        let mut building = sample_building();
+        let first = pull_changes(dir.path(), &building, None).unwrap();
+        assert_eq!(first.changes.len(), 2);
+        assert!(first.changes.iter().all(|c| c.op == ChangeOp::Created));
+
+        let unchanged = pull_changes(dir.path(), &building, Some(&first.cursor)).unwrap();
+        assert!(unchanged.changes.is_empty());
+
+        building.find_room_mut("room-1").unwrap().name = "Renamed".into();
+        building.floors[0].equipment.clear();
+        let delta = pull_changes(dir.path(), &building, Some(&first.cursor)).unwrap();
+        assert_eq!(delta.changes.len(), 2);
+        assert_eq!(find(&delta, "room-1").op, ChangeOp::Updated);
+        let tombstone = find(&delta, "eq-1");
+        assert_eq!(tombstone.op, ChangeOp::Deleted);
+        assert!(tombstone.object.is_none());
+        assert_ne!(delta.cursor, first.cursor);
+
+        // Already-delivered changes are not repeated; a fresh client gets no tombstones.
+        assert!(pull_changes(dir.path(), &building, Some(&delta.cursor))
+            .unwrap()
+            .changes
+            .is_empty());
+        let fresh = pull_changes(dir.path(), &building, None).unwrap();
+        assert_eq!(fresh.changes.len(), 1);
+        assert_eq!(fresh.changes[0].op, ChangeOp::Created);
+        assert!(pull_changes(dir.path(), &building, Some("99")).is_err());
+        assert!(pull_changes(dir.path(), &building, Some("abc")).is_err());
+    }
+
+    #[test]
+    fn cursor_is_a_sequence_and_rooms_ignore_their_equipment() {
+        let dir = tempfile::tempdir().unwrap();
+        let mut building = sample_building();
+        let first = pull_changes(dir.path(), &building, None).unwrap();
+        assert_eq!(first.cursor, "2");
+        assert!(find(&first, "room-1").object.as_ref().unwrap()["equipment"].is_null());
+
+        // Moving equipment into the room changes the equipment, not the room.
+        let mut eq = building.floors[0].equipment.remove(0);
+        eq.room_id = Some("room-1".into());
+        building.find_room_mut("room-1").unwrap().equipment.push(eq);
+        let moved = pull_changes(dir.path(), &building, Some(&first.cursor)).unwrap();
+        assert_eq!(moved.changes.len(), 1);
+        assert_eq!(moved.changes[0].id, "eq-1");
+        assert_eq!(moved.cursor, "3");
+    }
+
+    #[test]
+    fn sub_precision_noise_is_not_a_change() {
+        let dir = tempfile::tempdir().unwrap();
+        let mut building = sample_building();
+        building.floors[0].equipment[0].position.x = 0.3;
+        let first = pull_changes(dir.path(), &building, None).unwrap();
+
+        building.floors[0].equipment[0].position.x = 0.1 + 0.2;
+        let noise = pull_changes(dir.path(), &building, Some(&first.cursor)).unwrap();
+        assert!(noise.changes.is_empty());
+
+        building.floors[0].equipment[0].position.x = 0.31;
+        let moved = pull_changes(dir.path(), &building, Some(&first.cursor)).unwrap();
+        assert_eq!(find(&moved, "eq-1").op, ChangeOp::Updated);
+        assert_eq!(
+            moved.changes[0].object.as_ref().unwrap()["position"]["x"],
+            serde_json::json!(0.31)
+        );
+    }
+
+    #[test]
+    fn push_without_conflict_applies() {
+        let dir = tempfile::tempdir().unwrap();
+        let mut building = sample_building();
+        let pulled = pull_changes(dir.path(), &building, None).unwrap();
+        let room = find(&pulled, "room-1");
+
+        let mut edited = room.object.clone().unwrap();
+        edited["name"] = Value::String("Field Edit".into());
+        let mut new_eq = Equipment::new("VAV".into(), "/eq/vav".into(), EquipmentType::HVAC);
+        new_eq.id = "eq-2".into();
+        new_eq.room_id = Some("room-1".into());
+
+        let outcome = push_changes(
+            &mut building,
+            &[
+                ObjectChange {
+                    kind: SyncObjectKind::Room,
+                    id: "room-1".into(),
+                    op: ChangeOp::Updated,
+                    version: None,
+                    base_version: room.version.clone(),
+                    object: Some(edited),
+                    floor: None,
+                    wing: None,
+                },
+                ObjectChange {
+                    kind: SyncObjectKind::Equipment,
+                    id: "eq-2".into(),
+                    op: ChangeOp::Created,
+                    version: None,
+                    base_version: None,
+                    object: Some(serde_json::to_value(&new_eq).unwrap()),
+                    floor: None,
+                    wing: None,
+                },
+                ObjectChange {
+                    kind: SyncObjectKind::Equipment,
+                    id: "eq-1".into(),
+                    op: ChangeOp::Deleted,
+                    version: None,
+                    base_version: find(&pulled, "eq-1").version.clone(),
+                    object: None,
+                    floor: None,
+                    wing: None,
+                },
+            ],
+        );
+
+        assert!(outcome.conflicts.is_empty(), "{:?}", outcome.conflicts);
+        assert!(outcome.rejected.is_empty(), "{:?}", outcome.rejected);
+        assert_eq!(outcome.applied.len(), 3);
+        let room = building.find_room("room-1").unwrap();
+        assert_eq!(room.name, "Field Edit");
+        assert_eq!(room.equipment.len(), 1);
+        assert!(building.floors[0].equipment.is_empty());
+    }
+
+    #[test]
+    fn push_with_stale_base_returns_server_version() {
+        let dir = tempfile::tempdir().unwrap();
+        let mut building = sample_building();
+        let pulled = pull_changes(dir.path(), &building, None).unwrap();
+        let room = find(&pulled, "room-1").clone();
+
+        building.find_room_mut("room-1").unwrap().name = "Server Edit".into();
+
+        let mut edited = room.object.clone().unwrap();
+        edited["name"] = Value::String("Client Edit".into());
+        let outcome = push_changes(
+            &mut building,
+            &[ObjectChange {
+                base_version: room.version.clone(),
+                object: Some(edited),
+                op: ChangeOp::Updated,
+                ..room
+            }],
+        );
+
+        assert!(outcome.applied.is_empty());
+        assert_eq!(outcome.conflicts.len(), 1);
+        let conflict = &outcome.conflicts[0];
+        assert_eq!(
+            conflict.server_object.as_ref().unwrap()["name"],
+            Value::String("Server Edit".into())
+        );
+        assert_eq!(building.find_room("room-1").unwrap().name, "Server Edit");
+    }
+}
@@ -0,0 +1,549 @@
+//! Per-object change history from the git log of `building.yaml`.
+//!
+//! Each commit that touched `building.yaml` is loaded and the object is compared
+//! with its previous version in the same JSON form delta sync versions, so a
+//! history entry exists exactly when the object's sync version changed. Nested
+//! fields are reported as dotted paths (`spatial_properties.position.x`); arrays
+//! are compared as whole values.
+//!
+//! Every version keeps the object's full state, so a past state is read by replaying
+//! the timeline up to a timestamp ([`ObjectHistory::as_of`]); [`floor_as_of`] does the
+//! same for every object on a floor. Soft-deleted equipment is dropped from each
+//! version, so a soft delete shows up as a delete.
+
+use std::collections::{BTreeMap, BTreeSet};
+use std::path::Path;
+
+use chrono::{DateTime, TimeZone, Utc};
+use git2::{Repository, Sort};
+use serde::{Deserialize, Serialize};
+use serde_json::Value;
+
+use super::delta::{equipment_value, room_value, ChangeOp, SyncObjectKind};
+use crate::core::operations::strip_deleted;
+use crate::core::Building;
+use crate::yaml::BuildingYamlSerializer;
+
+/// Default page size for history queries.
+pub const DEFAULT_HISTORY_LIMIT: usize = 50;
+/// Largest page a caller may request.
+pub const MAX_HISTORY_LIMIT: usize = 500;
+
+const BUILDING_FILE: &str = "building.yaml";
+
+/// One field that differs between two versions; `None` means absent.
+#[derive(Debug, Clone, PartialEq, Serialize, Deserialize)]
+pub struct FieldChange {
+    pub field: String,
+    pub old: Option<Value>,
+    pub new: Option<Value>,
+}
+
+/// Who committed a version of `building.yaml`, and when.
+#[derive(Debug, Clone, PartialEq, Serialize, Deserialize)]
+pub struct VersionMeta {
+    pub commit: String,
+    pub actor: String,
+    pub timestamp: DateTime<Utc>,
+    pub message: String,
+}
+
+#[derive(Debug, Clone, PartialEq, Serialize, Deserialize)]
+pub struct ObjectVersion {
+    #[serde(flatten)]
+    pub meta: VersionMeta,
+    pub op: ChangeOp,
+    pub changes: Vec<FieldChange>,
+    /// Full object after this version; `None` for a delete.
+    #[serde(skip)]
+    pub state: Option<Value>,
+}
+
+/// An object's state at a point in time.
+#[derive(Debug, Clone, PartialEq)]
+pub enum ObjectAsOf<'a> {
+    /// The object did not exist yet.
+    NotCreated,
+    /// The object as written by `version`.
+    Exists {
+        version: &'a VersionMeta,
+        object: &'a Value,
+    },
+    /// The object had been deleted by `version`.
+    Deleted { version: &'a VersionMeta },
+}
+
+/// Every room and equipment on one floor as of a past commit.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct FloorSnapshot {
+    pub level: i32,
+    pub name: String,
+    /// The `building.yaml` version the snapshot was read from.
+    pub version: VersionMeta,
+    pub rooms: Vec<Value>,
+    pub equipment: Vec<Value>,
+}
+
+/// Filter and page for [`ObjectHistory::page`].
+#[derive(Debug, Clone, Default, Deserialize)]
+pub struct HistoryQuery {
+    /// Keep only changes to this field or fields nested under it.
+    pub field: Option<String>,
+    #[serde(default)]
+    pub offset: usize,
+    pub limit: Option<usize>,
+}
+
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct ObjectHistory {
+    pub id: String,
+    /// `None` when the id never appeared in the history.
+    pub kind: Option<SyncObjectKind>,
+    /// Versions oldest first.
+    pub versions: Vec<ObjectVersion>,
+}
+
+/// One page of an [`ObjectHistory`], after filtering.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct HistoryPage {
+    pub id: String,
+    pub kind: Option<SyncObjectKind>,
+    /// Versions matching the filter, before paging.
+    pub total: usize,
+    pub offset: usize,
+    pub limit: usize,
+    pub versions: Vec<ObjectVersion>,
+}
+
+impl ObjectHistory {
+    /// Build the timeline of `id` from successive building snapshots, oldest first.
+    ///
+    /// A `None` building (file missing at that commit) counts as the object being absent.
+    pub fn from_snapshots<I>(id: &str, snapshots: I) -> Self
+    where
+        I: IntoIterator<Item = (VersionMeta, Option<Building>)>,
+    {
+        let mut kind = None;
+        let mut previous: Option<Value> = None;
+        let mut versions = Vec::new();
+
+        for (meta, building) in snapshots {
+            let current = building.as_ref().and_then(|b| find_object(b, id));
+            let current = match current {
+                Some((k, value)) => {
+                    kind = Some(k);
+                    Some(value)
+                }
+                None => None,
+            };
+            let op = match (&previous, &current) {
+                (None, None) => continue,
+                (Some(old), Some(new)) if old == new => continue,
+                (None, Some(_)) => ChangeOp::Created,
+                (Some(_), Some(_)) => ChangeOp::Updated,
+                (Some(_), None) => ChangeOp::Deleted,
+            };
+            versions.push(ObjectVersion {
+                meta,
+                op,
+                changes: diff_fields(previous.as_ref(), current.as_ref()),
+                state: current.clone(),
+            });
+            previous = current;
+        }
+
+        Self {
+            id: id.to_string(),
+            kind,
+            versions,
+        }
+    }
+
+    /// State of the object at `at`: the last version committed at or before it.
+    pub fn as_of(&self, at: DateTime<Utc>) -> ObjectAsOf<'_> {
+        let Some(version) = self
+            .versions
+            .iter()
+            .take_while(|v| v.meta.timestamp <= at)
+            .last()
+        else {
+            return ObjectAsOf::NotCreated;
+        };
+        match &version.state {
+            Some(object) => ObjectAsOf::Exists {
+                version: &version.meta,
+                object,
+            },
+            None => ObjectAsOf::Deleted {
+                version: &version.meta,
+            },
+        }
+    }
+
+    /// Apply the field filter, then the offset and limit.
+    pub fn page(&self, query: &HistoryQuery) -> HistoryPage {
+        let limit = query
+            .limit
+            .unwrap_or(DEFAULT_HISTORY_LIMIT)
+            .clamp(1, MAX_HISTORY_LIMIT);
+        let matching: Vec<ObjectVersion> = match query.field.as_deref() {
+            Some(field) => self
+                .versions
+                .iter()
+                .filter_map(|v| {
+                    let changes: Vec<FieldChange> = v
+                        .changes
+                        .iter()
+                        .filter(|c| field_matches(&c.field, field))
+                        .cloned()
+                        .collect();
+                    (!changes.is_empty()).then(|| ObjectVersion {
+                        changes,
+                        ..v.clone()
+                    })
+                })
+                .collect(),
+            None => self.versions.clone(),
+        };
+
+        HistoryPage {
+            id: self.id.clone(),
+            kind: self.kind,
+            total: matching.len(),
+            offset: query.offset,
+            limit,
+            versions: matching
+                .into_iter()
+                .skip(query.offset)
+                .take(limit)
+                .collect(),
+        }
+    }
+}
+
+/// Successive versions of `building.yaml` in commit order, oldest first.
+///
+/// Commits that left the file untouched are skipped, and with `until` the walk stops
+/// at the first commit made after it. A `None` building means the file was absent.
+pub fn building_snapshots(
+    repo_root: &Path,
+    until: Option<DateTime<Utc>>,
+) -> anyhow::Result<Vec<(VersionMeta, Option<Building>)>> {
+    let repo = Repository::open(repo_root)?;
+    let mut revwalk = repo.revwalk()?;
+    revwalk.push_head()?;
+    revwalk.set_sorting(Sort::TOPOLOGICAL | Sort::TIME | Sort::REVERSE)?;
+
+    let mut snapshots = Vec::new();
+    let mut last_blob = None;
+    for oid in revwalk {
+        let commit = repo.find_commit(oid?)?;
+        let timestamp = Utc
+            .timestamp_opt(commit.time().seconds(), 0)
+            .single()
+            .unwrap_or_default();
+        if until.is_some_and(|until| timestamp > until) {
+            break;
+        }
+        let blob = commit
+            .tree()?
+            .get_path(Path::new(BUILDING_FILE))
+            .ok()
+            .map(|entry| entry.id());
+        // Skip commits that left building.yaml untouched.
+        if !snapshots.is_empty() && blob == last_blob {
+            continue;
+        }
+        last_blob = blob;
+
+        let building = match blob {
+            Some(blob) => {
+                let blob = repo.find_blob(blob)?;
+                let yaml = String::from_utf8_lossy(blob.content());
+                // A version that no longer parses is skipped rather than read as a delete.
+                match BuildingYamlSerializer::deserialize(&yaml) {
+                    Ok(data) => {
+                        let mut building = data.into_building();
+                        strip_deleted(&mut building);
+                        Some(building)
+                    }
+                    Err(_) => continue,
+                }
+            }
+            None => None,
+        };
+        let author = commit.author();
+        let actor = match (author.name(), author.email()) {
+            (Some(name), Some(email)) if !email.is_empty() => format!("{} <{}>", name, email),
+            (Some(name), _) => name.to_string(),
+            (None, email) => email.unwrap_or("unknown").to_string(),
+        };
+        let meta = VersionMeta {
+            commit: commit.id().to_string(),
+            actor,
+            timestamp,
+            message: commit.summary().unwrap_or("").to_string(),
+        };
+        snapshots.push((meta, building));
+    }
+    Ok(snapshots)
+}
+
+/// Timeline of `id` from the git history of `building.yaml` under `repo_root`.
+pub fn object_history_at(repo_root: &Path, id: &str) -> anyhow::Result<ObjectHistory> {
+    Ok(ObjectHistory::from_snapshots(
+        id,
+        building_snapshots(repo_root, None)?,
+    ))
+}
+
+/// Objects on floor `level` as of `at`.
+///
+/// `None` when `building.yaml` did not exist yet or had no such floor at that time.
+pub fn floor_as_of(
+    repo_root: &Path,
+    level: i32,
+    at: DateTime<Utc>,
+) -> anyhow::Result<Option<FloorSnapshot>> {
+    let Some((version, building)) = building_snapshots(repo_root, Some(at))?.pop() else {
+        return Ok(None);
+    };
+    Ok(building.and_then(|b| floor_snapshot(&b, level, version)))
+}
+
+fn floor_snapshot(building: &Building, level: i32, version: VersionMeta) -> Option<FloorSnapshot> {
+    let floor = building.floors.iter().find(|f| f.level == level)?;
+    let rooms = floor.wings.iter().flat_map(|w| &w.rooms);
+    let equipment = floor
+        .equipment
+        .iter()
+        .chain(floor.wings.iter().flat_map(|w| &w.equipment))
+        .chain(rooms.clone().flat_map(|r| &r.equipment));
+    Some(FloorSnapshot {
+        level,
+        name: floor.name.clone(),
+        version,
+        rooms: rooms.filter_map(room_value).collect(),
+        equipment: equipment.filter_map(equipment_value).collect(),
+    })
+}
+
+/// Parse an `as_of` value: RFC 3339 (`2026-03-01T12:00:00Z`) or Unix seconds.
+pub fn parse_timestamp(value: &str) -> Option<DateTime<Utc>> {
+    let value = value.trim();
+    if let Ok(ts) = DateTime::parse_from_rfc3339(value) {
+        return Some(ts.with_timezone(&Utc));
+    }
+    value
+        .parse::<i64>()
+        .ok()
+        .and_then(|secs| Utc.timestamp_opt(secs, 0).single())
+}
+
+fn find_object(building: &Building, id: &str) -> Option<(SyncObjectKind, Value)> {
+    if let Some(room) = building.get_all_rooms().into_iter().find(|r| r.id == id) {
+        return room_value(room).map(|v| (SyncObjectKind::Room, v));
+    }
+    building
+        .get_all_equipment()
+        .into_iter()
+        .find(|e| e.id == id)
+        .and_then(equipment_value)
+        .map(|v| (SyncObjectKind::Equipment, v))
+}
+
+/// `field` matches `filter` when equal or nested under it.
+fn field_matches(field: &str, filter: &str) -> bool {
+    field == filter
+        || field
+            .strip_prefix(filter)
+            .is_some_and(|rest| rest.starts_with('.'))
+}
+
+fn flatten(prefix: &str, value: &Value, out: &mut BTreeMap<String, Value>) {
+    match value {
+        // Empty maps contribute no fields, so adding the first key is a single change.
+        Value::Object(map) => {
+            for (key, child) in map {
+                let path = if prefix.is_empty() {
+                    key.clone()
+                } else {
+                    format!("{}.{}", prefix, key)
+                };
+                flatten(&path, child, out);
+            }
+        }
+        _ => {
+            out.insert(prefix.to_string(), value.clone());
+        }
+    }
+}
+
+/// Field-level differences between two object JSON forms, sorted by field path.
+pub fn diff_fields(old: Option<&Value>, new: Option<&Value>) -> Vec<FieldChange> {
+    let mut old_fields = BTreeMap::new();
+    let mut new_fields = BTreeMap::new();
+    if let Some(old) = old {
+        flatten("", old, &mut old_fields);
+    }
+    if let Some(new) = new {
+        flatten("", new, &mut new_fields);
+    }
+
+    let fields: BTreeSet<&String> = old_fields.keys().chain(new_fields.keys()).collect();
+    fields
+        .into_iter()
+        .filter_map(|field| {
+            let old = old_fields.get(field);
+            let new = new_fields.get(field);
+            (old != new).then(|| FieldChange {
+                field: field.clone(),
+                old: old.cloned(),
+                new: new.cloned(),
+            })
+        })
+        .collect()
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::core::{Equipment, EquipmentType, Floor};
+
+    fn meta(n: i64) -> VersionMeta {
+        VersionMeta {
+            commit: format!("c{}", n),
+            actor: "tester".into(),
+            timestamp: Utc.timestamp_opt(1_700_000_000 + n, 0).unwrap(),
+            message: format!("edit {}", n),
+        }
+    }
+
+    fn building_with(equipment: Option<Equipment>) -> Building {
+        let mut building = Building::new("Hist".into(), "/hist".into());
+        let mut floor = Floor::new("Ground".into(), 0);
+        floor.equipment.extend(equipment);
+        building.add_floor(floor);
+        building
+    }
+
+    fn timeline() -> ObjectHistory {
+        let mut eq = Equipment::new("AHU-1".into(), "/ahu-1".into(), EquipmentType::HVAC);
+        eq.id = "ahu-1".into();
+        let created = eq.clone();
+        eq.position.x = 4.0;
+        let moved = eq.clone();
+        eq.properties.insert("serial".into(), "SN-9".into());
+        let tagged = eq.clone();
+
+        ObjectHistory::from_snapshots(
+            "ahu-1",
+            vec![
+                (meta(0), Some(building_with(None))),
+                (meta(1), Some(building_with(Some(created)))),
+                (meta(2), Some(building_with(Some(moved.clone())))),
+                (meta(3), Some(building_with(Some(moved)))),
+                (meta(4), Some(building_with(Some(tagged)))),
+                (meta(5), Some(building_with(None))),
+            ],
+        )
+    }
+
+    #[test]
+    fn timeline_is_ordered_and_skips_unchanged_versions() {
+        let history = timeline();
+        assert_eq!(history.kind, Some(SyncObjectKind::Equipment));
+        let steps: Vec<(&str, ChangeOp)> = history
+            .versions
+            .iter()
+            .map(|v| (v.meta.commit.as_str(), v.op))
+            .collect();
+        assert_eq!(
+            steps,
+            vec![
+                ("c1", ChangeOp::Created),
+                ("c2", ChangeOp::Updated),
+                ("c4", ChangeOp::Updated),
+                ("c5", ChangeOp::Deleted),
+            ]
+        );
+        let moved = &history.versions[1].changes;
+        assert_eq!(moved.len(), 1);
+        assert_eq!(moved[0].field, "position.x");
+        assert_eq!(moved[0].new, Some(serde_json::json!(4.0)));
+    }
+
+    #[test]
+    fn reconstructs_past_states() {
+        let history = timeline();
+        let at = |n: i64| Utc.timestamp_opt(1_700_000_000 + n, 0).unwrap();
+        let field = |n: i64, pointer: &str| match history.as_of(at(n)) {
+            ObjectAsOf::Exists { object, .. } => object.pointer(pointer).cloned(),
+            other => panic!("expected object at {}, got {:?}", n, other),
+        };
+
+        assert_eq!(history.as_of(at(-10)), ObjectAsOf::NotCreated);
+        assert_eq!(history.as_of(at(0)), ObjectAsOf::NotCreated);
+        assert_eq!(field(1, "/position/x"), Some(serde_json::json!(0.0)));
+        assert_eq!(field(2, "/position/x"), Some(serde_json::json!(4.0)));
+        assert_eq!(field(3, "/position/x"), Some(serde_json::json!(4.0)));
+        assert_eq!(field(3, "/properties/serial"), None);
+        assert_eq!(
+            field(4, "/properties/serial"),
+            Some(serde_json::json!("SN-9"))
+        );
+        match history.as_of(at(100)) {
+            ObjectAsOf::Deleted { version } => assert_eq!(version.commit, "c5"),
+            other => panic!("expected tombstone, got {:?}", other),
+        }
+    }
+
+    #[test]
+    fn floor_snapshot_lists_objects_on_floor() {
+        let mut eq = Equipment::new("AHU-1".into(), "/ahu-1".into(), EquipmentType::HVAC);
+        eq.id = "ahu-1".into();
+        let building = building_with(Some(eq));
+        let snapshot = floor_snapshot(&building, 0, meta(1)).unwrap();
+        assert_eq!(snapshot.equipment.len(), 1);
+        assert_eq!(snapshot.equipment[0]["id"], "ahu-1");
+        assert!(floor_snapshot(&building, 3, meta(1)).is_none());
+
+        assert_eq!(
+            parse_timestamp("2023-11-14T22:13:20Z"),
+            parse_timestamp("1700000000")
+        );
+        assert!(parse_timestamp("yesterday").is_none());
+    }
+
+    #[test]
+    fn field_filter_and_paging() {
+        let history = timeline();
+        let page = history.page(&HistoryQuery {
+            field: Some("properties".into()),
+            ..Default::default()
+        });
+        let commits: Vec<&str> = page
+            .versions
+            .iter()
+            .map(|v| v.meta.commit.as_str())
+            .collect();
+        assert_eq!(commits, vec!["c4", "c5"]);
+        assert!(page
+            .versions
+            .iter()
+            .flat_map(|v| &v.changes)
+            .all(|c| c.field.starts_with("properties.")));
+
+        // "position" must not match a sibling that merely shares the prefix.
+        assert!(!field_matches("position_source", "position"));
+
+        let page = history.page(&HistoryQuery {
+            field: None,
+            offset: 1,
+            limit: Some(2),
+        });
+        assert_eq!(page.total, 4);
+        assert_eq!(page.versions.len(), 2);
+        assert_eq!(page.versions[0].meta.commit, "c2");
+    }
+}
@@ -1,11 +1,13 @@
-//! Shared import orchestration for IFC and LiDAR.
+//! Shared import orchestration for IFC, LiDAR, and equipment schedules.
 
 use std::path::Path;
 
 use anyhow::{anyhow, Context, Result};
 
 use crate::core::{Building, BuildingMetadata};
-use crate::ifc::mapping::{merge_building_with_policy, FidelityLevel, LossReport, MergePolicy};
+use crate::ifc::mapping::{
+    merge_building_with_policy, FidelityLevel, LossReport, MergePolicy, MergeStrategy,
+};
 use crate::ifc::IFCProcessor;
 use crate::spatial::lidar::LidarPipeline;
 use crate::validation::{validate_building, BuildingValidationReport};
@@ -18,6 +20,8 @@ pub enum IngestSource {
     Lidar,
     /// Text / AR command script edits (in-place; no hierarchy replace).
     Text,
+    /// Tabular equipment schedules (CSV) applied onto an existing building.
+    Schedule,
 }
 
 impl IngestSource {
@@ -26,7 +30,7 @@ impl IngestSource {
             IngestSource::Ifc => MergePolicy::ifc(),
             IngestSource::Lidar => MergePolicy::lidar(),
             // Text edits are applied in-place; merge only if caller supplies existing
-            IngestSource::Text => MergePolicy::lidar(),
+            IngestSource::Text | IngestSource::Schedule => MergePolicy::lidar(),
         }
     }
 
@@ -35,6 +39,7 @@ impl IngestSource {
             IngestSource::Ifc => "ifc",
             IngestSource::Lidar => "lidar",
             IngestSource::Text => "text",
+            IngestSource::Schedule => "schedule",
         }
     }
 }
@@ -121,13 +126,60 @@ pub fn finalize_ingest(
     }
 }
 
-/// Parse IFC at `path`, optionally merge with `existing_yaml` or sibling YAML, validate.
-pub fn import_ifc_path(
-    path: &Path,
+/// Output of an adapter's parse stage: the incoming model before merge and validation.
+///
+/// Parsing is the expensive step of an import; [`finish_import`] is cheap and can be
+/// re-run from a checkpointed `ParsedImport` (see [`super::checkpoint`]).
+#[derive(Debug, Clone)]
+pub struct ParsedImport {
+    pub source: IngestSource,
+    pub building: Building,
+    /// Parse-time warnings, prepended to the final report.
+    pub report: LossReport,
+}
+
+/// Merge a parsed model with `existing_yaml` (when present), validate, and build the report.
+pub fn finish_import(
+    parsed: ParsedImport,
     existing_yaml: Option<&Path>,
-    strict: bool,
     validate: bool,
 ) -> Result<IngestResult> {
+    finish_import_with_strategy(parsed, existing_yaml, validate, MergeStrategy::default())
+}
+
+/// [`finish_import`] with an explicit strategy for equipment matched in `existing_yaml`.
+pub fn finish_import_with_strategy(
+    parsed: ParsedImport,
+    existing_yaml: Option<&Path>,
+    validate: bool,
+    strategy: MergeStrategy,
+) -> Result<IngestResult> {
+    let existing = load_existing_yaml(existing_yaml)?;
+    let source = parsed.source;
+
+    let mut result = finalize_ingest(
+        parsed.building,
+        source,
+        IngestOptions {
+            validate,
+            existing,
+            policy: Some(source.merge_policy().with_strategy(strategy)),
+        },
+    );
+
+    // Prepend parse-time warnings
+    let mut warnings = parsed.report.warnings;
+    warnings.append(&mut result.report.warnings);
+    result.report.warnings = warnings;
+    if result.report.merge.is_none() {
+        result.report.merge = parsed.report.merge;
+    }
+
+    Ok(result)
+}
+
+/// Parse stage of an IFC import: native parse plus source metadata.
+pub fn parse_ifc_path(path: &Path, strict: bool) -> Result<ParsedImport> {
     crate::resource_limits::check_file_size(
         path,
         crate::resource_limits::max_ifc_bytes(),
@@ -143,8 +195,6 @@ pub fn import_ifc_path(
         .with_context(|| format!("native IFC parse failed for {}", path.display()))?;
 
     let mut building = parsed.building;
-    let base_report = parsed.report;
-
     building.metadata = Some(BuildingMetadata {
         source_file: Some(path.display().to_string()),
         parser_version: env!("CARGO_PKG_VERSION").to_string(),
@@ -156,37 +206,25 @@ pub fn import_ifc_path(
         properties: Default::default(),
     });
 
-    let existing = load_existing_yaml(existing_yaml)?;
-
-    let mut result = finalize_ingest(
+    Ok(ParsedImport {
+        source: IngestSource::Ifc,
         building,
-        IngestSource::Ifc,
-        IngestOptions {
-            validate,
-            existing,
-            policy: Some(MergePolicy::ifc()),
-        },
-    );
-
-    // Prepend parse-time warnings
-    let mut warnings = base_report.warnings;
-    warnings.append(&mut result.report.warnings);
-    result.report.warnings = warnings;
-    if result.report.merge.is_none() {
-        result.report.merge = base_report.merge;
-    }
-
-    Ok(result)
+        report: parsed.report,
+    })
 }
 
-/// Run LiDAR pipeline, optionally merge with existing YAML, validate.
-pub fn import_lidar_path(
+/// Parse IFC at `path`, optionally merge with `existing_yaml` or sibling YAML, validate.
+pub fn import_ifc_path(
     path: &Path,
     existing_yaml: Option<&Path>,
-    voxel_size: f64,
-    light_mode: bool,
+    strict: bool,
     validate: bool,
 ) -> Result<IngestResult> {
+    finish_import(parse_ifc_path(path, strict)?, existing_yaml, validate)
+}
+
+/// Parse stage of a LiDAR import: run the point-cloud pipeline.
+pub fn parse_lidar_path(path: &Path, voxel_size: f64, light_mode: bool) -> Result<ParsedImport> {
     crate::resource_limits::check_file_size(
         path,
         crate::resource_limits::max_lidar_bytes(),
@@ -198,20 +236,29 @@ pub fn import_lidar_path(
         .process(path)
         .with_context(|| format!("LiDAR pipeline failed for {}", path.display()))?;
 
-    let existing = load_existing_yaml(existing_yaml)?;
-
-    Ok(finalize_ingest(
+    Ok(ParsedImport {
+        source: IngestSource::Lidar,
         building,
-        IngestSource::Lidar,
-        IngestOptions {
-            validate,
-            existing,
-            policy: Some(MergePolicy::lidar()),
-        },
-    ))
+        report: LossReport::default(),
+    })
+}
+
+/// Run LiDAR pipeline, optionally merge with existing YAML, validate.
+pub fn import_lidar_path(
+    path: &Path,
+    existing_yaml: Option<&Path>,
+    voxel_size: f64,
+    light_mode: bool,
+    validate: bool,
+) -> Result<IngestResult> {
+    finish_import(
+        parse_lidar_path(path, voxel_size, light_mode)?,
+        existing_yaml,
+        validate,
+    )
 }
 
-fn load_existing_yaml(path: Option<&Path>) -> Result<Option<Building>> {
+pub(crate) fn load_existing_yaml(path: Option<&Path>) -> Result<Option<Building>> {
     let Some(path) = path else {
         return Ok(None);
     };
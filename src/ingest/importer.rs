@@ -0,0 +1,296 @@
+//! Pluggable importers: one trait over every file adapter, selected by content type
+//! or extension.
+//!
+//! New sources implement [`Importer`] and register with an [`ImporterRegistry`];
+//! callers then import any supported file through [`ImporterRegistry::import`]
+//! without knowing which adapter handles it.
+
+use std::path::Path;
+
+use anyhow::{anyhow, Result};
+
+use super::checkpoint::{import_with_checkpoint, ImportCheckpointStore};
+use super::import::{
+    finish_import, finish_import_with_strategy, parse_ifc_path, parse_lidar_path, IngestResult,
+    IngestSource, ParsedImport,
+};
+use super::schedule::parse_schedule_path;
+use crate::ifc::mapping::MergeStrategy;
+
+/// Shared options passed to every importer.
+#[derive(Debug, Clone, Copy, Default)]
+pub struct ImportOptions<'a> {
+    /// Existing building YAML to merge into (required by schedule imports).
+    pub existing_yaml: Option<&'a Path>,
+    pub validate: bool,
+    /// Whose record wins when incoming equipment matches an existing object.
+    pub merge_strategy: MergeStrategy,
+}
+
+/// A file adapter that produces an [`IngestResult`].
+///
+/// Imports run in two stages: [`Importer::parse`] (the expensive adapter work) and
+/// [`Importer::finish`] (merge + validate). The split lets a checkpointed parse be
+/// resumed without re-reading the source file.
+pub trait Importer: Send + Sync {
+    /// Short stable name (`ifc`, `lidar`, `schedule`).
+    fn name(&self) -> &'static str;
+
+    fn source(&self) -> IngestSource;
+
+    /// Whether this importer handles `path`. `content_type` is a MIME type when the
+    /// caller knows one (e.g. from an upload); otherwise the extension decides.
+    fn can_handle(&self, path: &Path, content_type: Option<&str>) -> bool;
+
+    fn parse(&self, path: &Path, options: &ImportOptions) -> Result<ParsedImport>;
+
+    fn finish(&self, parsed: ParsedImport, options: &ImportOptions) -> Result<IngestResult> {
+        finish_import_with_strategy(
+            parsed,
+            options.existing_yaml,
+            options.validate,
+            options.merge_strategy,
+        )
+    }
+
+    fn import(&self, path: &Path, options: &ImportOptions) -> Result<IngestResult> {
+        self.finish(self.parse(path, options)?, options)
+    }
+}
+
+fn extension_is(path: &Path, extensions: &[&str]) -> bool {
+    path.extension()
+        .and_then(|e| e.to_str())
+        .map(|e| extensions.iter().any(|x| e.eq_ignore_ascii_case(x)))
+        .unwrap_or(false)
+}
+
+fn content_type_is(content_type: &str, types: &[&str]) -> bool {
+    let base = content_type.split(';').next().unwrap_or("").trim();
+    types.iter().any(|t| base.eq_ignore_ascii_case(t))
+}
+
+/// IFC STEP files via the native parser.
+pub struct IfcImporter {
+    pub strict: bool,
+}
+
+impl Importer for IfcImporter {
+    fn name(&self) -> &'static str {
+        "ifc"
+    }
+
+    fn source(&self) -> IngestSource {
+        IngestSource::Ifc
+    }
+
+    fn can_handle(&self, path: &Path, content_type: Option<&str>) -> bool {
+        match content_type {
+            Some(ct) => {
+                content_type_is(ct, &["application/x-step", "model/ifc", "application/ifc"])
+            }
+            None => extension_is(path, &["ifc"]),
+        }
+    }
+
+    fn parse(&self, path: &Path, _options: &ImportOptions) -> Result<ParsedImport> {
+        parse_ifc_path(path, self.strict)
+    }
+}
+
+/// LiDAR point clouds via the voxel pipeline.
+///
+/// `.csv` / `.txt` point exports are not claimed here so they do not shadow
+/// equipment schedules; use `arx import lidar` for those explicitly.
+pub struct LidarImporter {
+    pub voxel_size: f64,
+    pub light_mode: bool,
+}
+
+impl Importer for LidarImporter {
+    fn name(&self) -> &'static str {
+        "lidar"
+    }
+
+    fn source(&self) -> IngestSource {
+        IngestSource::Lidar
+    }
+
+    fn can_handle(&self, path: &Path, content_type: Option<&str>) -> bool {
+        match content_type {
+            Some(ct) => content_type_is(ct, &["application/vnd.las", "application/x-ply"]),
+            None => extension_is(path, &["las", "laz", "ply", "xyz"]),
+        }
+    }
+
+    fn parse(&self, path: &Path, _options: &ImportOptions) -> Result<ParsedImport> {
+        parse_lidar_path(path, self.voxel_size, self.light_mode)
+    }
+}
+
+/// Equipment schedules as CSV (see [`super::schedule`]).
+pub struct ScheduleCsvImporter;
+
+impl Importer for ScheduleCsvImporter {
+    fn name(&self) -> &'static str {
+        "schedule"
+    }
+
+    fn source(&self) -> IngestSource {
+        IngestSource::Schedule
+    }
+
+    fn can_handle(&self, path: &Path, content_type: Option<&str>) -> bool {
+        match content_type {
+            Some(ct) => content_type_is(ct, &["text/csv", "application/csv"]),
+            None => extension_is(path, &["csv"]),
+        }
+    }
+
+    fn parse(&self, path: &Path, options: &ImportOptions) -> Result<ParsedImport> {
+        parse_schedule_path(path, options.existing_yaml)
+    }
+
+    /// The schedule was applied onto the existing building while parsing; don't merge again.
+    fn finish(&self, parsed: ParsedImport, options: &ImportOptions) -> Result<IngestResult> {
+        finish_import(parsed, None, options.validate)
+    }
+}
+
+/// Ordered set of importers; the first one that accepts a file wins.
+pub struct ImporterRegistry {
+    importers: Vec<Box<dyn Importer>>,
+}
+
+impl Default for ImporterRegistry {
+    /// IFC (non-strict), LiDAR (5 cm voxels), and CSV equipment schedules.
+    fn default() -> Self {
+        let mut registry = Self::empty();
+        registry.register(Box::new(IfcImporter { strict: false }));
+        registry.register(Box::new(LidarImporter {
+            voxel_size: 0.05,
+            light_mode: false,
+        }));
+        registry.register(Box::new(ScheduleCsvImporter));
+        registry
+    }
+}
+
+impl ImporterRegistry {
+    pub fn empty() -> Self {
+        Self {
+            importers: Vec::new(),
+        }
+    }
+
+    /// Add an importer. An importer with the same name is replaced in place.
+    pub fn register(&mut self, importer: Box<dyn Importer>) {
+        match self
+            .importers
+            .iter_mut()
+            .find(|i| i.name() == importer.name())
+        {
+            Some(slot) => *slot = importer,
+            None => self.importers.push(importer),
+        }
+    }
+
+    pub fn names(&self) -> Vec<&'static str> {
+        self.importers.iter().map(|i| i.name()).collect()
+    }
+
+    pub fn select(&self, path: &Path, content_type: Option<&str>) -> Option<&dyn Importer> {
+        self.importers
+            .iter()
+            .find(|i| i.can_handle(path, content_type))
+            .map(|i| i.as_ref())
+    }
+
+    /// Import `path` with whichever importer accepts it.
+    pub fn import(
+        &self,
+        path: &Path,
+        content_type: Option<&str>,
+        options: &ImportOptions,
+    ) -> Result<IngestResult> {
+        self.require(path, content_type)?.import(path, options)
+    }
+
+    /// Like [`ImporterRegistry::import`], but checkpoints the parse stage in `store`
+    /// so a retry after a failure resumes instead of re-parsing.
+    pub fn import_resumable(
+        &self,
+        path: &Path,
+        content_type: Option<&str>,
+        options: &ImportOptions,
+        store: &ImportCheckpointStore,
+    ) -> Result<IngestResult> {
+        let importer = self.require(path, content_type)?;
+        import_with_checkpoint(importer, path, options, store)
+    }
+
+    /// Like [`ImporterRegistry::select`], but an error naming the supported importers
+    /// when none accepts the file.
+    pub fn require(&self, path: &Path, content_type: Option<&str>) -> Result<&dyn Importer> {
+        self.select(path, content_type).ok_or_else(|| {
+            anyhow!(
+                "no importer for {} (supported: {})",
+                content_type.unwrap_or_else(|| path.to_str().unwrap_or("file")),
+                self.names().join(", ")
+            )
+        })
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::core::{Building, Floor};
+    use crate::yaml::BuildingYamlSerializer;
+    use tempfile::TempDir;
+
+    #[test]
+    fn selects_by_content_type_then_extension() {
+        let registry = ImporterRegistry::default();
+        let pick = |p: &str, ct| registry.select(Path::new(p), ct).map(|i| i.name());
+
+        assert_eq!(pick("model.ifc", None), Some("ifc"));
+        assert_eq!(pick("scan.PLY", None), Some("lidar"));
+        assert_eq!(pick("schedule.csv", None), Some("schedule"));
+        assert_eq!(
+            pick("upload.bin", Some("text/csv; charset=utf-8")),
+            Some("schedule")
+        );
+        assert_eq!(pick("notes.pdf", None), None);
+    }
+
+    #[test]
+    fn imports_schedule_through_registry() {
+        let temp = TempDir::new().unwrap();
+        let mut building = Building::new("HQ".into(), "/hq".into());
+        building.add_floor(Floor::new("Ground".into(), 0));
+        let yaml = temp.path().join("building.yaml");
+        std::fs::write(
+            &yaml,
+            BuildingYamlSerializer::serialize_building(&building).unwrap(),
+        )
+        .unwrap();
+        let csv = temp.path().join("equipment.csv");
+        std::fs::write(&csv, "name,type,x,y\nVAV-1,HVAC,3,4\nAP-1,Network,1,1\n").unwrap();
+
+        let options = ImportOptions {
+            existing_yaml: Some(&yaml),
+            validate: false,
+            ..Default::default()
+        };
+        let result = ImporterRegistry::default()
+            .import(&csv, None, &options)
+            .unwrap();
+        assert_eq!(result.source.tag(), "schedule");
+        assert_eq!(result.building.floors[0].equipment.len(), 2);
+
+        assert!(ImporterRegistry::default()
+            .import(&temp.path().join("x.pdf"), None, &options)
+            .is_err());
+    }
+}
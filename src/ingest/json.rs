@@ -0,0 +1,178 @@
+//! Building import from the JSON export.
+//!
+//! `arx export --format json` writes the `building.yaml` document as JSON. This is
+//! the inverse: the document is parsed back into a [`Building`], either keeping its
+//! ids (restore) or under fresh ids via [`clone_building`] (a copy that can live
+//! beside the original). Fields the current model does not know are reported as
+//! dropped rather than silently lost.
+
+use std::collections::{BTreeSet, HashMap};
+
+use anyhow::{anyhow, bail, Context, Result};
+use serde_json::Value;
+
+use crate::core::operations::{clone_building, CloneOptions};
+use crate::core::Building;
+use crate::yaml::{BuildingData, BUILDING_YAML_SCHEMA_VERSION};
+
+#[derive(Debug, Clone, Copy, Default)]
+pub struct JsonImportOptions {
+    /// Keep the exported ids instead of assigning fresh ones.
+    pub preserve_ids: bool,
+}
+
+#[derive(Debug, Clone)]
+pub struct JsonImport {
+    pub building: Building,
+    /// Exported id → imported id; empty when ids are preserved.
+    pub id_map: HashMap<String, String>,
+    /// Paths of exported fields the model does not support, e.g.
+    /// `building.floors[].wings[].rooms[].legacy_code`.
+    pub dropped: Vec<String>,
+}
+
+/// Rebuild a building from its JSON export.
+pub fn import_building_json(document: &Value, options: &JsonImportOptions) -> Result<JsonImport> {
+    let version = document
+        .get("schema_version")
+        .and_then(Value::as_u64)
+        .unwrap_or(1);
+    if version > BUILDING_YAML_SCHEMA_VERSION as u64 {
+        bail!(
+            "export schema_version {} is newer than supported version {}",
+            version,
+            BUILDING_YAML_SCHEMA_VERSION
+        );
+    }
+    if document.get("building").is_none() {
+        return Err(anyhow!("not a building export: missing 'building'"));
+    }
+
+    let data: BuildingData =
+        serde_json::from_value(document.clone()).context("Invalid building export")?;
+    let building = data.into_building();
+
+    let reserialized = serde_json::to_value(BuildingData::from_building(&building))?;
+    let mut exported = BTreeSet::new();
+    let mut kept = BTreeSet::new();
+    field_paths(document, "", &mut exported);
+    field_paths(&reserialized, "", &mut kept);
+    let dropped = exported.difference(&kept).cloned().collect();
+
+    if options.preserve_ids {
+        return Ok(JsonImport {
+            building,
+            id_map: HashMap::new(),
+            dropped,
+        });
+    }
+    let cloned = clone_building(
+        &building,
+        &CloneOptions {
+            name: building.name.clone(),
+            clean: false,
+        },
+    );
+    Ok(JsonImport {
+        building: cloned.building,
+        id_map: cloned.id_map,
+        dropped,
+    })
+}
+
+/// Every object key path in `value`, with array indices collapsed to `[]` so that
+/// reordering on save does not read as a difference.
+fn field_paths(value: &Value, prefix: &str, out: &mut BTreeSet<String>) {
+    match value {
+        Value::Object(map) => {
+            for (key, child) in map {
+                let path = if prefix.is_empty() {
+                    key.clone()
+                } else {
+                    format!("{}.{}", prefix, key)
+                };
+                out.insert(path.clone());
+                field_paths(child, &path, out);
+            }
+        }
+        Value::Array(items) => {
+            let path = format!("{}[]", prefix);
+            for item in items {
+                field_paths(item, &path, out);
+            }
+        }
+        _ => {}
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::core::{Equipment, EquipmentType, Floor, Room, RoomType, Wing};
+
+    fn export(building: &Building) -> Value {
+        serde_json::to_value(BuildingData::from_building(building)).unwrap()
+    }
+
+    fn shape(building: &Building) -> Vec<String> {
+        let mut names: Vec<String> = building
+            .floors
+            .iter()
+            .map(|f| format!("floor {} {}", f.level, f.name))
+            .chain(
+                building
+                    .get_all_rooms()
+                    .iter()
+                    .map(|r| format!("room {}", r.name)),
+            )
+            .chain(
+                building
+                    .get_all_equipment()
+                    .iter()
+                    .map(|e| format!("equipment {}", e.name)),
+            )
+            .collect();
+        names.sort();
+        names
+    }
+
+    #[test]
+    fn export_round_trips_with_preserved_or_fresh_ids() {
+        let mut building = Building::new("HQ".into(), "/hq".into());
+        let mut floor = Floor::new("Ground".into(), 0);
+        let mut wing = Wing::new("East".into());
+        let mut room = Room::new("Plant".into(), RoomType::Mechanical);
+        let mut ahu = Equipment::new("AHU-1".into(), "/ahu-1".into(), EquipmentType::HVAC);
+        ahu.room_id = Some(room.id.clone());
+        room.equipment.push(ahu);
+        wing.rooms.push(room);
+        floor.wings.push(wing);
+        building.add_floor(floor);
+        building.add_floor(Floor::new("Roof".into(), 1));
+
+        let mut document = export(&building);
+        let restored =
+            import_building_json(&document, &JsonImportOptions { preserve_ids: true }).unwrap();
+        assert_eq!(restored.building.id, building.id);
+        assert_eq!(shape(&restored.building), shape(&building));
+        assert_eq!(export(&restored.building), document);
+        assert!(restored.dropped.is_empty(), "{:?}", restored.dropped);
+
+        document["building"]["legacy_code"] = Value::from("B-17");
+        let copy = import_building_json(&document, &JsonImportOptions::default()).unwrap();
+        assert_ne!(copy.building.id, building.id);
+        assert_eq!(shape(&copy.building), shape(&building));
+        let new_room_id = &copy.building.get_all_rooms()[0].id;
+        assert_eq!(
+            copy.building.get_all_equipment()[0].room_id.as_ref(),
+            Some(new_room_id)
+        );
+        assert_eq!(copy.dropped, vec!["building.legacy_code".to_string()]);
+
+        document["schema_version"] = Value::from(BUILDING_YAML_SCHEMA_VERSION + 1);
+        assert!(import_building_json(&document, &JsonImportOptions::default()).is_err());
+        assert!(
+            import_building_json(&serde_json::json!({}), &JsonImportOptions::default()).is_err()
+        );
+    }
+}
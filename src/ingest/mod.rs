@@ -1,15 +1,27 @@
 //! Shared multi-source ingest: merge, validate, import orchestration.
 //!
-//! All adapters (IFC, LiDAR, text/AR) should finish through this module
+//! All adapters (IFC, LiDAR, schedules, text/AR) should finish through this module
 //! so merge policy and validation stay consistent.
 
+pub mod checkpoint;
+pub mod delta;
+pub mod history;
 mod import;
+pub mod importer;
+pub mod json;
+pub mod reconcile;
+pub mod schedule;
 mod sync;
 pub mod text;
 
 pub use import::{
-    finalize_ingest, import_ifc_path, import_lidar_path, IngestOptions, IngestResult, IngestSource,
+    finalize_ingest, finish_import, finish_import_with_strategy, import_ifc_path,
+    import_lidar_path, parse_ifc_path, parse_lidar_path, IngestOptions, IngestResult,
+    IngestSource, ParsedImport,
 };
+pub use importer::{ImportOptions, Importer, ImporterRegistry};
+pub use checkpoint::{ImportCheckpointStore, ImportStage};
+pub use schedule::import_schedule_path;
 pub use sync::{
     apply_text_to_sync_json, building_to_envelope, merge_sync_json, BuildingSyncEnvelope,
     SyncSource, STORAGE_KEY_ACTIVE_BUILDING, STORAGE_KEY_LEGACY_BUILDING, SYNC_SCHEMA_VERSION,
@@ -22,7 +34,7 @@ pub use text::{
 // Re-export merge / report types for a single ingest entry surface
 pub use crate::ifc::mapping::{
     merge_building, merge_building_with_policy, FidelityLevel, LossReport, MergePolicy,
-    MergeResult, MergeSource, MergeStats,
+    MergeResult, MergeSource, MergeStats, MergeStrategy,
 };
 pub use crate::validation::{validate_building, BuildingValidationReport};
 
@@ -89,11 +101,11 @@ pub fn persist_building_at(
                 None => e.message.clone(),
             })
             .collect();
-        return Err(format!(
+        return Err(crate::persistence::PersistenceError::ValidationError(format!(
             "Building validation failed ({} error(s)): {}",
             details.len(),
             details.join("; ")
-        )
+        ))
         .into());
     }
 
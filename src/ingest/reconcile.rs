@@ -0,0 +1,423 @@
+//! Reconcile building equipment against a CMMS asset export.
+//!
+//! The export is read with the schedule CSV parser ([`super::schedule`]). Each CMMS
+//! row is paired with at most one piece of equipment, best score first:
+//!
+//! - **id** (1.0): the row's `id` equals the equipment id or its `cmms_id` property;
+//! - **name** (0.9, +0.1 when the room agrees): names equal after normalization
+//!   (case, punctuation and leading zeros ignored, so `AHU-01` = `ahu 1`);
+//! - **fuzzy**: bigram similarity of the normalized names, weighted 0.8 with 0.2
+//!   for room agreement when the row names a room.
+//!
+//! Pairs below the minimum score are not matched. Applying a report links each pair
+//! by writing the CMMS id to the equipment's `cmms_id` property and can create the
+//! CMMS-only rows; equipment missing from the CMMS is exported as CSV for the other
+//! side.
+
+use std::collections::{HashMap, HashSet};
+
+use serde::Serialize;
+
+use super::schedule::{apply_schedule, ScheduleRow};
+use crate::core::{Building, Equipment};
+
+/// Equipment property holding the linked CMMS asset id.
+pub const CMMS_ID_PROPERTY: &str = "cmms_id";
+/// Default minimum score for a fuzzy match.
+pub const DEFAULT_MIN_SCORE: f64 = 0.6;
+
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Serialize)]
+#[serde(rename_all = "lowercase")]
+pub enum MatchBasis {
+    Id,
+    Name,
+    Fuzzy,
+}
+
+#[derive(Debug, Clone, Serialize)]
+pub struct MatchedPair {
+    pub equipment_id: String,
+    pub equipment_name: String,
+    pub row_line: usize,
+    pub row_name: String,
+    /// CMMS asset id; the row name when the export has no id column.
+    pub cmms_id: String,
+    pub basis: MatchBasis,
+    pub score: f64,
+}
+
+#[derive(Debug, Clone, Serialize)]
+pub struct ExtractedOnly {
+    pub id: String,
+    pub name: String,
+    pub equipment_type: String,
+    pub room: Option<String>,
+}
+
+#[derive(Debug, Clone, Serialize)]
+pub struct CmmsOnly {
+    pub line: usize,
+    pub name: String,
+    pub id: Option<String>,
+    pub room: Option<String>,
+}
+
+#[derive(Debug, Clone, Default, Serialize)]
+pub struct ReconcileReport {
+    pub matched: Vec<MatchedPair>,
+    pub extracted_only: Vec<ExtractedOnly>,
+    pub cmms_only: Vec<CmmsOnly>,
+}
+
+/// Outcome of [`apply_reconciliation`].
+#[derive(Debug, Clone, Default)]
+pub struct ReconcileApplied {
+    pub linked: usize,
+    pub created: usize,
+    /// CMMS-only rows that could not be placed.
+    pub skipped: Vec<String>,
+}
+
+/// Lowercase alphanumeric tokens with leading zeros stripped from numbers.
+fn normalize(name: &str) -> String {
+    name.split(|c: char| !c.is_alphanumeric())
+        .filter(|t| !t.is_empty())
+        .flat_map(split_digits)
+        .map(|t| {
+            if t.chars().all(|c| c.is_ascii_digit()) {
+                let trimmed = t.trim_start_matches('0');
+                if trimmed.is_empty() { "0" } else { trimmed }.to_string()
+            } else {
+                t.to_lowercase()
+            }
+        })
+        .collect::<Vec<_>>()
+        .join(" ")
+}
+
+/// Split `ahu01` into `ahu`, `01` so run-together and separated names compare equal.
+fn split_digits(token: &str) -> Vec<&str> {
+    let mut parts = Vec::new();
+    let mut start = 0;
+    let mut prev_digit = None;
+    for (i, c) in token.char_indices() {
+        let digit = c.is_ascii_digit();
+        if prev_digit.is_some_and(|p| p != digit) {
+            parts.push(&token[start..i]);
+            start = i;
+        }
+        prev_digit = Some(digit);
+    }
+    parts.push(&token[start..]);
+    parts
+}
+
+/// Sørensen–Dice coefficient over character bigrams.
+fn similarity(a: &str, b: &str) -> f64 {
+    fn bigrams(s: &str) -> Vec<(char, char)> {
+        let chars: Vec<char> = s.chars().filter(|c| !c.is_whitespace()).collect();
+        chars.windows(2).map(|w| (w[0], w[1])).collect()
+    }
+    if a == b {
+        return 1.0;
+    }
+    let (a, b) = (bigrams(a), bigrams(b));
+    if a.is_empty() || b.is_empty() {
+        return 0.0;
+    }
+    let mut counts: HashMap<(char, char), usize> = HashMap::new();
+    for g in &a {
+        *counts.entry(*g).or_default() += 1;
+    }
+    let mut shared = 0;
+    for g in &b {
+        if let Some(n) = counts.get_mut(g) {
+            if *n > 0 {
+                *n -= 1;
+                shared += 1;
+            }
+        }
+    }
+    2.0 * shared as f64 / (a.len() + b.len()) as f64
+}
+
+/// Every equipment with the name of the room it sits in.
+fn equipment_with_rooms(building: &Building) -> Vec<(&Equipment, Option<&str>)> {
+    let mut out = Vec::new();
+    for floor in &building.floors {
+        out.extend(floor.equipment.iter().map(|e| (e, None)));
+        for wing in &floor.wings {
+            out.extend(wing.equipment.iter().map(|e| (e, None)));
+            for room in &wing.rooms {
+                out.extend(room.equipment.iter().map(|e| (e, Some(room.name.as_str()))));
+            }
+        }
+    }
+    out
+}
+
+fn score(row: &ScheduleRow, eq: &Equipment, room: Option<&str>) -> (MatchBasis, f64) {
+    if let Some(id) = row.id.as_deref() {
+        let linked = eq.properties.get(CMMS_ID_PROPERTY).map(String::as_str);
+        if eq.id.eq_ignore_ascii_case(id) || linked.is_some_and(|l| l.eq_ignore_ascii_case(id)) {
+            return (MatchBasis::Id, 1.0);
+        }
+    }
+    let room_agrees = match (row.room.as_deref(), room) {
+        (Some(a), Some(b)) => Some(normalize(a) == normalize(b)),
+        (Some(_), None) => Some(false),
+        (None, _) => None,
+    };
+    let (row_name, eq_name) = (normalize(&row.name), normalize(&eq.name));
+    if row_name == eq_name {
+        let bonus = if room_agrees == Some(false) { 0.0 } else { 0.1 };
+        return (MatchBasis::Name, 0.9 + bonus);
+    }
+    let sim = similarity(&row_name, &eq_name);
+    let score = match room_agrees {
+        Some(agrees) => 0.8 * sim + if agrees { 0.2 } else { 0.0 },
+        None => sim,
+    };
+    (MatchBasis::Fuzzy, score)
+}
+
+/// Pair CMMS rows with building equipment; pairs scoring below `min_score` are left unmatched.
+pub fn reconcile(building: &Building, rows: &[ScheduleRow], min_score: f64) -> ReconcileReport {
+    let equipment = equipment_with_rooms(building);
+
+    let mut candidates = Vec::new();
+    for (r, row) in rows.iter().enumerate() {
+        for (e, (eq, room)) in equipment.iter().enumerate() {
+            let (basis, score) = score(row, eq, *room);
+            if score >= min_score {
+                candidates.push((score, r, e, basis));
+            }
+        }
+    }
+    // Best score first; ties go to the earlier row, then the earlier equipment.
+    candidates.sort_by(|a, b| {
+        b.0.partial_cmp(&a.0)
+            .unwrap_or(std::cmp::Ordering::Equal)
+            .then(a.1.cmp(&b.1))
+            .then(a.2.cmp(&b.2))
+    });
+
+    let mut report = ReconcileReport::default();
+    let mut rows_taken = HashSet::new();
+    let mut equipment_taken = HashSet::new();
+    for (score, r, e, basis) in candidates {
+        if rows_taken.contains(&r) || equipment_taken.contains(&e) {
+            continue;
+        }
+        rows_taken.insert(r);
+        equipment_taken.insert(e);
+        let (row, eq) = (&rows[r], equipment[e].0);
+        report.matched.push(MatchedPair {
+            equipment_id: eq.id.clone(),
+            equipment_name: eq.name.clone(),
+            row_line: row.line,
+            row_name: row.name.clone(),
+            cmms_id: row.id.clone().unwrap_or_else(|| row.name.clone()),
+            basis,
+            score: (score * 1000.0).round() / 1000.0,
+        });
+    }
+    report.matched.sort_by_key(|m| m.row_line);
+
+    report.extracted_only = equipment
+        .iter()
+        .enumerate()
+        .filter(|(e, _)| !equipment_taken.contains(e))
+        .map(|(_, (eq, room))| ExtractedOnly {
+            id: eq.id.clone(),
+            name: eq.name.clone(),
+            equipment_type: eq.equipment_type.to_string(),
+            room: room.map(str::to_string),
+        })
+        .collect();
+    report.cmms_only = rows
+        .iter()
+        .enumerate()
+        .filter(|(r, _)| !rows_taken.contains(r))
+        .map(|(_, row)| CmmsOnly {
+            line: row.line,
+            name: row.name.clone(),
+            id: row.id.clone(),
+            room: row.room.clone(),
+        })
+        .collect();
+    report
+}
+
+/// Link matched pairs and, with `create_missing`, add CMMS-only rows to the building.
+pub fn apply_reconciliation(
+    building: &mut Building,
+    report: &ReconcileReport,
+    rows: &[ScheduleRow],
+    create_missing: bool,
+) -> ReconcileApplied {
+    let mut applied = ReconcileApplied::default();
+    for pair in &report.matched {
+        if let Some(eq) = building.find_equipment_mut(&pair.equipment_id) {
+            if eq.properties.get(CMMS_ID_PROPERTY) != Some(&pair.cmms_id) {
+                eq.properties
+                    .insert(CMMS_ID_PROPERTY.to_string(), pair.cmms_id.clone());
+                applied.linked += 1;
+            }
+        }
+    }
+
+    if create_missing {
+        let lines: HashSet<usize> = report.cmms_only.iter().map(|c| c.line).collect();
+        let missing: Vec<ScheduleRow> = rows
+            .iter()
+            .filter(|r| lines.contains(&r.line))
+            .cloned()
+            .map(|mut row| {
+                if let Some(id) = &row.id {
+                    row.properties
+                        .insert(CMMS_ID_PROPERTY.to_string(), id.clone());
+                }
+                row
+            })
+            .collect();
+        let before = building.get_all_equipment().len();
+        applied.skipped = apply_schedule(building, &missing);
+        applied.created = building.get_all_equipment().len() - before;
+    }
+    applied
+}
+
+/// Equipment missing from the CMMS as a CSV (`id,name,type,room`) for import on that side.
+pub fn extracted_only_csv(report: &ReconcileReport) -> String {
+    fn field(value: &str) -> String {
+        if value.contains([',', '"', '\n']) {
+            format!("\"{}\"", value.replace('"', "\"\""))
+        } else {
+            value.to_string()
+        }
+    }
+    let mut out = String::from("id,name,type,room\n");
+    for eq in &report.extracted_only {
+        out.push_str(&format!(
+            "{},{},{},{}\n",
+            field(&eq.id),
+            field(&eq.name),
+            field(&eq.equipment_type),
+            field(eq.room.as_deref().unwrap_or(""))
+        ));
+    }
+    out
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::core::{EquipmentType, Floor, Room, RoomType, Wing};
+    use crate::ingest::schedule::parse_schedule_csv;
+
+    fn equipment(id: &str, name: &str) -> Equipment {
+        let mut eq = Equipment::new(name.into(), String::new(), EquipmentType::HVAC);
+        eq.id = id.into();
+        eq
+    }
+
+    fn building() -> Building {
+        let mut b = Building::new("HQ".into(), "/hq".into());
+        let mut floor = Floor::new("Ground".into(), 0);
+        let mut wing = Wing::new("Main".into());
+        let mut mech = Room::new("Mech 101".into(), RoomType::Mechanical);
+        mech.equipment.push(equipment("eq-ahu", "AHU-01"));
+        mech.equipment
+            .push(equipment("eq-pump", "Chilled Water Pump 2"));
+        mech.equipment.push(equipment("A-100", "Boiler"));
+        wing.add_room(mech);
+        wing.add_room(Room::new("Office 1".into(), RoomType::Office));
+        floor.add_wing(wing);
+        floor.equipment.push(equipment("eq-fan", "Exhaust Fan 7"));
+        b.add_floor(floor);
+        b
+    }
+
+    const EXPORT: &str = "\
+Id,Name,Room
+A-100,Main Boiler,Mech 101
+A-200,ahu 1,Mech 101
+A-300,Chilled Wtr Pump 2,Mech 101
+A-400,Cooling Tower,Roof
+A-500,Domestic Water Heater,
+";
+
+    #[test]
+    fn categorizes_matched_and_one_sided_sets() {
+        let b = building();
+        let rows = parse_schedule_csv(EXPORT).unwrap();
+        let report = reconcile(&b, &rows, DEFAULT_MIN_SCORE);
+
+        let by_row: HashMap<&str, &MatchedPair> = report
+            .matched
+            .iter()
+            .map(|m| (m.cmms_id.as_str(), m))
+            .collect();
+        assert_eq!(by_row["A-100"].equipment_id, "A-100");
+        assert_eq!(by_row["A-100"].basis, MatchBasis::Id);
+        assert_eq!(by_row["A-200"].equipment_id, "eq-ahu");
+        assert_eq!(by_row["A-200"].basis, MatchBasis::Name);
+        assert_eq!(by_row["A-200"].score, 1.0);
+        assert_eq!(by_row["A-300"].equipment_id, "eq-pump");
+        assert_eq!(by_row["A-300"].basis, MatchBasis::Fuzzy);
+        assert!(by_row["A-300"].score > DEFAULT_MIN_SCORE && by_row["A-300"].score < 0.9);
+
+        let extracted: Vec<&str> = report
+            .extracted_only
+            .iter()
+            .map(|e| e.id.as_str())
+            .collect();
+        assert_eq!(extracted, vec!["eq-fan"]);
+        let cmms: Vec<Option<&str>> = report.cmms_only.iter().map(|c| c.id.as_deref()).collect();
+        assert_eq!(cmms, vec![Some("A-400"), Some("A-500")]);
+        assert!(extracted_only_csv(&report).contains("eq-fan,Exhaust Fan 7,HVAC,\n"));
+    }
+
+    #[test]
+    fn disjoint_sets_do_not_match_and_scores_rank() {
+        let b = building();
+        let rows = parse_schedule_csv("Name\nElevator Controller\nFire Panel\n").unwrap();
+        let report = reconcile(&b, &rows, DEFAULT_MIN_SCORE);
+        assert!(report.matched.is_empty());
+        assert_eq!(report.extracted_only.len(), 4);
+        assert_eq!(report.cmms_only.len(), 2);
+
+        assert_eq!(normalize("AHU-01"), normalize("ahu 1"));
+        assert_eq!(normalize("AHU01"), "ahu 1");
+        assert!(
+            similarity("chw pump 2", "chilled water pump 2")
+                > similarity("chw pump 2", "exhaust fan 7")
+        );
+    }
+
+    #[test]
+    fn apply_links_pairs_and_creates_missing() {
+        let mut b = building();
+        let rows = parse_schedule_csv(EXPORT).unwrap();
+        let report = reconcile(&b, &rows, DEFAULT_MIN_SCORE);
+
+        let applied = apply_reconciliation(&mut b, &report, &rows, true);
+        assert_eq!(applied.linked, 3);
+        // Cooling Tower names a room that does not exist, so it is skipped.
+        assert_eq!(applied.created, 1);
+        assert_eq!(applied.skipped.len(), 1);
+        assert!(b.find_equipment("A-500").is_some());
+        assert_eq!(
+            b.find_equipment("eq-ahu")
+                .and_then(|e| e.properties.get(CMMS_ID_PROPERTY))
+                .map(String::as_str),
+            Some("A-200")
+        );
+
+        // Linked pairs now match by id, and re-applying changes nothing.
+        let again = reconcile(&b, &rows, DEFAULT_MIN_SCORE);
+        assert!(again.matched.iter().all(|m| m.basis == MatchBasis::Id));
+        assert_eq!(apply_reconciliation(&mut b, &again, &rows, false).linked, 0);
+    }
+}
@@ -0,0 +1,325 @@
+//! Equipment schedule import (CSV exported from Revit schedules, spreadsheets, CMMS).
+//!
+//! The first row is a header. Recognised columns (case-insensitive):
+//! `name` (required), `id`, `type` / `equipment_type`, `room`, `floor` / `level`,
+//! `x`, `y`, `z`, `status`. Every other column becomes an equipment property.
+//!
+//! Rows are placed in the named room, else on the given floor, else on the only
+//! floor. Rows matching existing equipment by id or name update it in place, so
+//! re-importing the same schedule does not duplicate equipment.
+
+use std::collections::HashMap;
+use std::path::Path;
+
+use anyhow::{anyhow, bail, Context, Result};
+
+use crate::core::{Building, Equipment, EquipmentStatus, EquipmentType, Position};
+
+use super::import::{finish_import, load_existing_yaml, IngestResult, IngestSource, ParsedImport};
+use super::LossReport;
+
+/// One parsed schedule row.
+#[derive(Debug, Clone, Default, PartialEq)]
+pub struct ScheduleRow {
+    pub line: usize,
+    pub name: String,
+    pub id: Option<String>,
+    pub equipment_type: Option<String>,
+    pub room: Option<String>,
+    pub floor: Option<i32>,
+    pub position: Option<(f64, f64, f64)>,
+    pub status: Option<String>,
+    pub properties: HashMap<String, String>,
+}
+
+/// Split one CSV record, honouring double-quoted fields and `""` escapes.
+fn split_csv_line(line: &str) -> Vec<String> {
+    let mut fields = Vec::new();
+    let mut field = String::new();
+    let mut in_quotes = false;
+    let mut chars = line.chars().peekable();
+
+    while let Some(c) = chars.next() {
+        match c {
+            '"' if in_quotes && chars.peek() == Some(&'"') => {
+                field.push('"');
+                chars.next();
+            }
+            '"' => in_quotes = !in_quotes,
+            ',' if !in_quotes => fields.push(std::mem::take(&mut field)),
+            _ => field.push(c),
+        }
+    }
+    fields.push(field);
+    fields.into_iter().map(|f| f.trim().to_string()).collect()
+}
+
+/// Parse schedule CSV text into rows. Rows without a name are reported as errors.
+pub fn parse_schedule_csv(content: &str) -> Result<Vec<ScheduleRow>> {
+    let mut lines = content
+        .lines()
+        .enumerate()
+        .filter(|(_, l)| !l.trim().is_empty());
+    let (_, header) = lines
+        .next()
+        .ok_or_else(|| anyhow!("schedule CSV is empty"))?;
+    let headers: Vec<String> = split_csv_line(header.trim_start_matches('\u{feff}'))
+        .into_iter()
+        .map(|h| h.to_lowercase().replace(' ', "_"))
+        .collect();
+    if !headers.iter().any(|h| h == "name") {
+        bail!("schedule CSV needs a 'name' column");
+    }
+
+    let mut rows = Vec::new();
+    for (idx, line) in lines {
+        let line_no = idx + 1;
+        let values = split_csv_line(line);
+        let mut row = ScheduleRow {
+            line: line_no,
+            ..Default::default()
+        };
+        let (mut x, mut y, mut z) = (None, None, None);
+
+        for (header, value) in headers.iter().zip(values.into_iter()) {
+            if value.is_empty() {
+                continue;
+            }
+            let number = || {
+                value
+                    .parse::<f64>()
+                    .with_context(|| format!("line {}: '{}' is not a number", line_no, header))
+            };
+            match header.as_str() {
+                "name" => row.name = value.clone(),
+                "id" => row.id = Some(value.clone()),
+                "type" | "equipment_type" => row.equipment_type = Some(value.clone()),
+                "room" => row.room = Some(value.clone()),
+                "floor" | "level" => {
+                    row.floor = Some(value.parse::<i32>().with_context(|| {
+                        format!("line {}: floor '{}' is not an integer", line_no, value)
+                    })?)
+                }
+                "x" => x = Some(number()?),
+                "y" => y = Some(number()?),
+                "z" => z = Some(number()?),
+                "status" => row.status = Some(value.clone()),
+                other => {
+                    row.properties.insert(other.to_string(), value.clone());
+                }
+            }
+        }
+
+        if row.name.is_empty() {
+            bail!("line {}: missing equipment name", line_no);
+        }
+        if x.is_some() || y.is_some() || z.is_some() {
+            row.position = Some((x.unwrap_or(0.0), y.unwrap_or(0.0), z.unwrap_or(0.0)));
+        }
+        rows.push(row);
+    }
+    Ok(rows)
+}
+
+fn parse_type(input: &str) -> EquipmentType {
+    match input.trim().to_lowercase().as_str() {
+        "hvac" => EquipmentType::HVAC,
+        "electrical" => EquipmentType::Electrical,
+        "av" => EquipmentType::AV,
+        "furniture" => EquipmentType::Furniture,
+        "safety" => EquipmentType::Safety,
+        "plumbing" => EquipmentType::Plumbing,
+        "network" => EquipmentType::Network,
+        _ => EquipmentType::Other(input.trim().to_string()),
+    }
+}
+
+fn parse_status(input: &str) -> Option<EquipmentStatus> {
+    match input.trim().to_lowercase().as_str() {
+        "active" => Some(EquipmentStatus::Active),
+        "inactive" => Some(EquipmentStatus::Inactive),
+        "maintenance" => Some(EquipmentStatus::Maintenance),
+        "outoforder" | "out_of_order" | "out-of-order" => Some(EquipmentStatus::OutOfOrder),
+        "unknown" => Some(EquipmentStatus::Unknown),
+        _ => None,
+    }
+}
+
+fn apply_row(eq: &mut Equipment, row: &ScheduleRow) {
+    if let Some(t) = &row.equipment_type {
+        eq.equipment_type = parse_type(t);
+    }
+    if let Some((x, y, z)) = row.position {
+        eq.position = Position {
+            x,
+            y,
+            z,
+            coordinate_system: eq.position.coordinate_system.clone(),
+        };
+    }
+    if let Some(status) = row.status.as_deref().and_then(parse_status) {
+        eq.status = status;
+    }
+    for (k, v) in &row.properties {
+        eq.properties.insert(k.clone(), v.clone());
+    }
+}
+
+/// Apply schedule rows to `building`. Returns one message per row that was skipped.
+pub fn apply_schedule(building: &mut Building, rows: &[ScheduleRow]) -> Vec<String> {
+    let mut skipped = Vec::new();
+
+    for row in rows {
+        let key = row.id.as_deref().unwrap_or(&row.name);
+        if let Some(existing) = building.find_equipment_mut(key) {
+            apply_row(existing, row);
+            continue;
+        }
+
+        let mut eq = Equipment::new(
+            row.name.clone(),
+            String::new(),
+            row.equipment_type
+                .as_deref()
+                .map(parse_type)
+                .unwrap_or(EquipmentType::Other("Unknown".to_string())),
+        );
+        if let Some(id) = &row.id {
+            eq.id = id.clone();
+        }
+        apply_row(&mut eq, row);
+
+        if let Some(room_key) = &row.room {
+            let room = building
+                .floors
+                .iter_mut()
+                .flat_map(|f| f.wings.iter_mut())
+                .flat_map(|w| w.rooms.iter_mut())
+                .find(|r| {
+                    r.name.eq_ignore_ascii_case(room_key) || r.id.eq_ignore_ascii_case(room_key)
+                });
+            match room {
+                Some(room) => {
+                    eq.room_id = Some(room.id.clone());
+                    room.add_equipment(eq);
+                }
+                None => skipped.push(format!(
+                    "line {}: room '{}' not found for '{}'",
+                    row.line, room_key, row.name
+                )),
+            }
+            continue;
+        }
+
+        let floor = match row.floor {
+            Some(level) => building.floors.iter_mut().find(|f| f.level == level),
+            None if building.floors.len() == 1 => building.floors.first_mut(),
+            None => None,
+        };
+        match floor {
+            Some(floor) => floor.equipment.push(eq),
+            None => skipped.push(format!(
+                "line {}: no room or floor to place '{}'",
+                row.line, row.name
+            )),
+        }
+    }
+
+    skipped
+}
+
+/// Parse stage of a schedule import: read the CSV and apply it onto the existing building.
+pub fn parse_schedule_path(path: &Path, existing_yaml: Option<&Path>) -> Result<ParsedImport> {
+    let content = std::fs::read_to_string(path)
+        .with_context(|| format!("read schedule {}", path.display()))?;
+    let rows = parse_schedule_csv(&content)?;
+    let mut building = load_existing_yaml(existing_yaml)?
+        .ok_or_else(|| anyhow!("equipment schedules import into an existing building.yaml"))?;
+
+    let skipped = apply_schedule(&mut building, &rows);
+    let mut report = LossReport::default();
+    report.warn(
+        "schedule",
+        format!("{} row(s) read, {} skipped", rows.len(), skipped.len()),
+    );
+    for msg in skipped {
+        report.warn("schedule.skipped", msg);
+    }
+    Ok(ParsedImport {
+        source: IngestSource::Schedule,
+        building,
+        report,
+    })
+}
+
+/// Import an equipment schedule CSV into the existing building YAML and validate.
+///
+/// The schedule is already applied onto the existing building, so no merge runs.
+pub fn import_schedule_path(
+    path: &Path,
+    existing_yaml: Option<&Path>,
+    validate: bool,
+) -> Result<IngestResult> {
+    finish_import(parse_schedule_path(path, existing_yaml)?, None, validate)
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::core::{Floor, Room, RoomType, Wing};
+
+    const SCHEDULE: &str = "\
+Name,Type,Room,Floor,X,Y,Z,Manufacturer,Capacity
+AHU-1,HVAC,Mech 101,,1,2,0,Trane,\"10,000 CFM\"
+Panel LP-1,Electrical,,0,5,5,0,Square D,225A
+Orphan,HVAC,Nowhere,,,,,,
+";
+
+    fn building() -> Building {
+        let mut b = Building::new("HQ".into(), "/hq".into());
+        let mut floor = Floor::new("Ground".into(), 0);
+        let mut wing = Wing::new("Main".into());
+        wing.add_room(Room::new("Mech 101".into(), RoomType::Mechanical));
+        floor.add_wing(wing);
+        b.add_floor(floor);
+        b
+    }
+
+    #[test]
+    fn parses_quoted_fields_and_extra_columns() {
+        let rows = parse_schedule_csv(SCHEDULE).unwrap();
+        assert_eq!(rows.len(), 3);
+        assert_eq!(rows[0].name, "AHU-1");
+        assert_eq!(rows[0].position, Some((1.0, 2.0, 0.0)));
+        assert_eq!(
+            rows[0].properties.get("capacity").map(String::as_str),
+            Some("10,000 CFM")
+        );
+        assert_eq!(rows[1].floor, Some(0));
+        assert!(parse_schedule_csv("type\nHVAC").is_err());
+    }
+
+    #[test]
+    fn places_equipment_and_is_idempotent() {
+        let mut b = building();
+        let rows = parse_schedule_csv(SCHEDULE).unwrap();
+
+        let skipped = apply_schedule(&mut b, &rows);
+        assert_eq!(skipped.len(), 1);
+        let room = &b.floors[0].wings[0].rooms[0];
+        assert_eq!(room.equipment.len(), 1);
+        assert_eq!(room.equipment[0].equipment_type, EquipmentType::HVAC);
+        assert_eq!(room.equipment[0].room_id.as_deref(), Some(room.id.as_str()));
+        assert_eq!(b.floors[0].equipment.len(), 1);
+        assert_eq!(
+            b.floors[0].equipment[0]
+                .properties
+                .get("manufacturer")
+                .map(String::as_str),
+            Some("Square D")
+        );
+
+        apply_schedule(&mut b, &rows);
+        assert_eq!(b.get_all_equipment().len(), 2);
+    }
+}
@@ -38,6 +38,7 @@ pub enum SyncSource {
     Ifc,
     Lidar,
     Text,
+    Schedule,
     Merge,
     Wasm,
 }
@@ -48,6 +49,7 @@ impl From<IngestSource> for SyncSource {
             IngestSource::Ifc => SyncSource::Ifc,
             IngestSource::Lidar => SyncSource::Lidar,
             IngestSource::Text => SyncSource::Text,
+            IngestSource::Schedule => SyncSource::Schedule,
         }
     }
 }
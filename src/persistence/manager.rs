@@ -122,7 +122,7 @@ impl PersistenceManager {
 
         let file_path = self.building_yaml_path();
         if !file_path.exists() {
-            return Err(PersistenceError::ValidationError(format!(
+            return Err(PersistenceError::NotFound(format!(
                 "No building SSOT found at {}",
                 file_path.display()
             )));
@@ -0,0 +1,660 @@
+//! Versioned, replay-safe migrations of the Building SSOT.
+//!
+//! Applied migrations are recorded in `.arxos/schema_migrations.yaml` next to
+//! `building.yaml`, so the history travels with the repository. Pending migrations
+//! run in version order against an in-memory copy and are written in one step: if
+//! any migration or the validation gate fails, nothing is written. Before each
+//! migration the current `building.yaml` is kept under `.arxos/migrations/`, which
+//! is what `down` restores; a rollback is refused if `building.yaml` changed since
+//! the migration was applied.
+//!
+//! While `up` or `down` writes, a marker file holding the writer's PID and start
+//! time signals the run is in progress; [`MigrationRunner::readiness`] reports
+//! not-ready until it is gone and no pending migration would change the model,
+//! which is what the agent's `/ready` probe checks. A marker whose process is gone
+//! or that is older than [`MIGRATION_STALE_SECS`] was left by an interrupted run:
+//! readiness says so, and the next `arx migrate` clears it and redoes whatever the
+//! run did not record. A repository created before migrations existed has no
+//! history; it is ready as long as its building already has the shape the
+//! migrations produce. The dry run behind that answer is cached until
+//! `building.yaml` or the history changes.
+
+use std::collections::HashMap;
+use std::path::{Path, PathBuf};
+use std::sync::Mutex;
+
+use chrono::{DateTime, Duration, Utc};
+use serde::{Deserialize, Serialize};
+use sha2::{Digest, Sha256};
+
+use super::{PersistenceError, PersistenceManager, PersistenceResult};
+use crate::core::operations::backfill_equipment_addresses;
+use crate::core::Building;
+
+/// History file, relative to the project root.
+pub const MIGRATIONS_FILE: &str = ".arxos/schema_migrations.yaml";
+/// Pre-migration snapshots, relative to the project root.
+pub const MIGRATION_SNAPSHOT_DIR: &str = ".arxos/migrations";
+/// Present while a migration run is writing, relative to the project root.
+pub const MIGRATION_IN_PROGRESS_FILE: &str = ".arxos/migrations/in_progress";
+/// Age after which an in-progress marker counts as left by an interrupted run.
+pub const MIGRATION_STALE_SECS: i64 = 3600;
+
+/// Last dry-run readiness per project root, with the inputs it was computed from.
+static READINESS: Mutex<Option<HashMap<PathBuf, (String, Result<(), String>)>>> = Mutex::new(None);
+
+/// One model migration. `up` returns how many objects it changed.
+#[derive(Clone, Copy)]
+pub struct Migration {
+    pub version: u32,
+    pub name: &'static str,
+    pub up: fn(&mut Building) -> Result<usize, String>,
+}
+
+/// Migrations shipped with this build, in version order.
+pub fn registered_migrations() -> Vec<Migration> {
+    vec![Migration {
+        version: 1,
+        name: "backfill_equipment_addresses",
+        up: |building| Ok(backfill_equipment_addresses(building)),
+    }]
+}
+
+#[derive(Debug, Clone, PartialEq, Eq, Serialize, Deserialize)]
+pub struct AppliedMigration {
+    pub version: u32,
+    pub name: String,
+    pub applied_at: DateTime<Utc>,
+    /// SHA-256 of `building.yaml` right after this migration was written.
+    pub building_sha256: String,
+}
+
+#[derive(Debug, Clone, Default, Serialize, Deserialize)]
+struct MigrationHistory {
+    #[serde(default)]
+    applied: Vec<AppliedMigration>,
+}
+
+/// Outcome of one migration in an `up` run.
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub struct MigrationStep {
+    pub version: u32,
+    pub name: &'static str,
+    pub changed: usize,
+}
+
+pub struct MigrationRunner {
+    root: PathBuf,
+    migrations: Vec<Migration>,
+}
+
+/// Contents of the in-progress marker.
+#[derive(Debug, Serialize, Deserialize)]
+struct InProgressMarker {
+    pid: u32,
+    started_at: DateTime<Utc>,
+}
+
+impl InProgressMarker {
+    fn is_live(&self, now: DateTime<Utc>) -> bool {
+        process_alive(self.pid) && now - self.started_at < Duration::seconds(MIGRATION_STALE_SECS)
+    }
+}
+
+/// Whether `pid` is running. Only `/proc` can tell; without it the marker's age
+/// alone decides.
+fn process_alive(pid: u32) -> bool {
+    let proc = Path::new("/proc");
+    !proc.is_dir() || proc.join(pid.to_string()).exists()
+}
+
+/// Removes the in-progress marker when a run ends, including on error.
+struct InProgress(PathBuf);
+
+impl Drop for InProgress {
+    fn drop(&mut self) {
+        let _ = std::fs::remove_file(&self.0);
+    }
+}
+
+impl MigrationRunner {
+    pub fn new(root: &Path) -> Self {
+        Self::with_migrations(root, registered_migrations())
+    }
+
+    pub fn with_migrations(root: &Path, mut migrations: Vec<Migration>) -> Self {
+        migrations.sort_by_key(|m| m.version);
+        Self {
+            root: root.to_path_buf(),
+            migrations,
+        }
+    }
+
+    fn history_path(&self) -> PathBuf {
+        self.root.join(MIGRATIONS_FILE)
+    }
+
+    fn snapshot_path(&self, version: u32) -> PathBuf {
+        self.root
+            .join(MIGRATION_SNAPSHOT_DIR)
+            .join(format!("{:04}.before.yaml", version))
+    }
+
+    fn building_path(&self) -> PathBuf {
+        PersistenceManager::at(&self.root).building_yaml_path()
+    }
+
+    fn mark_in_progress(&self) -> PersistenceResult<InProgress> {
+        let path = self.root.join(MIGRATION_IN_PROGRESS_FILE);
+        if let Some(dir) = path.parent() {
+            std::fs::create_dir_all(dir)?;
+        }
+        let marker = InProgressMarker {
+            pid: std::process::id(),
+            started_at: Utc::now(),
+        };
+        std::fs::write(&path, serde_yaml::to_string(&marker)?)?;
+        Ok(InProgress(path))
+    }
+
+    /// The in-progress marker, if any: whether its run is still live, and who
+    /// wrote it. An unreadable marker counts as stale.
+    fn marker_status(&self) -> Option<(bool, String)> {
+        let content = std::fs::read_to_string(self.root.join(MIGRATION_IN_PROGRESS_FILE)).ok()?;
+        Some(match serde_yaml::from_str::<InProgressMarker>(&content) {
+            Ok(marker) => (
+                marker.is_live(Utc::now()),
+                format!(
+                    "pid {}, started {}",
+                    marker.pid,
+                    marker.started_at.to_rfc3339()
+                ),
+            ),
+            Err(_) => (false, "unreadable marker".into()),
+        })
+    }
+
+    /// Refuse to write while another run is live, and clear the marker of an
+    /// interrupted one: `up` redoes whatever that run did not record.
+    fn take_over_marker(&self) -> PersistenceResult<()> {
+        match self.marker_status() {
+            Some((true, owner)) => Err(PersistenceError::ValidationError(format!(
+                "Another migration is running ({})",
+                owner
+            ))),
+            Some((false, _)) => {
+                std::fs::remove_file(self.root.join(MIGRATION_IN_PROGRESS_FILE))?;
+                Ok(())
+            }
+            None => Ok(()),
+        }
+    }
+
+    /// Whether the model is safe to serve: no run in progress and, when a building
+    /// exists, no pending migration that would change it (checked with a dry run,
+    /// cached until `building.yaml` or the history changes). The error says why not.
+    pub fn readiness(&self) -> Result<(), String> {
+        match self.marker_status() {
+            Some((true, _)) => return Err("migration in progress".into()),
+            Some((false, owner)) => {
+                return Err(format!(
+                    "interrupted migration ({}); run `arx migrate` to finish it",
+                    owner
+                ))
+            }
+            None => {}
+        }
+        if !self.building_path().exists() {
+            return Ok(());
+        }
+        if self.pending().map_err(|e| e.to_string())?.is_empty() {
+            return Ok(());
+        }
+        let key = self.readiness_key().map_err(|e| e.to_string())?;
+        if let Some((cached_key, result)) = READINESS
+            .lock()
+            .unwrap_or_else(|e| e.into_inner())
+            .get_or_insert_with(HashMap::new)
+            .get(&self.root)
+        {
+            if *cached_key == key {
+                return result.clone();
+            }
+        }
+        let result = self.dry_run_readiness();
+        READINESS
+            .lock()
+            .unwrap_or_else(|e| e.into_inner())
+            .get_or_insert_with(HashMap::new)
+            .insert(self.root.clone(), (key, result.clone()));
+        result
+    }
+
+    /// What a cached readiness depends on: the building, the history, and the
+    /// registered migrations.
+    fn readiness_key(&self) -> PersistenceResult<String> {
+        let mut hasher = Sha256::new();
+        hasher.update(std::fs::read(self.building_path())?);
+        if let Ok(history) = std::fs::read(self.history_path()) {
+            hasher.update(history);
+        }
+        for migration in &self.migrations {
+            hasher.update(format!(
+                "{}:{}:{:x};",
+                migration.version, migration.name, migration.up as usize
+            ));
+        }
+        Ok(sha256_hex(&hasher.finalize()))
+    }
+
+    fn dry_run_readiness(&self) -> Result<(), String> {
+        let needed: Vec<MigrationStep> = self
+            .up(None, true)
+            .map_err(|e| e.to_string())?
+            .into_iter()
+            .filter(|step| step.changed > 0)
+            .collect();
+        match needed.first() {
+            None => Ok(()),
+            Some(next) => Err(format!(
+                "{} pending migration(s), next {} ({}); run `arx migrate`",
+                needed.len(),
+                next.version,
+                next.name
+            )),
+        }
+    }
+
+    fn load_history(&self) -> PersistenceResult<MigrationHistory> {
+        let path = self.history_path();
+        if !path.exists() {
+            return Ok(MigrationHistory::default());
+        }
+        Ok(serde_yaml::from_str(&std::fs::read_to_string(path)?)?)
+    }
+
+    fn save_history(&self, history: &MigrationHistory) -> PersistenceResult<()> {
+        let path = self.history_path();
+        if let Some(dir) = path.parent() {
+            std::fs::create_dir_all(dir)?;
+        }
+        std::fs::write(path, serde_yaml::to_string(history)?)?;
+        Ok(())
+    }
+
+    /// Applied history, checked against the registered migrations.
+    ///
+    /// Fails if a recorded version is unknown to this build or if the history is
+    /// not the ordered prefix of the registered list (an out-of-order apply).
+    pub fn applied(&self) -> PersistenceResult<Vec<AppliedMigration>> {
+        let history = self.load_history()?;
+        for (i, record) in history.applied.iter().enumerate() {
+            match self.migrations.get(i) {
+                Some(m) if m.version == record.version => {}
+                Some(m) => {
+                    return Err(PersistenceError::ValidationError(format!(
+                        "Migration history out of order: expected version {} ({}) at position {}, found {} ({})",
+                        m.version, m.name, i + 1, record.version, record.name
+                    )))
+                }
+                None => {
+                    return Err(PersistenceError::ValidationError(format!(
+                        "Migration {} ({}) is recorded but unknown to this build",
+                        record.version, record.name
+                    )))
+                }
+            }
+        }
+        Ok(history.applied)
+    }
+
+    /// Registered migrations not yet applied, in order.
+    pub fn pending(&self) -> PersistenceResult<Vec<Migration>> {
+        let applied = self.applied()?.len();
+        Ok(self.migrations[applied..].to_vec())
+    }
+
+    /// Apply pending migrations up to and including `target` (all when `None`).
+    ///
+    /// With `dry_run`, migrations run in memory and report their changes but
+    /// nothing is written.
+    pub fn up(&self, target: Option<u32>, dry_run: bool) -> PersistenceResult<Vec<MigrationStep>> {
+        if !dry_run {
+            self.take_over_marker()?;
+        }
+        let mut history = MigrationHistory {
+            applied: self.applied()?,
+        };
+        if let Some(target) = target {
+            if !self.migrations.iter().any(|m| m.version == target) {
+                return Err(PersistenceError::ValidationError(format!(
+                    "Unknown migration version {}",
+                    target
+                )));
+            }
+            if history.applied.iter().any(|a| a.version == target) {
+                return Err(PersistenceError::ValidationError(format!(
+                    "Migration {} is already applied",
+                    target
+                )));
+            }
+        }
+
+        let pending: Vec<Migration> = self.migrations[history.applied.len()..]
+            .iter()
+            .filter(|m| target.map_or(true, |t| m.version <= t))
+            .copied()
+            .collect();
+        if pending.is_empty() {
+            return Ok(Vec::new());
+        }
+
+        let pm = PersistenceManager::at(&self.root);
+        let mut building = pm.load_building_data()?;
+        let mut before_yaml = std::fs::read_to_string(self.building_path())?;
+        let mut staged = Vec::new();
+
+        // Run every step in memory first so a failure leaves the repository untouched.
+        for migration in &pending {
+            let changed = (migration.up)(&mut building).map_err(|e| {
+                PersistenceError::ValidationError(format!(
+                    "Migration {} ({}) failed: {}",
+                    migration.version, migration.name, e
+                ))
+            })?;
+            let after_yaml = crate::yaml::BuildingYamlSerializer::serialize_building(&building)
+                .map_err(|e| PersistenceError::SerializationError(e.to_string()))?;
+            staged.push((*migration, changed, before_yaml, after_yaml.clone()));
+            before_yaml = after_yaml;
+        }
+        let report = crate::validation::validate_building(&building);
+        if report.has_errors() {
+            return Err(PersistenceError::ValidationError(format!(
+                "Migrated building fails validation ({} error(s)); nothing written",
+                report.errors().count()
+            )));
+        }
+
+        let steps: Vec<MigrationStep> = staged
+            .iter()
+            .map(|(m, changed, _, _)| MigrationStep {
+                version: m.version,
+                name: m.name,
+                changed: *changed,
+            })
+            .collect();
+        if dry_run {
+            return Ok(steps);
+        }
+
+        let _in_progress = self.mark_in_progress()?;
+        for (migration, _, before, _) in &staged {
+            let path = self.snapshot_path(migration.version);
+            if let Some(dir) = path.parent() {
+                std::fs::create_dir_all(dir)?;
+            }
+            std::fs::write(path, before)?;
+        }
+        let (_, _, _, final_yaml) = staged.last().expect("pending is non-empty");
+        std::fs::write(self.building_path(), final_yaml)?;
+        let now = Utc::now();
+        for (migration, _, _, after) in &staged {
+            history.applied.push(AppliedMigration {
+                version: migration.version,
+                name: migration.name.to_string(),
+                applied_at: now,
+                building_sha256: sha256_hex(after.as_bytes()),
+            });
+        }
+        self.save_history(&history)?;
+        Ok(steps)
+    }
+
+    /// Roll back the most recent migration by restoring its snapshot.
+    ///
+    /// Refused when `building.yaml` changed after the migration, unless `force`.
+    pub fn down(&self, force: bool) -> PersistenceResult<Option<AppliedMigration>> {
+        self.take_over_marker()?;
+        let mut history = MigrationHistory {
+            applied: self.applied()?,
+        };
+        let Some(last) = history.applied.last().cloned() else {
+            return Ok(None);
+        };
+
+        let current = std::fs::read(self.building_path())?;
+        if !force && sha256_hex(&current) != last.building_sha256 {
+            return Err(PersistenceError::ValidationError(format!(
+                "building.yaml changed since migration {} ({}) was applied; rolling back would discard those edits (use --force)",
+                last.version, last.name
+            )));
+        }
+
+        let snapshot = self.snapshot_path(last.version);
+        if !snapshot.exists() {
+            return Err(PersistenceError::ValidationError(format!(
+                "No snapshot for migration {} at {}",
+                last.version,
+                snapshot.display()
+            )));
+        }
+        let _in_progress = self.mark_in_progress()?;
+        std::fs::copy(&snapshot, self.building_path())?;
+        std::fs::remove_file(&snapshot)?;
+        history.applied.pop();
+        self.save_history(&history)?;
+        Ok(Some(last))
+    }
+}
+
+fn sha256_hex(bytes: &[u8]) -> String {
+    Sha256::digest(bytes)
+        .iter()
+        .map(|b| format!("{:02x}", b))
+        .collect()
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::core::Floor;
+    use tempfile::TempDir;
+
+    fn rename(building: &mut Building) -> Result<usize, String> {
+        building.name.push_str(" v2");
+        Ok(1)
+    }
+
+    fn add_floor(building: &mut Building) -> Result<usize, String> {
+        building.add_floor(Floor::new("Roof".into(), building.floors.len() as i32));
+        Ok(1)
+    }
+
+    /// Adds a second floor unless there is one already.
+    fn add_floor_once(building: &mut Building) -> Result<usize, String> {
+        if building.floors.len() > 1 {
+            return Ok(0);
+        }
+        add_floor(building)
+    }
+
+    fn no_op(_: &mut Building) -> Result<usize, String> {
+        Ok(0)
+    }
+
+    fn fail(_: &mut Building) -> Result<usize, String> {
+        Err("boom".into())
+    }
+
+    fn setup() -> TempDir {
+        let temp = TempDir::new().unwrap();
+        let mut building = Building::new("Plant".into(), "/plant".into());
+        building.add_floor(Floor::new("Ground".into(), 0));
+        PersistenceManager::at(temp.path())
+            .save_building_unchecked(&building)
+            .unwrap();
+        temp
+    }
+
+    fn runner(
+        root: &Path,
+        migrations: &[(u32, fn(&mut Building) -> Result<usize, String>)],
+    ) -> MigrationRunner {
+        let migrations = migrations
+            .iter()
+            .map(|(version, up)| Migration {
+                version: *version,
+                name: "test",
+                up: *up,
+            })
+            .collect();
+        MigrationRunner::with_migrations(root, migrations)
+    }
+
+    fn load(root: &Path) -> Building {
+        PersistenceManager::at(root).load_building_data().unwrap()
+    }
+
+    #[test]
+    fn applies_in_order_once_and_rolls_back() {
+        let temp = setup();
+        let runner = runner(temp.path(), &[(2, add_floor), (1, rename)]);
+
+        let dry = runner.up(None, true).unwrap();
+        assert_eq!(
+            dry.iter().map(|s| s.version).collect::<Vec<_>>(),
+            vec![1, 2]
+        );
+        assert!(runner.applied().unwrap().is_empty());
+
+        let steps = runner.up(Some(1), false).unwrap();
+        assert_eq!(steps.len(), 1);
+        assert_eq!(load(temp.path()).name, "Plant v2");
+        assert!(runner.up(Some(1), false).is_err());
+
+        runner.up(None, false).unwrap();
+        assert_eq!(load(temp.path()).floors.len(), 2);
+        assert!(runner.up(None, false).unwrap().is_empty());
+        assert_eq!(load(temp.path()).name, "Plant v2");
+
+        assert_eq!(runner.down(false).unwrap().unwrap().version, 2);
+        assert_eq!(load(temp.path()).floors.len(), 1);
+        assert_eq!(runner.pending().unwrap().len(), 1);
+        runner.up(None, false).unwrap();
+        assert_eq!(load(temp.path()).floors.len(), 2);
+    }
+
+    #[test]
+    fn failure_writes_nothing_and_out_of_order_is_refused() {
+        let temp = setup();
+        let before = std::fs::read_to_string(temp.path().join("building.yaml")).unwrap();
+        let failing = runner(temp.path(), &[(1, rename), (2, fail)]);
+        assert!(failing.up(None, false).is_err());
+        assert_eq!(
+            std::fs::read_to_string(temp.path().join("building.yaml")).unwrap(),
+            before
+        );
+        assert!(failing.applied().unwrap().is_empty());
+
+        // History written by a build that only knew migration 2.
+        runner(temp.path(), &[(2, add_floor)])
+            .up(None, false)
+            .unwrap();
+        assert!(runner(temp.path(), &[(1, rename), (2, add_floor)])
+            .applied()
+            .is_err());
+    }
+
+    #[test]
+    fn rollback_refuses_to_discard_later_edits() {
+        let temp = setup();
+        let runner = runner(temp.path(), &[(1, rename)]);
+        runner.up(None, false).unwrap();
+
+        let mut building = load(temp.path());
+        building.name = "Hand edit".into();
+        PersistenceManager::at(temp.path())
+            .save_building_unchecked(&building)
+            .unwrap();
+
+        assert!(runner.down(false).is_err());
+        runner.down(true).unwrap();
+        assert_eq!(load(temp.path()).name, "Plant");
+    }
+
+    #[test]
+    fn not_ready_while_migrating_or_pending() {
+        let temp = setup();
+        let runner = runner(temp.path(), &[(1, rename)]);
+        let err = runner.readiness().unwrap_err();
+        assert!(err.contains("1 pending migration(s)"), "{}", err);
+
+        // A run that is still writing (simulated by holding its marker).
+        let marker = runner.mark_in_progress().unwrap();
+        assert_eq!(runner.readiness().unwrap_err(), "migration in progress");
+        drop(marker);
+
+        runner.up(None, false).unwrap();
+        assert!(!temp.path().join(MIGRATION_IN_PROGRESS_FILE).exists());
+        assert!(runner.readiness().is_ok());
+
+        // A marker a live run holds blocks other runs too.
+        let marker = runner.mark_in_progress().unwrap();
+        assert!(runner.down(false).is_err());
+        drop(marker);
+
+        let empty = TempDir::new().unwrap();
+        assert!(MigrationRunner::new(empty.path()).readiness().is_ok());
+    }
+
+    #[test]
+    fn upgraded_repo_without_history_is_ready_when_nothing_changes() {
+        let temp = setup();
+        assert!(!temp.path().join(MIGRATIONS_FILE).exists());
+        // The shipped migrations have nothing to backfill in this building.
+        assert!(MigrationRunner::new(temp.path()).readiness().is_ok());
+        assert!(runner(temp.path(), &[(1, no_op)]).readiness().is_ok());
+
+        let err = runner(temp.path(), &[(1, no_op), (2, rename)])
+            .readiness()
+            .unwrap_err();
+        assert!(err.contains("1 pending migration(s), next 2"), "{}", err);
+        assert!(!temp.path().join(MIGRATIONS_FILE).exists());
+    }
+
+    #[test]
+    fn interrupted_run_is_reported_and_cleared_by_the_next_run() {
+        let temp = setup();
+        let runner = runner(temp.path(), &[(1, rename)]);
+        // A run killed mid-write leaves its marker behind.
+        let marker = InProgressMarker {
+            pid: std::process::id(),
+            started_at: Utc::now() - Duration::seconds(MIGRATION_STALE_SECS + 1),
+        };
+        let path = temp.path().join(MIGRATION_IN_PROGRESS_FILE);
+        std::fs::create_dir_all(path.parent().unwrap()).unwrap();
+        std::fs::write(&path, serde_yaml::to_string(&marker).unwrap()).unwrap();
+
+        let err = runner.readiness().unwrap_err();
+        assert!(err.starts_with("interrupted migration (pid "), "{}", err);
+        runner.up(None, false).unwrap();
+        assert!(!path.exists());
+        assert!(runner.readiness().is_ok());
+    }
+
+    #[test]
+    fn readiness_is_recomputed_when_the_building_changes() {
+        let temp = setup();
+        let runner = runner(temp.path(), &[(1, add_floor_once)]);
+        assert!(runner.readiness().is_err());
+        assert!(runner.readiness().is_err());
+
+        // Edited by hand into the migrated shape: nothing left to change.
+        let mut building = load(temp.path());
+        add_floor(&mut building).unwrap();
+        PersistenceManager::at(temp.path())
+            .save_building_unchecked(&building)
+            .unwrap();
+        assert!(runner.readiness().is_ok());
+    }
+}
@@ -4,6 +4,8 @@
 
 pub mod economy;
 pub mod manager;
+pub mod migrations;
+pub mod templates;
 
 use thiserror::Error;
 
@@ -18,6 +20,9 @@ pub enum PersistenceError {
 
     #[error("Validation error: {0}")]
     ValidationError(String),
+
+    #[error("{0}")]
+    NotFound(String),
 }
 
 impl From<serde_yaml::Error> for PersistenceError {
@@ -29,6 +34,7 @@ impl From<serde_yaml::Error> for PersistenceError {
 pub type PersistenceResult<T> = Result<T, PersistenceError>;
 
 pub use manager::{PersistenceManager, BUILDING_YAML};
+pub use migrations::{registered_migrations, Migration, MigrationRunner, MigrationStep};
 
 /// Load the canonical Building from `./building.yaml`.
 pub fn load_building_data_from_dir() -> Result<crate::core::Building, Box<dyn std::error::Error>> {
@@ -0,0 +1,154 @@
+//! Building template registry.
+//!
+//! A template is a clean building clone stored as `{root}/{name}/building.yaml`,
+//! by default under `~/.arxos/templates`, so every project on the machine can
+//! instantiate it. See [`crate::core::operations::clone_building`].
+
+use std::fs;
+use std::path::{Path, PathBuf};
+
+use serde::Serialize;
+
+use super::{PersistenceError, PersistenceManager, PersistenceResult};
+use crate::core::operations::{clone_building, CloneOptions};
+use crate::core::Building;
+
+/// Summary of a registered template.
+#[derive(Debug, Clone, Serialize)]
+pub struct TemplateInfo {
+    pub name: String,
+    pub floors: usize,
+    pub rooms: usize,
+    pub equipment: usize,
+}
+
+/// Default registry root: `~/.arxos/templates`.
+pub fn default_templates_dir() -> PathBuf {
+    dirs::home_dir()
+        .unwrap_or_else(|| PathBuf::from("."))
+        .join(".arxos")
+        .join("templates")
+}
+
+/// Registry root of one repository, `.arxos/templates`; used by the agent so
+/// templates registered over the API stay with the project.
+pub fn project_templates_dir(repo_root: &Path) -> PathBuf {
+    repo_root.join(".arxos").join("templates")
+}
+
+/// Template names are used as directory names: letters, digits, `-` and `_`.
+fn template_dir(root: &Path, name: &str) -> PersistenceResult<PathBuf> {
+    let valid = !name.is_empty()
+        && name
+            .chars()
+            .all(|c| c.is_ascii_alphanumeric() || c == '-' || c == '_');
+    if !valid {
+        return Err(PersistenceError::ValidationError(format!(
+            "invalid template name '{}' (use letters, digits, '-' and '_')",
+            name
+        )));
+    }
+    Ok(root.join(name))
+}
+
+/// Register a clean clone of `building` as template `name`, replacing any existing one.
+pub fn save_template(root: &Path, name: &str, building: &Building) -> PersistenceResult<PathBuf> {
+    let dir = template_dir(root, name)?;
+    fs::create_dir_all(&dir)?;
+    let template = clone_building(
+        building,
+        &CloneOptions {
+            name: name.to_string(),
+            clean: true,
+        },
+    );
+    let pm = PersistenceManager::at(&dir);
+    pm.save_building_validated(&template.building)?;
+    Ok(dir)
+}
+
+pub fn load_template(root: &Path, name: &str) -> PersistenceResult<Building> {
+    let dir = template_dir(root, name)?;
+    if !dir.exists() {
+        return Err(PersistenceError::ValidationError(format!(
+            "template '{}' not found in {}",
+            name,
+            root.display()
+        )));
+    }
+    PersistenceManager::at(&dir).load_building_data()
+}
+
+/// Registered templates, sorted by name. Directories that do not load are skipped.
+pub fn list_templates(root: &Path) -> PersistenceResult<Vec<TemplateInfo>> {
+    if !root.exists() {
+        return Ok(Vec::new());
+    }
+    let mut templates = Vec::new();
+    for entry in fs::read_dir(root)? {
+        let entry = entry?;
+        if !entry.file_type()?.is_dir() {
+            continue;
+        }
+        let name = entry.file_name().to_string_lossy().to_string();
+        let Ok(building) = PersistenceManager::at(entry.path()).load_building_data() else {
+            continue;
+        };
+        templates.push(TemplateInfo {
+            name,
+            floors: building.floors.len(),
+            rooms: building.get_all_rooms().len(),
+            equipment: building.get_all_equipment().len(),
+        });
+    }
+    templates.sort_by(|a, b| a.name.cmp(&b.name));
+    Ok(templates)
+}
+
+/// A new building named `name` from template `template`.
+pub fn instantiate_template(
+    root: &Path,
+    template: &str,
+    name: &str,
+) -> PersistenceResult<Building> {
+    let source = load_template(root, template)?;
+    let cloned = clone_building(
+        &source,
+        &CloneOptions {
+            name: name.to_string(),
+            clean: true,
+        },
+    );
+    Ok(cloned.building)
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::core::{Floor, Room, RoomType, Wing};
+
+    #[test]
+    fn registers_lists_and_instantiates_templates() {
+        let dir = tempfile::tempdir().unwrap();
+        let mut building = Building::new("Store 1".into(), "/store-1".into());
+        let mut floor = Floor::new("Sales".into(), 0);
+        let mut wing = Wing::new("Front".into());
+        wing.rooms
+            .push(Room::new("Register".into(), RoomType::Office));
+        floor.wings.push(wing);
+        building.add_floor(floor);
+
+        save_template(dir.path(), "retail-small", &building).unwrap();
+        assert!(save_template(dir.path(), "../escape", &building).is_err());
+
+        let listed = list_templates(dir.path()).unwrap();
+        assert_eq!(listed.len(), 1);
+        assert_eq!(listed[0].name, "retail-small");
+        assert_eq!(listed[0].rooms, 1);
+
+        let store = instantiate_template(dir.path(), "retail-small", "Store 2").unwrap();
+        assert_eq!(store.name, "Store 2");
+        assert_ne!(store.get_all_rooms()[0].id, building.get_all_rooms()[0].id);
+        assert!(instantiate_template(dir.path(), "missing", "Store 3").is_err());
+    }
+}
@@ -7,6 +7,7 @@
 
 use anyhow::{bail, Context, Result};
 use std::path::Path;
+use std::time::{Duration, Instant};
 
 /// Default max IFC file size (matches agent upload ceiling).
 pub const DEFAULT_MAX_IFC_BYTES: u64 = 50 * 1024 * 1024;
@@ -18,6 +19,13 @@ pub const DEFAULT_MAX_LIDAR_BYTES: u64 = 512 * 1024 * 1024;
 /// Light mode already bounds voxel map capacity; this caps stream length.
 pub const DEFAULT_MAX_LIDAR_INPUT_POINTS: usize = 20_000_000;
 
+/// Default max STEP entities held in memory while parsing one IFC file. A file within
+/// the size limit can still declare millions of tiny entities.
+pub const DEFAULT_MAX_IFC_ENTITIES: usize = 2_000_000;
+
+/// Default wall-time budget (seconds) for parsing and resolving one IFC file.
+pub const DEFAULT_MAX_IFC_PARSE_SECS: u64 = 300;
+
 fn env_u64(key: &str, default: u64) -> u64 {
     std::env::var(key)
         .ok()
@@ -47,6 +55,74 @@ pub fn max_lidar_input_points() -> usize {
     env_usize("ARX_MAX_LIDAR_INPUT_POINTS", DEFAULT_MAX_LIDAR_INPUT_POINTS)
 }
 
+/// `ARX_MAX_IFC_ENTITIES` or [`DEFAULT_MAX_IFC_ENTITIES`].
+pub fn max_ifc_entities() -> usize {
+    env_usize("ARX_MAX_IFC_ENTITIES", DEFAULT_MAX_IFC_ENTITIES)
+}
+
+/// `ARX_MAX_IFC_PARSE_SECS` or [`DEFAULT_MAX_IFC_PARSE_SECS`].
+pub fn max_ifc_parse_secs() -> u64 {
+    env_u64("ARX_MAX_IFC_PARSE_SECS", DEFAULT_MAX_IFC_PARSE_SECS)
+}
+
+/// Entity-count and wall-time budget for one parse, started when created.
+///
+/// Exceeding it aborts the import with a "too complex" error; nothing partial is
+/// written, since a truncated building would read as deletions on the next import.
+#[derive(Debug, Clone, Copy)]
+pub struct ParseBudget {
+    kind: &'static str,
+    pub max_entities: usize,
+    pub max_duration: Duration,
+    started: Instant,
+}
+
+impl ParseBudget {
+    pub fn new(kind: &'static str, max_entities: usize, max_duration: Duration) -> Self {
+        Self {
+            kind,
+            max_entities,
+            max_duration,
+            started: Instant::now(),
+        }
+    }
+
+    /// IFC budget from `ARX_MAX_IFC_ENTITIES` / `ARX_MAX_IFC_PARSE_SECS`.
+    pub fn ifc() -> Self {
+        Self::new(
+            "IFC",
+            max_ifc_entities(),
+            Duration::from_secs(max_ifc_parse_secs()),
+        )
+    }
+
+    /// Fail once `entities` or the elapsed time exceed the budget.
+    pub fn check(&self, entities: usize) -> Result<()> {
+        if entities > self.max_entities {
+            bail!(
+                "{kind} document too complex: {} entities exceeds limit {}. Re-export a lighter \
+                 model or raise the limit via env (ARX_MAX_{kind}_ENTITIES). See \
+                 docs/resource-limits.md.",
+                entities,
+                self.max_entities,
+                kind = self.kind
+            );
+        }
+        let elapsed = self.started.elapsed();
+        if elapsed > self.max_duration {
+            bail!(
+                "{kind} document too complex: parsing exceeds {}s budget ({} entities read). \
+                 Re-export a lighter model or raise the limit via env (ARX_MAX_{kind}_PARSE_SECS). \
+                 See docs/resource-limits.md.",
+                self.max_duration.as_secs(),
+                entities,
+                kind = self.kind
+            );
+        }
+        Ok(())
+    }
+}
+
 /// Refuse oversized files before expensive parse.
 pub fn check_file_size(path: &Path, max_bytes: u64, kind: &str) -> Result<()> {
     let meta = std::fs::metadata(path)
@@ -85,4 +161,17 @@ mod tests {
         let err = check_file_size(f.path(), 10, "test").unwrap_err();
         assert!(err.to_string().contains("too large"));
     }
+
+    #[test]
+    fn parse_budget_limits_entities_and_time() {
+        let budget = ParseBudget::new("IFC", 10, Duration::from_secs(60));
+        budget.check(10).unwrap();
+        let err = budget.check(11).unwrap_err().to_string();
+        assert!(err.contains("too complex") && err.contains("ARX_MAX_IFC_ENTITIES"));
+
+        let expired = ParseBudget::new("IFC", 10, Duration::ZERO);
+        std::thread::sleep(Duration::from_millis(1));
+        let err = expired.check(1).unwrap_err().to_string();
+        assert!(err.contains("exceeds 0s budget"), "{}", err);
+    }
 }
@@ -1,11 +1,16 @@
 //! Dashboard module - requires both tui and agent features.
 //!
 //! Shows agent repo context (no hardware/sensor polling — drivers deferred).
+//! Data is fetched off the UI thread on a configurable interval (`ui.dashboard_refresh_secs`,
+//! 0 disables auto-refresh) or on demand with `r`; starting a fetch cancels any in flight.
+//! Blocking work cannot be interrupted, so a cancelled fetch stops at the next
+//! [`FetchCancel`] check and its result is discarded.
 
 #![cfg(feature = "agent")]
 
 use crate::agent::dispatcher::AgentState;
 use anyhow::Result;
+use chrono::{DateTime, Local};
 use crossterm::{
     event::{self, DisableMouseCapture, EnableMouseCapture, Event, KeyCode},
     execute,
@@ -19,41 +24,258 @@ use ratatui::{
     widgets::{Block, Borders, List, ListItem, Paragraph},
     Frame, Terminal,
 };
+use std::path::PathBuf;
+use std::sync::atomic::{AtomicBool, Ordering};
 use std::sync::Arc;
-use std::time::Duration;
+use std::time::{Duration, Instant};
+use tokio::sync::mpsc;
+use tokio::task::JoinHandle;
+
+const SPINNER: [char; 4] = ['|', '/', '-', '\\'];
+
+/// Set when a fetch is superseded or the dashboard quits.
+#[derive(Debug, Clone, Default)]
+pub struct FetchCancel(Arc<AtomicBool>);
+
+impl FetchCancel {
+    pub fn cancel(&self) {
+        self.0.store(true, Ordering::SeqCst);
+    }
+
+    pub fn is_cancelled(&self) -> bool {
+        self.0.load(Ordering::SeqCst)
+    }
+
+    /// Error out if cancelled; sources call this between expensive steps.
+    pub fn check(&self) -> Result<()> {
+        if self.is_cancelled() {
+            anyhow::bail!("dashboard fetch cancelled");
+        }
+        Ok(())
+    }
+}
+
+/// Supplies the dashboard's status lines. Called on a blocking worker thread;
+/// implementations should return early once `cancel` is set.
+pub trait DashboardSource: Send + Sync {
+    fn fetch(&self, cancel: &FetchCancel) -> Result<Vec<String>>;
+}
+
+/// Reads building and git status from the agent's repository.
+pub struct RepoDashboardSource {
+    repo_root: PathBuf,
+}
+
+impl RepoDashboardSource {
+    pub fn new(repo_root: PathBuf) -> Self {
+        Self { repo_root }
+    }
+}
+
+impl DashboardSource for RepoDashboardSource {
+    fn fetch(&self, cancel: &FetchCancel) -> Result<Vec<String>> {
+        let mut lines = vec![
+            format!("Repo: {}", self.repo_root.display()),
+            "Mode: agent edge bridge (git + IFC)".to_string(),
+        ];
+
+        cancel.check()?;
+        match crate::persistence::load_building_at(&self.repo_root) {
+            Ok(building) => {
+                let rooms: usize = building
+                    .floors
+                    .iter()
+                    .flat_map(|f| &f.wings)
+                    .map(|w| w.rooms.len())
+                    .sum();
+                lines.push(format!(
+                    "Building: {} ({} floors, {} rooms)",
+                    building.name,
+                    building.floors.len(),
+                    rooms
+                ));
+            }
+            Err(e) => lines.push(format!("Building: unavailable ({})", e)),
+        }
+
+        cancel.check()?;
+        match crate::agent::git::status(&self.repo_root) {
+            Ok(status) => lines.push(format!(
+                "Git: {} @ {} ({} staged, {} unstaged, {} untracked)",
+                status.branch,
+                status.last_commit.chars().take(8).collect::<String>(),
+                status.staged_changes,
+                status.unstaged_changes,
+                status.untracked
+            )),
+            Err(e) => lines.push(format!("Git: unavailable ({})", e)),
+        }
+
+        lines.push("Hardware sensors: not in this build".to_string());
+        Ok(lines)
+    }
+}
+
+type FetchResult = (u64, Result<Vec<String>>);
+
+struct InFlight {
+    handle: JoinHandle<()>,
+    cancel: FetchCancel,
+}
 
 /// TUI Dashboard App State
 pub struct App {
     pub title: String,
     pub should_quit: bool,
     pub lines: Vec<String>,
+    /// Auto-refresh cadence; `None` means manual refresh only.
+    pub refresh_interval: Option<Duration>,
+    pub last_updated: Option<DateTime<Local>>,
+    pub last_error: Option<String>,
+    source: Arc<dyn DashboardSource>,
+    generation: u64,
+    in_flight: Option<InFlight>,
+    last_refresh_started: Option<Instant>,
+    spinner_frame: usize,
+    results_tx: mpsc::UnboundedSender<FetchResult>,
+    results_rx: mpsc::UnboundedReceiver<FetchResult>,
 }
 
 impl App {
-    pub fn new(title: &str, lines: Vec<String>) -> App {
+    pub fn new(
+        title: &str,
+        source: Arc<dyn DashboardSource>,
+        refresh_interval: Option<Duration>,
+    ) -> App {
+        let (results_tx, results_rx) = mpsc::unbounded_channel();
         App {
             title: title.to_string(),
             should_quit: false,
-            lines,
+            lines: Vec::new(),
+            refresh_interval,
+            last_updated: None,
+            last_error: None,
+            source,
+            generation: 0,
+            in_flight: None,
+            last_refresh_started: None,
+            spinner_frame: 0,
+            results_tx,
+            results_rx,
+        }
+    }
+
+    pub fn is_fetching(&self) -> bool {
+        self.in_flight.is_some()
+    }
+
+    pub fn handle_key(&mut self, code: KeyCode) {
+        match code {
+            KeyCode::Char('q') | KeyCode::Esc => self.should_quit = true,
+            KeyCode::Char('r') => self.start_refresh(),
+            _ => {}
+        }
+    }
+
+    /// Start a fetch without blocking, cancelling any fetch still in flight.
+    pub fn start_refresh(&mut self) {
+        self.cancel_refresh();
+        self.generation += 1;
+        self.last_refresh_started = Some(Instant::now());
+
+        let generation = self.generation;
+        let source = Arc::clone(&self.source);
+        let tx = self.results_tx.clone();
+        let cancel = FetchCancel::default();
+        let flag = cancel.clone();
+        let handle = tokio::spawn(async move {
+            let result = tokio::task::spawn_blocking(move || source.fetch(&flag))
+                .await
+                .unwrap_or_else(|e| Err(anyhow::anyhow!("dashboard fetch panicked: {}", e)));
+            let _ = tx.send((generation, result));
+        });
+        self.in_flight = Some(InFlight { handle, cancel });
+    }
+
+    /// Signal the running fetch to stop and drop its result.
+    pub fn cancel_refresh(&mut self) {
+        if let Some(in_flight) = self.in_flight.take() {
+            in_flight.cancel.cancel();
+            in_flight.handle.abort();
+        }
+    }
+
+    /// Apply finished fetches; results from superseded fetches are dropped.
+    pub fn poll_refresh(&mut self) {
+        while let Ok((generation, result)) = self.results_rx.try_recv() {
+            if generation != self.generation {
+                continue;
+            }
+            self.in_flight = None;
+            match result {
+                Ok(lines) => {
+                    self.lines = lines;
+                    self.last_error = None;
+                    self.last_updated = Some(Local::now());
+                }
+                Err(e) => self.last_error = Some(e.to_string()),
+            }
         }
     }
+
+    /// Advance the spinner and start an auto-refresh when the interval has elapsed.
+    pub fn on_tick(&mut self) {
+        self.poll_refresh();
+        if self.is_fetching() {
+            self.spinner_frame = (self.spinner_frame + 1) % SPINNER.len();
+            return;
+        }
+        let due = match (self.refresh_interval, self.last_refresh_started) {
+            (_, None) => true,
+            (Some(interval), Some(started)) => started.elapsed() >= interval,
+            (None, Some(_)) => false,
+        };
+        if due {
+            self.start_refresh();
+        }
+    }
+
+    fn status_line(&self) -> String {
+        let updated = self
+            .last_updated
+            .map(|t| t.format("%H:%M:%S").to_string())
+            .unwrap_or_else(|| "never".to_string());
+        let mut status = if self.is_fetching() {
+            format!(
+                "{} refreshing… | last updated: {}",
+                SPINNER[self.spinner_frame], updated
+            )
+        } else {
+            format!("Last updated: {}", updated)
+        };
+        match self.refresh_interval {
+            Some(interval) => status.push_str(&format!(" | auto {}s", interval.as_secs())),
+            None => status.push_str(" | auto off"),
+        }
+        status.push_str(" | r refresh, q / Esc quit");
+        status
+    }
 }
 
 pub async fn run_dashboard(state: Arc<AgentState>) -> Result<()> {
+    let refresh_secs = crate::config::ConfigManager::new()
+        .map(|m| m.get_config().ui.dashboard_refresh_secs)
+        .unwrap_or(30);
+    let refresh_interval = (refresh_secs > 0).then(|| Duration::from_secs(refresh_secs));
+    let source = Arc::new(RepoDashboardSource::new(state.repo_root.clone()));
+    let mut app = App::new("ArxOS Agent Dashboard", source, refresh_interval);
+
     enable_raw_mode()?;
     let mut stdout = std::io::stdout();
     execute!(stdout, EnterAlternateScreen, EnableMouseCapture)?;
     let backend = CrosstermBackend::new(stdout);
     let mut terminal = Terminal::new(backend)?;
 
-    let lines = vec![
-        format!("Repo: {}", state.repo_root.display()),
-        "Mode: agent edge bridge (git + IFC)".to_string(),
-        "Hardware sensors: not in this build".to_string(),
-        "Keys: q / Esc quit".to_string(),
-    ];
-    let mut app = App::new("ArxOS Agent Dashboard", lines);
-
     let res = run_app(&mut terminal, &mut app).await;
 
     disable_raw_mode()?;
@@ -75,18 +297,18 @@ async fn run_app<B: Backend>(terminal: &mut Terminal<B>, app: &mut App) -> Resul
     let tick_rate = Duration::from_millis(250);
 
     loop {
+        app.on_tick();
         terminal.draw(|f| ui(f, app))?;
 
-        if crossterm::event::poll(tick_rate)? {
+        // Poll on a blocking thread so the spawned fetch keeps making progress.
+        if tokio::task::block_in_place(|| crossterm::event::poll(tick_rate))? {
             if let Event::Key(key) = event::read()? {
-                match key.code {
-                    KeyCode::Char('q') | KeyCode::Esc => app.should_quit = true,
-                    _ => {}
-                }
+                app.handle_key(key.code);
             }
         }
 
         if app.should_quit {
+            app.cancel_refresh();
             return Ok(());
         }
     }
@@ -96,7 +318,14 @@ fn ui(f: &mut Frame, app: &mut App) {
     let chunks = Layout::default()
         .direction(Direction::Vertical)
         .margin(1)
-        .constraints([Constraint::Length(3), Constraint::Min(5)].as_ref())
+        .constraints(
+            [
+                Constraint::Length(3),
+                Constraint::Min(5),
+                Constraint::Length(3),
+            ]
+            .as_ref(),
+        )
         .split(f.size());
 
     let title = Paragraph::new(Line::from(vec![Span::styled(
@@ -108,15 +337,118 @@ fn ui(f: &mut Frame, app: &mut App) {
     .block(Block::default().borders(Borders::ALL).title("ArxOS"));
     f.render_widget(title, chunks[0]);
 
-    let items: Vec<ListItem> = app
+    let mut items: Vec<ListItem> = app
         .lines
         .iter()
         .map(|s| ListItem::new(s.as_str()))
         .collect();
+    if let Some(err) = &app.last_error {
+        items.push(
+            ListItem::new(format!("Refresh failed: {}", err))
+                .style(Style::default().fg(Color::Red)),
+        );
+    }
     let list = List::new(items).block(
         Block::default()
             .borders(Borders::ALL)
             .title("Status (no hardware drivers)"),
     );
     f.render_widget(list, chunks[1]);
+
+    let status = Paragraph::new(app.status_line())
+        .style(Style::default().fg(Color::DarkGray))
+        .block(Block::default().borders(Borders::ALL));
+    f.render_widget(status, chunks[2]);
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use std::sync::atomic::AtomicUsize;
+
+    struct CountingSource {
+        calls: AtomicUsize,
+    }
+
+    impl DashboardSource for CountingSource {
+        fn fetch(&self, _cancel: &FetchCancel) -> Result<Vec<String>> {
+            let n = self.calls.fetch_add(1, Ordering::SeqCst) + 1;
+            Ok(vec![format!("fetch {}", n)])
+        }
+    }
+
+    /// Blocks until cancelled, then records that it stopped.
+    struct StuckSource {
+        stopped: AtomicBool,
+    }
+
+    impl DashboardSource for StuckSource {
+        fn fetch(&self, cancel: &FetchCancel) -> Result<Vec<String>> {
+            for _ in 0..400 {
+                if let Err(e) = cancel.check() {
+                    self.stopped.store(true, Ordering::SeqCst);
+                    return Err(e);
+                }
+                std::thread::sleep(Duration::from_millis(5));
+            }
+            Ok(vec!["never cancelled".to_string()])
+        }
+    }
+
+    async fn settle(app: &mut App) {
+        for _ in 0..200 {
+            app.poll_refresh();
+            if !app.is_fetching() {
+                return;
+            }
+            tokio::time::sleep(Duration::from_millis(5)).await;
+        }
+        panic!("dashboard fetch did not finish");
+    }
+
+    #[tokio::test]
+    async fn manual_refresh_fetches_and_updates_timestamp() {
+        let source = Arc::new(CountingSource {
+            calls: AtomicUsize::new(0),
+        });
+        let mut app = App::new("test", source.clone(), None);
+        assert!(app.last_updated.is_none());
+
+        app.handle_key(KeyCode::Char('r'));
+        assert!(app.is_fetching());
+        settle(&mut app).await;
+
+        assert_eq!(source.calls.load(Ordering::SeqCst), 1);
+        let first = app.last_updated.expect("timestamp set after refresh");
+        assert_eq!(app.lines, vec!["fetch 1".to_string()]);
+
+        app.handle_key(KeyCode::Char('r'));
+        settle(&mut app).await;
+        assert_eq!(source.calls.load(Ordering::SeqCst), 2);
+        assert_eq!(app.lines, vec!["fetch 2".to_string()]);
+        assert!(app.last_updated.unwrap() >= first);
+        assert!(app.status_line().contains("auto off"));
+    }
+
+    #[tokio::test]
+    async fn cancel_stops_the_blocking_fetch() {
+        let source = Arc::new(StuckSource {
+            stopped: AtomicBool::new(false),
+        });
+        let mut app = App::new("test", source.clone(), None);
+        app.start_refresh();
+        tokio::time::sleep(Duration::from_millis(20)).await;
+        app.cancel_refresh();
+        assert!(!app.is_fetching());
+
+        for _ in 0..200 {
+            if source.stopped.load(Ordering::SeqCst) {
+                app.poll_refresh();
+                assert!(app.lines.is_empty() && app.last_error.is_none());
+                return;
+            }
+            tokio::time::sleep(Duration::from_millis(5)).await;
+        }
+        panic!("cancelled fetch kept running");
+    }
 }
@@ -0,0 +1,266 @@
+//! Coordinate bounds checks.
+//!
+//! A position far outside the building (millimetres read as metres, feet mixed with
+//! metres in an import) is valid data as far as the model is concerned, but breaks
+//! every viewport that frames the building. Room and equipment positions are checked
+//! against the building's extent grown by a margin, configured in
+//! `.arxos/bounds.yaml`:
+//!
+//! ```yaml
+//! margin_m: 10
+//! mode: reject        # or `flag` (default): warn but keep the write
+//! extent:             # optional
+//!   min: { x: 0, y: 0, z: -10 }
+//!   max: { x: 120, y: 80, z: 60 }
+//! ```
+//!
+//! Without an explicit `extent` the building's global bounding box is used, then the
+//! union of its floor bounding boxes. A building with none of these is not checked.
+
+use std::path::Path;
+
+use serde::{Deserialize, Serialize};
+
+use super::building::BuildingValidationReport;
+use super::rules::{ValidationResult, ValidationSeverity};
+use super::schema::SchemaMode;
+use crate::core::spatial::{BoundingBox3D, Point3D};
+use crate::core::{Building, Equipment, Position, Room};
+
+/// Project file holding the bounds configuration.
+pub const BOUNDS_FILE: &str = ".arxos/bounds.yaml";
+
+/// Distance outside the extent (metres) tolerated by default.
+pub const DEFAULT_BOUNDS_MARGIN_M: f64 = 10.0;
+
+/// Rule id of out-of-bounds findings.
+pub const RULE_OUT_OF_BOUNDS: &str = "position.out_of_bounds";
+
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct BoundsConfig {
+    #[serde(default = "default_margin")]
+    pub margin_m: f64,
+    /// Same semantics as property schemas: `flag` warns, `reject` refuses the write.
+    #[serde(default)]
+    pub mode: SchemaMode,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub extent: Option<BoundingBox3D>,
+}
+
+fn default_margin() -> f64 {
+    DEFAULT_BOUNDS_MARGIN_M
+}
+
+impl Default for BoundsConfig {
+    fn default() -> Self {
+        Self {
+            margin_m: DEFAULT_BOUNDS_MARGIN_M,
+            mode: SchemaMode::default(),
+            extent: None,
+        }
+    }
+}
+
+/// Distance from `p` to the nearest point of `extent`; zero inside.
+fn distance_outside(extent: &BoundingBox3D, p: &Point3D) -> f64 {
+    let axis = |v: f64, min: f64, max: f64| (min - v).max(v - max).max(0.0);
+    let dx = axis(p.x, extent.min.x, extent.max.x);
+    let dy = axis(p.y, extent.min.y, extent.max.y);
+    let dz = axis(p.z, extent.min.z, extent.max.z);
+    (dx * dx + dy * dy + dz * dz).sqrt()
+}
+
+fn point(position: &Position) -> Point3D {
+    Point3D::new(position.x, position.y, position.z)
+}
+
+impl BoundsConfig {
+    /// Load `.arxos/bounds.yaml` under `base`; a missing file yields the defaults.
+    pub fn load(base: &Path) -> Result<Self, String> {
+        let path = base.join(BOUNDS_FILE);
+        if !path.exists() {
+            return Ok(Self::default());
+        }
+        let content = std::fs::read_to_string(&path)
+            .map_err(|e| format!("read {}: {}", path.display(), e))?;
+        let config: BoundsConfig = serde_yaml::from_str(&content)
+            .map_err(|e| format!("parse {}: {}", path.display(), e))?;
+        if !config.margin_m.is_finite() || config.margin_m < 0.0 {
+            return Err(format!(
+                "{}: margin_m must be zero or positive",
+                path.display()
+            ));
+        }
+        Ok(config)
+    }
+
+    /// Extent positions are checked against, before the margin is applied.
+    pub fn extent(&self, building: &Building) -> Option<BoundingBox3D> {
+        if let Some(extent) = &self.extent {
+            return Some(extent.clone());
+        }
+        if let Some(bbox) = &building.global_bounding_box {
+            return Some(BoundingBox3D::new(point(&bbox.min), point(&bbox.max)));
+        }
+        let corners: Vec<Point3D> = building
+            .floors
+            .iter()
+            .filter_map(|f| f.bounding_box.as_ref())
+            .flat_map(|b| [b.min, b.max])
+            .collect();
+        BoundingBox3D::from_points(&corners)
+    }
+
+    /// How far beyond extent + margin each point lies, for the furthest one.
+    fn excess(&self, building: &Building, points: &[Point3D]) -> Option<f64> {
+        let extent = self.extent(building)?;
+        points
+            .iter()
+            .map(|p| distance_outside(&extent, p))
+            .filter(|d| *d > self.margin_m)
+            .reduce(f64::max)
+    }
+
+    pub fn check_equipment(&self, building: &Building, equipment: &Equipment) -> Option<String> {
+        self.excess(building, &[point(&equipment.position)])
+            .map(|d| {
+                format!(
+                    "equipment '{}' at ({:.2}, {:.2}, {:.2}) is {:.1} m outside the building extent",
+                    equipment.name,
+                    equipment.position.x,
+                    equipment.position.y,
+                    equipment.position.z,
+                    d
+                )
+            })
+    }
+
+    pub fn check_room(&self, building: &Building, room: &Room) -> Option<String> {
+        let spatial = &room.spatial_properties;
+        let points = [
+            point(&spatial.position),
+            point(&spatial.bounding_box.min),
+            point(&spatial.bounding_box.max),
+        ];
+        self.excess(building, &points).map(|d| {
+            format!(
+                "room '{}' extends {:.1} m outside the building extent",
+                room.name, d
+            )
+        })
+    }
+
+    fn gate(&self, finding: Option<String>) -> Result<(), String> {
+        let Some(message) = finding else {
+            return Ok(());
+        };
+        match self.mode {
+            SchemaMode::Reject => Err(message),
+            SchemaMode::Flag => {
+                log::warn!("{}", message);
+                Ok(())
+            }
+        }
+    }
+
+    /// Write-time check: an error in `reject` mode, a logged warning in `flag` mode.
+    pub fn gate_equipment(&self, building: &Building, equipment: &Equipment) -> Result<(), String> {
+        self.gate(self.check_equipment(building, equipment))
+    }
+
+    /// Write-time check for a room; see [`Self::gate_equipment`].
+    pub fn gate_room(&self, building: &Building, room: &Room) -> Result<(), String> {
+        self.gate(self.check_room(building, room))
+    }
+
+    /// Every out-of-bounds room and equipment, as errors in `reject` mode and warnings
+    /// in `flag` mode. `field` carries the object id.
+    pub fn evaluate(&self, building: &Building) -> BuildingValidationReport {
+        let severity = match self.mode {
+            SchemaMode::Reject => ValidationSeverity::Error,
+            SchemaMode::Flag => ValidationSeverity::Warning,
+        };
+        let mut report = BuildingValidationReport::default();
+        let rooms = building
+            .get_all_rooms()
+            .into_iter()
+            .map(|r| (r.id.clone(), self.check_room(building, r)));
+        let equipment = building
+            .get_all_equipment()
+            .into_iter()
+            .map(|e| (e.id.clone(), self.check_equipment(building, e)));
+        for (id, finding) in rooms.chain(equipment) {
+            if let Some(message) = finding {
+                report.results.push(ValidationResult {
+                    rule_id: RULE_OUT_OF_BOUNDS.to_string(),
+                    message,
+                    severity,
+                    field: Some(id),
+                });
+            }
+        }
+        report
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::core::{EquipmentType, Floor};
+
+    fn building() -> Building {
+        let mut building = Building::new("HQ".into(), "/hq".into());
+        let mut floor = Floor::new("Ground".into(), 0);
+        floor.bounding_box = Some(BoundingBox3D::new(
+            Point3D::new(0.0, 0.0, 0.0),
+            Point3D::new(50.0, 30.0, 4.0),
+        ));
+        for (name, x) in [("inside", 25.0), ("marginal", 55.0), ("wild", 25_000.0)] {
+            let mut eq = Equipment::new(name.into(), format!("/{}", name), EquipmentType::HVAC);
+            eq.position.x = x;
+            eq.position.y = 10.0;
+            floor.equipment.push(eq);
+        }
+        building.add_floor(floor);
+        building
+    }
+
+    #[test]
+    fn positions_are_checked_against_extent_plus_margin() {
+        let building = building();
+        let equipment: Vec<Equipment> = building.get_all_equipment().into_iter().cloned().collect();
+        let flag = BoundsConfig::default();
+        let reject = BoundsConfig {
+            mode: SchemaMode::Reject,
+            ..Default::default()
+        };
+
+        // 5 m beyond the floor box is within the default 10 m margin.
+        for config in [&flag, &reject] {
+            assert!(config.gate_equipment(&building, &equipment[0]).is_ok());
+            assert!(config.gate_equipment(&building, &equipment[1]).is_ok());
+        }
+        assert!(flag.gate_equipment(&building, &equipment[2]).is_ok());
+        let err = reject.gate_equipment(&building, &equipment[2]).unwrap_err();
+        assert!(err.contains("24950.0 m outside"), "{}", err);
+
+        let tight = BoundsConfig {
+            margin_m: 1.0,
+            mode: SchemaMode::Reject,
+            ..Default::default()
+        };
+        assert!(tight.gate_equipment(&building, &equipment[1]).is_err());
+
+        let report = reject.evaluate(&building);
+        let flagged: Vec<&str> = report
+            .errors()
+            .map(|r| r.field.as_deref().unwrap())
+            .collect();
+        assert_eq!(flagged, vec![equipment[2].id.as_str()]);
+        assert_eq!(flag.evaluate(&building).warnings().count(), 1);
+
+        let unbounded = Building::new("Empty".into(), "/empty".into());
+        assert!(reject.extent(&unbounded).is_none());
+        assert!(reject.gate_equipment(&unbounded, &equipment[2]).is_ok());
+    }
+}
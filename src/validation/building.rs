@@ -1,5 +1,6 @@
 //! Post-ingest validation of the canonical `Building` model.
 
+use crate::core::operations::without_deleted;
 use crate::core::Building;
 use crate::ifc::mapping::COORD_BUILDING_LOCAL;
 use super::rules::{ValidationResult, ValidationSeverity};
@@ -61,7 +62,10 @@ impl BuildingValidationReport {
 }
 
 /// Validate structural and semantic invariants of a building after any ingest path.
+/// Soft-deleted equipment is not checked.
 pub fn validate_building(building: &Building) -> BuildingValidationReport {
+    let building = without_deleted(building);
+    let building = building.as_ref();
     let mut report = BuildingValidationReport::default();
 
     if building.name.trim().is_empty() {
@@ -138,7 +142,35 @@ pub fn validate_building(building: &Building) -> BuildingValidationReport {
         }
     }
 
+    let mut seen_levels = std::collections::HashSet::new();
+    let mut by_level: Vec<&crate::core::Floor> = building.floors.iter().collect();
+    by_level.sort_by_key(|f| f.level);
+    for pair in by_level.windows(2) {
+        if let (Some(lower), Some(upper)) = (pair[0].elevation, pair[1].elevation) {
+            if upper < lower && pair[0].level != pair[1].level {
+                report.results.push(ValidationResult {
+                    rule_id: "floor.elevation.order".into(),
+                    message: format!(
+                        "Floor '{}' (level {}) is below floor '{}' (level {})",
+                        pair[1].name, pair[1].level, pair[0].name, pair[0].level
+                    ),
+                    severity: ValidationSeverity::Warning,
+                    field: Some(format!("floor[{}].elevation", pair[1].level)),
+                });
+            }
+        }
+    }
+
     for floor in &building.floors {
+        if !seen_levels.insert(floor.level) {
+            report.results.push(ValidationResult {
+                rule_id: "floor.level.duplicate".into(),
+                message: format!("More than one floor has level {}", floor.level),
+                severity: ValidationSeverity::Error,
+                field: Some(format!("floor[{}].level", floor.level)),
+            });
+        }
+
         if floor.name.trim().is_empty() {
             report.results.push(ValidationResult {
                 rule_id: "floor.name.required".into(),
@@ -0,0 +1,402 @@
+//! Custom metadata fields.
+//!
+//! Organizations track attributes the model has no field for (LEED rating, cost
+//! centre, facility manager). Values live in the existing free-form maps: building
+//! `metadata.properties`, floor and room `properties`. Field definitions in
+//! `.arxos/custom_fields.yaml` give them a type and make them required, either for
+//! everyone or for one organization:
+//!
+//! ```yaml
+//! fields:
+//!   leed_rating: { type: string, enum: [certified, silver, gold, platinum] }
+//! organizations:
+//!   acme:
+//!     cost_center: { type: string, pattern: "^CC-[0-9]+$", required: true }
+//!     usable_area_m2: { type: number, min: 0, applies_to: [floor, room] }
+//! ```
+//!
+//! An organization's definition replaces a global one of the same name. Fields apply
+//! to buildings unless `applies_to` says otherwise. Keys without a definition are
+//! stored untyped, as before, but only defined fields can be written through the
+//! agent ([`CustomFields::check_writable`]). Keys the model reads itself
+//! ([`RESERVED_KEYS`]) are never custom fields. Writes are checked with
+//! [`CustomFields::check_values`];
+//! [`CustomFields::typed_values`] returns values as JSON numbers and booleans, and
+//! [`CustomFields::filter_buildings`] selects buildings by a field value.
+
+use std::collections::{BTreeMap, HashMap};
+use std::path::Path;
+
+use serde::{Deserialize, Serialize};
+
+use super::schema::{PropertySpec, SchemaViolation, TypeSchema, ValueKind};
+use crate::core::review::{
+    PROP_PHOTO_REF, PROP_REVIEW_STATUS, PROP_VALIDATED_AT, PROP_VALIDATED_BY,
+};
+use crate::core::spatial::geo::{GEO_ELEVATION, GEO_LATITUDE, GEO_LONGITUDE, GEO_TRUE_NORTH};
+use crate::core::Building;
+
+/// Project file holding custom field definitions.
+pub const CUSTOM_FIELDS_FILE: &str = ".arxos/custom_fields.yaml";
+
+/// Property keys with a meaning to the model (site reference, field review); they
+/// cannot be defined or written as custom fields.
+pub const RESERVED_KEYS: &[&str] = &[
+    GEO_LATITUDE,
+    GEO_LONGITUDE,
+    GEO_ELEVATION,
+    GEO_TRUE_NORTH,
+    PROP_REVIEW_STATUS,
+    PROP_VALIDATED_BY,
+    PROP_VALIDATED_AT,
+    PROP_PHOTO_REF,
+];
+
+pub fn is_reserved(name: &str) -> bool {
+    RESERVED_KEYS.contains(&name)
+}
+
+/// Kind of object a field is set on.
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Serialize, Deserialize)]
+#[serde(rename_all = "lowercase")]
+pub enum FieldScope {
+    Building,
+    Floor,
+    Room,
+}
+
+#[derive(Debug, Clone, Default, Serialize, Deserialize)]
+pub struct FieldDefinition {
+    /// Type and constraints, as in property schemas.
+    #[serde(flatten)]
+    pub spec: PropertySpec,
+    #[serde(default)]
+    pub required: bool,
+    /// Objects the field is set on; buildings when empty.
+    #[serde(default, skip_serializing_if = "Vec::is_empty")]
+    pub applies_to: Vec<FieldScope>,
+}
+
+impl FieldDefinition {
+    pub fn applies_to(&self, scope: FieldScope) -> bool {
+        if self.applies_to.is_empty() {
+            scope == FieldScope::Building
+        } else {
+            self.applies_to.contains(&scope)
+        }
+    }
+
+    /// `value` as JSON of the declared type; strings that do not parse stay strings.
+    pub fn typed_value(&self, value: &str) -> serde_json::Value {
+        let trimmed = value.trim();
+        let typed = match self.spec.kind {
+            Some(ValueKind::Number) => trimmed
+                .parse::<f64>()
+                .ok()
+                .and_then(serde_json::Number::from_f64)
+                .map(serde_json::Value::Number),
+            Some(ValueKind::Integer) => trimmed.parse::<i64>().ok().map(serde_json::Value::from),
+            Some(ValueKind::Boolean) => trimmed.parse::<bool>().ok().map(serde_json::Value::Bool),
+            Some(ValueKind::String) | None => None,
+        };
+        typed.unwrap_or_else(|| serde_json::Value::String(value.to_string()))
+    }
+
+    /// Whether a stored value equals `wanted`: numerically for numbers, otherwise
+    /// case-insensitively.
+    pub fn value_matches(&self, stored: &str, wanted: &str) -> bool {
+        let (stored, wanted) = (stored.trim(), wanted.trim());
+        if matches!(self.spec.kind, Some(ValueKind::Number | ValueKind::Integer)) {
+            if let (Ok(a), Ok(b)) = (stored.parse::<f64>(), wanted.parse::<f64>()) {
+                return a == b;
+            }
+        }
+        stored.eq_ignore_ascii_case(wanted)
+    }
+}
+
+#[derive(Debug, Clone, Default, Serialize, Deserialize)]
+pub struct CustomFields {
+    /// Definitions for every organization.
+    #[serde(default)]
+    pub fields: BTreeMap<String, FieldDefinition>,
+    /// Per-organization definitions, keyed by organization name.
+    #[serde(default)]
+    pub organizations: BTreeMap<String, BTreeMap<String, FieldDefinition>>,
+}
+
+/// Custom field values of a building (its metadata properties).
+pub fn building_values(building: &Building) -> HashMap<String, String> {
+    building
+        .metadata
+        .as_ref()
+        .map(|m| m.properties.clone())
+        .unwrap_or_default()
+}
+
+fn check_definitions(fields: &BTreeMap<String, FieldDefinition>) -> Result<(), String> {
+    if fields.keys().any(|name| name.trim().is_empty()) {
+        return Err("field names must not be empty".into());
+    }
+    if let Some(name) = fields.keys().find(|name| is_reserved(name.trim())) {
+        return Err(format!("'{}' is a reserved property", name));
+    }
+    TypeSchema {
+        required: Vec::new(),
+        properties: fields
+            .iter()
+            .map(|(name, def)| (name.clone(), def.spec.clone()))
+            .collect(),
+    }
+    .validate()
+}
+
+impl CustomFields {
+    /// Load `.arxos/custom_fields.yaml` under `base`; a missing file defines no fields.
+    pub fn load(base: &Path) -> Result<Self, String> {
+        let path = base.join(CUSTOM_FIELDS_FILE);
+        if !path.exists() {
+            return Ok(Self::default());
+        }
+        let content = std::fs::read_to_string(&path)
+            .map_err(|e| format!("read {}: {}", path.display(), e))?;
+        let fields: CustomFields = serde_yaml::from_str(&content)
+            .map_err(|e| format!("parse {}: {}", path.display(), e))?;
+        fields.check()?;
+        Ok(fields)
+    }
+
+    pub fn save(&self, base: &Path) -> Result<(), String> {
+        let path = base.join(CUSTOM_FIELDS_FILE);
+        if let Some(parent) = path.parent() {
+            std::fs::create_dir_all(parent)
+                .map_err(|e| format!("create {}: {}", parent.display(), e))?;
+        }
+        let content = serde_yaml::to_string(self).map_err(|e| e.to_string())?;
+        std::fs::write(&path, content).map_err(|e| format!("write {}: {}", path.display(), e))
+    }
+
+    /// Reject empty names, invalid patterns and inverted bounds.
+    pub fn check(&self) -> Result<(), String> {
+        check_definitions(&self.fields)?;
+        for (org, fields) in &self.organizations {
+            check_definitions(fields).map_err(|e| format!("{}: {}", org, e))?;
+        }
+        Ok(())
+    }
+
+    /// Add or replace a definition, for `organization` or (`None`) for everyone.
+    pub fn define(
+        &mut self,
+        organization: Option<&str>,
+        name: &str,
+        definition: FieldDefinition,
+    ) -> Result<(), String> {
+        let name = name.trim();
+        check_definitions(&BTreeMap::from([(name.to_string(), definition.clone())]))?;
+        let fields = match organization {
+            Some(org) => self.organizations.entry(org.to_string()).or_default(),
+            None => &mut self.fields,
+        };
+        fields.insert(name.to_string(), definition);
+        Ok(())
+    }
+
+    /// Definitions visible to `organization`: global ones, replaced by its own.
+    pub fn definitions(&self, organization: Option<&str>) -> BTreeMap<String, FieldDefinition> {
+        let mut resolved = self.fields.clone();
+        if let Some(own) = organization.and_then(|org| self.organizations.get(org)) {
+            resolved.extend(own.iter().map(|(k, v)| (k.clone(), v.clone())));
+        }
+        resolved
+    }
+
+    /// Whether `name` may be written on an object of `scope`: it must be a field
+    /// defined for that scope, and not reserved.
+    pub fn check_writable(
+        &self,
+        organization: Option<&str>,
+        scope: FieldScope,
+        name: &str,
+    ) -> Result<(), String> {
+        if is_reserved(name) {
+            return Err(format!("'{}' is a reserved property", name));
+        }
+        match self.definitions(organization).get(name) {
+            Some(def) if def.applies_to(scope) => Ok(()),
+            Some(_) => Err("field does not apply to this kind of object".to_string()),
+            None => Err("no custom field is defined with this name".to_string()),
+        }
+    }
+
+    /// Defined fields of an object of `scope` that are missing or do not conform.
+    pub fn check_values(
+        &self,
+        organization: Option<&str>,
+        scope: FieldScope,
+        values: &HashMap<String, String>,
+    ) -> Vec<SchemaViolation> {
+        let mut violations = Vec::new();
+        for (name, def) in self.definitions(organization) {
+            if !def.applies_to(scope) {
+                continue;
+            }
+            match values.get(&name).filter(|v| !v.trim().is_empty()) {
+                None if def.required => violations.push(SchemaViolation {
+                    property: name,
+                    message: "required field is missing".to_string(),
+                }),
+                None => {}
+                Some(value) => {
+                    if let Some(message) = def.spec.check(value) {
+                        violations.push(SchemaViolation {
+                            property: name,
+                            message,
+                        });
+                    }
+                }
+            }
+        }
+        violations
+    }
+
+    /// Defined fields set on an object of `scope`, as typed JSON values.
+    pub fn typed_values(
+        &self,
+        organization: Option<&str>,
+        scope: FieldScope,
+        values: &HashMap<String, String>,
+    ) -> BTreeMap<String, serde_json::Value> {
+        self.definitions(organization)
+            .into_iter()
+            .filter(|(_, def)| def.applies_to(scope))
+            .filter_map(|(name, def)| {
+                let value = def.typed_value(values.get(&name)?);
+                Some((name, value))
+            })
+            .collect()
+    }
+
+    /// Buildings whose `field` equals `value`. Undefined fields compare as strings.
+    pub fn filter_buildings<'a>(
+        &self,
+        organization: Option<&str>,
+        buildings: impl IntoIterator<Item = &'a Building>,
+        field: &str,
+        value: &str,
+    ) -> Vec<&'a Building> {
+        let def = self
+            .definitions(organization)
+            .remove(field)
+            .unwrap_or_default();
+        buildings
+            .into_iter()
+            .filter(|b| {
+                b.metadata
+                    .as_ref()
+                    .and_then(|m| m.properties.get(field))
+                    .is_some_and(|stored| def.value_matches(stored, value))
+            })
+            .collect()
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn definition(kind: ValueKind, required: bool) -> FieldDefinition {
+        FieldDefinition {
+            spec: PropertySpec {
+                kind: Some(kind),
+                ..Default::default()
+            },
+            required,
+            applies_to: Vec::new(),
+        }
+    }
+
+    #[test]
+    fn org_fields_are_validated_and_filterable() {
+        let dir = tempfile::tempdir().unwrap();
+        let mut config = CustomFields::default();
+        let mut leed = definition(ValueKind::String, false);
+        leed.spec.allowed = vec!["silver".into(), "gold".into()];
+        config.define(None, "leed_rating", leed).unwrap();
+        let mut cost_center = definition(ValueKind::String, true);
+        cost_center.spec.pattern = Some("^CC-[0-9]+$".into());
+        config
+            .define(Some("acme"), "cost_center", cost_center)
+            .unwrap();
+        let mut area = definition(ValueKind::Number, false);
+        area.applies_to = vec![FieldScope::Floor];
+        config.define(Some("acme"), "area_m2", area).unwrap();
+        let mut broken = definition(ValueKind::Number, false);
+        broken.spec.pattern = Some("(".into());
+        assert!(config.define(None, "broken", broken).is_err());
+        let latitude = definition(ValueKind::Number, false);
+        assert!(config.define(None, GEO_LATITUDE, latitude).is_err());
+        config.save(dir.path()).unwrap();
+        let fields = CustomFields::load(dir.path()).unwrap();
+
+        let mut hq = Building::new("HQ".into(), "/hq".into());
+        hq.add_metadata_property("leed_rating".into(), "gold".into());
+        hq.add_metadata_property("cost_center".into(), "CC-12".into());
+        assert!(fields
+            .check_values(Some("acme"), FieldScope::Building, &building_values(&hq))
+            .is_empty());
+
+        // Non-conforming and missing values; the cost centre is only acme's field.
+        let mut annex = Building::new("Annex".into(), "/annex".into());
+        annex.add_metadata_property("leed_rating".into(), "bronze".into());
+        let violations =
+            fields.check_values(Some("acme"), FieldScope::Building, &building_values(&annex));
+        let properties: Vec<&str> = violations.iter().map(|v| v.property.as_str()).collect();
+        assert_eq!(properties, vec!["cost_center", "leed_rating"]);
+        assert_eq!(
+            fields
+                .check_values(
+                    Some("globex"),
+                    FieldScope::Building,
+                    &building_values(&annex)
+                )
+                .len(),
+            1
+        );
+
+        let floor_values = HashMap::from([("area_m2".to_string(), "1250.5".to_string())]);
+        assert!(fields
+            .check_values(Some("acme"), FieldScope::Floor, &floor_values)
+            .is_empty());
+        assert_eq!(
+            fields.typed_values(Some("acme"), FieldScope::Floor, &floor_values)["area_m2"],
+            serde_json::json!(1250.5)
+        );
+        let bad_area = HashMap::from([("area_m2".to_string(), "large".to_string())]);
+        assert_eq!(
+            fields.check_values(Some("acme"), FieldScope::Floor, &bad_area)[0].message,
+            "'large' is not a number"
+        );
+
+        // Only defined, unreserved fields of the right scope are writable.
+        assert!(fields
+            .check_writable(Some("acme"), FieldScope::Floor, "area_m2")
+            .is_ok());
+        for (scope, name) in [
+            (FieldScope::Building, "area_m2"),
+            (FieldScope::Building, "nickname"),
+            (FieldScope::Building, GEO_LONGITUDE),
+            (FieldScope::Floor, "cost_center"),
+        ] {
+            assert!(fields.check_writable(Some("acme"), scope, name).is_err());
+        }
+
+        let buildings = [hq, annex];
+        let gold = fields.filter_buildings(Some("acme"), &buildings, "leed_rating", "Gold");
+        assert_eq!(gold.len(), 1);
+        assert_eq!(gold[0].name, "HQ");
+        assert!(fields
+            .filter_buildings(Some("acme"), &buildings, "cost_center", "CC-99")
+            .is_empty());
+    }
+}
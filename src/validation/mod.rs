@@ -1,7 +1,17 @@
 //! Validation rules and constraints engine
 
+pub mod bounds;
 pub mod building;
+pub mod custom_fields;
+pub mod quality;
 pub mod rules;
+pub mod ruleset;
+pub mod schema;
 
+pub use bounds::BoundsConfig;
 pub use building::{validate_building, BuildingValidationReport, STRICT_ADDRESSES};
+pub use custom_fields::{CustomFields, FieldDefinition, FieldScope};
+pub use quality::{score_building, QualityFactor, QualityScore, QualityWeights};
 pub use rules::{ValidationResult, ValidationRule, ValidationRuleType, ValidationSeverity};
+pub use ruleset::{starter_ruleset, Condition, NamePattern, ObjectRule, RuleSet, RuleTarget};
+pub use schema::{PropertySchemas, SchemaMode};
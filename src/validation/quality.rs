@@ -0,0 +1,430 @@
+//! Composite data-quality score for a building.
+//!
+//! Five factors, each scored 0.0–1.0, are combined by weight into a 0–100 score:
+//!
+//! - `confidence` — mean LiDAR confidence (authored/IFC objects count as 1.0)
+//! - `reviewed` — share of auto-detected objects a human has accepted
+//! - `required_properties` — share of objects passing the rule set
+//! - `collisions` — room footprints overlapping another room on the same floor
+//! - `hierarchy` — equipment room references that resolve to their placement
+//!
+//! Weights default to [`QualityWeights::default`] and can be overridden per
+//! project in `.arxos/quality.yaml`.
+
+use std::collections::HashSet;
+use std::path::Path;
+
+use serde::{Deserialize, Serialize};
+
+use super::ruleset::RuleSet;
+use crate::core::operations::check_hierarchy;
+use crate::core::review::{equipment_review_status, room_review_status};
+use crate::core::{Building, ReviewStatus, Room};
+
+/// Default location of a project's quality weights, relative to the project root.
+pub const QUALITY_WEIGHTS_FILE: &str = ".arxos/quality.yaml";
+
+/// Footprint overlap (m²) below which two rooms are considered touching, not colliding.
+const OVERLAP_TOLERANCE_M2: f64 = 0.01;
+
+/// Relative weight of each factor. Weights are normalized, so only ratios matter.
+#[derive(Debug, Clone, PartialEq, Serialize, Deserialize)]
+#[serde(default)]
+pub struct QualityWeights {
+    pub confidence: f64,
+    pub reviewed: f64,
+    pub required_properties: f64,
+    pub collisions: f64,
+    pub hierarchy: f64,
+}
+
+impl Default for QualityWeights {
+    fn default() -> Self {
+        Self {
+            confidence: 0.25,
+            reviewed: 0.20,
+            required_properties: 0.25,
+            collisions: 0.15,
+            hierarchy: 0.15,
+        }
+    }
+}
+
+impl QualityWeights {
+    pub fn from_yaml(content: &str) -> Result<Self, String> {
+        let weights: Self =
+            serde_yaml::from_str(content).map_err(|e| format!("Invalid quality weights: {}", e))?;
+        weights.check()?;
+        Ok(weights)
+    }
+
+    pub fn load(path: &Path) -> Result<Self, String> {
+        let content = std::fs::read_to_string(path)
+            .map_err(|e| format!("Failed to read {}: {}", path.display(), e))?;
+        Self::from_yaml(&content)
+    }
+
+    /// Weights from `spec`, else the project's `.arxos/quality.yaml`, else defaults.
+    pub fn resolve(base: &Path, spec: Option<&str>) -> Result<Self, String> {
+        match spec {
+            Some(path) => Self::load(Path::new(path)),
+            None => {
+                let default = base.join(QUALITY_WEIGHTS_FILE);
+                if default.exists() {
+                    Self::load(&default)
+                } else {
+                    Ok(Self::default())
+                }
+            }
+        }
+    }
+
+    pub fn check(&self) -> Result<(), String> {
+        let all = [
+            self.confidence,
+            self.reviewed,
+            self.required_properties,
+            self.collisions,
+            self.hierarchy,
+        ];
+        if all.iter().any(|w| !w.is_finite() || *w < 0.0) {
+            return Err("Quality weights must be non-negative numbers".to_string());
+        }
+        if all.iter().sum::<f64>() <= 0.0 {
+            return Err("At least one quality weight must be positive".to_string());
+        }
+        Ok(())
+    }
+
+    fn total(&self) -> f64 {
+        self.confidence
+            + self.reviewed
+            + self.required_properties
+            + self.collisions
+            + self.hierarchy
+    }
+}
+
+/// One contributing factor of the score.
+#[derive(Debug, Clone, Serialize)]
+pub struct QualityFactor {
+    pub name: &'static str,
+    /// Factor score, 0.0–1.0.
+    pub score: f64,
+    /// Normalized weight, 0.0–1.0.
+    pub weight: f64,
+    /// Points lost to this factor out of 100.
+    pub deduction: f64,
+    pub detail: String,
+}
+
+#[derive(Debug, Clone, Serialize)]
+pub struct QualityScore {
+    /// Composite score, 0–100.
+    pub score: f64,
+    pub factors: Vec<QualityFactor>,
+}
+
+impl QualityScore {
+    /// Factors that lost points, largest deduction first.
+    pub fn weakest(&self) -> Vec<&QualityFactor> {
+        let mut factors: Vec<&QualityFactor> =
+            self.factors.iter().filter(|f| f.deduction > 0.0).collect();
+        factors.sort_by(|a, b| b.deduction.total_cmp(&a.deduction));
+        factors
+    }
+}
+
+/// Score `building` against `rules` (required properties) with `weights`.
+pub fn score_building(
+    building: &Building,
+    rules: &RuleSet,
+    weights: &QualityWeights,
+) -> QualityScore {
+    let rooms = building.get_all_rooms();
+    let equipment = building.get_all_equipment();
+    let objects = rooms.len() + equipment.len();
+
+    let mut factors = Vec::new();
+    let mut push = |name: &'static str, weight: f64, score: f64, detail: String| {
+        factors.push(QualityFactor {
+            name,
+            score,
+            weight,
+            deduction: 0.0,
+            detail,
+        })
+    };
+
+    // Confidence
+    let confidences: Vec<f64> = rooms
+        .iter()
+        .map(|r| r.lidar_enrichment.as_ref().map(|e| e.confidence_score))
+        .chain(
+            equipment
+                .iter()
+                .map(|e| e.lidar_enrichment.as_ref().map(|e| e.confidence_score)),
+        )
+        .map(|c| c.unwrap_or(1.0).clamp(0.0, 1.0))
+        .collect();
+    let (score, detail) = if confidences.is_empty() {
+        (0.0, "no rooms or equipment".to_string())
+    } else {
+        let mean = confidences.iter().sum::<f64>() / confidences.len() as f64;
+        (
+            mean,
+            format!("mean confidence {:.2} over {} objects", mean, objects),
+        )
+    };
+    push("confidence", weights.confidence, score, detail);
+
+    // Field review
+    let statuses: Vec<ReviewStatus> = rooms
+        .iter()
+        .filter_map(|r| room_review_status(r))
+        .chain(equipment.iter().filter_map(|e| equipment_review_status(e)))
+        .filter(|s| *s != ReviewStatus::Rejected)
+        .collect();
+    let accepted = statuses
+        .iter()
+        .filter(|s| **s == ReviewStatus::Accepted)
+        .count();
+    let score = ratio(accepted, statuses.len());
+    push(
+        "reviewed",
+        weights.reviewed,
+        score,
+        format!(
+            "{}/{} auto-detected objects accepted",
+            accepted,
+            statuses.len()
+        ),
+    );
+
+    // Required properties
+    let failing: HashSet<String> = rules
+        .evaluate(building)
+        .results
+        .into_iter()
+        .filter_map(|r| r.field)
+        .collect();
+    let score = if objects == 0 {
+        0.0
+    } else {
+        1.0 - ratio_capped(failing.len(), objects)
+    };
+    push(
+        "required_properties",
+        weights.required_properties,
+        score,
+        format!("{}/{} objects fail the rule set", failing.len(), objects),
+    );
+
+    // Collisions
+    let collisions = room_collisions(building);
+    push(
+        "collisions",
+        weights.collisions,
+        1.0 - ratio_capped(collisions, rooms.len()),
+        format!("{} overlapping room pair(s)", collisions),
+    );
+
+    // Hierarchy integrity
+    let issues = check_hierarchy(building).issues.len();
+    push(
+        "hierarchy",
+        weights.hierarchy,
+        1.0 - ratio_capped(issues, objects),
+        format!("{} hierarchy issue(s)", issues),
+    );
+
+    let total = weights.total();
+    let mut score = 0.0;
+    for factor in &mut factors {
+        factor.weight /= total;
+        factor.deduction = factor.weight * (1.0 - factor.score) * 100.0;
+        score += factor.weight * factor.score * 100.0;
+    }
+
+    QualityScore {
+        score: (score * 10.0).round() / 10.0,
+        factors,
+    }
+}
+
+/// `n / total`, treating an empty population as fully satisfied.
+fn ratio(n: usize, total: usize) -> f64 {
+    if total == 0 {
+        1.0
+    } else {
+        n as f64 / total as f64
+    }
+}
+
+fn ratio_capped(n: usize, total: usize) -> f64 {
+    if total == 0 {
+        if n == 0 {
+            0.0
+        } else {
+            1.0
+        }
+    } else {
+        (n as f64 / total as f64).min(1.0)
+    }
+}
+
+/// Count room pairs on the same floor whose XY footprints overlap.
+fn room_collisions(building: &Building) -> usize {
+    let mut count = 0;
+    for floor in &building.floors {
+        let rooms: Vec<&Room> = floor
+            .wings
+            .iter()
+            .flat_map(|w| &w.rooms)
+            .filter(|r| r.spatial_properties.bounding_box.is_valid())
+            .collect();
+        for (i, a) in rooms.iter().enumerate() {
+            for b in &rooms[i + 1..] {
+                if footprint_overlap(a, b) > OVERLAP_TOLERANCE_M2 {
+                    count += 1;
+                }
+            }
+        }
+    }
+    count
+}
+
+fn footprint_overlap(a: &Room, b: &Room) -> f64 {
+    let (a, b) = (
+        &a.spatial_properties.bounding_box,
+        &b.spatial_properties.bounding_box,
+    );
+    let dx = a.max.x.min(b.max.x) - a.min.x.max(b.min.x);
+    let dy = a.max.y.min(b.max.y) - a.min.y.max(b.min.y);
+    if dx <= 0.0 || dy <= 0.0 {
+        0.0
+    } else {
+        dx * dy
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::core::{
+        mark_proposed, Dimensions, Equipment, EquipmentType, Floor, LidarEnrichment, Position,
+        RoomType, SpatialProperties, Wing,
+    };
+    use crate::validation::starter_ruleset;
+
+    fn room(name: &str, x: f64) -> Room {
+        let mut room = Room::new(name.into(), RoomType::Storage);
+        room.spatial_properties = SpatialProperties::new(
+            Position {
+                x,
+                y: 0.0,
+                z: 0.0,
+                coordinate_system: "building_local".into(),
+            },
+            Dimensions {
+                width: 4.0,
+                depth: 4.0,
+                height: 3.0,
+            },
+            "building_local".into(),
+        );
+        room
+    }
+
+    fn hvac(name: &str, capacity: bool) -> Equipment {
+        let mut eq = Equipment::new(name.into(), format!("/eq/{}", name), EquipmentType::HVAC);
+        if capacity {
+            eq.properties.insert("capacity".into(), "10kW".into());
+        }
+        eq
+    }
+
+    fn building(rooms: Vec<Room>) -> Building {
+        let mut building = Building::new("Q".into(), "/q".into());
+        let mut floor = Floor::new("Ground".into(), 0);
+        let mut wing = Wing::new("Main".into());
+        wing.rooms = rooms;
+        floor.wings.push(wing);
+        building.add_floor(floor);
+        building
+    }
+
+    fn good_building() -> Building {
+        let mut a = room("A", 0.0);
+        a.equipment.push(hvac("AHU-1", true));
+        let b = room("B", 10.0);
+        building(vec![a, b])
+    }
+
+    fn poor_building() -> Building {
+        let mut a = room("A", 0.0);
+        a.lidar_enrichment = Some(LidarEnrichment {
+            point_count: 100,
+            confidence_score: 0.4,
+            last_scan_timestamp: None,
+            classification_heuristic: None,
+        });
+        mark_proposed(&mut a.properties);
+        for i in 0..3 {
+            let mut eq = hvac(&format!("AHU-{}", i), false);
+            eq.room_id = Some("missing-room".into());
+            a.equipment.push(eq);
+        }
+        // Overlaps A by half its footprint.
+        let b = room("B", 2.0);
+        building(vec![a, b])
+    }
+
+    fn factor<'a>(score: &'a QualityScore, name: &str) -> &'a QualityFactor {
+        score.factors.iter().find(|f| f.name == name).unwrap()
+    }
+
+    #[test]
+    fn clean_building_outscores_poor_one() {
+        let rules = starter_ruleset();
+        let weights = QualityWeights::default();
+        let good = score_building(&good_building(), &rules, &weights);
+        let poor = score_building(&poor_building(), &rules, &weights);
+
+        assert_eq!(good.score, 100.0);
+        assert!(good.weakest().is_empty());
+        assert!(poor.score < 50.0, "poor score {}", poor.score);
+        assert_eq!(
+            factor(&poor, "collisions").detail,
+            "1 overlapping room pair(s)"
+        );
+        assert_eq!(factor(&poor, "reviewed").score, 0.0);
+    }
+
+    #[test]
+    fn breakdown_follows_weights() {
+        let rules = starter_ruleset();
+        let weights = QualityWeights {
+            confidence: 0.0,
+            reviewed: 0.0,
+            required_properties: 0.0,
+            collisions: 0.0,
+            hierarchy: 1.0,
+        };
+        let poor = score_building(&poor_building(), &rules, &weights);
+        assert_eq!(poor.weakest()[0].name, "hierarchy");
+        assert_eq!(poor.weakest().len(), 1);
+
+        let defaults = score_building(&poor_building(), &rules, &QualityWeights::default());
+        let names: Vec<&str> = defaults.weakest().iter().map(|f| f.name).collect();
+        assert_eq!(names[..2], ["reviewed", "required_properties"]);
+        assert_eq!(names.len(), 5);
+
+        assert!(QualityWeights {
+            confidence: -1.0,
+            ..QualityWeights::default()
+        }
+        .check()
+        .is_err());
+    }
+}
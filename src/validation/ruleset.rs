@@ -0,0 +1,458 @@
+//! Declarative per-object rule sets (discipline / code checks).
+//!
+//! Rules are data, loaded from YAML or JSON, and evaluated against rooms or
+//! equipment. Each rule has an optional `when` guard and a `require` condition;
+//! conditions compose with `all` / `any` / `not`, and `contains` reaches from a
+//! room into its equipment.
+//!
+//! ```yaml
+//! rules:
+//!   - id: hvac.capacity
+//!     description: HVAC units need a capacity
+//!     severity: Warning
+//!     target: equipment
+//!     when: { type_is: hvac }
+//!     require: { has_property: capacity }
+//! ```
+//!
+//! Results reuse [`ValidationResult`]; `field` carries the offending object id.
+//! Rule sets are advisory and are not part of the persist hard gate.
+
+use std::path::Path;
+
+use regex::Regex;
+use serde::{Deserialize, Deserializer, Serialize, Serializer};
+
+use super::building::{validate_building, BuildingValidationReport};
+use super::rules::{ValidationResult, ValidationSeverity};
+use super::{BoundsConfig, PropertySchemas};
+use crate::core::operations::without_deleted;
+use crate::core::{Building, Equipment, Room};
+
+/// Default location of a project's rule set, relative to the project root.
+pub const RULESET_FILE: &str = ".arxos/rules.yaml";
+
+/// Object kind a rule applies to.
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Serialize, Deserialize)]
+#[serde(rename_all = "lowercase")]
+pub enum RuleTarget {
+    Room,
+    Equipment,
+}
+
+/// Composable predicate over a room or equipment item.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+#[serde(rename_all = "snake_case")]
+pub enum Condition {
+    All(Vec<Condition>),
+    Any(Vec<Condition>),
+    Not(Box<Condition>),
+    /// Property key is present and non-empty
+    HasProperty(String),
+    /// Property equals value (case-insensitive)
+    PropertyEquals {
+        key: String,
+        value: String,
+    },
+    /// Room type or equipment type (case-insensitive)
+    TypeIs(String),
+    /// Name matches a regular expression
+    NameMatches(NamePattern),
+    /// Room contains at least `min` equipment matching `matching`
+    Contains {
+        #[serde(default = "default_min")]
+        min: usize,
+        matching: Box<Condition>,
+    },
+}
+
+/// Regular expression of `name_matches`, compiled when the rule set is loaded.
+/// Serialized as its source.
+#[derive(Debug, Clone)]
+pub struct NamePattern(Regex);
+
+impl NamePattern {
+    pub fn new(pattern: &str) -> Result<Self, String> {
+        Regex::new(pattern)
+            .map(Self)
+            .map_err(|e| format!("invalid name_matches pattern '{}': {}", pattern, e))
+    }
+
+    pub fn as_str(&self) -> &str {
+        self.0.as_str()
+    }
+}
+
+impl Serialize for NamePattern {
+    fn serialize<S: Serializer>(&self, serializer: S) -> Result<S::Ok, S::Error> {
+        serializer.serialize_str(self.as_str())
+    }
+}
+
+impl<'de> Deserialize<'de> for NamePattern {
+    fn deserialize<D: Deserializer<'de>>(deserializer: D) -> Result<Self, D::Error> {
+        let pattern = String::deserialize(deserializer)?;
+        Self::new(&pattern).map_err(serde::de::Error::custom)
+    }
+}
+
+fn default_min() -> usize {
+    1
+}
+
+/// One rule in a rule set.
+#[derive(Debug, Clone, Serialize, Deserialize)]
+pub struct ObjectRule {
+    pub id: String,
+    pub description: String,
+    #[serde(default = "default_severity")]
+    pub severity: ValidationSeverity,
+    pub target: RuleTarget,
+    /// Only objects matching this guard are checked
+    #[serde(default)]
+    pub when: Option<Condition>,
+    pub require: Condition,
+}
+
+fn default_severity() -> ValidationSeverity {
+    ValidationSeverity::Warning
+}
+
+#[derive(Debug, Clone, Default, Serialize, Deserialize)]
+pub struct RuleSet {
+    #[serde(default)]
+    pub rules: Vec<ObjectRule>,
+}
+
+/// The object a condition is evaluated against.
+#[derive(Clone, Copy)]
+enum Subject<'a> {
+    Room(&'a Room),
+    Equipment(&'a Equipment),
+}
+
+impl Subject<'_> {
+    fn name(&self) -> &str {
+        match self {
+            Subject::Room(r) => &r.name,
+            Subject::Equipment(e) => &e.name,
+        }
+    }
+
+    fn type_name(&self) -> String {
+        match self {
+            Subject::Room(r) => r.room_type.to_string(),
+            Subject::Equipment(e) => e.equipment_type.to_string(),
+        }
+    }
+
+    fn property(&self, key: &str) -> Option<&str> {
+        let props = match self {
+            Subject::Room(r) => &r.properties,
+            Subject::Equipment(e) => &e.properties,
+        };
+        props.get(key).map(String::as_str)
+    }
+}
+
+impl Condition {
+    fn eval(&self, subject: Subject<'_>) -> bool {
+        match self {
+            Condition::All(cs) => cs.iter().all(|c| c.eval(subject)),
+            Condition::Any(cs) => cs.iter().any(|c| c.eval(subject)),
+            Condition::Not(c) => !c.eval(subject),
+            Condition::HasProperty(key) => {
+                subject.property(key).is_some_and(|v| !v.trim().is_empty())
+            }
+            Condition::PropertyEquals { key, value } => subject
+                .property(key)
+                .is_some_and(|v| v.trim().eq_ignore_ascii_case(value.trim())),
+            Condition::TypeIs(t) => subject.type_name().eq_ignore_ascii_case(t.trim()),
+            Condition::NameMatches(pattern) => pattern.0.is_match(subject.name()),
+            Condition::Contains { min, matching } => match subject {
+                Subject::Room(room) => {
+                    room.equipment
+                        .iter()
+                        .filter(|e| matching.eval(Subject::Equipment(e)))
+                        .count()
+                        >= *min
+                }
+                Subject::Equipment(_) => false,
+            },
+        }
+    }
+}
+
+impl RuleSet {
+    /// Parse a rule set from YAML (JSON is accepted as YAML).
+    pub fn from_yaml(content: &str) -> Result<Self, String> {
+        let set: RuleSet =
+            serde_yaml::from_str(content).map_err(|e| format!("invalid rule set: {}", e))?;
+        set.check()?;
+        Ok(set)
+    }
+
+    pub fn load(path: &Path) -> Result<Self, String> {
+        let content =
+            std::fs::read_to_string(path).map_err(|e| format!("read {}: {}", path.display(), e))?;
+        Self::from_yaml(&content)
+    }
+
+    /// Validate rule ids before evaluation. Patterns are checked when parsed.
+    pub fn check(&self) -> Result<(), String> {
+        let mut ids = std::collections::HashSet::new();
+        for rule in &self.rules {
+            if rule.id.trim().is_empty() {
+                return Err("rule id must not be empty".to_string());
+            }
+            if !ids.insert(rule.id.as_str()) {
+                return Err(format!("duplicate rule id '{}'", rule.id));
+            }
+        }
+        Ok(())
+    }
+
+    /// Append rules from another set (later sets can extend a starter set).
+    pub fn extend(&mut self, other: RuleSet) {
+        self.rules.extend(other.rules);
+    }
+
+    /// Run every rule against the building.
+    pub fn evaluate(&self, building: &Building) -> BuildingValidationReport {
+        let mut report = BuildingValidationReport::default();
+        let rooms = building.get_all_rooms();
+        let equipment = building.get_all_equipment();
+
+        for rule in &self.rules {
+            let subjects: Vec<(Subject<'_>, &str)> = match rule.target {
+                RuleTarget::Room => rooms
+                    .iter()
+                    .map(|r| (Subject::Room(r), r.id.as_str()))
+                    .collect(),
+                RuleTarget::Equipment => equipment
+                    .iter()
+                    .map(|e| (Subject::Equipment(e), e.id.as_str()))
+                    .collect(),
+            };
+
+            for (subject, id) in subjects {
+                if rule.when.as_ref().is_some_and(|w| !w.eval(subject)) {
+                    continue;
+                }
+                if !rule.require.eval(subject) {
+                    report.results.push(ValidationResult {
+                        rule_id: rule.id.clone(),
+                        message: format!("{}: {}", subject.name(), rule.description),
+                        severity: rule.severity,
+                        field: Some(id.to_string()),
+                    });
+                }
+            }
+        }
+
+        report
+    }
+}
+
+/// Built-in common code checks (`arx validate --rules starter`).
+pub fn starter_ruleset() -> RuleSet {
+    let exit_door = Condition::Any(vec![
+        Condition::TypeIs("door".into()),
+        Condition::PropertyEquals {
+            key: "exit".into(),
+            value: "true".into(),
+        },
+    ]);
+
+    RuleSet {
+        rules: vec![
+            ObjectRule {
+                id: "code.room.exit_door".into(),
+                description: "every occupiable room must have at least one exit door".into(),
+                severity: ValidationSeverity::Warning,
+                target: RuleTarget::Room,
+                when: Some(Condition::Not(Box::new(Condition::Any(vec![
+                    Condition::TypeIs("hallway".into()),
+                    Condition::TypeIs("storage".into()),
+                ])))),
+                require: Condition::Contains {
+                    min: 1,
+                    matching: Box::new(exit_door),
+                },
+            },
+            ObjectRule {
+                id: "code.panel.capacity".into(),
+                description: "electrical panels must have a capacity property".into(),
+                severity: ValidationSeverity::Error,
+                target: RuleTarget::Equipment,
+                when: Some(Condition::All(vec![
+                    Condition::TypeIs("electrical".into()),
+                    Condition::NameMatches(NamePattern::new("(?i)panel").expect("valid pattern")),
+                ])),
+                require: Condition::HasProperty("capacity".into()),
+            },
+            ObjectRule {
+                id: "code.hvac.capacity".into(),
+                description: "HVAC units need a capacity".into(),
+                severity: ValidationSeverity::Warning,
+                target: RuleTarget::Equipment,
+                when: Some(Condition::TypeIs("hvac".into())),
+                require: Condition::HasProperty("capacity".into()),
+            },
+        ],
+    }
+}
+
+/// Resolve `--rules`: `starter`, a file path, or the project's `.arxos/rules.yaml`.
+pub fn resolve_ruleset(base: &Path, spec: Option<&str>) -> Result<Option<RuleSet>, String> {
+    match spec {
+        Some("starter") => Ok(Some(starter_ruleset())),
+        Some(path) => RuleSet::load(Path::new(path)).map(Some),
+        None => {
+            let default = base.join(RULESET_FILE);
+            if default.exists() {
+                RuleSet::load(&default).map(Some)
+            } else {
+                Ok(None)
+            }
+        }
+    }
+}
+
+/// Everything `building.validate` reports: the built-in checks, `rules`, and the
+/// property schemas and floor bounds configured under `base`. Soft-deleted
+/// equipment is not checked.
+pub fn validate_project(
+    base: &Path,
+    building: &Building,
+    rules: Option<&RuleSet>,
+) -> Result<BuildingValidationReport, String> {
+    let building = without_deleted(building);
+    let building = building.as_ref();
+    let mut report = validate_building(building);
+    if let Some(set) = rules {
+        report.results.extend(set.evaluate(building).results);
+    }
+    let schemas = PropertySchemas::load(base)?;
+    report.results.extend(schemas.evaluate(building).results);
+    let bounds = BoundsConfig::load(base)?;
+    report.results.extend(bounds.evaluate(building).results);
+    Ok(report)
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::core::{EquipmentType, Floor, RoomType, Wing};
+
+    fn building_with(rooms: Vec<Room>, floor_equipment: Vec<Equipment>) -> Building {
+        let mut building = Building::new("HQ".into(), "/hq".into());
+        let mut floor = Floor::new("F0".into(), 0);
+        let mut wing = Wing::new("Main".into());
+        for room in rooms {
+            wing.add_room(room);
+        }
+        floor.add_wing(wing);
+        floor.equipment = floor_equipment;
+        building.add_floor(floor);
+        building
+    }
+
+    fn equipment(name: &str, kind: EquipmentType, props: &[(&str, &str)]) -> Equipment {
+        let mut eq = Equipment::new(name.into(), format!("/eq/{}", name), kind);
+        for (k, v) in props {
+            eq.properties.insert(k.to_string(), v.to_string());
+        }
+        eq
+    }
+
+    #[test]
+    fn starter_rules_flag_violations_and_pass_compliant_objects() {
+        let mut office = Room::new("Office 1".into(), RoomType::Office);
+        office
+            .equipment
+            .push(equipment("D1", EquipmentType::Other("Door".into()), &[]));
+        let lab = Room::new("Lab".into(), RoomType::Laboratory);
+        let hall = Room::new("Hall".into(), RoomType::Hallway);
+
+        let building = building_with(
+            vec![office, lab.clone(), hall],
+            vec![
+                equipment("Panel A", EquipmentType::Electrical, &[]),
+                equipment(
+                    "Panel B",
+                    EquipmentType::Electrical,
+                    &[("capacity", "200A")],
+                ),
+                equipment("AHU-1", EquipmentType::HVAC, &[("capacity", "10 ton")]),
+                equipment("AHU-2", EquipmentType::HVAC, &[]),
+            ],
+        );
+
+        let report = starter_ruleset().evaluate(&building);
+        let ids: Vec<&str> = report.results.iter().map(|r| r.rule_id.as_str()).collect();
+        assert_eq!(
+            ids,
+            vec![
+                "code.room.exit_door",
+                "code.panel.capacity",
+                "code.hvac.capacity"
+            ]
+        );
+        assert_eq!(report.results[0].field.as_deref(), Some(lab.id.as_str()));
+        assert!(report.has_errors());
+    }
+
+    #[test]
+    fn yaml_rules_compose() {
+        let set = RuleSet::from_yaml(
+            r#"{"rules": [{
+                "id": "lab.safety",
+                "description": "labs need an eyewash and a shower",
+                "severity": "Error",
+                "target": "room",
+                "when": {"type_is": "laboratory"},
+                "require": {"all": [
+                    {"contains": {"matching": {"name_matches": "(?i)eyewash"}}},
+                    {"contains": {"min": 1, "matching": {"name_matches": "(?i)shower"}}}
+                ]}
+            }]}"#,
+        )
+        .unwrap();
+
+        let mut ok = Room::new("Lab A".into(), RoomType::Laboratory);
+        ok.equipment
+            .push(equipment("Eyewash", EquipmentType::Safety, &[]));
+        ok.equipment
+            .push(equipment("Shower", EquipmentType::Safety, &[]));
+        let mut bad = Room::new("Lab B".into(), RoomType::Laboratory);
+        bad.equipment
+            .push(equipment("Eyewash 2", EquipmentType::Safety, &[]));
+
+        let report = set.evaluate(&building_with(vec![ok, bad.clone()], vec![]));
+        assert_eq!(report.results.len(), 1);
+        let saved = serde_json::to_value(&set).unwrap();
+        assert_eq!(
+            saved["rules"][0]["require"]["all"][0]["contains"]["matching"]["name_matches"],
+            "(?i)eyewash"
+        );
+        assert_eq!(report.results[0].field.as_deref(), Some(bad.id.as_str()));
+        assert_eq!(report.results[0].severity, ValidationSeverity::Error);
+    }
+
+    #[test]
+    fn invalid_rule_sets_are_rejected() {
+        assert!(RuleSet::from_yaml(
+            r#"{"rules": [{"id": "x", "description": "d", "target": "room",
+                "require": {"name_matches": "("}}]}"#
+        )
+        .is_err());
+        assert!(RuleSet::from_yaml(
+            r#"{"rules": [
+                {"id": "x", "description": "d", "target": "room", "require": {"has_property": "a"}},
+                {"id": "x", "description": "d", "target": "room", "require": {"has_property": "b"}}
+            ]}"#
+        )
+        .is_err());
+    }
+}
@@ -0,0 +1,467 @@
+//! Per-type property schemas.
+//!
+//! Room and equipment `properties` are free-form strings, so nothing stops a panel
+//! from losing its `capacity` or a unit from recording `tons: lots`. A project can
+//! declare the expected properties per type in `.arxos/property_schemas.yaml`:
+//!
+//! ```yaml
+//! version: 3
+//! mode: reject        # or `flag` (default): warn but keep the write
+//! equipment:
+//!   electrical:
+//!     required: [capacity]
+//!     properties:
+//!       capacity: { type: number, min: 0 }
+//!       phase: { type: string, enum: ["1", "3"] }
+//! rooms:
+//!   office:
+//!     properties:
+//!       occupancy: { type: integer, min: 0 }
+//! ```
+//!
+//! Types are matched case-insensitively against the room or equipment type name.
+//! Single objects are checked when created or updated through the model operations
+//! ([`PropertySchemas::gate_room`] / [`PropertySchemas::gate_equipment`]); whole
+//! buildings through [`PropertySchemas::evaluate`]. Results carry the schema version
+//! in the rule id (`schema.v3.electrical.capacity`) so reports say which revision
+//! an object failed.
+
+use std::collections::{BTreeMap, HashMap};
+use std::path::Path;
+
+use regex::Regex;
+use serde::{Deserialize, Serialize};
+
+use super::building::BuildingValidationReport;
+use super::rules::{ValidationResult, ValidationSeverity};
+use crate::core::equipment_types::EquipmentTypeRegistry;
+use crate::core::{Building, Equipment, Room};
+
+/// Project file holding property schemas.
+pub const PROPERTY_SCHEMAS_FILE: &str = ".arxos/property_schemas.yaml";
+
+/// What a write does with a non-conforming object.
+#[derive(Debug, Clone, Copy, Default, PartialEq, Eq, Serialize, Deserialize)]
+#[serde(rename_all = "lowercase")]
+pub enum SchemaMode {
+    /// Log a warning and keep the write.
+    #[default]
+    Flag,
+    /// Refuse the write.
+    Reject,
+}
+
+#[derive(Debug, Clone, Copy, PartialEq, Eq, Serialize, Deserialize)]
+#[serde(rename_all = "lowercase")]
+pub enum ValueKind {
+    String,
+    Number,
+    Integer,
+    Boolean,
+}
+
+/// Constraints on one property value.
+#[derive(Debug, Clone, Default, Serialize, Deserialize)]
+pub struct PropertySpec {
+    #[serde(rename = "type", default, skip_serializing_if = "Option::is_none")]
+    pub kind: Option<ValueKind>,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub min: Option<f64>,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub max: Option<f64>,
+    /// Allowed values (exact match).
+    #[serde(rename = "enum", default, skip_serializing_if = "Vec::is_empty")]
+    pub allowed: Vec<String>,
+    #[serde(default, skip_serializing_if = "Option::is_none")]
+    pub pattern: Option<String>,
+}
+
+/// Schema for one room or equipment type.
+#[derive(Debug, Clone, Default, Serialize, Deserialize)]
+pub struct TypeSchema {
+    #[serde(default)]
+    pub required: Vec<String>,
+    #[serde(default)]
+    pub properties: BTreeMap<String, PropertySpec>,
+}
+
+#[derive(Debug, Clone, Default, Serialize, Deserialize)]
+pub struct PropertySchemas {
+    #[serde(default = "default_version")]
+    pub version: u32,
+    #[serde(default)]
+    pub mode: SchemaMode,
+    /// Keyed by lowercase equipment type.
+    #[serde(default)]
+    pub equipment: BTreeMap<String, TypeSchema>,
+    /// Keyed by lowercase room type.
+    #[serde(default)]
+    pub rooms: BTreeMap<String, TypeSchema>,
+}
+
+fn default_version() -> u32 {
+    1
+}
+
+/// One property that does not conform.
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub struct SchemaViolation {
+    pub property: String,
+    pub message: String,
+}
+
+impl PropertySpec {
+    pub(super) fn check(&self, value: &str) -> Option<String> {
+        let value = value.trim();
+        let number = value.parse::<f64>().ok();
+        match self.kind {
+            Some(ValueKind::Number) if number.is_none() => {
+                return Some(format!("'{}' is not a number", value))
+            }
+            Some(ValueKind::Integer) if value.parse::<i64>().is_err() => {
+                return Some(format!("'{}' is not an integer", value))
+            }
+            Some(ValueKind::Boolean) if !matches!(value, "true" | "false") => {
+                return Some(format!("'{}' is not true or false", value))
+            }
+            _ => {}
+        }
+        if let Some(n) = number {
+            if self.min.is_some_and(|min| n < min) {
+                return Some(format!("{} is below the minimum {}", n, self.min?));
+            }
+            if self.max.is_some_and(|max| n > max) {
+                return Some(format!("{} is above the maximum {}", n, self.max?));
+            }
+        }
+        if !self.allowed.is_empty() && !self.allowed.iter().any(|a| a == value) {
+            return Some(format!(
+                "'{}' is not one of {}",
+                value,
+                self.allowed.join(", ")
+            ));
+        }
+        if let Some(pattern) = &self.pattern {
+            if !Regex::new(pattern).is_ok_and(|re| re.is_match(value)) {
+                return Some(format!("'{}' does not match {}", value, pattern));
+            }
+        }
+        None
+    }
+}
+
+impl TypeSchema {
+    /// Reject invalid patterns and inverted bounds.
+    pub fn validate(&self) -> Result<(), String> {
+        for (key, spec) in &self.properties {
+            if let Some(pattern) = &spec.pattern {
+                Regex::new(pattern)
+                    .map_err(|e| format!("{}: invalid pattern '{}': {}", key, pattern, e))?;
+            }
+            if let (Some(min), Some(max)) = (spec.min, spec.max) {
+                if min > max {
+                    return Err(format!("{}: min {} > max {}", key, min, max));
+                }
+            }
+        }
+        Ok(())
+    }
+
+    fn check(&self, properties: &HashMap<String, String>) -> Vec<SchemaViolation> {
+        let mut violations = Vec::new();
+        for key in &self.required {
+            if properties.get(key).map_or(true, |v| v.trim().is_empty()) {
+                violations.push(SchemaViolation {
+                    property: key.clone(),
+                    message: "required property is missing".to_string(),
+                });
+            }
+        }
+        for (key, spec) in &self.properties {
+            let Some(value) = properties.get(key).filter(|v| !v.trim().is_empty()) else {
+                continue;
+            };
+            if let Some(message) = spec.check(value) {
+                violations.push(SchemaViolation {
+                    property: key.clone(),
+                    message,
+                });
+            }
+        }
+        violations
+    }
+}
+
+impl PropertySchemas {
+    /// Load `.arxos/property_schemas.yaml` under `base`, plus the schemas of custom
+    /// equipment types it does not cover; missing files yield no schemas.
+    pub fn load(base: &Path) -> Result<Self, String> {
+        let path = base.join(PROPERTY_SCHEMAS_FILE);
+        let mut schemas = if path.exists() {
+            let content = std::fs::read_to_string(&path)
+                .map_err(|e| format!("read {}: {}", path.display(), e))?;
+            let schemas: PropertySchemas = serde_yaml::from_str(&content)
+                .map_err(|e| format!("parse {}: {}", path.display(), e))?;
+            schemas.check()?;
+            schemas
+        } else {
+            Self::default()
+        };
+        for (key, custom) in EquipmentTypeRegistry::load(base)?.types {
+            schemas.equipment.entry(key).or_insert(custom.schema);
+        }
+        Ok(schemas)
+    }
+
+    /// Compile every pattern once so typos surface at load time.
+    pub fn check(&self) -> Result<(), String> {
+        for (type_name, schema) in self.equipment.iter().chain(&self.rooms) {
+            schema
+                .validate()
+                .map_err(|e| format!("{}.{}", type_name, e))?;
+        }
+        Ok(())
+    }
+
+    pub fn is_empty(&self) -> bool {
+        self.equipment.is_empty() && self.rooms.is_empty()
+    }
+
+    pub fn check_equipment(&self, equipment: &Equipment) -> Vec<SchemaViolation> {
+        self.equipment
+            .get(&equipment.equipment_type.to_string().to_lowercase())
+            .map(|schema| schema.check(&equipment.properties))
+            .unwrap_or_default()
+    }
+
+    pub fn check_room(&self, room: &Room) -> Vec<SchemaViolation> {
+        self.rooms
+            .get(&room.room_type.to_string().to_lowercase())
+            .map(|schema| schema.check(&room.properties))
+            .unwrap_or_default()
+    }
+
+    fn gate(&self, kind: &str, name: &str, violations: Vec<SchemaViolation>) -> Result<(), String> {
+        if violations.is_empty() {
+            return Ok(());
+        }
+        let details: Vec<String> = violations
+            .iter()
+            .map(|v| format!("{}: {}", v.property, v.message))
+            .collect();
+        let message = format!(
+            "{} '{}' does not match property schema v{}: {}",
+            kind,
+            name,
+            self.version,
+            details.join("; ")
+        );
+        match self.mode {
+            SchemaMode::Reject => Err(message),
+            SchemaMode::Flag => {
+                log::warn!("{}", message);
+                Ok(())
+            }
+        }
+    }
+
+    /// Write-time check: an error in `reject` mode, a logged warning in `flag` mode.
+    pub fn gate_equipment(&self, equipment: &Equipment) -> Result<(), String> {
+        self.gate(
+            "equipment",
+            &equipment.name,
+            self.check_equipment(equipment),
+        )
+    }
+
+    /// Write-time check for a room; see [`Self::gate_equipment`].
+    pub fn gate_room(&self, room: &Room) -> Result<(), String> {
+        self.gate("room", &room.name, self.check_room(room))
+    }
+
+    /// Every non-conforming property in the building, as errors in `reject` mode and
+    /// warnings in `flag` mode. `field` carries the object id.
+    pub fn evaluate(&self, building: &Building) -> BuildingValidationReport {
+        let severity = match self.mode {
+            SchemaMode::Reject => ValidationSeverity::Error,
+            SchemaMode::Flag => ValidationSeverity::Warning,
+        };
+        let mut report = BuildingValidationReport::default();
+        let mut push =
+            |type_name: String, id: &str, name: &str, violations: Vec<SchemaViolation>| {
+                for v in violations {
+                    report.results.push(ValidationResult {
+                        rule_id: format!("schema.v{}.{}.{}", self.version, type_name, v.property),
+                        message: format!("{}: {} {}", name, v.property, v.message),
+                        severity,
+                        field: Some(id.to_string()),
+                    });
+                }
+            };
+        for room in building.get_all_rooms() {
+            push(
+                room.room_type.to_string().to_lowercase(),
+                &room.id,
+                &room.name,
+                self.check_room(room),
+            );
+        }
+        for eq in building.get_all_equipment() {
+            push(
+                eq.equipment_type.to_string().to_lowercase(),
+                &eq.id,
+                &eq.name,
+                self.check_equipment(eq),
+            );
+        }
+        report
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::core::{EquipmentType, Floor, RoomType, Wing};
+
+    fn schemas(mode: SchemaMode) -> PropertySchemas {
+        let mut equipment = BTreeMap::new();
+        equipment.insert(
+            "electrical".to_string(),
+            TypeSchema {
+                required: vec!["capacity".into()],
+                properties: [
+                    (
+                        "capacity".to_string(),
+                        PropertySpec {
+                            kind: Some(ValueKind::Number),
+                            min: Some(0.0),
+                            ..Default::default()
+                        },
+                    ),
+                    (
+                        "phase".to_string(),
+                        PropertySpec {
+                            allowed: vec!["1".into(), "3".into()],
+                            ..Default::default()
+                        },
+                    ),
+                ]
+                .into(),
+            },
+        );
+        equipment.insert(
+            "hvac".to_string(),
+            TypeSchema {
+                required: vec![],
+                properties: [(
+                    "serial".to_string(),
+                    PropertySpec {
+                        pattern: Some("^SN-[0-9]+$".into()),
+                        ..Default::default()
+                    },
+                )]
+                .into(),
+            },
+        );
+        let mut rooms = BTreeMap::new();
+        rooms.insert(
+            "office".to_string(),
+            TypeSchema {
+                required: vec![],
+                properties: [(
+                    "occupancy".to_string(),
+                    PropertySpec {
+                        kind: Some(ValueKind::Integer),
+                        ..Default::default()
+                    },
+                )]
+                .into(),
+            },
+        );
+        PropertySchemas {
+            version: 3,
+            mode,
+            equipment,
+            rooms,
+        }
+    }
+
+    fn equipment(equipment_type: EquipmentType, props: &[(&str, &str)]) -> Equipment {
+        let mut eq = Equipment::new("Unit".into(), "/unit".into(), equipment_type);
+        for (k, v) in props {
+            eq.properties.insert(k.to_string(), v.to_string());
+        }
+        eq
+    }
+
+    #[test]
+    fn conforming_properties_pass_per_type() {
+        let s = schemas(SchemaMode::Reject);
+        let panel = equipment(
+            EquipmentType::Electrical,
+            &[("capacity", "200"), ("phase", "3")],
+        );
+        assert!(s.check_equipment(&panel).is_empty());
+        let unit = equipment(EquipmentType::HVAC, &[("serial", "SN-42")]);
+        assert!(s.gate_equipment(&unit).is_ok());
+        // Types without a schema are unconstrained.
+        let pipe = equipment(EquipmentType::Plumbing, &[("anything", "goes")]);
+        assert!(s.check_equipment(&pipe).is_empty());
+    }
+
+    #[test]
+    fn non_conforming_properties_are_rejected_or_flagged() {
+        let s = schemas(SchemaMode::Reject);
+        let panel = equipment(EquipmentType::Electrical, &[("phase", "2")]);
+        let props: Vec<String> = s
+            .check_equipment(&panel)
+            .into_iter()
+            .map(|v| v.property)
+            .collect();
+        assert_eq!(props, vec!["capacity", "phase"]);
+        assert!(s.gate_equipment(&panel).is_err());
+
+        let negative = equipment(EquipmentType::Electrical, &[("capacity", "-5")]);
+        assert!(s.check_equipment(&negative)[0].message.contains("minimum"));
+        let unit = equipment(EquipmentType::HVAC, &[("serial", "42")]);
+        assert_eq!(s.check_equipment(&unit).len(), 1);
+
+        let flagged = schemas(SchemaMode::Flag);
+        assert!(flagged.gate_equipment(&panel).is_ok());
+    }
+
+    #[test]
+    fn bulk_report_lists_non_conforming_objects() {
+        let mut building = Building::new("S".into(), "/s".into());
+        let mut floor = Floor::new("G".into(), 0);
+        let mut wing = Wing::new("W".into());
+        let mut office = Room::new("Office 1".into(), RoomType::Office);
+        office.id = "room-1".into();
+        office.properties.insert("occupancy".into(), "4.5".into());
+        let mut panel = equipment(EquipmentType::Electrical, &[("capacity", "100")]);
+        panel.id = "eq-ok".into();
+        office.equipment.push(panel);
+        wing.rooms.push(office);
+        floor.wings.push(wing);
+        let mut bad = equipment(EquipmentType::Electrical, &[]);
+        bad.id = "eq-bad".into();
+        floor.equipment.push(bad);
+        building.add_floor(floor);
+
+        let report = schemas(SchemaMode::Reject).evaluate(&building);
+        let hits: Vec<(&str, &str)> = report
+            .results
+            .iter()
+            .map(|r| (r.field.as_deref().unwrap(), r.rule_id.as_str()))
+            .collect();
+        assert_eq!(
+            hits,
+            vec![
+                ("room-1", "schema.v3.office.occupancy"),
+                ("eq-bad", "schema.v3.electrical.capacity"),
+            ]
+        );
+        assert!(report.has_errors());
+        assert!(!schemas(SchemaMode::Flag).evaluate(&building).has_errors());
+    }
+}
@@ -22,6 +22,8 @@ impl BuildingYamlSerializer {
     pub fn serialize(data: &BuildingData) -> Result<String, Box<dyn std::error::Error>> {
         let mut sorted_data = BuildingData::from_building(&data.building);
         sorted_data.sort_deterministically();
+        crate::core::precision::CoordinatePrecision::configured()
+            .apply_building(&mut sorted_data.building);
         Ok(serde_yaml::to_string(&sorted_data)?)
     }
 
@@ -224,6 +224,7 @@ equipment: []
             token: Arc::new(Mutex::new(token_state)),
             metrics: Arc::new(arxos::agent::observability::AgentMetrics::new()),
             reload_handle: None,
+            idempotency: Default::default(),
         });
 
         tokio::runtime::Runtime::new().unwrap().block_on(async {
@@ -287,6 +288,7 @@ equipment: []
             token: Arc::new(Mutex::new(token_state)),
             metrics: metrics.clone(),
             reload_handle: None,
+            idempotency: Default::default(),
         });
 
         tokio::runtime::Runtime::new().unwrap().block_on(async {